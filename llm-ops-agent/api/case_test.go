@@ -19,8 +19,10 @@ import (
 )
 
 type caseState struct {
-	status  string
-	version int64
+	title    string
+	status   string
+	version  int64
+	timeline []db.CaseTimeline
 }
 
 type fakeService struct {
@@ -40,7 +42,9 @@ func (f *fakeService) CreateCase(ctx context.Context, args ports.CreateCaseArgs)
 	}
 	id := uuid.New()
 	row := db.CreateCaseRow{CaseID: pgtype.UUID{Bytes: id, Valid: true}, TenantID: args.TenantID, Title: args.Title, Severity: "INFO", Status: string(workflow.NEW), Version: 1}
-	f.cases[id.String()] = &caseState{status: string(workflow.NEW), version: 1}
+	f.cases[id.String()] = &caseState{title: args.Title, status: string(workflow.NEW), version: 1, timeline: []db.CaseTimeline{
+		{ID: 1, CaseID: row.CaseID, Event: pgtype.Text{String: "case_created", Valid: true}},
+	}}
 	if args.IdemKey != "" {
 		f.idem[args.IdemKey] = row
 	}
@@ -63,6 +67,14 @@ func (f *fakeService) Transition(ctx context.Context, args ports.TransitionArgs)
 	}
 	cs.version++
 	cs.status = string(intent.To)
+	payload, _ := json.Marshal(intent.Timeline)
+	cs.timeline = append(cs.timeline, db.CaseTimeline{
+		ID:      int64(len(cs.timeline) + 1),
+		CaseID:  args.CaseID,
+		Actor:   pgtype.Text{String: intent.Timeline.Actor, Valid: intent.Timeline.Actor != ""},
+		Event:   pgtype.Text{String: intent.Timeline.Event, Valid: true},
+		Payload: payload,
+	})
 	row := db.UpdateCaseStatusRow{CaseID: args.CaseID, Status: string(intent.To), Version: cs.version}
 	if args.IdemKey != "" {
 		f.idem[args.IdemKey] = row
@@ -70,6 +82,26 @@ func (f *fakeService) Transition(ctx context.Context, args ports.TransitionArgs)
 	return row, nil
 }
 
+func (f *fakeService) GetCase(ctx context.Context, caseID pgtype.UUID) (db.GetCaseRow, error) {
+	cs, ok := f.cases[caseID.String()]
+	if !ok {
+		return db.GetCaseRow{}, pgx.ErrNoRows
+	}
+	return db.GetCaseRow{CaseID: caseID, TenantID: 1, Title: cs.title, Severity: "INFO", Status: cs.status, Version: cs.version}, nil
+}
+
+func (f *fakeService) ListTimeline(ctx context.Context, caseID pgtype.UUID, limit int32, latest bool) ([]db.CaseTimeline, error) {
+	cs, ok := f.cases[caseID.String()]
+	if !ok {
+		return nil, nil
+	}
+	n := min(int(limit), len(cs.timeline))
+	if latest {
+		return cs.timeline[len(cs.timeline)-n:], nil
+	}
+	return cs.timeline[:n], nil
+}
+
 func setupRouter() (*gin.Engine, *fakeService) {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
@@ -170,3 +202,93 @@ func TestFailureParked(t *testing.T) {
 		t.Fatalf("expected PARKED, got %s", resp2["status"])
 	}
 }
+
+func transition(t *testing.T, r *gin.Engine, id, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest("PATCH", "/case/"+id+"/transition", bytes.NewBufferString(body))
+	req.Header.Set("X-Actor", "oncall@example.com")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestGuardsAndReadEndpoints(t *testing.T) {
+	r, _ := setupRouter()
+	id, _ := createCase(t, r)
+	for _, body := range []string{`{"event":"start_analysis"}`, `{"event":"analysis_done"}`, `{"event":"plan_ready","plan_complete":true}`} {
+		if w := transition(t, r, id, body); w.Code != http.StatusOK {
+			t.Fatalf("%s status %d", body, w.Code)
+		}
+	}
+	if w := transition(t, r, id, `{"event":"gate_approved","gate_approved":true}`); w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 outside the change window, got %d", w.Code)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/case/"+id, nil))
+	var got map[string]any
+	json.Unmarshal(w.Body.Bytes(), &got)
+	if w.Code != http.StatusOK || got["status"] != string(workflow.WAIT_GATE) || w.Header().Get("ETag") != "4" {
+		t.Fatalf("unexpected case %d %v etag %q", w.Code, got, w.Header().Get("ETag"))
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/case/"+id+"/timeline?limit=3", nil))
+	var tl struct {
+		Items []map[string]any `json:"items"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &tl)
+	if w.Code != http.StatusOK || len(tl.Items) != 3 || tl.Items[0]["event"] != "case_created" || tl.Items[1]["actor"] != "oncall@example.com" {
+		t.Fatalf("unexpected timeline %d %v", w.Code, tl.Items)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/case/"+uuid.NewString(), nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown case, got %d", w.Code)
+	}
+}
+
+func TestGuardFactsNeedAnActorAndAreRecorded(t *testing.T) {
+	r, _ := setupRouter()
+	id, _ := createCase(t, r)
+	for _, body := range []string{`{"event":"start_analysis"}`, `{"event":"analysis_done"}`} {
+		if w := transition(t, r, id, body); w.Code != http.StatusOK {
+			t.Fatalf("%s status %d", body, w.Code)
+		}
+	}
+	req := httptest.NewRequest("PATCH", "/case/"+id+"/transition", bytes.NewBufferString(`{"event":"plan_ready","plan_complete":true}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for guard facts without X-Actor, got %d", w.Code)
+	}
+	if w := transition(t, r, id, `{"event":"plan_ready","plan_complete":true}`); w.Code != http.StatusOK {
+		t.Fatalf("plan_ready status %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/case/"+id+"/timeline?limit=2&latest=true", nil))
+	var tl struct {
+		Items []struct {
+			Event   string `json:"event"`
+			Actor   string `json:"actor"`
+			Payload struct {
+				Extras map[string][]string
+			} `json:"payload"`
+		} `json:"items"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &tl)
+	if w.Code != http.StatusOK || len(tl.Items) != 2 || tl.Items[0].Event != "analysis_done" || tl.Items[1].Event != "plan_ready" {
+		t.Fatalf("expected the newest two entries oldest first, got %d %+v", w.Code, tl.Items)
+	}
+	if got := tl.Items[1]; got.Actor != "oncall@example.com" || len(got.Payload.Extras["asserted"]) != 1 || got.Payload.Extras["asserted"][0] != "plan_complete" {
+		t.Fatalf("expected the asserted fact recorded with the actor, got %+v", got)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/case/"+id+"/timeline?latest=maybe", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid latest, got %d", w.Code)
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -27,9 +28,16 @@ func RegisterRoutes(r gin.IRoutes, svc orchestrator.Service) {
 
 	h := &caseHandler{svc: svc}
 	r.POST("/case/create", h.createCase)
+	r.GET("/case/:id", h.getCase)
+	r.GET("/case/:id/timeline", h.listTimeline)
 	r.PATCH("/case/:id/transition", h.transitionCase)
 }
 
+const (
+	defaultTimelineLimit = 100
+	maxTimelineLimit     = 1000
+)
+
 type caseHandler struct {
 	svc orchestrator.Service
 }
@@ -60,8 +68,119 @@ func (h *caseHandler) createCase(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"case_id": row.CaseID.String(), "status": row.Status, "version": row.Version})
 }
 
+func (h *caseHandler) getCase(c *gin.Context) {
+	id, ok := caseID(c)
+	if !ok {
+		return
+	}
+	row, err := h.svc.GetCase(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "case not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	labels := json.RawMessage(row.Labels)
+	if len(labels) == 0 {
+		labels = json.RawMessage("{}")
+	}
+	c.Header("ETag", fmt.Sprintf("%d", row.Version))
+	c.JSON(http.StatusOK, gin.H{
+		"case_id":    row.CaseID.String(),
+		"tenant_id":  row.TenantID,
+		"title":      row.Title,
+		"severity":   row.Severity,
+		"status":     row.Status,
+		"labels":     labels,
+		"version":    row.Version,
+		"created_at": row.CreatedAt.Time,
+		"updated_at": row.UpdatedAt.Time,
+	})
+}
+
+func (h *caseHandler) listTimeline(c *gin.Context) {
+	id, ok := caseID(c)
+	if !ok {
+		return
+	}
+	limit := defaultTimelineLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		limit = min(n, maxTimelineLimit)
+	}
+	// latest=true returns the newest limit entries instead of the oldest,
+	// still oldest first.
+	latest := false
+	if v := c.Query("latest"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid latest"})
+			return
+		}
+		latest = b
+	}
+	rows, err := h.svc.ListTimeline(c.Request.Context(), id, int32(limit), latest)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	items := make([]gin.H, 0, len(rows))
+	for _, row := range rows {
+		item := gin.H{"id": row.ID, "ts": row.Ts.Time, "actor": row.Actor.String, "event": row.Event.String}
+		if len(row.Payload) > 0 {
+			item["payload"] = json.RawMessage(row.Payload)
+		}
+		items = append(items, item)
+	}
+	c.JSON(http.StatusOK, gin.H{"case_id": id.String(), "items": items})
+}
+
+// caseID parses the :id path parameter and answers 400 when it is not a UUID.
+func caseID(c *gin.Context) (pgtype.UUID, bool) {
+	uid, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return pgtype.UUID{}, false
+	}
+	return pgtype.UUID{Bytes: uid, Valid: true}, true
+}
+
+// transitionReq carries the event plus the facts the workflow guards check,
+// e.g. gate_approved and change_window for the gate_approved event. The
+// server does not verify the facts: it trusts the caller, requires X-Actor
+// when any is asserted and records them with the actor in the timeline.
 type transitionReq struct {
-	Event string `json:"event"`
+	Event        string `json:"event"`
+	Reason       string `json:"reason"`
+	PlanComplete bool   `json:"plan_complete"`
+	GateApproved bool   `json:"gate_approved"`
+	ChangeWindow bool   `json:"change_window"`
+	VerifyPassed bool   `json:"verify_passed"`
+}
+
+// guardFacts lists the facts the request asserts.
+func (r transitionReq) guardFacts() []string {
+	var facts []string
+	for _, f := range []struct {
+		name string
+		set  bool
+	}{
+		{"plan_complete", r.PlanComplete},
+		{"gate_approved", r.GateApproved},
+		{"change_window", r.ChangeWindow},
+		{"verify_passed", r.VerifyPassed},
+	} {
+		if f.set {
+			facts = append(facts, f.name)
+		}
+	}
+	return facts
 }
 
 func (h *caseHandler) transitionCase(c *gin.Context) {
@@ -70,10 +189,8 @@ func (h *caseHandler) transitionCase(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	idStr := c.Param("id")
-	uid, err := uuid.Parse(idStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+	id, ok := caseID(c)
+	if !ok {
 		return
 	}
 	var ver int64
@@ -83,9 +200,26 @@ func (h *caseHandler) transitionCase(c *gin.Context) {
 	}
 	idem := c.GetHeader("Idempotency-Key")
 	actor := c.GetHeader("X-Actor")
-	ctx := workflow.Context{Now: time.Now(), Actor: actor}
+	asserted := req.guardFacts()
+	if len(asserted) > 0 && actor == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "X-Actor is required to assert guard facts"})
+		return
+	}
+	ctx := workflow.Context{
+		Now:          time.Now(),
+		Actor:        actor,
+		Reason:       req.Reason,
+		PlanComplete: req.PlanComplete,
+		GateApproved: req.GateApproved,
+		ChangeWindow: req.ChangeWindow,
+		VerifyPassed: req.VerifyPassed,
+		IdemKey:      idem,
+	}
+	if len(asserted) > 0 {
+		ctx.Extras = map[string]any{"asserted": asserted}
+	}
 	row, err := h.svc.Transition(c.Request.Context(), ports.TransitionArgs{
-		CaseID:  pgtype.UUID{Bytes: uid, Valid: true},
+		CaseID:  id,
 		Event:   workflow.Event(req.Event),
 		Ctx:     ctx,
 		IfMatch: ver,
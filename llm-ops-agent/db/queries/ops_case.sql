@@ -3,6 +3,11 @@ INSERT INTO ops_case (tenant_id, title, severity, status, resource_id)
 VALUES ($1, $2, $3, $4, $5)
 RETURNING case_id, tenant_id, title, severity::text AS severity, status, resource_id, created_at, updated_at, labels, version;
 
+-- name: GetCase :one
+SELECT case_id, tenant_id, title, severity::text AS severity, status, resource_id, created_at, updated_at, labels, version
+FROM ops_case
+WHERE case_id = $1;
+
 -- name: GetCaseForUpdate :one
 SELECT case_id, tenant_id, title, severity::text AS severity, status, resource_id, created_at, updated_at, labels, version
 FROM ops_case
@@ -19,6 +24,20 @@ RETURNING case_id, tenant_id, title, severity::text AS severity, status, resourc
 INSERT INTO case_timeline (case_id, ts, actor, event, payload)
 VALUES ($1, $2, $3, $4, $5);
 
+-- name: ListCaseTimeline :many
+SELECT id, case_id, ts, actor, event, payload
+FROM case_timeline
+WHERE case_id = $1
+ORDER BY ts, id
+LIMIT $2;
+
+-- name: ListLatestCaseTimeline :many
+SELECT id, case_id, ts, actor, event, payload
+FROM case_timeline
+WHERE case_id = $1
+ORDER BY ts DESC, id DESC
+LIMIT $2;
+
 -- name: InsertOutbox :exec
 INSERT INTO outbox (aggregate, aggregate_id, topic, payload)
 VALUES ($1, $2, $3, $4);
@@ -62,6 +62,43 @@ func (q *Queries) CreateCase(ctx context.Context, arg CreateCaseParams) (CreateC
 	return i, err
 }
 
+const getCase = `-- name: GetCase :one
+SELECT case_id, tenant_id, title, severity::text AS severity, status, resource_id, created_at, updated_at, labels, version
+FROM ops_case
+WHERE case_id = $1
+`
+
+type GetCaseRow struct {
+	CaseID     pgtype.UUID
+	TenantID   int64
+	Title      string
+	Severity   string
+	Status     string
+	ResourceID pgtype.Int8
+	CreatedAt  pgtype.Timestamptz
+	UpdatedAt  pgtype.Timestamptz
+	Labels     []byte
+	Version    int64
+}
+
+func (q *Queries) GetCase(ctx context.Context, caseID pgtype.UUID) (GetCaseRow, error) {
+	row := q.db.QueryRow(ctx, getCase, caseID)
+	var i GetCaseRow
+	err := row.Scan(
+		&i.CaseID,
+		&i.TenantID,
+		&i.Title,
+		&i.Severity,
+		&i.Status,
+		&i.ResourceID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Labels,
+		&i.Version,
+	)
+	return i, err
+}
+
 const getCaseForUpdate = `-- name: GetCaseForUpdate :one
 SELECT case_id, tenant_id, title, severity::text AS severity, status, resource_id, created_at, updated_at, labels, version
 FROM ops_case
@@ -187,6 +224,86 @@ func (q *Queries) InsertTimeline(ctx context.Context, arg InsertTimelineParams)
 	return err
 }
 
+const listCaseTimeline = `-- name: ListCaseTimeline :many
+SELECT id, case_id, ts, actor, event, payload
+FROM case_timeline
+WHERE case_id = $1
+ORDER BY ts, id
+LIMIT $2
+`
+
+type ListCaseTimelineParams struct {
+	CaseID pgtype.UUID
+	Limit  int32
+}
+
+func (q *Queries) ListCaseTimeline(ctx context.Context, arg ListCaseTimelineParams) ([]CaseTimeline, error) {
+	rows, err := q.db.Query(ctx, listCaseTimeline, arg.CaseID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CaseTimeline
+	for rows.Next() {
+		var i CaseTimeline
+		if err := rows.Scan(
+			&i.ID,
+			&i.CaseID,
+			&i.Ts,
+			&i.Actor,
+			&i.Event,
+			&i.Payload,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLatestCaseTimeline = `-- name: ListLatestCaseTimeline :many
+SELECT id, case_id, ts, actor, event, payload
+FROM case_timeline
+WHERE case_id = $1
+ORDER BY ts DESC, id DESC
+LIMIT $2
+`
+
+type ListLatestCaseTimelineParams struct {
+	CaseID pgtype.UUID
+	Limit  int32
+}
+
+func (q *Queries) ListLatestCaseTimeline(ctx context.Context, arg ListLatestCaseTimelineParams) ([]CaseTimeline, error) {
+	rows, err := q.db.Query(ctx, listLatestCaseTimeline, arg.CaseID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CaseTimeline
+	for rows.Next() {
+		var i CaseTimeline
+		if err := rows.Scan(
+			&i.ID,
+			&i.CaseID,
+			&i.Ts,
+			&i.Actor,
+			&i.Event,
+			&i.Payload,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listUnpublishedOutbox = `-- name: ListUnpublishedOutbox :many
 SELECT id, aggregate, aggregate_id, topic, payload, created_at
 FROM outbox
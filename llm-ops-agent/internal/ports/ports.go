@@ -27,5 +27,7 @@ type TransitionArgs struct {
 type CaseRepository interface {
     CreateCase(ctx context.Context, args CreateCaseArgs) (db.CreateCaseRow, error)
     Transition(ctx context.Context, args TransitionArgs) (db.UpdateCaseStatusRow, error)
+    GetCase(ctx context.Context, caseID pgtype.UUID) (db.GetCaseRow, error)
+    ListTimeline(ctx context.Context, caseID pgtype.UUID, limit int32, latest bool) ([]db.CaseTimeline, error)
 }
 
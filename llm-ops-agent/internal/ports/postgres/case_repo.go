@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"encoding/json"
+	"slices"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -186,3 +187,22 @@ func (r *CaseRepository) Transition(ctx context.Context, args ports.TransitionAr
 	}
 	return updated, nil
 }
+
+// GetCase returns the current state and version of a case.
+func (r *CaseRepository) GetCase(ctx context.Context, caseID pgtype.UUID) (db.GetCaseRow, error) {
+	return r.queries.GetCase(ctx, caseID)
+}
+
+// ListTimeline returns the oldest limit timeline entries of a case, or the
+// newest ones when latest is set. Either way they are in order of time.
+func (r *CaseRepository) ListTimeline(ctx context.Context, caseID pgtype.UUID, limit int32, latest bool) ([]db.CaseTimeline, error) {
+	if !latest {
+		return r.queries.ListCaseTimeline(ctx, db.ListCaseTimelineParams{CaseID: caseID, Limit: limit})
+	}
+	rows, err := r.queries.ListLatestCaseTimeline(ctx, db.ListLatestCaseTimelineParams{CaseID: caseID, Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+	slices.Reverse(rows)
+	return rows, nil
+}
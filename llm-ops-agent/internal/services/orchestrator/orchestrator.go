@@ -3,6 +3,8 @@ package orchestrator
 import (
 	"context"
 
+	"github.com/jackc/pgx/v5/pgtype"
+
 	db "github.com/yourname/XOpsAgent/db/sqlc"
 	"github.com/yourname/XOpsAgent/internal/ports"
 )
@@ -10,6 +12,8 @@ import (
 type Service interface {
 	CreateCase(ctx context.Context, args ports.CreateCaseArgs) (db.CreateCaseRow, error)
 	Transition(ctx context.Context, args ports.TransitionArgs) (db.UpdateCaseStatusRow, error)
+	GetCase(ctx context.Context, caseID pgtype.UUID) (db.GetCaseRow, error)
+	ListTimeline(ctx context.Context, caseID pgtype.UUID, limit int32, latest bool) ([]db.CaseTimeline, error)
 }
 
 type service struct {
@@ -27,3 +31,11 @@ func (s *service) CreateCase(ctx context.Context, args ports.CreateCaseArgs) (db
 func (s *service) Transition(ctx context.Context, args ports.TransitionArgs) (db.UpdateCaseStatusRow, error) {
 	return s.repo.Transition(ctx, args)
 }
+
+func (s *service) GetCase(ctx context.Context, caseID pgtype.UUID) (db.GetCaseRow, error) {
+	return s.repo.GetCase(ctx, caseID)
+}
+
+func (s *service) ListTimeline(ctx context.Context, caseID pgtype.UUID, limit int32, latest bool) ([]db.CaseTimeline, error) {
+	return s.repo.ListTimeline(ctx, caseID, limit, latest)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/xscopehub/mcp-server/internal/conformance"
+	"github.com/xscopehub/mcp-server/internal/server"
+	"github.com/xscopehub/mcp-server/pkg/manifest"
+)
+
+// validate checks the manifest and that the registry built from it and the
+// backend flags matches what it lists. It exits 1 on errors, and on warnings
+// with --strict.
+func validate(args []string) {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	manifestPath := fs.String("manifest", "manifest.json", "Path to manifest file")
+	asJSON := fs.Bool("json", false, "Print problems as JSON")
+	strict := fs.Bool("strict", false, "Fail on warnings too")
+	backends := registerBackendFlags(fs)
+	_ = fs.Parse(args)
+	backends.auditLog = ""
+
+	problems := checkManifest(*manifestPath, backends)
+	if *asJSON {
+		if problems == nil {
+			problems = []manifest.Problem{}
+		}
+		writeJSON(os.Stdout, problems)
+	} else {
+		for _, p := range problems {
+			fmt.Println(p)
+		}
+		if len(problems) == 0 {
+			fmt.Printf("%s: ok\n", *manifestPath)
+		}
+	}
+	if manifest.HasErrors(problems) || (*strict && len(problems) > 0) {
+		os.Exit(1)
+	}
+}
+
+func checkManifest(path string, backends *backendFlags) []manifest.Problem {
+	mf, err := manifest.LoadStrict(path)
+	if err != nil {
+		return []manifest.Problem{{Severity: manifest.SeverityError, Field: "manifest", Message: err.Error()}}
+	}
+	problems := mf.Validate()
+	if manifest.HasErrors(problems) {
+		return problems
+	}
+	_, rl, err := buildServer(path, backends, server.Options{})
+	if err != nil {
+		return append(problems, manifest.Problem{Severity: manifest.SeverityError, Field: "registry", Message: err.Error()})
+	}
+	return append(problems, conformance.CheckRegistry(mf, rl.reg)...)
+}
+
+// inspect starts the server in-process, runs a scripted MCP session against
+// it and prints a conformance report. It exits 1 when a check fails.
+func inspect(args []string) {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	manifestPath := fs.String("manifest", "manifest.json", "Path to manifest file")
+	asJSON := fs.Bool("json", false, "Print the report as JSON")
+	skipCalls := fs.Bool("skip-calls", false, "Only list tools, resources and prompts; do not call or read them")
+	allowWrites := fs.Bool("allow-writes", false, "Also call tools that need a write scope, such as create_case")
+	timeout := fs.Duration("timeout", 2*time.Minute, "Timeout of the whole session")
+	backends := registerBackendFlags(fs)
+	_ = fs.Parse(args)
+	backends.auditLog = ""
+	log.SetOutput(os.Stderr)
+
+	srv, rl, err := buildServer(*manifestPath, backends, server.Options{})
+	if err != nil {
+		log.Fatalf("failed to %v", err)
+	}
+	mf, err := manifest.Load(*manifestPath)
+	if err != nil {
+		log.Fatalf("failed to load manifest: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	report := conformance.Inspect(ctx, srv, rl.reg, mf, conformance.Options{SkipCalls: *skipCalls, AllowWrites: *allowWrites})
+	cancel()
+	srv.Close()
+
+	if *asJSON {
+		writeJSON(os.Stdout, report)
+	} else {
+		report.WriteText(os.Stdout)
+	}
+	if report.Failed() {
+		os.Exit(1)
+	}
+}
+
+func writeJSON(w io.Writer, v interface{}) {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		log.Fatalf("failed to encode output: %v", err)
+	}
+}
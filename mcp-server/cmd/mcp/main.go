@@ -14,8 +14,16 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/xscopehub/mcp-server/internal/audit"
+	"github.com/xscopehub/mcp-server/internal/auth"
+	"github.com/xscopehub/mcp-server/internal/cases"
+	"github.com/xscopehub/mcp-server/internal/events"
+	"github.com/xscopehub/mcp-server/internal/gateway"
+	"github.com/xscopehub/mcp-server/internal/knowledge"
 	"github.com/xscopehub/mcp-server/internal/registry"
 	"github.com/xscopehub/mcp-server/internal/server"
+	"github.com/xscopehub/mcp-server/internal/telemetry"
+	"github.com/xscopehub/mcp-server/internal/topology"
 	"github.com/xscopehub/mcp-server/pkg/manifest"
 )
 
@@ -29,8 +37,14 @@ func main() {
 	switch cmd {
 	case "serve":
 		serve(os.Args[2:])
+	case "stdio":
+		stdio(os.Args[2:])
 	case "manifest":
 		printManifest(os.Args[2:])
+	case "validate":
+		validate(os.Args[2:])
+	case "inspect":
+		inspect(os.Args[2:])
 	default:
 		usage()
 		os.Exit(1)
@@ -38,39 +52,52 @@ func main() {
 }
 
 func usage() {
-	fmt.Fprintf(os.Stderr, "Usage: %s <serve|manifest> [flags]\n", filepath.Base(os.Args[0]))
+	fmt.Fprintf(os.Stderr, "Usage: %s <serve|stdio|manifest|validate|inspect> [flags]\n", filepath.Base(os.Args[0]))
 }
 
 func serve(args []string) {
 	fs := flag.NewFlagSet("serve", flag.ExitOnError)
 	addr := fs.String("addr", ":8000", "Address to listen on")
 	manifestPath := fs.String("manifest", "manifest.json", "Path to manifest file")
+	backends := registerBackendFlags(fs)
 	readTimeout := fs.Duration("read-timeout", 5*time.Second, "HTTP server read timeout")
 	writeTimeout := fs.Duration("write-timeout", 10*time.Second, "HTTP server write timeout")
+	sessionTTL := fs.Duration("session-ttl", 30*time.Minute, "Drop HTTP sessions idle for longer than this; 0 keeps them until the client deletes them")
+	maxSessions := fs.Int("max-sessions", 1000, "HTTP sessions open at once; further initialize requests get 503, 0 means unlimited")
+	var origins stringList
+	fs.Var(&origins, "allowed-origin", "Browser origin, such as https://console.example.com, allowed to call /mcp; may be repeated. Requests from other origins are refused")
+	authCfg := registerAuthFlags(fs)
 	_ = fs.Parse(args)
 
-	mf, err := manifest.Load(*manifestPath)
+	authn, err := auth.New(authCfg.config())
 	if err != nil {
-		log.Fatalf("failed to load manifest: %v", err)
+		log.Fatalf("failed to set up authentication: %v", err)
 	}
-
-	reg := registry.New()
-	reg.RegisterResources(registry.StaticResources())
-	reg.RegisterTools(registry.StaticTools())
-
-	srv := server.New(server.Options{
-		Manifest:     mf,
-		Registry:     reg,
-		ReadTimeout:  *readTimeout,
-		WriteTimeout: *writeTimeout,
+	if authn == nil {
+		log.Printf("authentication disabled; anyone reaching %s can call every tool", *addr)
+	}
+	srv, rl := newServer(*manifestPath, backends, server.Options{
+		ReadTimeout:    *readTimeout,
+		WriteTimeout:   *writeTimeout,
+		SessionTTL:     *sessionTTL,
+		MaxSessions:    *maxSessions,
+		AllowedOrigins: origins,
+		Auth:           authn,
 	})
 
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go rl.watch(ctx, backends)
+	go forwardEvents(ctx, backends, srv)
+	go srv.ExpireSessions(ctx)
+
 	httpSrv := &http.Server{
 		Addr:         *addr,
 		Handler:      srv,
 		ReadTimeout:  *readTimeout,
 		WriteTimeout: *writeTimeout,
 	}
+	httpSrv.RegisterOnShutdown(srv.Close)
 
 	go func() {
 		log.Printf("mcp server listening on %s", *addr)
@@ -79,18 +106,216 @@ func serve(args []string) {
 		}
 	}()
 
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-	<-sigCh
+	<-ctx.Done()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if err := httpSrv.Shutdown(ctx); err != nil {
+	if err := httpSrv.Shutdown(shutdownCtx); err != nil {
 		log.Printf("graceful shutdown failed: %v", err)
 	}
 }
 
+// stdio serves a single MCP session over stdin/stdout. Logs go to stderr so
+// they never interleave with protocol messages.
+func stdio(args []string) {
+	fs := flag.NewFlagSet("stdio", flag.ExitOnError)
+	manifestPath := fs.String("manifest", "manifest.json", "Path to manifest file")
+	backends := registerBackendFlags(fs)
+	_ = fs.Parse(args)
+
+	log.SetOutput(os.Stderr)
+	srv, rl := newServer(*manifestPath, backends, server.Options{})
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go rl.watch(ctx, backends)
+	go forwardEvents(ctx, backends, srv)
+	if err := srv.ServeStdio(ctx, os.Stdin, os.Stdout); err != nil {
+		log.Fatalf("stdio session error: %v", err)
+	}
+}
+
+// backendFlags holds the settings of the services backing live resources
+// and tools.
+type backendFlags struct {
+	gateway   gateway.Config
+	resources telemetry.Options
+	opsAgent  cases.Config
+	cases     cases.Options
+	knowledge knowledge.Config
+	topology  topology.Config
+
+	toolTimeout        time.Duration
+	maxConcurrentTools int
+
+	pluginDirs    stringList
+	watchInterval time.Duration
+
+	events events.Config
+
+	auditLog string
+}
+
+func registerBackendFlags(fs *flag.FlagSet) *backendFlags {
+	b := &backendFlags{}
+	fs.StringVar(&b.gateway.BaseURL, "gateway-url", os.Getenv("XSCOPE_GATEWAY_URL"), "observe-gateway base URL; static sample resources are served when empty")
+	fs.StringVar(&b.gateway.Tenant, "gateway-tenant", os.Getenv("XSCOPE_GATEWAY_TENANT"), "Tenant sent to observe-gateway")
+	fs.StringVar(&b.gateway.Token, "gateway-token", os.Getenv("XSCOPE_GATEWAY_TOKEN"), "Bearer token sent to observe-gateway")
+	fs.DurationVar(&b.gateway.Timeout, "gateway-timeout", 30*time.Second, "observe-gateway request timeout")
+	fs.DurationVar(&b.resources.CacheTTL, "resource-cache-ttl", 30*time.Second, "How long live resource results are cached")
+	fs.IntVar(&b.resources.PageSize, "resource-page-size", 50, "Maximum items per resource page")
+	fs.IntVar(&b.resources.MaxBytes, "resource-max-bytes", 32<<10, "Maximum encoded size of a resource page")
+	fs.StringVar(&b.resources.TraceStream, "trace-stream", "default", "OpenObserve trace stream used for slow traces")
+	fs.StringVar(&b.opsAgent.BaseURL, "ops-agent-url", os.Getenv("XSCOPE_OPS_AGENT_URL"), "llm-ops-agent base URL; case tools are disabled when empty")
+	fs.StringVar(&b.opsAgent.Token, "ops-agent-token", os.Getenv("XSCOPE_OPS_AGENT_TOKEN"), "Bearer token sent to llm-ops-agent")
+	fs.StringVar(&b.opsAgent.Actor, "ops-agent-actor", "mcp-server", "Actor recorded on case timelines")
+	fs.DurationVar(&b.opsAgent.Timeout, "ops-agent-timeout", 30*time.Second, "llm-ops-agent request timeout")
+	fs.Int64Var(&b.cases.TenantID, "case-tenant-id", 1, "Tenant of cases opened by stdio and unauthenticated sessions without an explicit tenant_id")
+	fs.Var((*tenantIDs)(&b.cases.Tenants), "case-tenant", "tenant=id: llm-ops-agent tenant_id of an authenticated tenant's cases; may be repeated, callers of other tenants cannot use the case tools")
+	fs.StringVar(&b.knowledge.DatabaseURL, "knowledge-db-url", os.Getenv("XSCOPE_KNOWLEDGE_DB_URL"), "PostgreSQL database with semantic_objects and kb_chunk; search_knowledge is disabled when empty")
+	fs.StringVar(&b.knowledge.EmbedderURL, "embedder-url", os.Getenv("XSCOPE_EMBEDDER_URL"), "OpenAI-compatible embeddings endpoint used to embed search queries")
+	fs.StringVar(&b.knowledge.EmbedderModel, "embedder-model", "bge-m3", "Embedding model; must match the one the knowledge tables were filled with")
+	fs.StringVar(&b.knowledge.EmbedderToken, "embedder-token", os.Getenv("XSCOPE_EMBEDDER_TOKEN"), "Bearer token sent to the embedder")
+	fs.DurationVar(&b.knowledge.EmbedderTimeout, "embedder-timeout", 10*time.Second, "Embedding request timeout")
+	fs.StringVar(&b.topology.DatabaseURL, "topology-db-url", os.Getenv("XSCOPE_TOPOLOGY_DB_URL"), "PostgreSQL database with the Apache AGE service graph and topo_edge_time; topology tools are disabled when empty")
+	fs.StringVar(&b.topology.Graph, "topology-graph", "xinsight", "AGE graph holding the current service topology")
+	fs.DurationVar(&b.toolTimeout, "tool-timeout", 60*time.Second, "Timeout of a tool call; 0 disables it")
+	fs.IntVar(&b.maxConcurrentTools, "max-concurrent-tools", 16, "Tool calls running at once; 0 means unlimited")
+	fs.Var(&b.pluginDirs, "plugin-dir", "Directory of plugin files; may be repeated")
+	fs.DurationVar(&b.watchInterval, "watch-interval", 2*time.Second, "How often the manifest and plugin directories are checked for changes; 0 disables reloading")
+	fs.StringVar(&b.events.URL, "nats-url", os.Getenv("XSCOPE_NATS_URL"), "NATS server llm-ops-agent publishes case events to; resource update notifications are disabled when empty")
+	fs.StringVar(&b.events.CaseSubject, "nats-case-subject", events.CaseTransitionSubject, "NATS subject of case transition events")
+	fs.StringVar(&b.auditLog, "audit-log", "-", "File tool calls are audited to as JSON lines; - writes to the log output, empty disables auditing")
+	return b
+}
+
+// authFlags holds the HTTP authentication settings. stdio sessions run as
+// the user who started the server and are not authenticated.
+type authFlags struct {
+	auth.Config
+	audience    stringList
+	authServers stringList
+}
+
+func registerAuthFlags(fs *flag.FlagSet) *authFlags {
+	a := &authFlags{}
+	fs.StringVar(&a.TokensFile, "auth-tokens-file", os.Getenv("XSCOPE_MCP_TOKENS_FILE"), "JSON file of static bearer tokens with their tenant and scopes")
+	fs.StringVar(&a.JWKSURL, "oauth-jwks-url", os.Getenv("XSCOPE_MCP_JWKS_URL"), "JWKS of the authorization server; enables OAuth access tokens")
+	fs.StringVar(&a.Issuer, "oauth-issuer", "", "Required issuer of OAuth access tokens")
+	fs.Var(&a.audience, "oauth-audience", "Accepted audience of OAuth access tokens, normally the resource URL; required with --oauth-jwks-url, may be repeated and a token must match one")
+	fs.StringVar(&a.TenantClaim, "oauth-tenant-claim", "tenant", "Access token claim holding the caller's tenant")
+	fs.DurationVar(&a.CacheTTL, "oauth-jwks-ttl", time.Hour, "How long the JWKS is cached")
+	fs.StringVar(&a.Resource, "resource-url", "", "Canonical URL of this MCP endpoint published in the protected resource metadata; derived from the request when empty")
+	fs.Var(&a.authServers, "oauth-authorization-server", "Authorization server published in the protected resource metadata; may be repeated")
+	return a
+}
+
+func (a *authFlags) config() auth.Config {
+	cfg := a.Config
+	cfg.Audience = a.audience
+	cfg.AuthorizationServers = a.authServers
+	cfg.ScopesSupported = []string{auth.ScopeTelemetryRead, auth.ScopeCasesRead, auth.ScopeCasesWrite, auth.ScopeKnowledgeRead}
+	return cfg
+}
+
+// forwardEvents notifies sessions subscribed to a resource when a NATS event
+// changes it, until ctx is done.
+func forwardEvents(ctx context.Context, b *backendFlags, srv *server.Server) {
+	sub := events.New(b.events, srv.ResourceUpdated)
+	if sub == nil {
+		return
+	}
+	if err := sub.Run(ctx); err != nil {
+		log.Printf("resource update events stopped: %v", err)
+	}
+}
+
+// openAuditLog resolves the --audit-log flag.
+func openAuditLog(path string) (*audit.Logger, error) {
+	switch path {
+	case "":
+		return nil, nil
+	case "-":
+		return audit.New(nil), nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open audit log: %w", err)
+	}
+	return audit.New(f), nil
+}
+
+// newServer loads the manifest and plugins and builds the registry shared by
+// every transport. The returned reloader keeps them up to date.
+func newServer(manifestPath string, backends *backendFlags, opts server.Options) (*server.Server, *reloader) {
+	srv, rl, err := buildServer(manifestPath, backends, opts)
+	if err != nil {
+		log.Fatalf("failed to %v", err)
+	}
+	return srv, rl
+}
+
+// buildServer is newServer returning errors instead of exiting, for the
+// validate and inspect commands.
+func buildServer(manifestPath string, backends *backendFlags, opts server.Options) (*server.Server, *reloader, error) {
+	mf, err := manifest.Load(manifestPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load manifest: %w", err)
+	}
+
+	reg := registry.New()
+	reg.SetToolLimits(backends.toolTimeout, backends.maxConcurrentTools)
+	reg.RegisterResources(registry.StaticResources(), auth.ScopeTelemetryRead)
+	reg.RegisterTools(registry.StaticTools())
+
+	gw := gateway.New(backends.gateway)
+	if live := telemetry.NewProvider(gw, backends.resources); live != nil {
+		if err := live.Register(reg); err != nil {
+			return nil, nil, fmt.Errorf("register live telemetry: %w", err)
+		}
+	}
+	if caseTools := cases.NewProvider(cases.New(backends.opsAgent), backends.cases); caseTools != nil {
+		if err := caseTools.Register(reg); err != nil {
+			return nil, nil, fmt.Errorf("register case tools: %w", err)
+		}
+	}
+
+	search, err := knowledge.New(backends.knowledge)
+	if err != nil {
+		return nil, nil, fmt.Errorf("set up knowledge search: %w", err)
+	}
+	if search != nil {
+		if err := search.Register(reg); err != nil {
+			return nil, nil, fmt.Errorf("register knowledge search: %w", err)
+		}
+	}
+
+	graph, err := topology.New(backends.topology)
+	if err != nil {
+		return nil, nil, fmt.Errorf("set up topology tools: %w", err)
+	}
+	if graph != nil {
+		graph.Register(reg)
+	}
+
+	auditLog, err := openAuditLog(backends.auditLog)
+	if err != nil {
+		return nil, nil, fmt.Errorf("set up auditing: %w", err)
+	}
+
+	opts.Manifest = mf
+	opts.Registry = reg
+	opts.Audit = auditLog
+	srv := server.New(opts)
+
+	rl := &reloader{manifestPath: manifestPath, pluginDirs: backends.pluginDirs, srv: srv, reg: reg}
+	if err := rl.reload(); err != nil {
+		return nil, nil, fmt.Errorf("load prompts and plugins: %w", err)
+	}
+	return srv, rl, nil
+}
+
 func printManifest(args []string) {
 	fs := flag.NewFlagSet("manifest", flag.ExitOnError)
 	manifestPath := fs.String("manifest", "manifest.json", "Path to manifest file")
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/xscopehub/mcp-server/internal/plugins"
+	"github.com/xscopehub/mcp-server/internal/prompts"
+	"github.com/xscopehub/mcp-server/internal/registry"
+	"github.com/xscopehub/mcp-server/internal/server"
+	"github.com/xscopehub/mcp-server/internal/toolplugin"
+	"github.com/xscopehub/mcp-server/internal/watch"
+	"github.com/xscopehub/mcp-server/pkg/manifest"
+)
+
+// Sources name the registrations owned by the manifest and by the plugin
+// directories.
+const (
+	manifestSource = "manifest"
+	pluginSource   = "plugins"
+)
+
+// reloader applies the manifest and plugin directories to a running server.
+type reloader struct {
+	manifestPath string
+	pluginDirs   []string
+	srv          *server.Server
+	reg          *registry.Registry
+
+	mu          sync.Mutex
+	promptFiles []string
+}
+
+// reload re-reads the manifest and plugin directories. On error the server
+// keeps what it loaded last.
+func (l *reloader) reload() error {
+	mf, err := manifest.Load(l.manifestPath)
+	if err != nil {
+		return err
+	}
+	catalog, promptFiles, err := prompts.Load(filepath.Dir(l.manifestPath), mf.Prompts)
+	if err != nil {
+		return err
+	}
+	tools, err := toolplugin.Tools(filepath.Dir(l.manifestPath), mf.ToolPlugins)
+	if err != nil {
+		return err
+	}
+	files, err := plugins.LoadDirs(l.pluginDirs...)
+	if err != nil {
+		return err
+	}
+	pluginTools, err := plugins.Tools(files)
+	if err != nil {
+		return err
+	}
+	if err := l.reg.ReplaceSource(manifestSource, registry.Set{Tools: tools, Prompts: catalog}); err != nil {
+		return err
+	}
+	if err := l.reg.ReplaceSource(pluginSource, registry.Set{Resources: plugins.Resources(files), Tools: pluginTools}); err != nil {
+		return err
+	}
+	l.srv.SetManifest(mf)
+
+	l.mu.Lock()
+	l.promptFiles = promptFiles
+	l.mu.Unlock()
+	return nil
+}
+
+// watchedPaths returns the manifest, the files and directories of the
+// prompts it loaded, and the plugin directories. Prompt directories are
+// included so files newly matching a glob are picked up.
+func (l *reloader) watchedPaths() []string {
+	paths := []string{l.manifestPath}
+	l.mu.Lock()
+	seen := map[string]bool{}
+	for _, f := range l.promptFiles {
+		if dir := filepath.Dir(f); !seen[dir] {
+			seen[dir] = true
+			paths = append(paths, dir)
+		}
+		paths = append(paths, f)
+	}
+	l.mu.Unlock()
+	return append(paths, l.pluginDirs...)
+}
+
+// watch reloads whenever the manifest, a prompt file or a plugin directory
+// changes, until ctx is done.
+func (l *reloader) watch(ctx context.Context, b *backendFlags) {
+	w := watch.NewFunc(b.watchInterval, func() {
+		if err := l.reload(); err != nil {
+			log.Printf("reload failed, keeping the previous configuration: %v", err)
+			return
+		}
+		log.Printf("reloaded %s", strings.Join(l.watchedPaths(), ", "))
+	}, l.watchedPaths)
+	if w != nil {
+		w.Run(ctx)
+	}
+}
+
+// stringList is a repeatable string flag.
+type stringList []string
+
+func (s *stringList) String() string { return fmt.Sprint(*s) }
+
+func (s *stringList) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+// tenantIDs is a repeatable tenant=id flag.
+type tenantIDs map[string]int64
+
+func (t *tenantIDs) String() string { return fmt.Sprint(map[string]int64(*t)) }
+
+func (t *tenantIDs) Set(v string) error {
+	tenant, raw, ok := strings.Cut(v, "=")
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if !ok || tenant == "" || err != nil {
+		return fmt.Errorf("want tenant=id, got %q", v)
+	}
+	if *t == nil {
+		*t = tenantIDs{}
+	}
+	(*t)[tenant] = id
+	return nil
+}
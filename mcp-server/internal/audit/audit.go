@@ -0,0 +1,93 @@
+// Package audit records every MCP tool call.
+package audit
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Outcomes of a tool call.
+const (
+	OutcomeOK                   = "ok"
+	OutcomeError                = "error"
+	OutcomeDenied               = "denied"
+	OutcomeInvalid              = "invalid_arguments"
+	OutcomeConfirmationRequired = "confirmation_required"
+	OutcomeCancelled            = "cancelled"
+)
+
+// Entry describes one tool call.
+type Entry struct {
+	Time      time.Time              `json:"time"`
+	Session   string                 `json:"session,omitempty"`
+	Subject   string                 `json:"subject,omitempty"`
+	Tenant    string                 `json:"tenant,omitempty"`
+	Tool      string                 `json:"tool"`
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+	Outcome   string                 `json:"outcome"`
+	Error     string                 `json:"error,omitempty"`
+	Duration  time.Duration          `json:"duration"`
+}
+
+// Logger writes audit entries as JSON lines.
+type Logger struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// New creates a logger writing to out, or to the standard logger's output
+// when out is nil.
+func New(out io.Writer) *Logger {
+	if out == nil {
+		out = log.Writer()
+	}
+	return &Logger{out: out}
+}
+
+// Log writes an entry. Arguments whose names suggest secrets are redacted.
+// A nil logger discards entries.
+func (l *Logger) Log(entry Entry) {
+	if l == nil {
+		return
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	entry.Time = entry.Time.UTC()
+	entry.Arguments = redact(entry.Arguments)
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("encode audit entry for %s: %v", entry.Tool, err)
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.out.Write(append(data, '\n')); err != nil {
+		log.Printf("write audit entry: %v", err)
+	}
+}
+
+var secretWords = []string{"token", "password", "secret", "credential", "api_key"}
+
+func redact(args map[string]interface{}) map[string]interface{} {
+	if len(args) == 0 {
+		return nil
+	}
+	out := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		out[k] = v
+		lower := strings.ToLower(k)
+		for _, w := range secretWords {
+			if strings.Contains(lower, w) {
+				out[k] = "[redacted]"
+				break
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,370 @@
+// Package auth authenticates MCP clients on the Streamable HTTP transport.
+// Two credential kinds are accepted as bearer tokens: static tokens listed in
+// a file, and OAuth 2.1 access tokens (JWTs) verified against the
+// authorization server's JWKS, as the MCP authorization spec asks of a
+// resource server. JWKS handling follows observe-gateway's authenticator.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+var (
+	// ErrNoCredentials is returned when a request carries no bearer token.
+	ErrNoCredentials = errors.New("bearer token required")
+	// ErrInvalidToken is returned when a token is unknown, expired or fails
+	// verification.
+	ErrInvalidToken = errors.New("invalid bearer token")
+	// ErrNoTenant is returned for an authenticated caller without a tenant,
+	// who must not be served another tenant's data instead.
+	ErrNoTenant = errors.New("authenticated caller has no tenant")
+)
+
+// Scopes required by the built-in tools.
+const (
+	ScopeTelemetryRead = "telemetry:read"
+	ScopeCasesRead     = "cases:read"
+	ScopeCasesWrite    = "cases:write"
+	ScopeKnowledgeRead = "knowledge:read"
+)
+
+// Config configures authentication. It is disabled when neither TokensFile
+// nor JWKSURL is set.
+type Config struct {
+	// TokensFile lists static bearer tokens; see LoadTokens.
+	TokensFile string
+
+	// JWKSURL enables OAuth access tokens signed by a key in this set.
+	JWKSURL string
+	// Issuer is checked when set. Audience is required with JWKSURL: a
+	// token must name at least one of these audiences, normally the
+	// server's canonical resource URI, so tokens minted for other resources
+	// are refused.
+	Issuer   string
+	Audience []string
+	// TenantClaim and SubjectClaim name the claims holding the tenant and
+	// the caller; they default to "tenant" and "sub".
+	TenantClaim  string
+	SubjectClaim string
+	// CacheTTL is how long the key set is reused before it is fetched again.
+	// Defaults to an hour.
+	CacheTTL time.Duration
+
+	// Resource and AuthorizationServers are published as protected resource
+	// metadata (RFC 9728) so clients can discover where to get a token.
+	Resource             string
+	AuthorizationServers []string
+	// ScopesSupported lists the scopes tools ask for, for the metadata.
+	ScopesSupported []string
+}
+
+// Principal is an authenticated caller.
+type Principal struct {
+	Subject string
+	Tenant  string
+	Scopes  []string
+}
+
+// HasScopes reports whether p was granted every scope in required.
+func (p Principal) HasScopes(required []string) bool {
+	for _, want := range required {
+		found := false
+		for _, have := range p.Scopes {
+			if have == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+type contextKey struct{}
+
+// WithPrincipal returns a context carrying p.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext returns the principal of an authenticated request.
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(contextKey{}).(Principal)
+	return p, ok
+}
+
+// TenantFromContext returns the tenant of an authenticated request, or "".
+func TenantFromContext(ctx context.Context) string {
+	p, _ := FromContext(ctx)
+	return p.Tenant
+}
+
+// CallerTenant returns the tenant whose data the request may see: the
+// principal's tenant, or "" for stdio and unauthenticated sessions, which
+// are not limited to a tenant. An authenticated caller without a tenant
+// gets ErrNoTenant.
+func CallerTenant(ctx context.Context) (string, error) {
+	p, ok := FromContext(ctx)
+	if !ok {
+		return "", nil
+	}
+	if p.Tenant == "" {
+		return "", ErrNoTenant
+	}
+	return p.Tenant, nil
+}
+
+// Authenticator verifies bearer tokens.
+type Authenticator struct {
+	cfg    Config
+	tokens map[[sha256.Size]byte]Principal
+
+	client    *http.Client
+	mu        sync.RWMutex
+	set       jwk.Set
+	fetchedAt time.Time
+}
+
+// New creates an authenticator. It returns nil when authentication is not
+// configured. The token file and, when set, the JWKS are loaded up front so
+// misconfiguration fails at startup.
+func New(cfg Config) (*Authenticator, error) {
+	if cfg.TokensFile == "" && cfg.JWKSURL == "" {
+		return nil, nil
+	}
+	if cfg.TenantClaim == "" {
+		cfg.TenantClaim = "tenant"
+	}
+	if cfg.SubjectClaim == "" {
+		cfg.SubjectClaim = "sub"
+	}
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = time.Hour
+	}
+	var audience []string
+	for _, aud := range cfg.Audience {
+		if aud != "" {
+			audience = append(audience, aud)
+		}
+	}
+	cfg.Audience = audience
+	if cfg.JWKSURL != "" && len(cfg.Audience) == 0 {
+		return nil, errors.New("oauth access tokens require an accepted audience")
+	}
+
+	a := &Authenticator{cfg: cfg, client: &http.Client{Timeout: 10 * time.Second}}
+	if cfg.TokensFile != "" {
+		tokens, err := LoadTokens(cfg.TokensFile)
+		if err != nil {
+			return nil, err
+		}
+		a.tokens = tokens
+	}
+	if cfg.JWKSURL != "" {
+		if err := a.refresh(context.Background()); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// LoadTokens reads a static token file:
+//
+//	{"tokens": [{"token": "...", "subject": "grafana", "tenant": "acme", "scopes": ["telemetry:read"]}]}
+func LoadTokens(path string) (map[[sha256.Size]byte]Principal, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read tokens: %w", err)
+	}
+	var file struct {
+		Tokens []struct {
+			Token   string   `json:"token"`
+			Subject string   `json:"subject"`
+			Tenant  string   `json:"tenant"`
+			Scopes  []string `json:"scopes"`
+		} `json:"tokens"`
+	}
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("decode tokens: %w", err)
+	}
+	tokens := make(map[[sha256.Size]byte]Principal, len(file.Tokens))
+	for i, t := range file.Tokens {
+		if t.Token == "" || t.Subject == "" {
+			return nil, fmt.Errorf("tokens[%d]: token and subject are required", i)
+		}
+		// Tokens are looked up by hash so the comparison does not leak
+		// how much of a guess matched.
+		tokens[sha256.Sum256([]byte(t.Token))] = Principal{Subject: t.Subject, Tenant: t.Tenant, Scopes: t.Scopes}
+	}
+	return tokens, nil
+}
+
+// Authenticate verifies an Authorization header value.
+func (a *Authenticator) Authenticate(ctx context.Context, header string) (Principal, error) {
+	if header == "" {
+		return Principal{}, ErrNoCredentials
+	}
+	if !strings.HasPrefix(strings.ToLower(header), "bearer ") {
+		return Principal{}, fmt.Errorf("%w: authorization header must be a bearer token", ErrInvalidToken)
+	}
+	token := strings.TrimSpace(header[7:])
+	if token == "" {
+		return Principal{}, ErrNoCredentials
+	}
+
+	if p, ok := a.tokens[sha256.Sum256([]byte(token))]; ok {
+		return p, nil
+	}
+	if a.cfg.JWKSURL == "" {
+		return Principal{}, ErrInvalidToken
+	}
+	return a.verifyJWT(ctx, token)
+}
+
+func (a *Authenticator) verifyJWT(ctx context.Context, raw string) (Principal, error) {
+	set, err := a.keySet(ctx)
+	if err != nil {
+		return Principal{}, err
+	}
+	opts := []jwt.ParseOption{jwt.WithKeySet(set), jwt.WithValidate(true), jwt.WithValidator(a.audienceValidator())}
+	if a.cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(a.cfg.Issuer))
+	}
+	token, err := jwt.ParseString(raw, opts...)
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	p := Principal{
+		Subject: claimString(token, a.cfg.SubjectClaim),
+		Tenant:  claimString(token, a.cfg.TenantClaim),
+		Scopes:  tokenScopes(token),
+	}
+	if p.Subject == "" {
+		return Principal{}, fmt.Errorf("%w: missing %s claim", ErrInvalidToken, a.cfg.SubjectClaim)
+	}
+	return p, nil
+}
+
+// audienceValidator accepts a token naming any of the configured audiences.
+// jwt.WithAudience cannot express this: each one given is required.
+func (a *Authenticator) audienceValidator() jwt.Validator {
+	return jwt.ValidatorFunc(func(_ context.Context, t jwt.Token) jwt.ValidationError {
+		for _, have := range t.Audience() {
+			for _, want := range a.cfg.Audience {
+				if have == want {
+					return nil
+				}
+			}
+		}
+		return jwt.ErrInvalidAudience()
+	})
+}
+
+func (a *Authenticator) keySet(ctx context.Context) (jwk.Set, error) {
+	a.mu.RLock()
+	set, fetched := a.set, a.fetchedAt
+	a.mu.RUnlock()
+	if set != nil && time.Since(fetched) < a.cfg.CacheTTL {
+		return set, nil
+	}
+	if err := a.refresh(ctx); err != nil {
+		return nil, err
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.set, nil
+}
+
+func (a *Authenticator) refresh(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	set, err := jwk.Fetch(ctx, a.cfg.JWKSURL, jwk.WithHTTPClient(a.client))
+	if err != nil {
+		return fmt.Errorf("fetch jwks: %w", err)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.set = set
+	a.fetchedAt = time.Now()
+	return nil
+}
+
+// ResourceMetadata is the OAuth 2.0 protected resource metadata document
+// (RFC 9728) served at /.well-known/oauth-protected-resource.
+type ResourceMetadata struct {
+	Resource               string   `json:"resource"`
+	AuthorizationServers   []string `json:"authorization_servers,omitempty"`
+	ScopesSupported        []string `json:"scopes_supported,omitempty"`
+	BearerMethodsSupported []string `json:"bearer_methods_supported"`
+}
+
+// Metadata returns the protected resource metadata.
+func (a *Authenticator) Metadata() ResourceMetadata {
+	return ResourceMetadata{
+		Resource:               a.cfg.Resource,
+		AuthorizationServers:   a.cfg.AuthorizationServers,
+		ScopesSupported:        a.cfg.ScopesSupported,
+		BearerMethodsSupported: []string{"header"},
+	}
+}
+
+func claimString(token jwt.Token, claim string) string {
+	value, ok := token.Get(claim)
+	if !ok {
+		return ""
+	}
+	switch v := value.(type) {
+	case string:
+		return v
+	case []string:
+		if len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	case fmt.Stringer:
+		return v.String()
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// tokenScopes reads the space separated "scope" claim of RFC 9068 access
+// tokens, falling back to the "scp" array some providers issue.
+func tokenScopes(token jwt.Token) []string {
+	if v, ok := token.Get("scope"); ok {
+		if s, ok := v.(string); ok {
+			return strings.Fields(s)
+		}
+	}
+	v, ok := token.Get("scp")
+	if !ok {
+		return nil
+	}
+	switch scp := v.(type) {
+	case string:
+		return strings.Fields(scp)
+	case []interface{}:
+		out := make([]string, 0, len(scp))
+		for _, s := range scp {
+			if str, ok := s.(string); ok {
+				out = append(out, str)
+			}
+		}
+		return out
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+func TestStaticTokens(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	os.WriteFile(path, []byte(`{"tokens":[{"token":"s3cret","subject":"grafana","tenant":"acme","scopes":["telemetry:read"]}]}`), 0o600)
+	a, err := New(Config{TokensFile: path})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+
+	p, err := a.Authenticate(context.Background(), "Bearer s3cret")
+	if err != nil || p.Subject != "grafana" || p.Tenant != "acme" || !p.HasScopes([]string{ScopeTelemetryRead}) || p.HasScopes([]string{ScopeCasesWrite}) {
+		t.Fatalf("unexpected principal %+v (%v)", p, err)
+	}
+	if _, err := a.Authenticate(context.Background(), "Bearer guess"); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected an invalid token, got %v", err)
+	}
+	if _, err := a.Authenticate(context.Background(), ""); !errors.Is(err, ErrNoCredentials) {
+		t.Fatalf("expected missing credentials, got %v", err)
+	}
+}
+
+func TestOAuthAccessTokens(t *testing.T) {
+	raw, _ := rsa.GenerateKey(rand.Reader, 2048)
+	key, _ := jwk.FromRaw(raw)
+	key.Set(jwk.KeyIDKey, "k1")
+	key.Set(jwk.AlgorithmKey, jwa.RS256)
+	pub, _ := key.PublicKey()
+	set := jwk.NewSet()
+	set.AddKey(pub)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(set)
+	}))
+	defer ts.Close()
+
+	if _, err := New(Config{JWKSURL: ts.URL, Issuer: "https://idp.example"}); err == nil {
+		t.Fatalf("expected OAuth without an audience to be refused")
+	}
+	a, err := New(Config{JWKSURL: ts.URL, Issuer: "https://idp.example", Audience: []string{"https://mcp.example/mcp", "https://mcp.internal/mcp"}})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	sign := func(aud string) string {
+		tok, _ := jwt.NewBuilder().
+			Issuer("https://idp.example").
+			Audience([]string{aud}).
+			Subject("alice").
+			Expiration(time.Now().Add(time.Hour)).
+			Claim("tenant", "acme").
+			Claim("scope", "telemetry:read cases:read").
+			Build()
+		signed, err := jwt.Sign(tok, jwt.WithKey(jwa.RS256, key))
+		if err != nil {
+			t.Fatalf("sign: %v", err)
+		}
+		return string(signed)
+	}
+
+	p, err := a.Authenticate(context.Background(), "Bearer "+sign("https://mcp.example/mcp"))
+	if err != nil || p.Subject != "alice" || p.Tenant != "acme" || !p.HasScopes([]string{ScopeTelemetryRead, ScopeCasesRead}) {
+		t.Fatalf("unexpected principal %+v (%v)", p, err)
+	}
+	// Any one of the accepted audiences is enough.
+	if _, err := a.Authenticate(context.Background(), "Bearer "+sign("https://mcp.internal/mcp")); err != nil {
+		t.Fatalf("expected the second audience to be accepted, got %v", err)
+	}
+	if _, err := a.Authenticate(context.Background(), "Bearer "+sign("https://other.example")); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected tokens for other resources to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,211 @@
+package cases
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxErrorBody bounds how much of an error response is kept in messages.
+const maxErrorBody = 4 << 10
+
+// Config holds the connection settings for llm-ops-agent.
+type Config struct {
+	BaseURL string
+	Token   string
+	// Actor is recorded as X-Actor on every case change. Defaults to
+	// "mcp-server".
+	Actor   string
+	Timeout time.Duration
+}
+
+// Case is the state of an incident case as returned by llm-ops-agent.
+type Case struct {
+	ID        string          `json:"case_id"`
+	TenantID  int64           `json:"tenant_id,omitempty"`
+	Title     string          `json:"title,omitempty"`
+	Severity  string          `json:"severity,omitempty"`
+	Status    string          `json:"status"`
+	Labels    json.RawMessage `json:"labels,omitempty"`
+	Version   int64           `json:"version"`
+	CreatedAt *time.Time      `json:"created_at,omitempty"`
+	UpdatedAt *time.Time      `json:"updated_at,omitempty"`
+}
+
+// TimelineEntry is one recorded event of a case.
+type TimelineEntry struct {
+	ID      int64           `json:"id"`
+	TS      time.Time       `json:"ts"`
+	Actor   string          `json:"actor,omitempty"`
+	Event   string          `json:"event"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// Transition mirrors the PATCH /case/:id/transition payload: the event and
+// the facts the workflow guards check.
+type Transition struct {
+	Event        string `json:"event"`
+	Reason       string `json:"reason,omitempty"`
+	PlanComplete bool   `json:"plan_complete,omitempty"`
+	GateApproved bool   `json:"gate_approved,omitempty"`
+	ChangeWindow bool   `json:"change_window,omitempty"`
+	VerifyPassed bool   `json:"verify_passed,omitempty"`
+}
+
+// Error is a non-2xx answer from llm-ops-agent.
+type Error struct {
+	Status  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	switch e.Status {
+	case http.StatusConflict:
+		return fmt.Sprintf("transition rejected by the case workflow: %s", e.Message)
+	case http.StatusPreconditionFailed:
+		return fmt.Sprintf("case changed since it was read (%s); read it again and retry", e.Message)
+	}
+	return fmt.Sprintf("llm-ops-agent returned %d: %s", e.Status, e.Message)
+}
+
+// Client calls llm-ops-agent's case API.
+type Client struct {
+	baseURL string
+	token   string
+	actor   string
+	http    *http.Client
+}
+
+// New builds a client. A nil client is returned when no base URL is set.
+func New(cfg Config) *Client {
+	if cfg.BaseURL == "" {
+		return nil
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	actor := cfg.Actor
+	if actor == "" {
+		actor = "mcp-server"
+	}
+	return &Client{
+		baseURL: strings.TrimRight(cfg.BaseURL, "/"),
+		token:   cfg.Token,
+		actor:   actor,
+		http:    &http.Client{Timeout: timeout},
+	}
+}
+
+// Create opens a case. Calls repeated with the same idempotency key return
+// the case created first.
+func (c *Client) Create(ctx context.Context, tenantID int64, title, idemKey string) (Case, error) {
+	body := map[string]interface{}{"tenant_id": tenantID, "title": title}
+	header := http.Header{}
+	if idemKey != "" {
+		header.Set("Idempotency-Key", idemKey)
+	}
+	var out Case
+	err := c.do(ctx, http.MethodPost, "/case/create", header, body, &out)
+	return out, err
+}
+
+// Get reads the current state and version of a case.
+func (c *Client) Get(ctx context.Context, id string) (Case, error) {
+	var out Case
+	err := c.do(ctx, http.MethodGet, "/case/"+url.PathEscape(id), nil, nil, &out)
+	return out, err
+}
+
+// Timeline lists the oldest limit events of a case, or the newest ones when
+// latest is set. Either way they come oldest first.
+func (c *Client) Timeline(ctx context.Context, id string, limit int, latest bool) ([]TimelineEntry, error) {
+	query := url.Values{}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	if latest {
+		query.Set("latest", "true")
+	}
+	path := "/case/" + url.PathEscape(id) + "/timeline"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	var out struct {
+		Items []TimelineEntry `json:"items"`
+	}
+	err := c.do(ctx, http.MethodGet, path, nil, nil, &out)
+	return out.Items, err
+}
+
+// Transition applies an event to a case at version ifMatch. A zero ifMatch
+// skips the version check.
+func (c *Client) Transition(ctx context.Context, id string, tr Transition, ifMatch int64, idemKey string) (Case, error) {
+	header := http.Header{}
+	if ifMatch != 0 {
+		header.Set("If-Match", strconv.FormatInt(ifMatch, 10))
+	}
+	if idemKey != "" {
+		header.Set("Idempotency-Key", idemKey)
+	}
+	var out Case
+	if err := c.do(ctx, http.MethodPatch, "/case/"+url.PathEscape(id)+"/transition", header, tr, &out); err != nil {
+		return Case{}, err
+	}
+	out.ID = id
+	return out, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, header http.Header, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("X-Actor", c.actor)
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		var payload struct {
+			Error string `json:"error"`
+		}
+		msg := strings.TrimSpace(string(raw))
+		if json.Unmarshal(raw, &payload) == nil && payload.Error != "" {
+			msg = payload.Error
+		}
+		return &Error{Status: resp.StatusCode, Message: msg}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode llm-ops-agent response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package cases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/xscopehub/mcp-server/internal/auth"
+	"github.com/xscopehub/mcp-server/internal/registry"
+	"github.com/xscopehub/mcp-server/internal/types"
+)
+
+// caseTemplate addresses one case. Clients can subscribe to it to learn when
+// the case changes state.
+const caseTemplate = registry.ResourceURIPrefix + "cases/{case_id}"
+
+// CaseURI is the resource of the case with id.
+func CaseURI(id string) string {
+	return registry.ResourceURIPrefix + "cases/" + id
+}
+
+// Register adds the case tools and the case resource template to reg.
+func (p *Provider) Register(reg *registry.Registry) error {
+	err := reg.RegisterTemplate(types.ResourceTemplate{
+		URITemplate: caseTemplate,
+		Name:        "case",
+		Title:       "Incident case",
+		Description: "An incident case with its recent timeline. Subscribe to be notified when it changes state.",
+		MimeType:    "application/json",
+	}, p.readCase, auth.ScopeCasesRead)
+	if err != nil {
+		return err
+	}
+	reg.RegisterTools(p.Tools())
+	return nil
+}
+
+func (p *Provider) readCase(ctx context.Context, uri string, vars map[string]string) (types.ResourcePayload, error) {
+	id := vars["case_id"]
+	c, err := p.get(ctx, id)
+	var apiErr *Error
+	if errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound {
+		return types.ResourcePayload{}, fmt.Errorf("%w: %s", registry.ErrResourceNotFound, uri)
+	}
+	if err != nil {
+		return types.ResourcePayload{}, err
+	}
+	items, err := p.client.Timeline(ctx, id, defaultTimelineLimit, true)
+	if err != nil {
+		return types.ResourcePayload{}, err
+	}
+	return types.ResourcePayload{
+		URI:      uri,
+		Name:     "case",
+		MimeType: "application/json",
+		Data:     map[string]interface{}{"case": c, "timeline": items},
+	}, nil
+}
@@ -0,0 +1,310 @@
+package cases
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xscopehub/mcp-server/internal/auth"
+	"github.com/xscopehub/mcp-server/internal/registry"
+	"github.com/xscopehub/mcp-server/internal/types"
+)
+
+const (
+	defaultTimelineLimit = 50
+	maxTimelineLimit     = 1000
+)
+
+// events are the workflow events transition_case accepts.
+var events = []string{
+	"start_analysis", "analysis_done", "analysis_failed",
+	"plan_ready", "plan_failed",
+	"gate_approved", "gate_rejected",
+	"exec_done", "exec_failed",
+	"verify_pass", "verify_failed",
+	"force_park",
+}
+
+// gatedEvents approve or record changes to production and need a human to
+// confirm them.
+var gatedEvents = map[string]bool{
+	"gate_approved": true,
+	"exec_done":     true,
+	"exec_failed":   true,
+}
+
+type createCaseArgs struct {
+	Title          string `json:"title" description:"Short description of the incident." jsonschema:"minLength=1"`
+	TenantID       *int64 `json:"tenant_id,omitempty" description:"Tenant the case belongs to. Defaults to the server's configured tenant; authenticated callers always open cases in their own tenant."`
+	IdempotencyKey string `json:"idempotency_key,omitempty" description:"Key that makes retries return the case created first. Derived from tenant, title and the current hour when omitted."`
+}
+
+type caseArgs struct {
+	CaseID string `json:"case_id" jsonschema:"format=uuid"`
+}
+
+type timelineArgs struct {
+	CaseID string `json:"case_id" jsonschema:"format=uuid"`
+	Limit  int    `json:"limit" jsonschema:"minimum=1,maximum=1000,default=50"`
+}
+
+type transitionArgs struct {
+	CaseID         string `json:"case_id" jsonschema:"format=uuid"`
+	Event          string `json:"event" jsonschema:"enum=start_analysis|analysis_done|analysis_failed|plan_ready|plan_failed|gate_approved|gate_rejected|exec_done|exec_failed|verify_pass|verify_failed|force_park"`
+	Reason         string `json:"reason,omitempty" description:"Why the case moves, recorded on the timeline."`
+	IfMatch        int64  `json:"if_match,omitempty" description:"Case version the transition applies to. Defaults to the version read just before."`
+	IdempotencyKey string `json:"idempotency_key,omitempty" description:"Key that makes retries return the first result. Derived from case, event and version when omitted."`
+	PlanComplete   bool   `json:"plan_complete,omitempty" description:"The remediation plan is complete; required by plan_ready."`
+	ChangeWindow   bool   `json:"change_window,omitempty" description:"The change window is open; required by gate_approved."`
+	VerifyPassed   bool   `json:"verify_passed,omitempty" description:"Verification succeeded; required by verify_pass."`
+	DryRun         bool   `json:"dry_run,omitempty" description:"Only preview the transition; nothing is changed."`
+}
+
+// Options tunes the case tools.
+type Options struct {
+	// TenantID is used by create_case when a stdio or unauthenticated call
+	// names no tenant.
+	TenantID int64
+	// Tenants maps the tenant of an authenticated caller to the llm-ops-agent
+	// tenant_id of its cases. Callers whose tenant is not listed are refused.
+	Tenants map[string]int64
+}
+
+// Provider serves the case workflow tools backed by llm-ops-agent.
+type Provider struct {
+	client *Client
+	opts   Options
+	now    func() time.Time
+	// secret signs confirmation tokens so they cannot be forged.
+	secret []byte
+}
+
+// NewProvider builds the case tools. It returns nil when client is nil.
+func NewProvider(client *Client, opts Options) *Provider {
+	if client == nil {
+		return nil
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(err)
+	}
+	return &Provider{client: client, opts: opts, now: time.Now, secret: secret}
+}
+
+// Tools returns create_case, get_case, list_case_timeline and
+// transition_case.
+func (p *Provider) Tools() map[string]registry.Tool {
+	return map[string]registry.Tool{
+		"create_case": registry.TypedTool(types.ToolDescriptor{
+			Name:        "create_case",
+			Title:       "Create incident case",
+			Description: "Open an incident case in llm-ops-agent. New cases start in status NEW.",
+		}, p.createCase).WithScopes(auth.ScopeCasesWrite),
+		"get_case": registry.TypedTool(types.ToolDescriptor{
+			Name:        "get_case",
+			Title:       "Get incident case",
+			Description: "Read the status and version of an incident case.",
+		}, p.getCase).WithScopes(auth.ScopeCasesRead),
+		"list_case_timeline": registry.TypedTool(types.ToolDescriptor{
+			Name:        "list_case_timeline",
+			Title:       "List case timeline",
+			Description: "List the recorded events of an incident case, oldest first.",
+		}, p.listTimeline).WithScopes(auth.ScopeCasesRead),
+		"transition_case": registry.TypedTool(types.ToolDescriptor{
+			Name:        "transition_case",
+			Title:       "Transition incident case",
+			Description: "Advance an incident case through its workflow. gate_approved, exec_done and exec_failed are applied only after the user approves them in the client; use dry_run to preview any transition.",
+		}, p.transitionCase).WithScopes(auth.ScopeCasesWrite),
+	}
+}
+
+func (p *Provider) createCase(ctx context.Context, args createCaseArgs) (types.ToolResult, error) {
+	title := strings.TrimSpace(args.Title)
+	if title == "" {
+		return types.ToolResult{}, errors.New("title is required")
+	}
+	tenantID, scoped, err := p.callerTenantID(ctx)
+	if err != nil {
+		return types.ToolResult{}, err
+	}
+	switch {
+	case !scoped && args.TenantID != nil:
+		tenantID = *args.TenantID
+	case !scoped:
+		tenantID = p.opts.TenantID
+	case args.TenantID != nil && *args.TenantID != tenantID:
+		return types.ToolResult{}, errors.New("tenant_id must be the caller's own tenant")
+	}
+	key := args.IdempotencyKey
+	if key == "" {
+		key = idempotencyKey("create", strconv.FormatInt(tenantID, 10), title, p.now().UTC().Format("2006010215"))
+	}
+
+	c, err := p.client.Create(ctx, tenantID, title, key)
+	if err != nil {
+		return types.ToolResult{}, err
+	}
+	return types.ToolResult{Name: "create_case", Output: c}, nil
+}
+
+func (p *Provider) getCase(ctx context.Context, args caseArgs) (types.ToolResult, error) {
+	if args.CaseID == "" {
+		return types.ToolResult{}, errors.New("case_id is required")
+	}
+	c, err := p.get(ctx, args.CaseID)
+	if err != nil {
+		return types.ToolResult{}, err
+	}
+	return types.ToolResult{Name: "get_case", Output: c}, nil
+}
+
+func (p *Provider) listTimeline(ctx context.Context, args timelineArgs) (types.ToolResult, error) {
+	if args.CaseID == "" {
+		return types.ToolResult{}, errors.New("case_id is required")
+	}
+	limit := defaultTimelineLimit
+	if args.Limit > 0 {
+		limit = min(args.Limit, maxTimelineLimit)
+	}
+	if _, err := p.get(ctx, args.CaseID); err != nil {
+		return types.ToolResult{}, err
+	}
+	items, err := p.client.Timeline(ctx, args.CaseID, limit, false)
+	if err != nil {
+		return types.ToolResult{}, err
+	}
+	return types.ToolResult{Name: "list_case_timeline", Output: map[string]interface{}{
+		"case_id": args.CaseID,
+		"items":   items,
+	}}, nil
+}
+
+func (p *Provider) transitionCase(ctx context.Context, args transitionArgs) (types.ToolResult, error) {
+	id, event := args.CaseID, args.Event
+	if id == "" {
+		return types.ToolResult{}, errors.New("case_id is required")
+	}
+	if !knownEvent(event) {
+		return types.ToolResult{}, fmt.Errorf("event must be one of %s", strings.Join(events, ", "))
+	}
+
+	current, err := p.get(ctx, id)
+	if err != nil {
+		return types.ToolResult{}, err
+	}
+	version := current.Version
+	if args.IfMatch > 0 {
+		version = args.IfMatch
+	}
+
+	tr := Transition{
+		Event:        event,
+		Reason:       strings.TrimSpace(args.Reason),
+		PlanComplete: args.PlanComplete,
+		GateApproved: event == "gate_approved",
+		ChangeWindow: args.ChangeWindow,
+		VerifyPassed: args.VerifyPassed,
+	}
+	gated := gatedEvents[event]
+	token := p.confirmationToken(id, event, version)
+	preview := map[string]interface{}{
+		"case_id":               id,
+		"title":                 current.Title,
+		"status":                current.Status,
+		"version":               version,
+		"transition":            tr,
+		"requires_confirmation": gated,
+	}
+
+	if args.DryRun {
+		return types.ToolResult{Name: "transition_case", Output: map[string]interface{}{"dry_run": true, "preview": preview}}, nil
+	}
+	// Only the server sets the confirmation, after the user accepted the
+	// preview; nothing the model passes in can approve a gated event.
+	if gated && !hmac.Equal([]byte(registry.Confirmed(ctx)), []byte(token)) {
+		return types.ToolResult{}, &registry.ConfirmationError{
+			Message: fmt.Sprintf("%s on case %q (status %s, version %d) needs human confirmation", event, current.Title, current.Status, version),
+			Preview: preview,
+			Token:   token,
+		}
+	}
+
+	key := args.IdempotencyKey
+	if key == "" {
+		key = idempotencyKey("transition", id, event, strconv.FormatInt(version, 10))
+	}
+	updated, err := p.client.Transition(ctx, id, tr, version, key)
+	if err != nil {
+		return types.ToolResult{}, err
+	}
+	return types.ToolResult{Name: "transition_case", Output: map[string]interface{}{
+		"case_id":         id,
+		"event":           event,
+		"previous_status": current.Status,
+		"status":          updated.Status,
+		"version":         updated.Version,
+	}}, nil
+}
+
+// callerTenantID returns the llm-ops-agent tenant an authenticated caller's
+// cases belong to. scoped is false for stdio and unauthenticated sessions,
+// which may work on the cases of any tenant.
+func (p *Provider) callerTenantID(ctx context.Context) (id int64, scoped bool, err error) {
+	tenant, err := auth.CallerTenant(ctx)
+	if err != nil || tenant == "" {
+		return 0, false, err
+	}
+	id, ok := p.opts.Tenants[tenant]
+	if !ok {
+		return 0, false, fmt.Errorf("no case tenant is configured for tenant %q", tenant)
+	}
+	return id, true, nil
+}
+
+// get reads a case the caller may see. Another tenant's case is reported as
+// not found, so callers cannot probe which case IDs exist.
+func (p *Provider) get(ctx context.Context, id string) (Case, error) {
+	tenantID, scoped, err := p.callerTenantID(ctx)
+	if err != nil {
+		return Case{}, err
+	}
+	c, err := p.client.Get(ctx, id)
+	if err != nil {
+		return Case{}, err
+	}
+	if scoped && c.TenantID != tenantID {
+		return Case{}, &Error{Status: http.StatusNotFound, Message: "case not found"}
+	}
+	return c, nil
+}
+
+// confirmationToken binds an approval to one event on one case version, so a
+// confirmation goes stale as soon as the case moves.
+func (p *Provider) confirmationToken(id, event string, version int64) string {
+	mac := hmac.New(sha256.New, p.secret)
+	fmt.Fprintf(mac, "%s\x00%s\x00%d", id, event, version)
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// idempotencyKey derives a stable key from the parts identifying a request.
+func idempotencyKey(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return "mcp-" + hex.EncodeToString(sum[:16])
+}
+
+func knownEvent(event string) bool {
+	for _, e := range events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,203 @@
+package cases
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/xscopehub/mcp-server/internal/auth"
+	"github.com/xscopehub/mcp-server/internal/registry"
+)
+
+const caseID = "6f1c9a52-0d7e-4a43-9b3e-2f1d9c1f7a10"
+
+func TestTransitionNeedsConfirmationForGatedEvents(t *testing.T) {
+	var patches []*http.Request
+	var body Transition
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/case/"+caseID:
+			w.Write([]byte(`{"case_id":"` + caseID + `","title":"p95 spike","status":"WAIT_GATE","version":4}`))
+		case r.Method == http.MethodPatch && r.URL.Path == "/case/"+caseID+"/transition":
+			patches = append(patches, r)
+			json.NewDecoder(r.Body).Decode(&body)
+			w.Write([]byte(`{"status":"EXECUTING","version":5}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	p := NewProvider(New(Config{BaseURL: ts.URL, Actor: "assistant"}), Options{})
+	reg := registry.New()
+	if err := p.Register(reg); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	args := transitionArgs{CaseID: caseID, Event: "gate_approved", ChangeWindow: true}
+
+	dry, err := p.transitionCase(ctx, transitionArgs{CaseID: caseID, Event: "gate_approved", DryRun: true})
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if out := dry.Output.(map[string]interface{}); len(out) != 2 || out["preview"] == nil || len(patches) != 0 {
+		t.Fatalf("expected only a preview from the dry run, got %+v", out)
+	}
+
+	var confirm *registry.ConfirmationError
+	if _, err := p.transitionCase(ctx, args); !errors.As(err, &confirm) || confirm.Token == "" {
+		t.Fatalf("expected a confirmation error, got %v", err)
+	}
+	if strings.Contains(confirm.Error(), confirm.Token) {
+		t.Fatalf("confirmation error leaks the token: %v", confirm)
+	}
+	// The model cannot approve the event by passing a token as an argument
+	// or by confirming another case version.
+	if _, err := reg.InvokeTool(ctx, "transition_case", map[string]interface{}{
+		"case_id": caseID, "event": "gate_approved", "confirmation_token": confirm.Token,
+	}); err == nil {
+		t.Fatalf("expected a token argument to be rejected")
+	}
+	stale := p.confirmationToken(caseID, "gate_approved", 3)
+	if _, err := p.transitionCase(registry.WithConfirmation(ctx, stale), args); !errors.As(err, &confirm) {
+		t.Fatalf("expected a stale confirmation to be refused, got %v", err)
+	}
+	if len(patches) != 0 {
+		t.Fatalf("transition applied without confirmation")
+	}
+
+	res, err := p.transitionCase(registry.WithConfirmation(ctx, confirm.Token), args)
+	if err != nil {
+		t.Fatalf("confirmed transition: %v", err)
+	}
+	req := patches[0]
+	if req.Header.Get("If-Match") != "4" || req.Header.Get("Idempotency-Key") == "" || req.Header.Get("X-Actor") != "assistant" {
+		t.Fatalf("unexpected headers %v", req.Header)
+	}
+	if !body.GateApproved || !body.ChangeWindow || body.Event != "gate_approved" {
+		t.Fatalf("unexpected transition body %+v", body)
+	}
+	out := res.Output.(map[string]interface{})
+	if out["previous_status"] != "WAIT_GATE" || out["status"] != "EXECUTING" || out["version"] != int64(5) {
+		t.Fatalf("unexpected output %+v", out)
+	}
+}
+
+func TestTransitionSurfacesConflicts(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`{"case_id":"` + caseID + `","status":"NEW","version":1}`))
+			return
+		}
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"error":"illegal transition"}`))
+	}))
+	defer ts.Close()
+	p := NewProvider(New(Config{BaseURL: ts.URL}), Options{})
+
+	_, err := p.transitionCase(context.Background(), transitionArgs{CaseID: caseID, Event: "verify_pass"})
+	var caseErr *Error
+	if !errors.As(err, &caseErr) || caseErr.Status != http.StatusConflict || caseErr.Message != "illegal transition" {
+		t.Fatalf("expected a conflict, got %v", err)
+	}
+	if _, err := p.transitionCase(context.Background(), transitionArgs{CaseID: caseID, Event: "close"}); err == nil {
+		t.Fatalf("expected unknown events to be rejected")
+	}
+}
+
+func TestCasesAreScopedToTenant(t *testing.T) {
+	var created map[string]interface{}
+	var patched bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/case/create":
+			json.NewDecoder(r.Body).Decode(&created)
+			w.Write([]byte(`{"case_id":"` + caseID + `","status":"NEW","version":1}`))
+		case r.URL.Path == "/case/"+caseID:
+			w.Write([]byte(`{"case_id":"` + caseID + `","tenant_id":2,"status":"NEW","version":1}`))
+		case r.Method == http.MethodPatch:
+			patched = true
+			w.Write([]byte(`{"status":"ANALYZING","version":2}`))
+		default:
+			w.Write([]byte(`{"items":[]}`))
+		}
+	}))
+	defer ts.Close()
+	p := NewProvider(New(Config{BaseURL: ts.URL}), Options{TenantID: 9, Tenants: map[string]int64{"acme": 1, "globex": 2}})
+	acme := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "alice", Tenant: "acme"})
+
+	if _, err := p.createCase(acme, createCaseArgs{Title: "p95 spike"}); err != nil || created["tenant_id"] != float64(1) {
+		t.Fatalf("expected the case in the caller's tenant, got %v %+v", err, created)
+	}
+	other := int64(2)
+	if _, err := p.createCase(acme, createCaseArgs{Title: "p95 spike", TenantID: &other}); err == nil {
+		t.Fatalf("expected another tenant_id to be rejected")
+	}
+
+	var caseErr *Error
+	if _, err := p.getCase(acme, caseArgs{CaseID: caseID}); !errors.As(err, &caseErr) || caseErr.Status != http.StatusNotFound {
+		t.Fatalf("expected another tenant's case to be not found, got %v", err)
+	}
+	if _, err := p.listTimeline(acme, timelineArgs{CaseID: caseID}); err == nil {
+		t.Fatalf("expected another tenant's timeline to be refused")
+	}
+	if _, err := p.transitionCase(acme, transitionArgs{CaseID: caseID, Event: "start_analysis"}); err == nil || patched {
+		t.Fatalf("expected another tenant's case not to move, got %v", err)
+	}
+	reg := registry.New()
+	if err := p.Register(reg); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reg.ReadResource(acme, CaseURI(caseID)); !errors.Is(err, registry.ErrResourceNotFound) {
+		t.Fatalf("expected another tenant's case resource to be not found, got %v", err)
+	}
+
+	globex := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "bob", Tenant: "globex"})
+	if _, err := p.transitionCase(globex, transitionArgs{CaseID: caseID, Event: "start_analysis"}); err != nil || !patched {
+		t.Fatalf("expected the owning tenant to move its case, got %v", err)
+	}
+	unknown := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "eve", Tenant: "initech"})
+	if _, err := p.getCase(unknown, caseArgs{CaseID: caseID}); err == nil {
+		t.Fatalf("expected a tenant without cases to be refused")
+	}
+	if _, err := p.getCase(context.Background(), caseArgs{CaseID: caseID}); err != nil {
+		t.Fatalf("stdio sessions see every case: %v", err)
+	}
+}
+
+func TestCaseResource(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/case/" + caseID:
+			w.Write([]byte(`{"case_id":"` + caseID + `","status":"ANALYZING","version":2}`))
+		case "/case/" + caseID + "/timeline":
+			if r.URL.Query().Get("latest") != "true" {
+				http.Error(w, "expected the latest entries", http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"items":[{"event":"start_analysis"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	reg := registry.New()
+	if err := NewProvider(New(Config{BaseURL: ts.URL}), Options{}).Register(reg); err != nil {
+		t.Fatal(err)
+	}
+
+	payload, err := reg.ReadResource(context.Background(), CaseURI(caseID))
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	data := payload.Data.(map[string]interface{})
+	if data["case"].(Case).Status != "ANALYZING" || len(data["timeline"].([]TimelineEntry)) != 1 {
+		t.Fatalf("unexpected case resource %+v", data)
+	}
+	if _, err := reg.ReadResource(context.Background(), CaseURI("missing")); !errors.Is(err, registry.ErrResourceNotFound) {
+		t.Fatalf("expected an unknown case to be not found, got %v", err)
+	}
+}
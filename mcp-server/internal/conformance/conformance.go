@@ -0,0 +1,560 @@
+// Package conformance checks a server against its manifest and the MCP
+// protocol. It backs the `mcp validate` and `mcp inspect` commands.
+package conformance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/xscopehub/mcp-server/internal/registry"
+	"github.com/xscopehub/mcp-server/internal/server"
+	"github.com/xscopehub/mcp-server/internal/types"
+	"github.com/xscopehub/mcp-server/pkg/manifest"
+)
+
+// CheckRegistry compares the resources and tools the manifest lists with
+// what reg actually registers. A listed name that is not registered is an
+// error; a registered tool the manifest does not list is a warning, since
+// clients reading the manifest will not know about it. Tool plugins count
+// as listed.
+func CheckRegistry(mf manifest.Manifest, reg *registry.Registry) []manifest.Problem {
+	var problems []manifest.Problem
+
+	resources := map[string]bool{}
+	for _, r := range reg.ListResources() {
+		resources[r.Name] = true
+	}
+	for _, t := range reg.ListTemplates() {
+		resources[t.Name] = true
+	}
+	for i, name := range mf.Resources {
+		if !resources[name] {
+			problems = append(problems, manifest.Problem{Severity: manifest.SeverityError, Field: fmt.Sprintf("resources[%d]", i), Message: fmt.Sprintf("resource %s is not registered", name)})
+		}
+	}
+
+	tools := map[string]bool{}
+	for _, t := range reg.ListTools() {
+		tools[t.Name] = true
+	}
+	listed := map[string]bool{}
+	for i, name := range mf.Tools {
+		listed[name] = true
+		if !tools[name] {
+			problems = append(problems, manifest.Problem{Severity: manifest.SeverityError, Field: fmt.Sprintf("tools[%d]", i), Message: fmt.Sprintf("tool %s is not registered", name)})
+		}
+	}
+	for _, tp := range mf.ToolPlugins {
+		listed[tp.Name] = true
+	}
+	for _, t := range reg.ListTools() {
+		if !listed[t.Name] {
+			problems = append(problems, manifest.Problem{Severity: manifest.SeverityWarning, Field: "tools", Message: fmt.Sprintf("tool %s is registered but not listed", t.Name)})
+		}
+	}
+	return problems
+}
+
+// Statuses of a Check.
+const (
+	StatusPass = "pass"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+	StatusSkip = "skip"
+)
+
+// Check is one step of an inspection.
+type Check struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Report is the result of Inspect.
+type Report struct {
+	Server   string  `json:"server"`
+	Version  string  `json:"version"`
+	Protocol string  `json:"protocol"`
+	Checks   []Check `json:"checks"`
+}
+
+// Failed reports whether any check failed.
+func (r Report) Failed() bool {
+	for _, c := range r.Checks {
+		if c.Status == StatusFail {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteText writes the report as one line per check followed by a summary.
+func (r Report) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "%s %s (MCP %s)\n", r.Server, r.Version, r.Protocol)
+	counts := map[string]int{}
+	for _, c := range r.Checks {
+		counts[c.Status]++
+		line := fmt.Sprintf("%-4s  %s", strings.ToUpper(c.Status), c.Name)
+		if c.Detail != "" {
+			line += ": " + c.Detail
+		}
+		fmt.Fprintln(w, line)
+	}
+	fmt.Fprintf(w, "%d passed, %d warnings, %d failed, %d skipped\n", counts[StatusPass], counts[StatusWarn], counts[StatusFail], counts[StatusSkip])
+}
+
+// Options tunes Inspect.
+type Options struct {
+	// SkipCalls lists tools and prompts without calling them.
+	SkipCalls bool
+	// AllowWrites also calls tools needing a scope ending in ":write", such
+	// as create_case. They are skipped by default since inspect runs against
+	// real backends.
+	AllowWrites bool
+}
+
+// JSON-RPC error codes the session expects.
+const (
+	codeMethodNotFound   = -32601
+	codeInvalidParams    = -32602
+	codeResourceNotFound = -32002
+)
+
+var toolNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,128}$`)
+
+// Inspect runs a scripted MCP session against srv in-process: initialize,
+// ping, listing tools, resources, templates and prompts, calling every tool
+// and prompt with sample arguments and reading every resource, plus the
+// error paths a client relies on. reg must be the registry srv serves; it
+// provides the prompt argument schemas.
+func Inspect(ctx context.Context, srv *server.Server, reg *registry.Registry, mf manifest.Manifest, opts Options) Report {
+	in := &inspector{srv: srv, sess: srv.NewSession(), reg: reg, opts: opts}
+	defer srv.CloseSession(in.sess.ID)
+
+	report := Report{Server: mf.Name, Version: mf.Version, Protocol: server.LatestProtocolVersion}
+	in.initialize(ctx, mf)
+	if !in.failed {
+		in.ping(ctx)
+		in.tools(ctx)
+		in.resources(ctx)
+		in.prompts(ctx)
+		in.expectError(ctx, "unknown method", "tools/unknown", nil, codeMethodNotFound)
+	}
+	report.Checks = in.checks
+	return report
+}
+
+type inspector struct {
+	srv    *server.Server
+	sess   *server.Session
+	reg    *registry.Registry
+	opts   Options
+	nextID int
+	checks []Check
+	failed bool
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (in *inspector) add(name, status, format string, args ...interface{}) {
+	in.checks = append(in.checks, Check{Name: name, Status: status, Detail: fmt.Sprintf(format, args...)})
+	if status == StatusFail {
+		in.failed = true
+	}
+}
+
+// call sends a request and decodes its result into out.
+func (in *inspector) call(ctx context.Context, method string, params, out interface{}) (*rpcError, error) {
+	in.nextID++
+	msg := map[string]interface{}{"jsonrpc": "2.0", "id": in.nextID, "method": method}
+	if params != nil {
+		msg["params"] = params
+	}
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	raw := in.srv.Handle(ctx, in.sess, payload)
+	if raw == nil {
+		return nil, fmt.Errorf("no response to %s", method)
+	}
+	var resp struct {
+		JSONRPC string          `json:"jsonrpc"`
+		ID      int             `json:"id"`
+		Result  json.RawMessage `json:"result"`
+		Error   *rpcError       `json:"error"`
+	}
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	if resp.JSONRPC != "2.0" || resp.ID != in.nextID {
+		return nil, fmt.Errorf("response must echo jsonrpc 2.0 and id %d, got %q and %d", in.nextID, resp.JSONRPC, resp.ID)
+	}
+	if resp.Error != nil {
+		return resp.Error, nil
+	}
+	if len(resp.Result) == 0 {
+		return nil, fmt.Errorf("response has neither result nor error")
+	}
+	if out != nil {
+		if err := json.Unmarshal(resp.Result, out); err != nil {
+			return nil, fmt.Errorf("decode result: %w", err)
+		}
+	}
+	return nil, nil
+}
+
+// do is call for requests expected to succeed; it records a failure and
+// returns false otherwise.
+func (in *inspector) do(ctx context.Context, check, method string, params, out interface{}) bool {
+	rpcErr, err := in.call(ctx, method, params, out)
+	switch {
+	case err != nil:
+		in.add(check, StatusFail, "%v", err)
+		return false
+	case rpcErr != nil:
+		in.add(check, StatusFail, "error %d: %s", rpcErr.Code, rpcErr.Message)
+		return false
+	}
+	return true
+}
+
+func (in *inspector) expectError(ctx context.Context, check, method string, params interface{}, code int) {
+	rpcErr, err := in.call(ctx, method, params, nil)
+	switch {
+	case err != nil:
+		in.add(check, StatusFail, "%v", err)
+	case rpcErr == nil:
+		in.add(check, StatusFail, "expected error %d, got a result", code)
+	case rpcErr.Code != code:
+		in.add(check, StatusFail, "expected error %d, got %d: %s", code, rpcErr.Code, rpcErr.Message)
+	default:
+		in.add(check, StatusPass, "error %d", code)
+	}
+}
+
+func (in *inspector) initialize(ctx context.Context, mf manifest.Manifest) {
+	var res struct {
+		ProtocolVersion string                     `json:"protocolVersion"`
+		Capabilities    map[string]json.RawMessage `json:"capabilities"`
+		ServerInfo      server.Implementation      `json:"serverInfo"`
+	}
+	params := map[string]interface{}{
+		"protocolVersion": server.LatestProtocolVersion,
+		"capabilities":    map[string]interface{}{},
+		"clientInfo":      map[string]string{"name": "mcp-inspect", "version": mf.Version},
+	}
+	if !in.do(ctx, "initialize", "initialize", params, &res) {
+		return
+	}
+	var problems []string
+	if res.ProtocolVersion != server.LatestProtocolVersion {
+		problems = append(problems, fmt.Sprintf("negotiated %q instead of %q", res.ProtocolVersion, server.LatestProtocolVersion))
+	}
+	if res.ServerInfo.Name != mf.Name || res.ServerInfo.Version != mf.Version {
+		problems = append(problems, fmt.Sprintf("serverInfo %s %s does not match the manifest's %s %s", res.ServerInfo.Name, res.ServerInfo.Version, mf.Name, mf.Version))
+	}
+	for _, capability := range []string{"tools", "resources", "prompts"} {
+		if _, ok := res.Capabilities[capability]; !ok {
+			problems = append(problems, "missing capability "+capability)
+		}
+	}
+	if len(problems) > 0 {
+		in.add("initialize", StatusFail, "%s", strings.Join(problems, "; "))
+		return
+	}
+	in.add("initialize", StatusPass, "protocol %s", res.ProtocolVersion)
+
+	if raw := in.srv.Handle(ctx, in.sess, []byte(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)); raw != nil {
+		in.add("notifications/initialized", StatusFail, "a notification must not be answered, got %s", raw)
+		return
+	}
+	in.add("notifications/initialized", StatusPass, "")
+}
+
+func (in *inspector) ping(ctx context.Context) {
+	var res map[string]interface{}
+	if in.do(ctx, "ping", "ping", nil, &res) {
+		in.add("ping", StatusPass, "")
+	}
+}
+
+func (in *inspector) tools(ctx context.Context) {
+	var list struct {
+		Tools []types.ToolDescriptor `json:"tools"`
+	}
+	if !in.do(ctx, "tools/list", "tools/list", nil, &list) {
+		return
+	}
+	names := make([]string, len(list.Tools))
+	for i, t := range list.Tools {
+		names[i] = t.Name
+	}
+	if problems := checkNames(names); problems != "" {
+		in.add("tools/list", StatusFail, "%s", problems)
+	} else {
+		in.add("tools/list", StatusPass, "%d tools", len(list.Tools))
+	}
+
+	for _, t := range list.Tools {
+		check := "tool " + t.Name
+		if !toolNamePattern.MatchString(t.Name) {
+			in.add(check, StatusFail, "name must match %s", toolNamePattern)
+			continue
+		}
+		schema, err := registry.CompileSchema(t.InputSchema)
+		if err != nil {
+			in.add(check, StatusFail, "inputSchema: %v", err)
+			continue
+		}
+		if t.Description == "" {
+			in.add(check, StatusWarn, "no description; models pick tools by their description")
+		}
+		if in.opts.SkipCalls {
+			in.add(check, StatusPass, "listed")
+			continue
+		}
+		if scope := writeScope(in.reg.ToolScopes(t.Name)); scope != "" && !in.opts.AllowWrites {
+			in.add(check, StatusSkip, "needs %s; pass --allow-writes to call it", scope)
+			continue
+		}
+		args, ok := schema.Sample()
+		if !ok {
+			in.add(check, StatusSkip, "could not build sample arguments")
+			continue
+		}
+		in.callTool(ctx, check, t.Name, args)
+	}
+
+	in.expectError(ctx, "tools/call unknown tool", "tools/call", map[string]interface{}{"name": "mcp_inspect_missing"}, codeInvalidParams)
+}
+
+func (in *inspector) callTool(ctx context.Context, check, name string, args map[string]interface{}) {
+	var res struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		IsError bool `json:"isError"`
+	}
+	if !in.do(ctx, check, "tools/call", map[string]interface{}{"name": name, "arguments": args}, &res) {
+		return
+	}
+	if len(res.Content) == 0 {
+		in.add(check, StatusFail, "result has no content")
+		return
+	}
+	for _, c := range res.Content {
+		if c.Type == "" {
+			in.add(check, StatusFail, "content item without a type")
+			return
+		}
+	}
+	if res.IsError {
+		// The protocol worked; the tool itself failed, typically because a
+		// backend is unreachable or rejects the sample arguments.
+		in.add(check, StatusWarn, "tool reported an error: %s", truncate(res.Content[0].Text))
+		return
+	}
+	in.add(check, StatusPass, "called with %s", encode(args))
+}
+
+func (in *inspector) resources(ctx context.Context) {
+	var list struct {
+		Resources []types.ResourceDescriptor `json:"resources"`
+	}
+	if !in.do(ctx, "resources/list", "resources/list", nil, &list) {
+		return
+	}
+	uris := make([]string, len(list.Resources))
+	for i, r := range list.Resources {
+		uris[i] = r.URI
+	}
+	if problems := checkNames(uris); problems != "" {
+		in.add("resources/list", StatusFail, "%s", problems)
+	} else {
+		in.add("resources/list", StatusPass, "%d resources", len(list.Resources))
+	}
+
+	for _, r := range list.Resources {
+		check := "resource " + r.URI
+		if r.Name == "" {
+			in.add(check, StatusFail, "resource without a name")
+			continue
+		}
+		if in.opts.SkipCalls {
+			in.add(check, StatusPass, "listed")
+			continue
+		}
+		var res struct {
+			Contents []struct {
+				URI      string  `json:"uri"`
+				MimeType string  `json:"mimeType"`
+				Text     *string `json:"text"`
+				Blob     *string `json:"blob"`
+			} `json:"contents"`
+		}
+		rpcErr, err := in.call(ctx, "resources/read", map[string]string{"uri": r.URI}, &res)
+		switch {
+		case err != nil:
+			in.add(check, StatusFail, "%v", err)
+		case rpcErr != nil && rpcErr.Code == codeResourceNotFound:
+			in.add(check, StatusFail, "listed but not found: %s", rpcErr.Message)
+		case rpcErr != nil:
+			// Like a tool error: the backend behind the resource is
+			// unavailable rather than the protocol broken.
+			in.add(check, StatusWarn, "read failed with error %d: %s", rpcErr.Code, truncate(rpcErr.Message))
+		case len(res.Contents) == 0:
+			in.add(check, StatusFail, "no contents")
+		case res.Contents[0].URI == "" || (res.Contents[0].Text == nil && res.Contents[0].Blob == nil):
+			in.add(check, StatusFail, "contents need a uri and text or blob")
+		default:
+			in.add(check, StatusPass, "%s", res.Contents[0].MimeType)
+		}
+	}
+
+	var templates struct {
+		ResourceTemplates []types.ResourceTemplate `json:"resourceTemplates"`
+	}
+	if in.do(ctx, "resources/templates/list", "resources/templates/list", nil, &templates) {
+		bad := 0
+		for _, t := range templates.ResourceTemplates {
+			if t.URITemplate == "" || t.Name == "" {
+				bad++
+			}
+		}
+		if bad > 0 {
+			in.add("resources/templates/list", StatusFail, "%d templates without a uriTemplate or name", bad)
+		} else {
+			in.add("resources/templates/list", StatusPass, "%d templates", len(templates.ResourceTemplates))
+		}
+	}
+
+	in.expectError(ctx, "resources/read unknown uri", "resources/read", map[string]string{"uri": registry.ResourceURIPrefix + "mcp-inspect-missing"}, codeResourceNotFound)
+}
+
+func (in *inspector) prompts(ctx context.Context) {
+	var list struct {
+		Prompts []types.PromptDescriptor `json:"prompts"`
+	}
+	if !in.do(ctx, "prompts/list", "prompts/list", nil, &list) {
+		return
+	}
+	names := make([]string, len(list.Prompts))
+	for i, p := range list.Prompts {
+		names[i] = p.Name
+	}
+	if problems := checkNames(names); problems != "" {
+		in.add("prompts/list", StatusFail, "%s", problems)
+	} else {
+		in.add("prompts/list", StatusPass, "%d prompts", len(list.Prompts))
+	}
+
+	for _, p := range list.Prompts {
+		check := "prompt " + p.Name
+		if in.opts.SkipCalls {
+			in.add(check, StatusPass, "listed")
+			continue
+		}
+		schema, ok := in.reg.PromptSchema(p.Name)
+		if !ok {
+			in.add(check, StatusFail, "listed but not registered")
+			continue
+		}
+		sample, ok := schema.Sample()
+		if !ok {
+			in.add(check, StatusSkip, "could not build sample arguments")
+			continue
+		}
+		args := make(map[string]string, len(sample))
+		for k, v := range sample {
+			args[k] = fmt.Sprint(v)
+		}
+		var res types.PromptResult
+		if !in.do(ctx, check, "prompts/get", map[string]interface{}{"name": p.Name, "arguments": args}, &res) {
+			continue
+		}
+		if problem := checkMessages(res.Messages); problem != "" {
+			in.add(check, StatusFail, "%s", problem)
+			continue
+		}
+		in.add(check, StatusPass, "%d messages", len(res.Messages))
+
+		for _, a := range p.Arguments {
+			if a.Required {
+				in.expectError(ctx, check+" without "+a.Name, "prompts/get", map[string]interface{}{"name": p.Name}, codeInvalidParams)
+				break
+			}
+		}
+	}
+}
+
+func checkMessages(messages []types.PromptMessage) string {
+	if len(messages) == 0 {
+		return "no messages"
+	}
+	for i, m := range messages {
+		if m.Role != "user" && m.Role != "assistant" {
+			return fmt.Sprintf("messages[%d]: role must be user or assistant", i)
+		}
+		switch m.Content.Type {
+		case "text":
+			if m.Content.Text == "" {
+				return fmt.Sprintf("messages[%d]: empty text", i)
+			}
+		case "resource_link":
+			if m.Content.URI == "" || m.Content.Name == "" {
+				return fmt.Sprintf("messages[%d]: resource_link needs a uri and name", i)
+			}
+		default:
+			return fmt.Sprintf("messages[%d]: unexpected content type %q", i, m.Content.Type)
+		}
+	}
+	return ""
+}
+
+// checkNames reports duplicates and lists that are not sorted, which keeps
+// paginating clients and diffs of list output stable.
+func checkNames(names []string) string {
+	seen := map[string]bool{}
+	for _, n := range names {
+		if seen[n] {
+			return "duplicate " + n
+		}
+		seen[n] = true
+	}
+	if !sort.StringsAreSorted(names) {
+		return "not sorted"
+	}
+	return ""
+}
+
+func writeScope(scopes []string) string {
+	for _, s := range scopes {
+		if strings.HasSuffix(s, ":write") {
+			return s
+		}
+	}
+	return ""
+}
+
+func encode(v interface{}) string {
+	raw, _ := json.Marshal(v)
+	return string(raw)
+}
+
+func truncate(s string) string {
+	const max = 200
+	if len(s) > max {
+		return s[:max] + "..."
+	}
+	return s
+}
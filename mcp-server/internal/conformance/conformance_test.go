@@ -0,0 +1,88 @@
+package conformance
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/xscopehub/mcp-server/internal/registry"
+	"github.com/xscopehub/mcp-server/internal/server"
+	"github.com/xscopehub/mcp-server/internal/types"
+	"github.com/xscopehub/mcp-server/pkg/manifest"
+)
+
+func newRegistry(t *testing.T) *registry.Registry {
+	t.Helper()
+	reg := registry.New()
+	reg.RegisterResources(registry.StaticResources())
+	reg.RegisterTools(registry.StaticTools())
+	err := reg.AddPrompts(registry.Prompt{
+		Descriptor: types.PromptDescriptor{Name: "triage_service", Arguments: []types.PromptArgument{{Name: "service", Required: true}}},
+		Func: func(ctx context.Context, args map[string]string) ([]types.PromptMessage, error) {
+			return []types.PromptMessage{{Role: "user", Content: types.PromptContent{Type: "text", Text: "Triage " + args["service"]}}}, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reg
+}
+
+func TestInspectPassesAgainstTheServer(t *testing.T) {
+	reg := newRegistry(t)
+	writeTool := registry.Tool{
+		Descriptor: types.ToolDescriptor{Name: "restart_pod", Description: "Restart a pod"},
+		Func: func(ctx context.Context, args map[string]interface{}) (types.ToolResult, error) {
+			t.Errorf("write tools must not be called by default")
+			return types.ToolResult{}, nil
+		},
+		Scopes: []string{"cluster:write"},
+	}
+	if err := reg.AddTools(writeTool); err != nil {
+		t.Fatal(err)
+	}
+	mf := manifest.Manifest{Name: "xscopehub", Version: "1.0.0"}
+	srv := server.New(server.Options{Manifest: mf, Registry: reg})
+
+	report := Inspect(context.Background(), srv, reg, mf, Options{})
+	var text strings.Builder
+	report.WriteText(&text)
+	if report.Failed() {
+		t.Fatalf("expected the built-in server to conform:\n%s", text.String())
+	}
+	for _, want := range []string{"PASS  tool query_logs", "SKIP  tool restart_pod", "PASS  prompt triage_service without service", "PASS  unknown method"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("report lacks %q:\n%s", want, text.String())
+		}
+	}
+}
+
+func TestInspectReportsManifestMismatch(t *testing.T) {
+	reg := newRegistry(t)
+	srv := server.New(server.Options{Manifest: manifest.Manifest{Name: "xscopehub", Version: "1.0.0"}, Registry: reg})
+
+	report := Inspect(context.Background(), srv, reg, manifest.Manifest{Name: "xscopehub", Version: "2.0.0"}, Options{})
+	if !report.Failed() || report.Checks[0].Name != "initialize" || report.Checks[0].Status != StatusFail {
+		t.Fatalf("expected initialize to fail on a version mismatch, got %+v", report.Checks)
+	}
+}
+
+func TestCheckRegistry(t *testing.T) {
+	mf := manifest.Manifest{
+		Resources:   []string{"logs", "runbooks"},
+		Tools:       []string{"query_logs", "restart_pod"},
+		ToolPlugins: []manifest.ToolPlugin{{Name: "summarize_alerts"}},
+	}
+	problems := CheckRegistry(mf, newRegistry(t))
+	var got []string
+	for _, p := range problems {
+		got = append(got, p.String())
+	}
+	want := []string{
+		"error: resources[1]: resource runbooks is not registered",
+		"error: tools[1]: tool restart_pod is not registered",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected problems:\n%s", strings.Join(got, "\n"))
+	}
+}
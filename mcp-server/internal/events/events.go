@@ -0,0 +1,94 @@
+// Package events turns llm-ops-agent's NATS events into MCP resource update
+// notifications. llm-ops-agent's outbox publisher emits a message on
+// evt.case.transition.v1 whenever a case is created or changes state; each
+// one marks the case's xscope://cases/{case_id} resource as updated.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/xscopehub/mcp-server/internal/cases"
+)
+
+// CaseTransitionSubject is the subject llm-ops-agent publishes case
+// transitions on.
+const CaseTransitionSubject = "evt.case.transition.v1"
+
+// Config holds the NATS settings.
+type Config struct {
+	URL string
+	// CaseSubject defaults to CaseTransitionSubject.
+	CaseSubject string
+}
+
+// Subscriber forwards events to a function announcing updated resources.
+type Subscriber struct {
+	cfg     Config
+	updated func(uri string)
+}
+
+// New creates a subscriber calling updated with the URI of every resource an
+// event changes. It returns nil when no NATS URL is configured.
+func New(cfg Config, updated func(uri string)) *Subscriber {
+	if cfg.URL == "" {
+		return nil
+	}
+	if cfg.CaseSubject == "" {
+		cfg.CaseSubject = CaseTransitionSubject
+	}
+	return &Subscriber{cfg: cfg, updated: updated}
+}
+
+// Run subscribes and forwards events until ctx is done. The connection is
+// retried in the background for as long as NATS is unreachable, so the
+// server starts without it; events published meanwhile are missed, as NATS
+// core does not retain them.
+func (s *Subscriber) Run(ctx context.Context) error {
+	nc, err := nats.Connect(s.cfg.URL,
+		nats.Name("mcp-server"),
+		nats.RetryOnFailedConnect(true),
+		nats.MaxReconnects(-1),
+		nats.ReconnectWait(2*time.Second),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				log.Printf("nats disconnected: %v", err)
+			}
+		}),
+		nats.ReconnectHandler(func(nc *nats.Conn) {
+			log.Printf("nats reconnected to %s", nc.ConnectedUrl())
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("connect nats: %w", err)
+	}
+	defer nc.Close()
+
+	if _, err := nc.Subscribe(s.cfg.CaseSubject, func(m *nats.Msg) { s.handleCase(m.Data) }); err != nil {
+		return fmt.Errorf("subscribe %s: %w", s.cfg.CaseSubject, err)
+	}
+	<-ctx.Done()
+	return nc.Drain()
+}
+
+// caseEvent is the payload of evt.case.transition.v1.
+type caseEvent struct {
+	CaseID string `json:"case_id"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Event  string `json:"event"`
+}
+
+func (s *Subscriber) handleCase(data []byte) {
+	var ev caseEvent
+	if err := json.Unmarshal(data, &ev); err != nil || ev.CaseID == "" {
+		log.Printf("ignoring malformed %s event: %s", s.cfg.CaseSubject, data)
+		return
+	}
+	s.updated(cases.CaseURI(ev.CaseID))
+}
@@ -0,0 +1,21 @@
+package events
+
+import (
+	"testing"
+)
+
+func TestHandleCaseEvent(t *testing.T) {
+	var updated []string
+	s := New(Config{URL: "nats://localhost:4222"}, func(uri string) { updated = append(updated, uri) })
+
+	s.handleCase([]byte(`{"case_id":"7f9c2b1e-3d4a-4b5c-9e8f-0a1b2c3d4e5f","from":"NEW","to":"ANALYZING","event":"start_analysis"}`))
+	s.handleCase([]byte(`{"from":"NEW"}`))
+	s.handleCase([]byte(`not json`))
+
+	if len(updated) != 1 || updated[0] != "xscope://cases/7f9c2b1e-3d4a-4b5c-9e8f-0a1b2c3d4e5f" {
+		t.Fatalf("unexpected updates %v", updated)
+	}
+	if New(Config{}, nil) != nil {
+		t.Fatalf("expected no subscriber without a NATS URL")
+	}
+}
@@ -0,0 +1,195 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xscopehub/mcp-server/internal/auth"
+)
+
+// maxErrorBody bounds how much of an error response is kept in messages.
+const maxErrorBody = 4 << 10
+
+// Config holds the connection settings for observe-gateway.
+type Config struct {
+	BaseURL string
+	Tenant  string
+	Token   string
+	Timeout time.Duration
+}
+
+// Request mirrors the observe-gateway POST /api/query payload.
+type Request struct {
+	Lang  string    `json:"lang"`
+	Query string    `json:"query"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Step  string    `json:"step,omitempty"`
+}
+
+// Response mirrors the observe-gateway query response envelope.
+type Response struct {
+	Lang   string          `json:"lang"`
+	Tenant string          `json:"tenant"`
+	Result json.RawMessage `json:"result"`
+	Stats  Stats           `json:"stats"`
+}
+
+// Stats describes how the gateway served a query.
+type Stats struct {
+	Backend    string `json:"backend"`
+	Cached     bool   `json:"cached"`
+	DurationMS int64  `json:"duration_ms"`
+	Cost       int64  `json:"cost"`
+}
+
+// Error is a non-2xx answer from the gateway.
+type Error struct {
+	Status  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("gateway returned %d: %s", e.Status, e.Message)
+}
+
+// Client calls observe-gateway's query API on behalf of a tenant.
+type Client struct {
+	baseURL string
+	tenant  string
+	token   string
+	http    *http.Client
+}
+
+// New builds a client. A nil client is returned when no base URL is set.
+func New(cfg Config) *Client {
+	if cfg.BaseURL == "" {
+		return nil
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return &Client{
+		baseURL: strings.TrimRight(cfg.BaseURL, "/"),
+		tenant:  cfg.Tenant,
+		token:   cfg.Token,
+		http:    &http.Client{Timeout: timeout},
+	}
+}
+
+// Query runs a PromQL, LogQL or TraceQL query through the gateway.
+func (c *Client) Query(ctx context.Context, req Request) (Response, error) {
+	// Authenticated MCP callers query as their own tenant; the configured
+	// tenant serves stdio and unauthenticated sessions.
+	tenant, err := auth.CallerTenant(ctx)
+	if err != nil {
+		return Response{}, err
+	}
+	if tenant == "" {
+		tenant = c.tenant
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return Response{}, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/query", bytes.NewReader(body))
+	if err != nil {
+		return Response{}, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if p, ok := auth.FromContext(ctx); ok {
+		httpReq.Header.Set("X-User", p.Subject)
+	}
+	if tenant != "" {
+		httpReq.Header.Set("X-Tenant", tenant)
+	}
+	if c.token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(httpReq)
+	if err != nil {
+		return Response{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		var payload struct {
+			Error string `json:"error"`
+		}
+		msg := strings.TrimSpace(string(raw))
+		if json.Unmarshal(raw, &payload) == nil && payload.Error != "" {
+			msg = payload.Error
+		}
+		return Response{}, &Error{Status: resp.StatusCode, Message: msg}
+	}
+
+	var out Response
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return Response{}, fmt.Errorf("decode gateway response: %w", err)
+	}
+	return out, nil
+}
+
+// Hits extracts the rows of a log or trace search result.
+func Hits(result json.RawMessage) ([]map[string]interface{}, error) {
+	var body struct {
+		Hits []map[string]interface{} `json:"hits"`
+	}
+	if err := json.Unmarshal(result, &body); err != nil {
+		return nil, fmt.Errorf("decode hits: %w", err)
+	}
+	return body.Hits, nil
+}
+
+// Series is one PromQL series from a vector or matrix result.
+type Series struct {
+	Metric map[string]string `json:"metric"`
+	Value  []interface{}     `json:"value,omitempty"`
+	Values [][]interface{}   `json:"values,omitempty"`
+}
+
+// PromSeries extracts the series of a PromQL vector or matrix result.
+func PromSeries(result json.RawMessage) ([]Series, error) {
+	var body struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+		Data   struct {
+			ResultType string          `json:"resultType"`
+			Result     json.RawMessage `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(result, &body); err != nil {
+		return nil, fmt.Errorf("decode promql result: %w", err)
+	}
+	if body.Status != "" && body.Status != "success" {
+		return nil, fmt.Errorf("promql %s: %s", body.Status, body.Error)
+	}
+	switch body.Data.ResultType {
+	case "vector", "matrix":
+	case "":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unsupported promql result type %q", body.Data.ResultType)
+	}
+	var series []Series
+	if err := json.Unmarshal(body.Data.Result, &series); err != nil {
+		return nil, fmt.Errorf("decode promql series: %w", err)
+	}
+	return series, nil
+}
+
+// QuoteLabelValue quotes s for use inside a PromQL or LogQL matcher.
+func QuoteLabelValue(s string) string {
+	return strconv.Quote(s)
+}
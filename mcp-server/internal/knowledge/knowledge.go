@@ -0,0 +1,187 @@
+// Package knowledge serves semantic search over the knowledge observe-bridge
+// and the knowledge base keep in PostgreSQL: semantic_objects (log excerpts,
+// alerts, changes, playbooks) and kb_chunk (runbook and postmortem chunks).
+// Queries are embedded with the configured embedder and ranked by a blend of
+// vector similarity and full-text relevance.
+package knowledge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/xscopehub/mcp-server/internal/auth"
+	"github.com/xscopehub/mcp-server/internal/registry"
+	"github.com/xscopehub/mcp-server/internal/types"
+)
+
+const (
+	objectTemplate = registry.ResourceURIPrefix + "knowledge/objects/{id}"
+	chunkTemplate  = registry.ResourceURIPrefix + "knowledge/chunks/{id}"
+)
+
+// ObjectURI is the resource of the semantic object with id.
+func ObjectURI(id string) string {
+	return registry.ResourceURIPrefix + "knowledge/objects/" + id
+}
+
+// ChunkURI is the resource of the knowledge base chunk with id.
+func ChunkURI(id int64) string {
+	return registry.ResourceURIPrefix + "knowledge/chunks/" + strconv.FormatInt(id, 10)
+}
+
+// Config holds the database and embedder settings.
+type Config struct {
+	// DatabaseURL is the PostgreSQL database holding semantic_objects and
+	// kb_chunk. Search is disabled when it is empty.
+	DatabaseURL string
+	// EmbedderURL is an OpenAI-compatible embeddings endpoint. It must
+	// produce vectors of the size the tables were filled with.
+	EmbedderURL   string
+	EmbedderModel string
+	EmbedderToken string
+	// EmbedderTimeout bounds one embedding request.
+	EmbedderTimeout time.Duration
+}
+
+// Provider serves search_knowledge and the resources its hits point to.
+type Provider struct {
+	store backend
+	embed *embedder
+}
+
+// New connects to the database lazily and returns the provider. It returns
+// nil when no database is configured.
+func New(cfg Config) (*Provider, error) {
+	if cfg.DatabaseURL == "" {
+		return nil, nil
+	}
+	if cfg.EmbedderURL == "" {
+		return nil, errors.New("knowledge search needs an embedder URL")
+	}
+	pool, err := pgxpool.New(context.Background(), cfg.DatabaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("knowledge database: %w", err)
+	}
+	return &Provider{
+		store: &pgStore{pool: pool},
+		embed: &embedder{
+			url:    cfg.EmbedderURL,
+			model:  cfg.EmbedderModel,
+			token:  cfg.EmbedderToken,
+			client: &http.Client{Timeout: cfg.EmbedderTimeout},
+		},
+	}, nil
+}
+
+// Register adds search_knowledge and the object and chunk resource
+// templates to reg.
+func (p *Provider) Register(reg *registry.Registry) error {
+	err := reg.RegisterTemplate(types.ResourceTemplate{
+		URITemplate: objectTemplate,
+		Name:        "knowledge-object",
+		Title:       "Semantic object",
+		Description: "A log excerpt, alert, change or playbook indexed for search, with the reference to its source record.",
+		MimeType:    "application/json",
+	}, p.readObject, auth.ScopeKnowledgeRead)
+	if err != nil {
+		return err
+	}
+	err = reg.RegisterTemplate(types.ResourceTemplate{
+		URITemplate: chunkTemplate,
+		Name:        "knowledge-chunk",
+		Title:       "Knowledge base chunk",
+		Description: "A chunk of a knowledge base document such as a runbook or postmortem, with its document.",
+		MimeType:    "application/json",
+	}, p.readChunk, auth.ScopeKnowledgeRead)
+	if err != nil {
+		return err
+	}
+	reg.RegisterTools(map[string]registry.Tool{"search_knowledge": p.Tool()})
+	return nil
+}
+
+type searchArgs struct {
+	Query   string `json:"query" description:"What to look for, in natural language." jsonschema:"minLength=1"`
+	Limit   int    `json:"limit" jsonschema:"minimum=1,maximum=50,default=10"`
+	Service string `json:"service,omitempty" description:"Only return semantic objects of this service. Knowledge base chunks are not tied to a service and are always searched."`
+}
+
+// Tool returns search_knowledge.
+func (p *Provider) Tool() registry.Tool {
+	return registry.TypedTool(types.ToolDescriptor{
+		Name:        "search_knowledge",
+		Title:       "Search knowledge",
+		Description: "Find runbooks, past incidents, alerts and log excerpts related to a question. Hits are ranked by semantic and keyword relevance; read a hit's uri for the full record and its source reference.",
+	}, p.search).WithScopes(auth.ScopeKnowledgeRead)
+}
+
+func (p *Provider) search(ctx context.Context, args searchArgs) (types.ToolResult, error) {
+	text := strings.TrimSpace(args.Query)
+	if text == "" {
+		return types.ToolResult{}, errors.New("query is required")
+	}
+	tenant, err := auth.CallerTenant(ctx)
+	if err != nil {
+		return types.ToolResult{}, err
+	}
+	vec, err := p.embed.embed(ctx, text)
+	if err != nil {
+		return types.ToolResult{}, err
+	}
+	hits, skipped, err := p.store.search(ctx, query{vector: vec, text: text, service: args.Service, tenant: tenant, limit: args.Limit})
+	if err != nil {
+		return types.ToolResult{}, err
+	}
+	if hits == nil {
+		hits = []Hit{}
+	}
+	out := map[string]interface{}{"query": text, "hits": hits}
+	if len(skipped) > 0 {
+		out["skipped"] = skipped
+	}
+	return types.ToolResult{Name: "search_knowledge", Output: out}, nil
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+func (p *Provider) readObject(ctx context.Context, uri string, vars map[string]string) (types.ResourcePayload, error) {
+	id := vars["id"]
+	if !uuidPattern.MatchString(id) {
+		return types.ResourcePayload{}, fmt.Errorf("%w: %s", registry.ErrResourceNotFound, uri)
+	}
+	o, err := p.store.object(ctx, id)
+	if errors.Is(err, errNotFound) {
+		return types.ResourcePayload{}, fmt.Errorf("%w: %s", registry.ErrResourceNotFound, uri)
+	}
+	if err != nil {
+		return types.ResourcePayload{}, err
+	}
+	return types.ResourcePayload{URI: uri, Name: "knowledge-object", MimeType: "application/json", Data: o}, nil
+}
+
+func (p *Provider) readChunk(ctx context.Context, uri string, vars map[string]string) (types.ResourcePayload, error) {
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		return types.ResourcePayload{}, fmt.Errorf("%w: %s", registry.ErrResourceNotFound, uri)
+	}
+	tenant, err := auth.CallerTenant(ctx)
+	if err != nil {
+		return types.ResourcePayload{}, err
+	}
+	c, err := p.store.chunk(ctx, id, tenant)
+	if errors.Is(err, errNotFound) {
+		return types.ResourcePayload{}, fmt.Errorf("%w: %s", registry.ErrResourceNotFound, uri)
+	}
+	if err != nil {
+		return types.ResourcePayload{}, err
+	}
+	return types.ResourcePayload{URI: uri, Name: "knowledge-chunk", MimeType: "application/json", Data: c}, nil
+}
@@ -0,0 +1,133 @@
+package knowledge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/xscopehub/mcp-server/internal/auth"
+	"github.com/xscopehub/mcp-server/internal/registry"
+)
+
+type fakeStore struct {
+	got  query
+	hits []Hit
+}
+
+func (f *fakeStore) search(ctx context.Context, q query) ([]Hit, []Skipped, error) {
+	f.got = q
+	return f.hits, []Skipped{{Source: sourceChunks, Reason: "embeddings have 1536 dimensions, the query has 3"}}, nil
+}
+
+func (f *fakeStore) object(ctx context.Context, id string) (Object, error) {
+	if id == "0b9f4c55-7a53-4c1e-9a51-5d1a3f0e2c11" {
+		return Object{ID: id, ObjectType: "alert", Content: "ingestion stalled", RefSource: "postgres:event_envelope"}, nil
+	}
+	return Object{}, errNotFound
+}
+
+// chunk 7 belongs to a document of tenant acme.
+func (f *fakeStore) chunk(ctx context.Context, id int64, tenant string) (Chunk, error) {
+	if id == 7 && (tenant == "" || tenant == "acme") {
+		return Chunk{ID: 7, Content: "restart the ingester"}, nil
+	}
+	return Chunk{}, errNotFound
+}
+
+func newTestProvider(t *testing.T, store backend) (*Provider, *embedRequest) {
+	t.Helper()
+	var got embedRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"data":[{"index":0,"embedding":[0.25,-1,0.5]}]}`))
+	}))
+	t.Cleanup(ts.Close)
+	return &Provider{store: store, embed: &embedder{url: ts.URL, model: "bge-m3", token: "secret", client: ts.Client()}}, &got
+}
+
+func TestSearchKnowledge(t *testing.T) {
+	store := &fakeStore{hits: []Hit{{URI: ObjectURI("0b9f4c55-7a53-4c1e-9a51-5d1a3f0e2c11"), Source: sourceObjects, Score: 0.8}}}
+	p, embedded := newTestProvider(t, store)
+	reg := registry.New()
+	if err := p.Register(reg); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	res, err := reg.InvokeTool(context.Background(), "search_knowledge", map[string]interface{}{"query": " ingestion stalled ", "service": "observe-bridge"})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if embedded.Model != "bge-m3" || len(embedded.Input) != 1 || embedded.Input[0] != "ingestion stalled" {
+		t.Fatalf("unexpected embedding request %+v", embedded)
+	}
+	if store.got.text != "ingestion stalled" || store.got.service != "observe-bridge" || store.got.limit != 10 || len(store.got.vector) != 3 {
+		t.Fatalf("unexpected query %+v", store.got)
+	}
+	out := res.Output.(map[string]interface{})
+	if hits := out["hits"].([]Hit); len(hits) != 1 || hits[0].URI != "xscope://knowledge/objects/0b9f4c55-7a53-4c1e-9a51-5d1a3f0e2c11" {
+		t.Fatalf("unexpected hits %+v", out["hits"])
+	}
+	if skipped := out["skipped"].([]Skipped); len(skipped) != 1 || skipped[0].Source != sourceChunks {
+		t.Fatalf("unexpected skipped %+v", out["skipped"])
+	}
+
+	payload, err := reg.ReadResource(context.Background(), ObjectURI("0b9f4c55-7a53-4c1e-9a51-5d1a3f0e2c11"))
+	if err != nil || payload.Data.(Object).RefSource != "postgres:event_envelope" {
+		t.Fatalf("read object: %+v %v", payload, err)
+	}
+	for _, uri := range []string{ObjectURI("not-a-uuid"), ObjectURI("6f1c9a52-0d7e-4a43-9b3e-2f1d9c1f7a10"), ChunkURI(8), "xscope://knowledge/chunks/x"} {
+		if _, err := reg.ReadResource(context.Background(), uri); !errors.Is(err, registry.ErrResourceNotFound) {
+			t.Fatalf("%s: expected not found, got %v", uri, err)
+		}
+	}
+}
+
+func TestKnowledgeIsScopedToTenant(t *testing.T) {
+	store := &fakeStore{}
+	p, _ := newTestProvider(t, store)
+	reg := registry.New()
+	if err := p.Register(reg); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "grafana", Tenant: "globex"})
+
+	if _, err := reg.InvokeTool(ctx, "search_knowledge", map[string]interface{}{"query": "ingestion stalled"}); err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if store.got.tenant != "globex" {
+		t.Fatalf("expected the search to be limited to the caller's tenant, got %+v", store.got)
+	}
+	if _, err := reg.ReadResource(ctx, ChunkURI(7)); !errors.Is(err, registry.ErrResourceNotFound) {
+		t.Fatalf("expected another tenant's chunk to be not found, got %v", err)
+	}
+	if _, err := reg.ReadResource(auth.WithPrincipal(context.Background(), auth.Principal{Subject: "alice", Tenant: "acme"}), ChunkURI(7)); err != nil {
+		t.Fatalf("read own chunk: %v", err)
+	}
+	noTenant := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "intern"})
+	if _, err := reg.InvokeTool(noTenant, "search_knowledge", map[string]interface{}{"query": "ingestion stalled"}); err == nil {
+		t.Fatalf("expected a caller without a tenant to be refused")
+	}
+}
+
+func TestSearchKnowledgeEmbedderFailure(t *testing.T) {
+	p, _ := newTestProvider(t, &fakeStore{})
+	p.embed.token = "wrong"
+	_, err := p.search(context.Background(), searchArgs{Query: "disk full", Limit: 5})
+	if err == nil || !strings.Contains(err.Error(), "embedder returned 401") {
+		t.Fatalf("expected the embedder error, got %v", err)
+	}
+}
+
+func TestVectorLiteral(t *testing.T) {
+	if got := vectorLiteral([]float32{0.25, -1, 1e-7}); got != "[0.25,-1,1e-07]" {
+		t.Fatalf("got %s", got)
+	}
+}
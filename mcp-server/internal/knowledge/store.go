@@ -0,0 +1,328 @@
+package knowledge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Sources searched, named after their tables.
+const (
+	sourceObjects = "semantic_objects"
+	sourceChunks  = "kb_chunk"
+)
+
+// Hit is one ranked search result. Score blends the cosine similarity of the
+// embeddings (70%) with the full-text rank of the query (30%), the weighting
+// of observe-bridge's vector.DAO.SearchHybrid.
+type Hit struct {
+	URI         string          `json:"uri"`
+	Source      string          `json:"source"`
+	ID          string          `json:"id"`
+	ObjectType  string          `json:"object_type,omitempty"`
+	Service     string          `json:"service,omitempty"`
+	Title       string          `json:"title,omitempty"`
+	Snippet     string          `json:"snippet"`
+	Score       float64         `json:"score"`
+	VectorScore float64         `json:"vector_score"`
+	TextScore   float64         `json:"text_score"`
+	TS          *time.Time      `json:"ts,omitempty"`
+	RefSource   string          `json:"ref_source,omitempty"`
+	RefKey      json.RawMessage `json:"ref_key,omitempty"`
+}
+
+// Skipped names a source a search could not use and why.
+type Skipped struct {
+	Source string `json:"source"`
+	Reason string `json:"reason"`
+}
+
+// Object is a row of semantic_objects.
+type Object struct {
+	ID         string          `json:"id"`
+	ObjectType string          `json:"object_type"`
+	Service    string          `json:"service,omitempty"`
+	Host       string          `json:"host,omitempty"`
+	TraceID    string          `json:"trace_id,omitempty"`
+	SpanID     string          `json:"span_id,omitempty"`
+	TS         time.Time       `json:"ts"`
+	Title      string          `json:"title,omitempty"`
+	Content    string          `json:"content"`
+	Labels     json.RawMessage `json:"labels,omitempty"`
+	RefSource  string          `json:"ref_source,omitempty"`
+	RefKey     json.RawMessage `json:"ref_key,omitempty"`
+}
+
+// Chunk is a row of kb_chunk with the document it belongs to.
+type Chunk struct {
+	ID       int64           `json:"id"`
+	DocID    int64           `json:"doc_id"`
+	Index    int             `json:"chunk_idx"`
+	Content  string          `json:"content"`
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+	Doc      struct {
+		Source    string          `json:"source,omitempty"`
+		Title     string          `json:"title,omitempty"`
+		URL       string          `json:"url,omitempty"`
+		Metadata  json.RawMessage `json:"metadata,omitempty"`
+		CreatedAt *time.Time      `json:"created_at,omitempty"`
+	} `json:"doc"`
+}
+
+// errNotFound is returned for an object or chunk that does not exist.
+var errNotFound = errors.New("not found")
+
+// query is one search. Knowledge base chunks are limited to tenant's
+// documents unless tenant is empty.
+type query struct {
+	vector  []float32
+	text    string
+	service string
+	tenant  string
+	limit   int
+}
+
+// backend is the storage the provider searches; pgStore in production.
+type backend interface {
+	search(ctx context.Context, q query) ([]Hit, []Skipped, error)
+	object(ctx context.Context, id string) (Object, error)
+	// chunk returns a chunk of a document of tenant, or of any document
+	// when tenant is empty.
+	chunk(ctx context.Context, id int64, tenant string) (Chunk, error)
+}
+
+// Each source takes its 200 nearest neighbours and reranks them with the
+// full-text score. Neither table has a tsvector column, so the text side is
+// computed on the fly with the language-neutral simple configuration.
+// kb_doc.tenant_id refers to dim_tenant, whose code is the caller's tenant;
+// chunks are filtered before the nearest neighbours are taken so another
+// tenant's documents cannot crowd out the caller's.
+const searchObjects = `
+WITH v AS (
+    SELECT id, 1 - (embedding <=> $1::vector) AS vscore
+    FROM semantic_objects
+    WHERE ts >= now() - interval '30 days'
+      AND ($4 = '' OR service = $4)
+    ORDER BY embedding <=> $1::vector
+    LIMIT 200
+)
+SELECT s.id::text, s.object_type, coalesce(s.service, ''), s.ts, coalesce(s.title, ''), s.content,
+       v.vscore,
+       ts_rank(to_tsvector('simple', coalesce(s.title, '') || ' ' || s.content), plainto_tsquery('simple', $2)) AS tscore,
+       coalesce(s.ref_source, ''), s.ref_key
+FROM v JOIN semantic_objects s USING (id)
+ORDER BY v.vscore * 0.7 + tscore * 0.3 DESC
+LIMIT $3`
+
+const searchChunks = `
+WITH v AS (
+    SELECT chunk_id, 1 - (embedding <=> $1::vector) AS vscore
+    FROM kb_chunk
+    WHERE $4 = '' OR doc_id IN (
+        SELECT d.doc_id FROM kb_doc d JOIN dim_tenant t ON t.tenant_id = d.tenant_id WHERE t.code = $4)
+    ORDER BY embedding <=> $1::vector
+    LIMIT 200
+)
+SELECT c.chunk_id, coalesce(d.title, ''), c.content, d.created_at,
+       v.vscore,
+       ts_rank(to_tsvector('simple', coalesce(d.title, '') || ' ' || c.content), plainto_tsquery('simple', $2)) AS tscore,
+       coalesce(d.source, ''),
+       jsonb_strip_nulls(jsonb_build_object('doc_id', c.doc_id, 'chunk_idx', c.chunk_idx, 'url', d.url))
+FROM v JOIN kb_chunk c USING (chunk_id) LEFT JOIN kb_doc d ON d.doc_id = c.doc_id
+ORDER BY v.vscore * 0.7 + tscore * 0.3 DESC
+LIMIT $3`
+
+// pgStore searches semantic_objects and kb_chunk in PostgreSQL with
+// pgvector.
+type pgStore struct {
+	pool *pgxpool.Pool
+
+	mu sync.Mutex
+	// dims caches the embedding dimension of each source's table; 0 means
+	// the table does not exist.
+	dims map[string]int
+}
+
+// dimensions reads the declared size of the embedding column of each source.
+// The tables are created by migrations, so the answer is cached once read.
+func (s *pgStore) dimensions(ctx context.Context) (map[string]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.dims != nil {
+		return s.dims, nil
+	}
+	rows, err := s.pool.Query(ctx, `
+SELECT c.relname, a.atttypmod
+FROM pg_attribute a JOIN pg_class c ON c.oid = a.attrelid
+WHERE c.relname = ANY($1) AND a.attname = 'embedding' AND NOT a.attisdropped
+  AND pg_table_is_visible(c.oid)`, []string{sourceObjects, sourceChunks})
+	if err != nil {
+		return nil, fmt.Errorf("read embedding dimensions: %w", err)
+	}
+	dims := map[string]int{sourceObjects: 0, sourceChunks: 0}
+	for rows.Next() {
+		var name string
+		var n int
+		if err := rows.Scan(&name, &n); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("read embedding dimensions: %w", err)
+		}
+		dims[name] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read embedding dimensions: %w", err)
+	}
+	s.dims = dims
+	return dims, nil
+}
+
+func (s *pgStore) search(ctx context.Context, q query) ([]Hit, []Skipped, error) {
+	dims, err := s.dimensions(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	vec := vectorLiteral(q.vector)
+
+	var hits []Hit
+	var skipped []Skipped
+	for _, source := range []string{sourceObjects, sourceChunks} {
+		// pgvector refuses to compare vectors of different sizes, so a
+		// table embedded with another model cannot be searched.
+		switch n := dims[source]; {
+		case n == 0:
+			skipped = append(skipped, Skipped{Source: source, Reason: "table does not exist"})
+			continue
+		case n > 0 && n != len(q.vector):
+			skipped = append(skipped, Skipped{Source: source, Reason: fmt.Sprintf("embeddings have %d dimensions, the query has %d", n, len(q.vector))})
+			continue
+		}
+		var found []Hit
+		if source == sourceObjects {
+			found, err = s.searchObjects(ctx, vec, q)
+		} else {
+			found, err = s.searchChunks(ctx, vec, q)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("search %s: %w", source, err)
+		}
+		hits = append(hits, found...)
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	if len(hits) > q.limit {
+		hits = hits[:q.limit]
+	}
+	return hits, skipped, nil
+}
+
+func (s *pgStore) searchObjects(ctx context.Context, vec string, q query) ([]Hit, error) {
+	rows, err := s.pool.Query(ctx, searchObjects, vec, q.text, q.limit, q.service)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var hits []Hit
+	for rows.Next() {
+		var h Hit
+		var ts time.Time
+		var content string
+		if err := rows.Scan(&h.ID, &h.ObjectType, &h.Service, &ts, &h.Title, &content, &h.VectorScore, &h.TextScore, &h.RefSource, &h.RefKey); err != nil {
+			return nil, err
+		}
+		h.Source, h.URI, h.TS, h.Snippet = sourceObjects, ObjectURI(h.ID), &ts, snippet(content)
+		h.Score = blend(h.VectorScore, h.TextScore)
+		hits = append(hits, h)
+	}
+	return hits, rows.Err()
+}
+
+func (s *pgStore) searchChunks(ctx context.Context, vec string, q query) ([]Hit, error) {
+	rows, err := s.pool.Query(ctx, searchChunks, vec, q.text, q.limit, q.tenant)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var hits []Hit
+	for rows.Next() {
+		var h Hit
+		var id int64
+		var content string
+		if err := rows.Scan(&id, &h.Title, &content, &h.TS, &h.VectorScore, &h.TextScore, &h.RefSource, &h.RefKey); err != nil {
+			return nil, err
+		}
+		h.ID = strconv.FormatInt(id, 10)
+		h.Source, h.URI, h.Snippet = sourceChunks, ChunkURI(id), snippet(content)
+		h.Score = blend(h.VectorScore, h.TextScore)
+		hits = append(hits, h)
+	}
+	return hits, rows.Err()
+}
+
+func (s *pgStore) object(ctx context.Context, id string) (Object, error) {
+	var o Object
+	err := s.pool.QueryRow(ctx, `
+SELECT id::text, object_type, coalesce(service, ''), coalesce(host, ''), coalesce(trace_id, ''), coalesce(span_id, ''),
+       ts, coalesce(title, ''), content, labels, coalesce(ref_source, ''), ref_key
+FROM semantic_objects WHERE id = $1::uuid`, id).Scan(
+		&o.ID, &o.ObjectType, &o.Service, &o.Host, &o.TraceID, &o.SpanID,
+		&o.TS, &o.Title, &o.Content, &o.Labels, &o.RefSource, &o.RefKey)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Object{}, errNotFound
+	}
+	return o, err
+}
+
+func (s *pgStore) chunk(ctx context.Context, id int64, tenant string) (Chunk, error) {
+	var c Chunk
+	err := s.pool.QueryRow(ctx, `
+SELECT c.chunk_id, c.doc_id, c.chunk_idx, c.content, c.metadata,
+       coalesce(d.source, ''), coalesce(d.title, ''), coalesce(d.url, ''), d.metadata, d.created_at
+FROM kb_chunk c LEFT JOIN kb_doc d ON d.doc_id = c.doc_id
+WHERE c.chunk_id = $1
+  AND ($2 = '' OR d.tenant_id = (SELECT tenant_id FROM dim_tenant WHERE code = $2))`, id, tenant).Scan(
+		&c.ID, &c.DocID, &c.Index, &c.Content, &c.Metadata,
+		&c.Doc.Source, &c.Doc.Title, &c.Doc.URL, &c.Doc.Metadata, &c.Doc.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Chunk{}, errNotFound
+	}
+	return c, err
+}
+
+func blend(vscore, tscore float64) float64 {
+	return vscore*0.7 + tscore*0.3
+}
+
+// vectorLiteral formats v as pgvector's text input, e.g. [0.1,0.2], so no
+// pgvector driver type is needed.
+func vectorLiteral(v []float32) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, f := range v {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(f), 'g', -1, 32))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// maxSnippet bounds a hit's snippet in characters.
+const maxSnippet = 320
+
+func snippet(content string) string {
+	content = strings.Join(strings.Fields(content), " ")
+	if r := []rune(content); len(r) > maxSnippet {
+		return string(r[:maxSnippet]) + "…"
+	}
+	return content
+}
@@ -0,0 +1,117 @@
+// Package plugins loads the plugin files that extend the server without a
+// rebuild. Every *.json file in a plugin directory is one plugin, declaring
+// static resources and out-of-process tools.
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/xscopehub/mcp-server/internal/registry"
+	"github.com/xscopehub/mcp-server/internal/toolplugin"
+	"github.com/xscopehub/mcp-server/internal/types"
+	"github.com/xscopehub/mcp-server/pkg/manifest"
+)
+
+// File is one plugin file.
+type File struct {
+	// Name identifies the plugin in logs. It defaults to the file name
+	// without its extension.
+	Name string `json:"name"`
+	// Resources are served as static resources, under xscope://<name> unless
+	// they carry a URI.
+	Resources []types.ResourcePayload `json:"resources"`
+	// Tools are run by toolplugin; see manifest.ToolPlugin.
+	Tools []manifest.ToolPlugin `json:"tools"`
+
+	// Dir is the directory of the file, which relative tool commands are
+	// resolved against.
+	Dir string `json:"-"`
+}
+
+// LoadDirs reads the plugin files of every directory, ordered by directory
+// and file name. A directory that does not exist holds no plugins. Resources
+// or tools declared by two plugins are an error.
+func LoadDirs(dirs ...string) ([]File, error) {
+	var files []File
+	seen := map[string]string{}
+	for _, dir := range dirs {
+		paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+		if err != nil {
+			return nil, fmt.Errorf("plugin dir %s: %w", dir, err)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			f, err := loadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			for _, res := range f.Resources {
+				key := res.URI
+				if key == "" {
+					key = res.Name
+				}
+				if prev, ok := seen[key]; ok {
+					return nil, fmt.Errorf("plugin %s: resource %s already declared by %s", path, key, prev)
+				}
+				seen[key] = path
+			}
+			for _, tool := range f.Tools {
+				key := "tool " + tool.Name
+				if prev, ok := seen[key]; ok {
+					return nil, fmt.Errorf("plugin %s: tool %s already declared by %s", path, tool.Name, prev)
+				}
+				seen[key] = path
+			}
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+func loadFile(path string) (File, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return File{}, fmt.Errorf("read plugin: %w", err)
+	}
+	var f File
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return File{}, fmt.Errorf("decode plugin %s: %w", path, err)
+	}
+	if f.Name == "" {
+		f.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	f.Dir = filepath.Dir(path)
+	for i, res := range f.Resources {
+		if res.Name == "" && res.URI == "" {
+			return File{}, fmt.Errorf("plugin %s: resource %d needs a name or uri", path, i)
+		}
+	}
+	return f, nil
+}
+
+// Resources collects the resources of all plugins.
+func Resources(files []File) []types.ResourcePayload {
+	var out []types.ResourcePayload
+	for _, f := range files {
+		out = append(out, f.Resources...)
+	}
+	return out
+}
+
+// Tools builds the tools of all plugins.
+func Tools(files []File) ([]registry.Tool, error) {
+	var out []registry.Tool
+	for _, f := range files {
+		tools, err := toolplugin.Tools(f.Dir, f.Tools)
+		if err != nil {
+			return nil, fmt.Errorf("plugin %s: %w", f.Name, err)
+		}
+		out = append(out, tools...)
+	}
+	return out, nil
+}
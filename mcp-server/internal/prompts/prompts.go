@@ -0,0 +1,258 @@
+// Package prompts loads the prompt catalog from the JSON files the manifest
+// references. Message texts and resource link URIs are Go templates over the
+// prompt's arguments, e.g. "Triage {{.service}}" or
+// "xscope://logs/{{.service}}?since={{.since}}".
+package prompts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/xscopehub/mcp-server/internal/registry"
+	"github.com/xscopehub/mcp-server/internal/types"
+)
+
+// File is one prompt file.
+type File struct {
+	Prompts []Definition `json:"prompts"`
+}
+
+// Definition declares one prompt.
+type Definition struct {
+	Name        string     `json:"name"`
+	Title       string     `json:"title,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Arguments   []Argument `json:"arguments,omitempty"`
+	Messages    []Message  `json:"messages"`
+}
+
+// Argument declares a prompt argument and how it is validated.
+type Argument struct {
+	Name        string   `json:"name"`
+	Title       string   `json:"title,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Required    bool     `json:"required,omitempty"`
+	Default     string   `json:"default,omitempty"`
+	Enum        []string `json:"enum,omitempty"`
+	Pattern     string   `json:"pattern,omitempty"`
+	// Format is a JSON Schema format the registry checks: date-time or uuid.
+	Format string `json:"format,omitempty"`
+}
+
+// Message is either text or a link to a resource.
+type Message struct {
+	// Role is user or assistant; it defaults to user.
+	Role         string `json:"role,omitempty"`
+	Text         string `json:"text,omitempty"`
+	ResourceLink *Link  `json:"resource_link,omitempty"`
+}
+
+// Link points the model at a resource, typically a live telemetry resource
+// it can read with resources/read. Argument values are URL-escaped in URI.
+type Link struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// Load reads the prompt files matching patterns, which are file paths or
+// globs relative to dir. It returns the prompts and the files they came
+// from. A pattern matching nothing is an error, and so is a prompt name
+// declared twice.
+func Load(dir string, patterns []string) ([]registry.Prompt, []string, error) {
+	var files []string
+	for _, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, nil, fmt.Errorf("prompt files %s: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, nil, fmt.Errorf("prompt files %s: no such file", pattern)
+		}
+		sort.Strings(matches)
+		files = append(files, matches...)
+	}
+
+	var out []registry.Prompt
+	seen := map[string]string{}
+	for _, path := range files {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("read prompts: %w", err)
+		}
+		var f File
+		if err := json.Unmarshal(raw, &f); err != nil {
+			return nil, nil, fmt.Errorf("decode prompts %s: %w", path, err)
+		}
+		for _, def := range f.Prompts {
+			if prev, ok := seen[def.Name]; ok {
+				return nil, nil, fmt.Errorf("%s: prompt %s already declared in %s", path, def.Name, prev)
+			}
+			seen[def.Name] = path
+			p, err := def.Compile()
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", path, err)
+			}
+			out = append(out, p)
+		}
+	}
+	return out, files, nil
+}
+
+type compiledMessage struct {
+	role string
+	text *template.Template
+	link *Link
+	uri  *template.Template
+	name *template.Template
+}
+
+// Compile turns a definition into a registry prompt. Templates are parsed
+// and tried with every argument set, so a reference to an undeclared
+// argument fails here rather than on prompts/get.
+func (d Definition) Compile() (registry.Prompt, error) {
+	if d.Name == "" {
+		return registry.Prompt{}, fmt.Errorf("prompt without a name")
+	}
+	if len(d.Messages) == 0 {
+		return registry.Prompt{}, fmt.Errorf("prompt %s: no messages", d.Name)
+	}
+
+	desc := types.PromptDescriptor{Name: d.Name, Title: d.Title, Description: d.Description}
+	props := map[string]interface{}{}
+	required := []string{}
+	sample := map[string]string{}
+	for _, a := range d.Arguments {
+		if a.Name == "" {
+			return registry.Prompt{}, fmt.Errorf("prompt %s: argument without a name", d.Name)
+		}
+		desc.Arguments = append(desc.Arguments, types.PromptArgument{Name: a.Name, Title: a.Title, Description: a.Description, Required: a.Required})
+		prop := map[string]interface{}{"type": "string"}
+		if a.Default != "" {
+			prop["default"] = a.Default
+		}
+		if len(a.Enum) > 0 {
+			prop["enum"] = a.Enum
+		}
+		if a.Pattern != "" {
+			prop["pattern"] = a.Pattern
+		}
+		if a.Format != "" {
+			prop["format"] = a.Format
+		}
+		props[a.Name] = prop
+		if a.Required {
+			required = append(required, a.Name)
+		}
+		sample[a.Name] = "x"
+	}
+	schema, _ := json.Marshal(map[string]interface{}{
+		"type":                 "object",
+		"properties":           props,
+		"required":             required,
+		"additionalProperties": false,
+	})
+
+	var messages []compiledMessage
+	for i, m := range d.Messages {
+		cm := compiledMessage{role: m.Role}
+		if cm.role == "" {
+			cm.role = "user"
+		}
+		if cm.role != "user" && cm.role != "assistant" {
+			return registry.Prompt{}, fmt.Errorf("prompt %s: messages[%d]: role must be user or assistant", d.Name, i)
+		}
+		var err error
+		switch {
+		case m.ResourceLink != nil && m.Text == "":
+			cm.link = m.ResourceLink
+			if cm.uri, err = parse(d.Name, i, m.ResourceLink.URI, sample); err == nil {
+				cm.name, err = parse(d.Name, i, m.ResourceLink.Name, sample)
+			}
+		case m.ResourceLink == nil && m.Text != "":
+			cm.text, err = parse(d.Name, i, m.Text, sample)
+		default:
+			err = fmt.Errorf("prompt %s: messages[%d]: set exactly one of text and resource_link", d.Name, i)
+		}
+		if err != nil {
+			return registry.Prompt{}, err
+		}
+		messages = append(messages, cm)
+	}
+
+	return registry.Prompt{
+		Descriptor:     desc,
+		ArgumentSchema: schema,
+		Func: func(ctx context.Context, args map[string]string) ([]types.PromptMessage, error) {
+			return render(messages, args)
+		},
+	}, nil
+}
+
+func parse(prompt string, i int, text string, sample map[string]string) (*template.Template, error) {
+	t, err := template.New(prompt).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("prompt %s: messages[%d]: %w", prompt, i, err)
+	}
+	if err := t.Execute(new(strings.Builder), sample); err != nil {
+		return nil, fmt.Errorf("prompt %s: messages[%d]: %w", prompt, i, err)
+	}
+	return t, nil
+}
+
+func render(messages []compiledMessage, args map[string]string) ([]types.PromptMessage, error) {
+	escaped := make(map[string]string, len(args))
+	for k, v := range args {
+		escaped[k] = url.PathEscape(v)
+	}
+
+	out := make([]types.PromptMessage, 0, len(messages))
+	for _, m := range messages {
+		var content types.PromptContent
+		if m.text != nil {
+			text, err := execute(m.text, args)
+			if err != nil {
+				return nil, err
+			}
+			content = types.PromptContent{Type: "text", Text: strings.TrimSpace(text)}
+		} else {
+			uri, err := execute(m.uri, escaped)
+			if err != nil {
+				return nil, err
+			}
+			name, err := execute(m.name, args)
+			if err != nil {
+				return nil, err
+			}
+			if name == "" {
+				name = uri
+			}
+			mime := m.link.MimeType
+			if mime == "" && strings.HasPrefix(uri, registry.ResourceURIPrefix) {
+				mime = "application/json"
+			}
+			content = types.PromptContent{Type: "resource_link", URI: uri, Name: name, Description: m.link.Description, MimeType: mime}
+		}
+		out = append(out, types.PromptMessage{Role: m.role, Content: content})
+	}
+	return out, nil
+}
+
+func execute(t *template.Template, data map[string]string) (string, error) {
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,75 @@
+package prompts
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xscopehub/mcp-server/internal/registry"
+)
+
+func TestLoadShippedCatalog(t *testing.T) {
+	catalog, files, err := Load("../..", []string{"prompts/*.json"})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(files) == 0 {
+		t.Fatalf("expected prompt files")
+	}
+	reg := registry.New()
+	if err := reg.AddPrompts(catalog...); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	res, err := reg.GetPrompt(context.Background(), "triage_service", map[string]string{"service": "checkout"})
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if !strings.Contains(res.Messages[0].Content.Text, "checkout over the last 30m") {
+		t.Fatalf("default since not applied: %q", res.Messages[0].Content.Text)
+	}
+	link := res.Messages[1].Content
+	if link.Type != "resource_link" || link.URI != "xscope://logs/checkout?since=30m" || link.MimeType != "application/json" {
+		t.Fatalf("unexpected link %+v", link)
+	}
+
+	_, err = reg.GetPrompt(context.Background(), "triage_service", map[string]string{"service": "bad service", "extra": "x"})
+	var invalid *registry.ValidationError
+	if !errors.As(err, &invalid) || len(invalid.Errors) != 2 {
+		t.Fatalf("expected two argument errors, got %v", err)
+	}
+	_, err = reg.GetPrompt(context.Background(), "draft_postmortem", map[string]string{"case_id": "42"})
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected case_id to be checked as a uuid, got %v", err)
+	}
+}
+
+func TestCompileRejectsUndeclaredArguments(t *testing.T) {
+	def := Definition{
+		Name:      "broken",
+		Arguments: []Argument{{Name: "service", Required: true}},
+		Messages:  []Message{{Text: "Look at {{.servce}}"}},
+	}
+	if _, err := def.Compile(); err == nil {
+		t.Fatalf("expected an error for an undeclared argument")
+	}
+}
+
+func TestLoadRejectsDuplicates(t *testing.T) {
+	dir := t.TempDir()
+	body := `{"prompts":[{"name":"same","messages":[{"text":"hi"}]}]}`
+	for _, name := range []string{"a.json", "b.json"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, err := Load(dir, []string{"*.json"}); err == nil || !strings.Contains(err.Error(), "already declared") {
+		t.Fatalf("expected duplicate error, got %v", err)
+	}
+	if _, _, err := Load(dir, []string{"missing/*.json"}); err == nil {
+		t.Fatalf("expected error for a pattern matching nothing")
+	}
+}
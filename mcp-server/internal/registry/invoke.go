@@ -0,0 +1,146 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"time"
+
+	"github.com/xscopehub/mcp-server/internal/types"
+)
+
+// ErrToolPanicked is returned when a tool panics. The panic is logged with
+// its stack and does not take the server down.
+var ErrToolPanicked = errors.New("tool panicked")
+
+type toolEntry struct {
+	fn      ToolFunc
+	schema  *Schema
+	timeout time.Duration
+	slots   chan struct{}
+	scopes  []string
+}
+
+// SetToolLimits sets the timeout of tools registered without one and caps the
+// number of tool calls running at once across the registry. Zero disables
+// either limit. Calls already waiting keep the limits they started with.
+func (r *Registry) SetToolLimits(timeout time.Duration, maxConcurrent int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.toolTimeout = timeout
+	r.toolSlots = nil
+	if maxConcurrent > 0 {
+		r.toolSlots = make(chan struct{}, maxConcurrent)
+	}
+}
+
+// InvokeTool executes a tool by name. Arguments are validated and coerced
+// against the tool's input schema first; a *ValidationError lists every
+// mismatch. The call then waits for a free slot while
+// concurrency limits are reached and is bounded by the tool's timeout. When
+// ctx ends first, InvokeTool returns at once even if the tool ignores ctx.
+func (r *Registry) InvokeTool(ctx context.Context, name string, arguments map[string]interface{}) (types.ToolResult, error) {
+	r.mu.RLock()
+	entry, ok := r.tools[name]
+	defaultTimeout, registrySlots := r.toolTimeout, r.toolSlots
+	r.mu.RUnlock()
+	if !ok {
+		return types.ToolResult{}, fmt.Errorf("%w: %s", ErrToolNotFound, name)
+	}
+	if entry.fn == nil {
+		return types.ToolResult{}, errors.New("tool implementation missing")
+	}
+	arguments, fieldErrs := entry.schema.Validate(arguments)
+	if len(fieldErrs) > 0 {
+		return types.ToolResult{}, &ValidationError{Name: name, Errors: fieldErrs}
+	}
+
+	release, err := acquire(ctx, registrySlots, entry.slots)
+	if err != nil {
+		return types.ToolResult{}, fmt.Errorf("%s: waiting for a free slot: %w", name, err)
+	}
+
+	timeout := entry.timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	type outcome struct {
+		res types.ToolResult
+		err error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		// Slots are held until the tool really returns so a tool that
+		// ignores ctx still counts against the limits.
+		defer release()
+		defer func() {
+			if p := recover(); p != nil {
+				log.Printf("tool %s panicked: %v\n%s", name, p, debug.Stack())
+				done <- outcome{err: fmt.Errorf("%w: %s: %v", ErrToolPanicked, name, p)}
+			}
+		}()
+		res, err := entry.fn(ctx, arguments)
+		done <- outcome{res, err}
+	}()
+
+	select {
+	case out := <-done:
+		return out.res, out.err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && timeout > 0 {
+			return types.ToolResult{}, fmt.Errorf("%s timed out after %s: %w", name, timeout, ctx.Err())
+		}
+		return types.ToolResult{}, fmt.Errorf("%s: %w", name, ctx.Err())
+	}
+}
+
+// acquire takes one slot from each non-nil semaphore, in order, and returns
+// a func releasing them.
+func acquire(ctx context.Context, sems ...chan struct{}) (func(), error) {
+	held := make([]chan struct{}, 0, len(sems))
+	release := func() {
+		for _, sem := range held {
+			<-sem
+		}
+	}
+	for _, sem := range sems {
+		if sem == nil {
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+			held = append(held, sem)
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
+
+// ProgressFunc delivers a progress update of a running tool call to the
+// client. total is zero when unknown.
+type ProgressFunc func(progress, total float64, message string)
+
+type progressKey struct{}
+
+// WithProgress attaches a progress sink to the context a tool runs with.
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// ReportProgress sends a progress update for the tool call running with ctx.
+// It does nothing when the client did not ask for progress.
+func ReportProgress(ctx context.Context, progress, total float64, message string) {
+	if fn, ok := ctx.Value(progressKey{}).(ProgressFunc); ok && fn != nil {
+		fn(progress, total, message)
+	}
+}
@@ -0,0 +1,155 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/xscopehub/mcp-server/internal/types"
+)
+
+// ErrPromptNotFound is returned when no prompt matches a name.
+var ErrPromptNotFound = errors.New("prompt not found")
+
+// PromptFunc renders the messages of a prompt. args holds every declared
+// argument after validation; omitted optional arguments without a default
+// are empty strings.
+type PromptFunc func(ctx context.Context, args map[string]string) ([]types.PromptMessage, error)
+
+// Prompt describes a prompt registration payload.
+type Prompt struct {
+	Descriptor types.PromptDescriptor
+	// ArgumentSchema validates the arguments, which MCP passes as strings.
+	// When empty it is derived from Descriptor.Arguments: every argument is
+	// a string, required ones must be present and unknown ones are refused.
+	ArgumentSchema json.RawMessage
+	Func           PromptFunc
+}
+
+type promptEntry struct {
+	desc   types.PromptDescriptor
+	schema *Schema
+	fn     PromptFunc
+}
+
+func compilePrompts(prompts []Prompt) ([]*promptEntry, error) {
+	out := make([]*promptEntry, len(prompts))
+	for i, p := range prompts {
+		raw := p.ArgumentSchema
+		if len(raw) == 0 {
+			raw = argumentSchema(p.Descriptor.Arguments)
+		}
+		schema, err := CompileSchema(raw)
+		if err != nil {
+			return nil, fmt.Errorf("prompt %s: %w", p.Descriptor.Name, err)
+		}
+		out[i] = &promptEntry{desc: p.Descriptor, schema: schema, fn: p.Func}
+	}
+	return out, nil
+}
+
+func argumentSchema(args []types.PromptArgument) json.RawMessage {
+	s := Schema{Type: schemaTypes{"object"}, Properties: map[string]*Schema{}, AdditionalProperties: json.RawMessage("false")}
+	for _, a := range args {
+		s.Properties[a.Name] = &Schema{Type: schemaTypes{"string"}, Description: a.Description}
+		if a.Required {
+			s.Required = append(s.Required, a.Name)
+		}
+	}
+	raw, _ := json.Marshal(s)
+	return raw
+}
+
+// AddPrompts registers prompts. Nothing is registered when one of the
+// argument schemas is invalid.
+func (r *Registry) AddPrompts(prompts ...Prompt) error {
+	entries, err := compilePrompts(prompts)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	for _, e := range entries {
+		r.prompts[e.desc.Name] = e
+	}
+	r.mu.Unlock()
+	r.notify(PromptsChanged)
+	return nil
+}
+
+// UnregisterPrompt removes a prompt and reports whether it was registered.
+func (r *Registry) UnregisterPrompt(name string) bool {
+	r.mu.Lock()
+	_, ok := r.prompts[name]
+	delete(r.prompts, name)
+	r.mu.Unlock()
+	if ok {
+		r.notify(PromptsChanged)
+	}
+	return ok
+}
+
+// ListPrompts returns prompt descriptors ordered by name.
+func (r *Registry) ListPrompts() []types.PromptDescriptor {
+	r.mu.RLock()
+	descriptors := make([]types.PromptDescriptor, 0, len(r.prompts))
+	for _, e := range r.prompts {
+		descriptors = append(descriptors, e.desc)
+	}
+	r.mu.RUnlock()
+	sort.Slice(descriptors, func(i, j int) bool { return descriptors[i].Name < descriptors[j].Name })
+	return descriptors
+}
+
+// PromptSchema returns the compiled argument schema of a prompt.
+func (r *Registry) PromptSchema(name string) (*Schema, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	entry, ok := r.prompts[name]
+	if !ok {
+		return nil, false
+	}
+	return entry.schema, true
+}
+
+// GetPrompt validates args against the prompt's argument schema and renders
+// it. A *ValidationError lists every argument that does not match.
+func (r *Registry) GetPrompt(ctx context.Context, name string, args map[string]string) (types.PromptResult, error) {
+	r.mu.RLock()
+	entry, ok := r.prompts[name]
+	r.mu.RUnlock()
+	if !ok {
+		return types.PromptResult{}, fmt.Errorf("%w: %s", ErrPromptNotFound, name)
+	}
+
+	raw := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		raw[k] = v
+	}
+	validated, fieldErrs := entry.schema.Validate(raw)
+	if len(fieldErrs) > 0 {
+		return types.PromptResult{}, &ValidationError{Name: name, Errors: fieldErrs}
+	}
+
+	values := make(map[string]string, len(entry.desc.Arguments))
+	for _, a := range entry.desc.Arguments {
+		values[a.Name] = ""
+	}
+	for k, v := range validated {
+		switch val := v.(type) {
+		case string:
+			values[k] = val
+		case float64:
+			values[k] = formatNumber(val)
+		default:
+			values[k] = fmt.Sprint(val)
+		}
+	}
+
+	messages, err := entry.fn(ctx, values)
+	if err != nil {
+		return types.PromptResult{}, fmt.Errorf("render prompt %s: %w", name, err)
+	}
+	return types.PromptResult{Description: entry.desc.Description, Messages: messages}, nil
+}
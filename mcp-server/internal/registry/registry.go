@@ -1,105 +1,579 @@
 package registry
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/xscopehub/mcp-server/internal/auth"
 	"github.com/xscopehub/mcp-server/internal/types"
 )
 
-// Registry maintains the available resources and tools.
+// ResourceURIPrefix is the URI scheme used for resources registered without
+// an explicit URI.
+const ResourceURIPrefix = "xscope://"
+
+var (
+	// ErrResourceNotFound is returned when no resource matches a URI.
+	ErrResourceNotFound = errors.New("resource not found")
+	// ErrToolNotFound is returned when no tool matches a name.
+	ErrToolNotFound = errors.New("tool not found")
+)
+
+// Registry maintains the available resources and tools. It is safe for
+// concurrent use; registrations may change while the server runs and
+// listeners added with OnChange hear about them.
 type Registry struct {
+	mu           sync.RWMutex
 	resources    map[string]types.ResourceDescriptor
 	resourceData map[string]types.ResourcePayload
-	tools        map[string]ToolFunc
-	toolInfo     map[string]types.ToolDescriptor
+	resourceFns  map[string]ResourceFunc
+	// resourceScopes holds the scopes needed to read a concrete resource.
+	resourceScopes map[string][]string
+	templates      []templateEntry
+	tools          map[string]*toolEntry
+	toolInfo       map[string]types.ToolDescriptor
+	prompts        map[string]*promptEntry
+
+	// toolTimeout applies to tools registered without their own timeout and
+	// toolSlots caps the tool calls running at once; see SetToolLimits.
+	toolTimeout time.Duration
+	toolSlots   chan struct{}
+
+	// sources remembers what each ReplaceSource caller registered so the
+	// next replacement can drop it.
+	sources   map[string]sourceEntries
+	listeners []func(Change)
+}
+
+type sourceEntries struct {
+	resources []string
+	tools     []string
+	prompts   []string
+}
+
+// Change tells OnChange listeners which lists an update touched.
+type Change int
+
+const (
+	// ToolsChanged is set when tools were added, replaced or removed.
+	ToolsChanged Change = 1 << iota
+	// ResourcesChanged is set when resources or templates were added,
+	// replaced or removed.
+	ResourcesChanged
+	// PromptsChanged is set when prompts were added, replaced or removed.
+	PromptsChanged
+)
+
+// ResourceFunc produces resource contents when a resource is read. vars holds
+// the values bound by the resource's URI template, if any.
+type ResourceFunc func(ctx context.Context, uri string, vars map[string]string) (types.ResourcePayload, error)
+
+type templateEntry struct {
+	desc     types.ResourceTemplate
+	template *uriTemplate
+	fn       ResourceFunc
+	scopes   []string
+}
+
+// ToolFunc represents the implementation of an MCP tool call. ctx is
+// cancelled when the call times out, the client cancels the request or the
+// client goes away; long-running tools report progress with ReportProgress.
+type ToolFunc func(ctx context.Context, arguments map[string]interface{}) (types.ToolResult, error)
+
+// ConfirmationError is returned by a tool whose call needs human approval.
+// The server asks the user through elicitation when the client supports it
+// and, once the user accepts, calls the tool again with Token attached to the
+// context (see Confirmed). Token never leaves the server: otherwise the
+// error and preview are returned to the model, which cannot approve the call
+// itself, so a human has to act outside the conversation.
+type ConfirmationError struct {
+	Message string
+	Preview interface{}
+	Token   string
 }
 
-// ToolFunc represents the implementation of an MCP tool call.
-type ToolFunc func(arguments map[string]interface{}) (types.ToolResult, error)
+func (e *ConfirmationError) Error() string {
+	return e.Message + "; it needs approval by a human, which this client cannot ask for"
+}
+
+type confirmationKey struct{}
+
+// WithConfirmation marks the tool call running with ctx as approved by the
+// user for the ConfirmationError carrying token.
+func WithConfirmation(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, confirmationKey{}, token)
+}
+
+// Confirmed returns the token the user approved for the tool call running
+// with ctx, or "" when the call was not confirmed.
+func Confirmed(ctx context.Context) string {
+	token, _ := ctx.Value(confirmationKey{}).(string)
+	return token
+}
 
 // New creates an empty registry.
 func New() *Registry {
 	return &Registry{
-		resources:    make(map[string]types.ResourceDescriptor),
-		resourceData: make(map[string]types.ResourcePayload),
-		tools:        make(map[string]ToolFunc),
-		toolInfo:     make(map[string]types.ToolDescriptor),
+		resources:      make(map[string]types.ResourceDescriptor),
+		resourceData:   make(map[string]types.ResourcePayload),
+		resourceFns:    make(map[string]ResourceFunc),
+		resourceScopes: make(map[string][]string),
+		tools:          make(map[string]*toolEntry),
+		toolInfo:       make(map[string]types.ToolDescriptor),
+		prompts:        make(map[string]*promptEntry),
+		sources:        make(map[string]sourceEntries),
+	}
+}
+
+// OnChange adds a listener called after every update of the tool or resource
+// lists. Listeners run synchronously on the updating goroutine and must not
+// block or register into the registry.
+func (r *Registry) OnChange(fn func(Change)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.listeners = append(r.listeners, fn)
+}
+
+func (r *Registry) notify(c Change) {
+	if c == 0 {
+		return
+	}
+	r.mu.RLock()
+	listeners := append([]func(Change){}, r.listeners...)
+	r.mu.RUnlock()
+	for _, fn := range listeners {
+		fn(c)
 	}
 }
 
-// RegisterResources adds resource descriptors and payloads.
-func (r *Registry) RegisterResources(resources []types.ResourcePayload) {
+// RegisterResources adds resource descriptors and payloads. Authenticated
+// callers need scopes to read or subscribe to them.
+func (r *Registry) RegisterResources(resources []types.ResourcePayload, scopes ...string) {
+	r.mu.Lock()
 	for _, res := range resources {
-		desc := types.ResourceDescriptor{
-			Name:        res.Name,
-			Title:       strings.Title(strings.ReplaceAll(res.Name, "_", " ")),
-			Description: res.Description,
-		}
-		r.resources[res.Name] = desc
-		r.resourceData[res.Name] = res
+		r.resourceScopes[r.addResource(res)] = scopes
 	}
+	r.mu.Unlock()
+	r.notify(ResourcesChanged)
 }
 
-// ListResources returns all resource descriptors.
+func (r *Registry) addResource(res types.ResourcePayload) string {
+	if res.URI == "" {
+		res.URI = ResourceURIPrefix + res.Name
+	}
+	if res.MimeType == "" {
+		res.MimeType = "application/json"
+	}
+	desc := types.ResourceDescriptor{
+		URI:         res.URI,
+		Name:        res.Name,
+		Title:       strings.Title(strings.ReplaceAll(res.Name, "_", " ")),
+		Description: res.Description,
+		MimeType:    res.MimeType,
+	}
+	r.resources[res.URI] = desc
+	r.resourceData[res.URI] = res
+	delete(r.resourceFns, res.URI)
+	delete(r.resourceScopes, res.URI)
+	return res.URI
+}
+
+// ListResources returns all resource descriptors ordered by URI.
 func (r *Registry) ListResources() []types.ResourceDescriptor {
+	r.mu.RLock()
 	descriptors := make([]types.ResourceDescriptor, 0, len(r.resources))
 	for _, desc := range r.resources {
 		descriptors = append(descriptors, desc)
 	}
+	r.mu.RUnlock()
+	sort.Slice(descriptors, func(i, j int) bool { return descriptors[i].URI < descriptors[j].URI })
 	return descriptors
 }
 
-// Resource returns the payload for a resource.
-func (r *Registry) Resource(name string) (types.ResourcePayload, error) {
-	payload, ok := r.resourceData[name]
-	if !ok {
-		return types.ResourcePayload{}, fmt.Errorf("resource %s not found", name)
+// RegisterResourceFunc adds a resource whose contents are produced on read.
+// Authenticated callers need scopes to read or subscribe to it.
+func (r *Registry) RegisterResourceFunc(desc types.ResourceDescriptor, fn ResourceFunc, scopes ...string) {
+	r.mu.Lock()
+	r.resources[desc.URI] = desc
+	delete(r.resourceData, desc.URI)
+	r.resourceFns[desc.URI] = fn
+	r.resourceScopes[desc.URI] = scopes
+	r.mu.Unlock()
+	r.notify(ResourcesChanged)
+}
+
+// UnregisterResource removes a concrete resource and reports whether it was
+// registered.
+func (r *Registry) UnregisterResource(uri string) bool {
+	r.mu.Lock()
+	ok := r.removeResource(uri)
+	r.mu.Unlock()
+	if ok {
+		r.notify(ResourcesChanged)
+	}
+	return ok
+}
+
+func (r *Registry) removeResource(uri string) bool {
+	if _, ok := r.resources[uri]; !ok {
+		return false
+	}
+	delete(r.resources, uri)
+	delete(r.resourceData, uri)
+	delete(r.resourceFns, uri)
+	delete(r.resourceScopes, uri)
+	return true
+}
+
+// RegisterTemplate adds a resource template, replacing one registered with
+// the same URI template. Reads of URIs matching it that are not registered
+// as concrete resources are served by fn; authenticated callers need scopes
+// for them.
+func (r *Registry) RegisterTemplate(desc types.ResourceTemplate, fn ResourceFunc, scopes ...string) error {
+	t, err := compileTemplate(desc.URITemplate)
+	if err != nil {
+		return err
+	}
+	entry := templateEntry{desc: desc, template: t, fn: fn, scopes: scopes}
+
+	r.mu.Lock()
+	replaced := false
+	for i := range r.templates {
+		if r.templates[i].desc.URITemplate == desc.URITemplate {
+			r.templates[i] = entry
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		r.templates = append(r.templates, entry)
+	}
+	r.mu.Unlock()
+	r.notify(ResourcesChanged)
+	return nil
+}
+
+// UnregisterTemplate removes a resource template and reports whether it was
+// registered.
+func (r *Registry) UnregisterTemplate(uriTemplate string) bool {
+	r.mu.Lock()
+	ok := false
+	for i, t := range r.templates {
+		if t.desc.URITemplate == uriTemplate {
+			r.templates = append(r.templates[:i:i], r.templates[i+1:]...)
+			ok = true
+			break
+		}
+	}
+	r.mu.Unlock()
+	if ok {
+		r.notify(ResourcesChanged)
+	}
+	return ok
+}
+
+// ListTemplates returns all resource templates ordered by URI template.
+func (r *Registry) ListTemplates() []types.ResourceTemplate {
+	r.mu.RLock()
+	templates := make([]types.ResourceTemplate, 0, len(r.templates))
+	for _, t := range r.templates {
+		templates = append(templates, t.desc)
+	}
+	r.mu.RUnlock()
+	sort.Slice(templates, func(i, j int) bool { return templates[i].URITemplate < templates[j].URITemplate })
+	return templates
+}
+
+// HasResource reports whether uri names a concrete resource or matches a
+// template.
+func (r *Registry) HasResource(uri string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if _, ok := r.resources[uri]; ok {
+		return true
+	}
+	for _, t := range r.templates {
+		if _, ok := t.template.match(uri); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ResourceScopes returns the scopes needed to read or subscribe to uri,
+// resolved the same way as ReadResource.
+func (r *Registry) ResourceScopes(uri string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if _, ok := r.resources[uri]; ok {
+		return r.resourceScopes[uri]
+	}
+	for _, t := range r.templates {
+		if _, ok := t.template.match(uri); ok {
+			return t.scopes
+		}
+	}
+	return nil
+}
+
+// ReadResource returns the contents of a concrete resource or of the first
+// template matching uri, in registration order.
+func (r *Registry) ReadResource(ctx context.Context, uri string) (types.ResourcePayload, error) {
+	r.mu.RLock()
+	payload, static := r.resourceData[uri]
+	fn, vars := r.resourceFns[uri], map[string]string{}
+	if fn == nil && !static {
+		for _, t := range r.templates {
+			if v, ok := t.template.match(uri); ok {
+				fn, vars = t.fn, v
+				break
+			}
+		}
+	}
+	r.mu.RUnlock()
+
+	switch {
+	case static:
+		return payload, nil
+	case fn != nil:
+		return fn(ctx, uri, vars)
 	}
-	return payload, nil
+	return types.ResourcePayload{}, fmt.Errorf("%w: %s", ErrResourceNotFound, uri)
 }
 
-// RegisterTool registers a single tool implementation.
+// RegisterTool registers a single tool implementation without limits of its
+// own. It panics when the descriptor's input schema is invalid, which is a
+// programming error for tools compiled into the server; use AddTool for
+// tools whose schema comes from elsewhere.
 func (r *Registry) RegisterTool(desc types.ToolDescriptor, fn ToolFunc) {
-	r.toolInfo[desc.Name] = desc
-	r.tools[desc.Name] = fn
+	if err := r.AddTool(Tool{Descriptor: desc, Func: fn}); err != nil {
+		panic(err)
+	}
 }
 
-// RegisterTools registers multiple tool implementations.
+// RegisterTools registers multiple tool implementations. Like RegisterTool,
+// it panics on invalid input schemas.
 func (r *Registry) RegisterTools(tools map[string]Tool) {
+	list := make([]Tool, 0, len(tools))
 	for _, tool := range tools {
-		r.RegisterTool(tool.Descriptor, tool.Func)
+		list = append(list, tool)
+	}
+	if err := r.AddTools(list...); err != nil {
+		panic(err)
+	}
+}
+
+// AddTool registers a tool together with its limits. Calls are validated
+// against the descriptor's input schema, which must be a JSON Schema object.
+func (r *Registry) AddTool(tool Tool) error {
+	return r.AddTools(tool)
+}
+
+// AddTools registers several tools at once. Nothing is registered when one
+// of the input schemas is invalid.
+func (r *Registry) AddTools(tools ...Tool) error {
+	entries, err := compileTools(tools)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	for _, c := range entries {
+		r.toolInfo[c.desc.Name] = c.desc
+		r.tools[c.desc.Name] = c.entry
+	}
+	r.mu.Unlock()
+	r.notify(ToolsChanged)
+	return nil
+}
+
+type compiledTool struct {
+	desc  types.ToolDescriptor
+	entry *toolEntry
+}
+
+func compileTools(tools []Tool) ([]compiledTool, error) {
+	out := make([]compiledTool, len(tools))
+	for i, tool := range tools {
+		schema, err := CompileSchema(tool.Descriptor.InputSchema)
+		if err != nil {
+			return nil, fmt.Errorf("tool %s: %w", tool.Descriptor.Name, err)
+		}
+		if len(tool.Descriptor.InputSchema) == 0 {
+			tool.Descriptor.InputSchema = json.RawMessage(`{"type":"object"}`)
+		}
+		entry := &toolEntry{fn: tool.Func, schema: schema, timeout: tool.Timeout, scopes: tool.Scopes}
+		if tool.MaxConcurrent > 0 {
+			entry.slots = make(chan struct{}, tool.MaxConcurrent)
+		}
+		out[i] = compiledTool{desc: tool.Descriptor, entry: entry}
+	}
+	return out, nil
+}
+
+// ToolScopes returns the scopes needed to call a tool.
+func (r *Registry) ToolScopes(name string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if entry, ok := r.tools[name]; ok {
+		return entry.scopes
+	}
+	return nil
+}
+
+// UnregisterTool removes a tool and reports whether it was registered. Calls
+// already running finish normally.
+func (r *Registry) UnregisterTool(name string) bool {
+	r.mu.Lock()
+	_, ok := r.tools[name]
+	delete(r.tools, name)
+	delete(r.toolInfo, name)
+	r.mu.Unlock()
+	if ok {
+		r.notify(ToolsChanged)
 	}
+	return ok
+}
+
+// Set is everything one source contributes to the registry.
+type Set struct {
+	Resources []types.ResourcePayload
+	Tools     []Tool
+	Prompts   []Prompt
+}
+
+// ReplaceSource swaps everything a source registered through ReplaceSource
+// for a new set in one step, so a reloaded plugin directory or manifest never
+// leaves stale entries behind. Nothing changes when a tool or prompt schema
+// is invalid, or when a tool or prompt name is already registered by
+// something other than this source.
+func (r *Registry) ReplaceSource(source string, set Set) error {
+	tools, err := compileTools(set.Tools)
+	if err != nil {
+		return fmt.Errorf("source %s: %w", source, err)
+	}
+	prompts, err := compilePrompts(set.Prompts)
+	if err != nil {
+		return fmt.Errorf("source %s: %w", source, err)
+	}
+
+	r.mu.Lock()
+	old := r.sources[source]
+	if err := r.checkNames(old, tools, prompts); err != nil {
+		r.mu.Unlock()
+		return fmt.Errorf("source %s: %w", source, err)
+	}
+	var change Change
+	if len(old.resources) > 0 || len(set.Resources) > 0 {
+		change |= ResourcesChanged
+	}
+	if len(old.tools) > 0 || len(set.Tools) > 0 {
+		change |= ToolsChanged
+	}
+	if len(old.prompts) > 0 || len(set.Prompts) > 0 {
+		change |= PromptsChanged
+	}
+	for _, uri := range old.resources {
+		r.removeResource(uri)
+	}
+	for _, name := range old.tools {
+		delete(r.tools, name)
+		delete(r.toolInfo, name)
+	}
+	for _, name := range old.prompts {
+		delete(r.prompts, name)
+	}
+	var next sourceEntries
+	for _, res := range set.Resources {
+		next.resources = append(next.resources, r.addResource(res))
+	}
+	for _, c := range tools {
+		r.toolInfo[c.desc.Name] = c.desc
+		r.tools[c.desc.Name] = c.entry
+		next.tools = append(next.tools, c.desc.Name)
+	}
+	for _, p := range prompts {
+		r.prompts[p.desc.Name] = p
+		next.prompts = append(next.prompts, p.desc.Name)
+	}
+	r.sources[source] = next
+	r.mu.Unlock()
+
+	r.notify(change)
+	return nil
+}
+
+// checkNames refuses tool and prompt names declared twice in a set or owned
+// by another registration. The caller holds the lock.
+func (r *Registry) checkNames(old sourceEntries, tools []compiledTool, prompts []*promptEntry) error {
+	owned := map[string]bool{}
+	for _, name := range old.tools {
+		owned["tool "+name] = true
+	}
+	for _, name := range old.prompts {
+		owned["prompt "+name] = true
+	}
+	seen := map[string]bool{}
+	check := func(key string, registered bool) error {
+		if seen[key] || (registered && !owned[key]) {
+			return fmt.Errorf("%s is already registered", key)
+		}
+		seen[key] = true
+		return nil
+	}
+	for _, c := range tools {
+		_, ok := r.tools[c.desc.Name]
+		if err := check("tool "+c.desc.Name, ok); err != nil {
+			return err
+		}
+	}
+	for _, p := range prompts {
+		_, ok := r.prompts[p.desc.Name]
+		if err := check("prompt "+p.desc.Name, ok); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // Tool describes a tool registration payload.
 type Tool struct {
 	Descriptor types.ToolDescriptor
 	Func       ToolFunc
+	// Timeout bounds a single call. Zero falls back to the registry-wide
+	// timeout set with SetToolLimits.
+	Timeout time.Duration
+	// MaxConcurrent caps the calls of this tool running at once. Zero means
+	// no per-tool cap.
+	MaxConcurrent int
+	// Scopes are the OAuth scopes an authenticated caller needs to see and
+	// call the tool. Empty means any authenticated caller.
+	Scopes []string
+}
+
+// WithScopes returns a copy of t requiring scopes.
+func (t Tool) WithScopes(scopes ...string) Tool {
+	t.Scopes = scopes
+	return t
 }
 
-// ListTools returns tool descriptors.
+// ListTools returns tool descriptors ordered by name.
 func (r *Registry) ListTools() []types.ToolDescriptor {
+	r.mu.RLock()
 	descriptors := make([]types.ToolDescriptor, 0, len(r.toolInfo))
 	for _, desc := range r.toolInfo {
 		descriptors = append(descriptors, desc)
 	}
+	r.mu.RUnlock()
+	sort.Slice(descriptors, func(i, j int) bool { return descriptors[i].Name < descriptors[j].Name })
 	return descriptors
 }
 
-// InvokeTool executes a tool by name.
-func (r *Registry) InvokeTool(name string, arguments map[string]interface{}) (types.ToolResult, error) {
-	fn, ok := r.tools[name]
-	if !ok {
-		return types.ToolResult{}, fmt.Errorf("tool %s not found", name)
-	}
-	if fn == nil {
-		return types.ToolResult{}, errors.New("tool implementation missing")
-	}
-	return fn(arguments)
-}
-
 // StaticResources returns example resources for the skeleton server.
 func StaticResources() []types.ResourcePayload {
 	return []types.ResourcePayload{
@@ -203,9 +677,10 @@ func StaticTools() map[string]Tool {
 			Descriptor: types.ToolDescriptor{
 				Name:        "query_logs",
 				Description: "Filter logs by service name and severity.",
-				InputSchema: "{\"service\":\"string\",\"level\":\"string\"}",
+				InputSchema: json.RawMessage(`{"type":"object","properties":{"service":{"type":"string"},"level":{"type":"string"}}}`),
 			},
-			Func: func(arguments map[string]interface{}) (types.ToolResult, error) {
+			Scopes: []string{auth.ScopeTelemetryRead},
+			Func: func(ctx context.Context, arguments map[string]interface{}) (types.ToolResult, error) {
 				service, _ := arguments["service"].(string)
 				level, _ := arguments["level"].(string)
 				result := fmt.Sprintf("queried logs for service=%s level=%s", service, level)
@@ -216,9 +691,10 @@ func StaticTools() map[string]Tool {
 			Descriptor: types.ToolDescriptor{
 				Name:        "summarize_alerts",
 				Description: "Summarize active alerts for operator review.",
-				InputSchema: "{}",
+				InputSchema: json.RawMessage(`{"type":"object","properties":{}}`),
 			},
-			Func: func(arguments map[string]interface{}) (types.ToolResult, error) {
+			Scopes: []string{auth.ScopeTelemetryRead},
+			Func: func(ctx context.Context, arguments map[string]interface{}) (types.ToolResult, error) {
 				_ = arguments
 				summary := "2 alerts active: 1 critical (OpenObserve ingestion stalled), 1 warning (Vector agent backpressure)."
 				return types.ToolResult{Name: "summarize_alerts", Output: map[string]string{"summary": summary}}, nil
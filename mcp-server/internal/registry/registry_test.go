@@ -0,0 +1,90 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/xscopehub/mcp-server/internal/types"
+)
+
+func TestReplaceSourceSwapsRegistrations(t *testing.T) {
+	r := New()
+	var changes []Change
+	r.OnChange(func(c Change) { changes = append(changes, c) })
+
+	noop := func(ctx context.Context, args map[string]interface{}) (types.ToolResult, error) {
+		return types.ToolResult{}, nil
+	}
+	tool := func(name string) Tool { return Tool{Descriptor: types.ToolDescriptor{Name: name}, Func: noop} }
+
+	if err := r.ReplaceSource("plugins", Set{Resources: []types.ResourcePayload{{Name: "runbooks"}, {Name: "oncall"}}, Tools: []Tool{tool("kubectl_get"), tool("git_blame")}}); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	if err := r.ReplaceSource("plugins", Set{Resources: []types.ResourcePayload{{Name: "runbooks"}}}); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	if got := r.ListResources(); len(got) != 1 || got[0].URI != "xscope://runbooks" {
+		t.Fatalf("unexpected resources %+v", got)
+	}
+	if got := r.ListTools(); len(got) != 0 {
+		t.Fatalf("expected plugin tools to be dropped, got %+v", got)
+	}
+	if len(changes) != 2 || changes[1] != ToolsChanged|ResourcesChanged {
+		t.Fatalf("unexpected changes %v", changes)
+	}
+
+	bad := tool("broken")
+	bad.Descriptor.InputSchema = []byte(`{"type":"string"}`)
+	if err := r.ReplaceSource("plugins", Set{Tools: []Tool{bad}}); err == nil {
+		t.Fatalf("expected an invalid schema to be rejected")
+	}
+	if got := r.ListResources(); len(got) != 1 {
+		t.Fatalf("a failed replacement must keep the previous set, got %+v", got)
+	}
+
+	r.RegisterTools(StaticTools())
+	if err := r.ReplaceSource("plugins", Set{Tools: []Tool{tool("query_logs")}}); err == nil {
+		t.Fatalf("expected a plugin shadowing a built-in tool to be rejected")
+	}
+	if err := r.ReplaceSource("plugins", Set{Tools: []Tool{tool("git_blame"), tool("git_blame")}}); err == nil {
+		t.Fatalf("expected a tool declared twice to be rejected")
+	}
+
+	if !r.UnregisterResource("xscope://runbooks") || r.UnregisterResource("xscope://runbooks") {
+		t.Fatalf("unregister should report whether the resource existed")
+	}
+}
+
+func TestRegistryConcurrentUse(t *testing.T) {
+	r := New()
+	r.RegisterTools(StaticTools())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("tool_%d", i)
+			r.RegisterTool(types.ToolDescriptor{Name: name}, func(ctx context.Context, args map[string]interface{}) (types.ToolResult, error) {
+				return types.ToolResult{Name: name}, nil
+			})
+			r.ReplaceSource("plugins", Set{Resources: []types.ResourcePayload{{Name: name}}})
+			r.UnregisterTool(name)
+		}(i)
+		go func() {
+			defer wg.Done()
+			if _, err := r.InvokeTool(context.Background(), "query_logs", map[string]interface{}{"service": "api"}); err != nil {
+				t.Errorf("invoke: %v", err)
+			}
+			r.ListTools()
+			r.ListResources()
+		}()
+	}
+	wg.Wait()
+
+	if got := r.ListTools(); len(got) != 2 || got[0].Name != "query_logs" || got[1].Name != "summarize_alerts" {
+		t.Fatalf("unexpected tools %+v", got)
+	}
+}
@@ -0,0 +1,89 @@
+package registry
+
+import (
+	"strings"
+	"time"
+)
+
+// sampleStrings are tried in order for strings without a default, enum or
+// known format, so common patterns such as durations and hex IDs match.
+var sampleStrings = []string{"example", "30m", "0123456789abcdef0123456789abcdef", "1"}
+
+// Sample returns arguments the schema accepts, for conformance checks that
+// call every tool and prompt. Only required properties are filled, from
+// their default, first enum value, format or a few generic candidates. ok is
+// false when no accepted arguments could be built.
+func (s *Schema) Sample() (map[string]interface{}, bool) {
+	args, _ := s.sample().(map[string]interface{})
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	_, errs := s.Validate(args)
+	return args, len(errs) == 0
+}
+
+func (s *Schema) sample() interface{} {
+	if s.Default != nil {
+		return s.Default
+	}
+	if len(s.Enum) > 0 {
+		return s.Enum[0]
+	}
+	switch {
+	case s.Type.allows("object") && (len(s.Type) > 0 || len(s.Properties) > 0):
+		obj := map[string]interface{}{}
+		for _, name := range s.Required {
+			if prop, ok := s.Properties[name]; ok {
+				obj[name] = prop.sample()
+			} else if s.additional != nil {
+				obj[name] = s.additional.sample()
+			} else {
+				obj[name] = "example"
+			}
+		}
+		return obj
+	case s.Type.allows("string"):
+		return s.sampleString()
+	case s.Type.allows("integer") || s.Type.allows("number"):
+		n := 1.0
+		if s.Minimum != nil && n < *s.Minimum {
+			n = *s.Minimum
+		}
+		if s.Maximum != nil && n > *s.Maximum {
+			n = *s.Maximum
+		}
+		return n
+	case s.Type.allows("boolean"):
+		return false
+	case s.Type.allows("array"):
+		items := []interface{}{}
+		if s.MinItems != nil && s.Items != nil {
+			for i := 0; i < *s.MinItems; i++ {
+				items = append(items, s.Items.sample())
+			}
+		}
+		return items
+	}
+	return nil
+}
+
+func (s *Schema) sampleString() string {
+	var candidates []string
+	switch s.Format {
+	case "uuid":
+		candidates = append(candidates, "00000000-0000-4000-8000-000000000000")
+	case "date-time":
+		candidates = append(candidates, time.Now().UTC().Add(-time.Hour).Format(time.RFC3339))
+	}
+	candidates = append(candidates, sampleStrings...)
+	if s.MinLength != nil {
+		candidates = append(candidates, strings.Repeat("a", *s.MinLength))
+	}
+	for _, c := range candidates {
+		var errs []FieldError
+		if s.validate("", c, &errs); len(errs) == 0 {
+			return c
+		}
+	}
+	return candidates[0]
+}
@@ -0,0 +1,386 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is the subset of JSON Schema used to describe tool arguments: type,
+// properties, required, additionalProperties, items, enum, minimum/maximum,
+// minLength/maxLength, minItems/maxItems, pattern, format (date-time, uuid)
+// and default. Other keywords are accepted and ignored.
+type Schema struct {
+	Type        schemaTypes        `json:"type,omitempty"`
+	Title       string             `json:"title,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Enum        []interface{}      `json:"enum,omitempty"`
+	Minimum     *float64           `json:"minimum,omitempty"`
+	Maximum     *float64           `json:"maximum,omitempty"`
+	MinLength   *int               `json:"minLength,omitempty"`
+	MaxLength   *int               `json:"maxLength,omitempty"`
+	MinItems    *int               `json:"minItems,omitempty"`
+	MaxItems    *int               `json:"maxItems,omitempty"`
+	Pattern     string             `json:"pattern,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	Default     interface{}        `json:"default,omitempty"`
+	// AdditionalProperties is either a bool or a schema for the values of
+	// properties not listed in Properties.
+	AdditionalProperties json.RawMessage `json:"additionalProperties,omitempty"`
+
+	pattern    *regexp.Regexp
+	additional *Schema
+	closed     bool
+}
+
+// schemaTypes accepts both "type": "string" and "type": ["string", "null"].
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*t = schemaTypes{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return fmt.Errorf("type must be a string or an array of strings")
+	}
+	*t = many
+	return nil
+}
+
+func (t schemaTypes) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+func (t schemaTypes) allows(name string) bool {
+	if len(t) == 0 {
+		return true
+	}
+	for _, v := range t {
+		if v == name || (v == "number" && name == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// CompileSchema parses a tool input schema. An empty schema accepts any
+// object.
+func CompileSchema(raw json.RawMessage) (*Schema, error) {
+	if len(raw) == 0 {
+		return &Schema{Type: schemaTypes{"object"}}, nil
+	}
+	var s Schema
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, fmt.Errorf("parse schema: %w", err)
+	}
+	if err := s.compile(""); err != nil {
+		return nil, err
+	}
+	if !s.Type.allows("object") {
+		return nil, fmt.Errorf("tool input schema must be of type object")
+	}
+	return &s, nil
+}
+
+func (s *Schema) compile(path string) error {
+	for _, t := range s.Type {
+		switch t {
+		case "object", "array", "string", "integer", "number", "boolean", "null":
+		default:
+			return fmt.Errorf("%s: unknown type %q", displayPath(path), t)
+		}
+	}
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("%s: pattern: %w", displayPath(path), err)
+		}
+		s.pattern = re
+	}
+	switch raw := strings.TrimSpace(string(s.AdditionalProperties)); raw {
+	case "", "true":
+	case "false":
+		s.closed = true
+	default:
+		var sub Schema
+		if err := json.Unmarshal(s.AdditionalProperties, &sub); err != nil {
+			return fmt.Errorf("%s: additionalProperties: %w", displayPath(path), err)
+		}
+		if err := sub.compile(path + ".*"); err != nil {
+			return err
+		}
+		s.additional = &sub
+	}
+	for name, prop := range s.Properties {
+		if err := prop.compile(joinPath(path, name)); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		if err := s.Items.compile(path + "[]"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FieldError locates one argument that does not match the schema. Path is
+// dotted with [i] for array items, e.g. labels.env or ids[2].
+type FieldError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every argument that failed validation. Name is the
+// tool or prompt called.
+type ValidationError struct {
+	Name   string
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		parts[i] = displayPath(fe.Path) + ": " + fe.Message
+	}
+	return fmt.Sprintf("invalid arguments for %s: %s", e.Name, strings.Join(parts, "; "))
+}
+
+// Validate checks args against the schema and returns a copy with defaults
+// applied and loosely typed values coerced: numeric and boolean strings
+// become numbers and booleans, numbers become strings where strings are
+// expected, and null optional properties are dropped. Numbers stay float64
+// as encoding/json decodes them.
+func (s *Schema) Validate(args map[string]interface{}) (map[string]interface{}, []FieldError) {
+	var errs []FieldError
+	out, _ := s.validate("", args, &errs).(map[string]interface{})
+	if out == nil {
+		out = map[string]interface{}{}
+	}
+	return out, errs
+}
+
+func (s *Schema) validate(path string, v interface{}, errs *[]FieldError) interface{} {
+	fail := func(format string, args ...interface{}) interface{} {
+		*errs = append(*errs, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+		return v
+	}
+
+	v = s.coerce(v)
+	kind := jsonKind(v)
+	if !s.Type.allows(kind) {
+		return fail("must be %s, got %s", strings.Join(s.Type, " or "), kind)
+	}
+	if len(s.Enum) > 0 && !inEnum(s.Enum, v) {
+		return fail("must be one of %s", enumList(s.Enum))
+	}
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		return s.validateObject(path, val, errs)
+	case []interface{}:
+		if s.MinItems != nil && len(val) < *s.MinItems {
+			return fail("must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(val) > *s.MaxItems {
+			return fail("must have at most %d items", *s.MaxItems)
+		}
+		if s.Items == nil {
+			return val
+		}
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, errs)
+		}
+		return out
+	case string:
+		n := len([]rune(val))
+		if s.MinLength != nil && n < *s.MinLength {
+			return fail("must be at least %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			return fail("must be at most %d characters", *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(val) {
+			return fail("must match %s", s.Pattern)
+		}
+		if msg := checkFormat(s.Format, val); msg != "" {
+			return fail("%s", msg)
+		}
+	case float64:
+		if s.Minimum != nil && val < *s.Minimum {
+			return fail("must be >= %s", formatNumber(*s.Minimum))
+		}
+		if s.Maximum != nil && val > *s.Maximum {
+			return fail("must be <= %s", formatNumber(*s.Maximum))
+		}
+	}
+	return v
+}
+
+func (s *Schema) validateObject(path string, obj map[string]interface{}, errs *[]FieldError) interface{} {
+	out := make(map[string]interface{}, len(obj))
+	required := make(map[string]bool, len(s.Required))
+	for _, name := range s.Required {
+		required[name] = true
+	}
+
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		val := obj[name]
+		prop, known := s.Properties[name]
+		switch {
+		case known:
+			if val == nil && !required[name] && !prop.Type.allows("null") {
+				continue
+			}
+			out[name] = prop.validate(joinPath(path, name), val, errs)
+		case s.additional != nil:
+			out[name] = s.additional.validate(joinPath(path, name), val, errs)
+		case s.closed:
+			*errs = append(*errs, FieldError{Path: joinPath(path, name), Message: "is not allowed"})
+		default:
+			out[name] = val
+		}
+	}
+
+	props := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		props = append(props, name)
+	}
+	sort.Strings(props)
+	for _, name := range props {
+		if _, ok := out[name]; ok {
+			continue
+		}
+		if def := s.Properties[name].Default; def != nil {
+			out[name] = def
+		} else if required[name] {
+			*errs = append(*errs, FieldError{Path: joinPath(path, name), Message: "is required"})
+		}
+	}
+	return out
+}
+
+// coerce converts v to the schema's type where the conversion is lossless.
+func (s *Schema) coerce(v interface{}) interface{} {
+	if s.Type.allows(jsonKind(v)) {
+		return v
+	}
+	switch val := v.(type) {
+	case string:
+		trimmed := strings.TrimSpace(val)
+		if s.Type.allows("integer") || s.Type.allows("number") {
+			if f, err := strconv.ParseFloat(trimmed, 64); err == nil {
+				return f
+			}
+		}
+		if s.Type.allows("boolean") {
+			if b, err := strconv.ParseBool(trimmed); err == nil {
+				return b
+			}
+		}
+	case float64:
+		if s.Type.allows("string") {
+			return formatNumber(val)
+		}
+	case bool:
+		if s.Type.allows("string") {
+			return strconv.FormatBool(val)
+		}
+	}
+	return v
+}
+
+func checkFormat(format, v string) string {
+	switch format {
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, v); err != nil {
+			return "must be an RFC 3339 date-time"
+		}
+	case "uuid":
+		if !uuidPattern.MatchString(v) {
+			return "must be a UUID"
+		}
+	}
+	return ""
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// jsonKind names the JSON type of a decoded value. Integral numbers report
+// "integer"; Type.allows lets them pass as "number" too.
+func jsonKind(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		if val == math.Trunc(val) && !math.IsInf(val, 0) {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func inEnum(enum []interface{}, v interface{}) bool {
+	for _, e := range enum {
+		if fmt.Sprint(e) == fmt.Sprint(v) && jsonKind(e) == jsonKind(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func enumList(enum []interface{}) string {
+	parts := make([]string, len(enum))
+	for i, e := range enum {
+		raw, _ := json.Marshal(e)
+		parts[i] = string(raw)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "arguments"
+	}
+	return path
+}
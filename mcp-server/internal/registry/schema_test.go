@@ -0,0 +1,146 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/xscopehub/mcp-server/internal/types"
+)
+
+func TestValidateCoercesAndReportsPaths(t *testing.T) {
+	s, err := CompileSchema(json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"limit": {"type": "integer", "minimum": 1, "default": 20},
+			"since": {"type": "string", "default": "15m"},
+			"errors_only": {"type": "boolean"},
+			"level": {"type": "string", "enum": ["info", "error"]},
+			"labels": {"type": "object", "additionalProperties": {"type": "string", "pattern": "^[a-z]+$"}},
+			"ids": {"type": "array", "items": {"type": "string", "format": "uuid"}},
+			"note": {"type": "string"}
+		},
+		"required": ["level"],
+		"additionalProperties": false
+	}`))
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+
+	got, errs := s.Validate(map[string]interface{}{"limit": "10", "errors_only": "true", "level": "error", "labels": map[string]interface{}{"env": "prod"}, "note": nil})
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors %+v", errs)
+	}
+	want := map[string]interface{}{"limit": 10.0, "since": "15m", "errors_only": true, "level": "error", "labels": map[string]interface{}{"env": "prod"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %#v, want %#v", got, want)
+	}
+
+	_, errs = s.Validate(map[string]interface{}{
+		"limit":  0.0,
+		"level":  "debug",
+		"labels": map[string]interface{}{"env": "PROD"},
+		"ids":    []interface{}{"6f1c9a52-0d7e-4a43-9b3e-2f1d9c1f7a10", "nope"},
+		"extra":  1.0,
+	})
+	wantErrs := []FieldError{
+		{Path: "extra", Message: "is not allowed"},
+		{Path: "ids[1]", Message: "must be a UUID"},
+		{Path: "labels.env", Message: "must match ^[a-z]+$"},
+		{Path: "level", Message: `must be one of "info", "error"`},
+		{Path: "limit", Message: "must be >= 1"},
+	}
+	if !reflect.DeepEqual(errs, wantErrs) {
+		t.Fatalf("got errors %+v", errs)
+	}
+
+	if _, errs := s.Validate(nil); len(errs) != 1 || errs[0].Path != "level" || errs[0].Message != "is required" {
+		t.Fatalf("expected a missing level, got %+v", errs)
+	}
+}
+
+type searchArgs struct {
+	Query  string            `json:"query" description:"Free text." jsonschema:"minLength=1"`
+	Limit  int               `json:"limit" jsonschema:"minimum=1,maximum=100,default=10"`
+	Kind   string            `json:"kind,omitempty" jsonschema:"enum=log|trace"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Since  *string           `json:"since"`
+	Region string            `json:"region,omitempty" jsonschema:"minLength=2,pattern=^[a-z]{2,3}(-[0-9]{1,2})?$"`
+}
+
+func TestTypedToolGeneratesSchemaAndDecodes(t *testing.T) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal(SchemaFor[searchArgs](), &schema); err != nil {
+		t.Fatalf("decode schema: %v", err)
+	}
+	props := schema["properties"].(map[string]interface{})
+	if !reflect.DeepEqual(schema["required"], []interface{}{"query"}) || schema["additionalProperties"] != false {
+		t.Fatalf("unexpected schema %v", schema)
+	}
+	if limit := props["limit"].(map[string]interface{}); limit["default"] != 10.0 || limit["maximum"] != 100.0 {
+		t.Fatalf("unexpected limit schema %v", limit)
+	}
+	if kind := props["kind"].(map[string]interface{}); !reflect.DeepEqual(kind["enum"], []interface{}{"log", "trace"}) {
+		t.Fatalf("unexpected kind schema %v", kind)
+	}
+	if region := props["region"].(map[string]interface{}); region["pattern"] != "^[a-z]{2,3}(-[0-9]{1,2})?$" || region["minLength"] != 2.0 {
+		t.Fatalf("expected the pattern kept whole, commas included, got %v", region)
+	}
+
+	var got searchArgs
+	r := New()
+	r.RegisterTools(map[string]Tool{"search": TypedTool(types.ToolDescriptor{Name: "search"}, func(ctx context.Context, args searchArgs) (types.ToolResult, error) {
+		got = args
+		return types.ToolResult{Name: "search"}, nil
+	})})
+
+	if _, err := r.InvokeTool(context.Background(), "search", map[string]interface{}{"query": "timeout", "labels": map[string]interface{}{"pod": 7.0}}); err != nil {
+		t.Fatalf("invoke: %v", err)
+	}
+	if got.Query != "timeout" || got.Limit != 10 || got.Labels["pod"] != "7" || got.Since != nil {
+		t.Fatalf("unexpected arguments %+v", got)
+	}
+
+	_, err := r.InvokeTool(context.Background(), "search", map[string]interface{}{"query": "", "kind": "metric", "region": "europe"})
+	var verr *ValidationError
+	if !errors.As(err, &verr) || len(verr.Errors) != 3 || verr.Errors[0].Path != "kind" || verr.Errors[1].Path != "query" || verr.Errors[2].Path != "region" {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+}
+
+func TestSchemaSample(t *testing.T) {
+	schema, err := CompileSchema(json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"case_id": {"type": "string", "format": "uuid"},
+			"event": {"type": "string", "enum": ["ack", "resolve"]},
+			"since": {"type": "string", "pattern": "^[0-9]+[smh]$"},
+			"trace_id": {"type": "string", "pattern": "^[0-9a-f]{32}$"},
+			"limit": {"type": "integer", "minimum": 5},
+			"filters": {"type": "object", "properties": {"service": {"type": "string"}}, "required": ["service"]},
+			"optional": {"type": "string"}
+		},
+		"required": ["case_id", "event", "since", "trace_id", "limit", "filters"],
+		"additionalProperties": false
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	args, ok := schema.Sample()
+	if !ok {
+		t.Fatalf("expected sample arguments to validate, got %v", args)
+	}
+	if args["event"] != "ack" || args["since"] != "30m" || args["limit"] != 5.0 {
+		t.Fatalf("unexpected sample %v", args)
+	}
+	if _, present := args["optional"]; present {
+		t.Fatalf("optional properties must be left out, got %v", args)
+	}
+
+	impossible, _ := CompileSchema(json.RawMessage(`{"type":"object","properties":{"code":{"type":"string","pattern":"^Z{3}$"}},"required":["code"]}`))
+	if _, ok := impossible.Sample(); ok {
+		t.Fatalf("expected no sample for an unmatched pattern")
+	}
+}
@@ -0,0 +1,87 @@
+package registry
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var templateVarRegex = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// uriTemplate matches URIs against a subset of RFC 6570: simple {name}
+// expressions in the path and a trailing {?a,b} query expression.
+type uriTemplate struct {
+	raw    string
+	path   *regexp.Regexp
+	names  []string
+	params []string
+}
+
+func compileTemplate(raw string) (*uriTemplate, error) {
+	path, query := raw, ""
+	if i := strings.Index(raw, "{?"); i >= 0 {
+		if !strings.HasSuffix(raw, "}") {
+			return nil, fmt.Errorf("uri template %s: query expression must be last", raw)
+		}
+		path, query = raw[:i], raw[i+2:len(raw)-1]
+	}
+
+	t := &uriTemplate{raw: raw}
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	last := 0
+	for _, loc := range templateVarRegex.FindAllStringSubmatchIndex(path, -1) {
+		pattern.WriteString(regexp.QuoteMeta(path[last:loc[0]]))
+		pattern.WriteString("([^/?#]+)")
+		t.names = append(t.names, path[loc[2]:loc[3]])
+		last = loc[1]
+	}
+	rest := path[last:]
+	if strings.ContainsAny(rest, "{}") {
+		return nil, fmt.Errorf("uri template %s: unsupported expression", raw)
+	}
+	pattern.WriteString(regexp.QuoteMeta(rest))
+	pattern.WriteString("$")
+
+	re, err := regexp.Compile(pattern.String())
+	if err != nil {
+		return nil, fmt.Errorf("uri template %s: %w", raw, err)
+	}
+	t.path = re
+	if query != "" {
+		t.params = strings.Split(query, ",")
+	}
+	return t, nil
+}
+
+// match binds the template variables from uri. Query parameters not declared
+// by the template are ignored.
+func (t *uriTemplate) match(uri string) (map[string]string, bool) {
+	path, rawQuery, _ := strings.Cut(uri, "?")
+	m := t.path.FindStringSubmatch(path)
+	if m == nil {
+		return nil, false
+	}
+
+	vars := make(map[string]string, len(t.names)+len(t.params))
+	for i, name := range t.names {
+		v, err := url.PathUnescape(m[i+1])
+		if err != nil {
+			return nil, false
+		}
+		vars[name] = v
+	}
+	if rawQuery != "" {
+		q, err := url.ParseQuery(rawQuery)
+		if err != nil {
+			return nil, false
+		}
+		for _, name := range t.params {
+			if v := q.Get(name); v != "" {
+				vars[name] = v
+			}
+		}
+	}
+	return vars, true
+}
@@ -0,0 +1,214 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xscopehub/mcp-server/internal/types"
+)
+
+// TypedTool builds a tool whose arguments are decoded into T. The input
+// schema is generated from T with SchemaFor unless desc already carries one,
+// so the registry validates calls before fn sees them.
+func TypedTool[T any](desc types.ToolDescriptor, fn func(ctx context.Context, args T) (types.ToolResult, error)) Tool {
+	if len(desc.InputSchema) == 0 {
+		desc.InputSchema = SchemaFor[T]()
+	}
+	return Tool{
+		Descriptor: desc,
+		Func: func(ctx context.Context, arguments map[string]interface{}) (types.ToolResult, error) {
+			raw, err := json.Marshal(arguments)
+			if err != nil {
+				return types.ToolResult{}, fmt.Errorf("encode arguments: %w", err)
+			}
+			var args T
+			if err := json.Unmarshal(raw, &args); err != nil {
+				return types.ToolResult{}, fmt.Errorf("decode arguments: %w", err)
+			}
+			return fn(ctx, args)
+		},
+	}
+}
+
+// SchemaFor generates the JSON Schema of a struct type from its json tags.
+// Fields are required unless they are pointers or tagged omitempty, and
+// unknown properties are rejected. Two more tags refine a field:
+//
+//	description:"Shown to the model."
+//	jsonschema:"enum=a|b,minimum=1,maximum=200,default=20,format=uuid,minLength=1"
+//
+// A pattern may contain commas, so it must be the last key: everything after
+// "pattern=" is the regular expression, e.g. jsonschema:"minLength=1,pattern=^[a-z]{1,3}$".
+//
+// It panics when T is not a struct or a tag cannot be parsed, since argument
+// types are fixed at compile time.
+func SchemaFor[T any]() json.RawMessage {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("SchemaFor: %s is not a struct", t))
+	}
+	raw, err := json.Marshal(schemaOf(t))
+	if err != nil {
+		panic(fmt.Sprintf("SchemaFor %s: %v", t, err))
+	}
+	return raw
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func schemaOf(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return &Schema{Type: schemaTypes{"string"}, Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: schemaTypes{"string"}}
+	case reflect.Bool:
+		return &Schema{Type: schemaTypes{"boolean"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Type: schemaTypes{"integer"}}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		zero := 0.0
+		return &Schema{Type: schemaTypes{"integer"}, Minimum: &zero}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: schemaTypes{"number"}}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: schemaTypes{"array"}, Items: schemaOf(t.Elem())}
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			panic(fmt.Sprintf("SchemaFor: map key of %s must be a string", t))
+		}
+		values, _ := json.Marshal(schemaOf(t.Elem()))
+		return &Schema{Type: schemaTypes{"object"}, AdditionalProperties: values}
+	case reflect.Struct:
+		return structSchema(t)
+	case reflect.Interface:
+		return &Schema{}
+	}
+	panic(fmt.Sprintf("SchemaFor: unsupported type %s", t))
+}
+
+func structSchema(t reflect.Type) *Schema {
+	s := &Schema{
+		Type:                 schemaTypes{"object"},
+		Properties:           map[string]*Schema{},
+		AdditionalProperties: json.RawMessage("false"),
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			embedded := structSchema(f.Type)
+			for k, v := range embedded.Properties {
+				s.Properties[k] = v
+			}
+			s.Required = append(s.Required, embedded.Required...)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		prop := schemaOf(f.Type)
+		prop.Description = f.Tag.Get("description")
+		if err := applySchemaTag(prop, f.Tag.Get("jsonschema")); err != nil {
+			panic(fmt.Sprintf("SchemaFor: %s.%s: %v", t, f.Name, err))
+		}
+		s.Properties[name] = prop
+		if f.Type.Kind() != reflect.Pointer && !strings.Contains(opts, "omitempty") && prop.Default == nil {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return s
+}
+
+func applySchemaTag(s *Schema, tag string) error {
+	if tag == "" {
+		return nil
+	}
+	for tag != "" {
+		part := tag
+		if strings.HasPrefix(tag, "pattern=") {
+			tag = ""
+		} else {
+			part, tag, _ = strings.Cut(tag, ",")
+		}
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return fmt.Errorf("jsonschema tag %q: want key=value", part)
+		}
+		switch key {
+		case "enum":
+			for _, v := range strings.Split(val, "|") {
+				s.Enum = append(s.Enum, tagValue(s, v))
+			}
+		case "default":
+			s.Default = tagValue(s, val)
+		case "format":
+			s.Format = val
+		case "pattern":
+			s.Pattern = val
+		case "minimum", "maximum":
+			f, err := strconv.ParseFloat(val, 64)
+			if err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			if key == "minimum" {
+				s.Minimum = &f
+			} else {
+				s.Maximum = &f
+			}
+		case "minLength", "maxLength", "minItems", "maxItems":
+			n, err := strconv.Atoi(val)
+			if err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			switch key {
+			case "minLength":
+				s.MinLength = &n
+			case "maxLength":
+				s.MaxLength = &n
+			case "minItems":
+				s.MinItems = &n
+			default:
+				s.MaxItems = &n
+			}
+		default:
+			return fmt.Errorf("unknown jsonschema key %q", key)
+		}
+	}
+	return nil
+}
+
+// tagValue parses an enum or default value written in a tag according to
+// the field's type.
+func tagValue(s *Schema, v string) interface{} {
+	switch {
+	case s.Type.allows("string"):
+		return v
+	case s.Type.allows("boolean"):
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	case s.Type.allows("integer"), s.Type.allows("number"):
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	return v
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xscopehub/mcp-server/internal/audit"
+	"github.com/xscopehub/mcp-server/internal/auth"
+)
+
+func TestAuthScopesAndAudit(t *testing.T) {
+	tokens := filepath.Join(t.TempDir(), "tokens.json")
+	os.WriteFile(tokens, []byte(`{"tokens":[
+		{"token":"reader","subject":"grafana","tenant":"acme","scopes":["telemetry:read"]},
+		{"token":"nobody","subject":"intern"}
+	]}`), 0o600)
+	authn, err := auth.New(auth.Config{TokensFile: tokens})
+	if err != nil {
+		t.Fatalf("auth: %v", err)
+	}
+	var auditLog bytes.Buffer
+	srv := newTestServer()
+	srv.auth, srv.audit = authn, audit.New(&auditLog)
+
+	post := func(token, session, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		if session != "" {
+			req.Header.Set(SessionHeader, session)
+		}
+		res := httptest.NewRecorder()
+		srv.ServeHTTP(res, req)
+		return res
+	}
+
+	res := post("", "", initializeBody)
+	if res.Code != http.StatusUnauthorized || !strings.Contains(res.Header().Get("WWW-Authenticate"), `resource_metadata="http://example.com/.well-known/oauth-protected-resource"`) {
+		t.Fatalf("expected a 401 challenge, got %d %v", res.Code, res.Header())
+	}
+	md := httptest.NewRecorder()
+	srv.ServeHTTP(md, httptest.NewRequest(http.MethodGet, "/.well-known/oauth-protected-resource", nil))
+	if !strings.Contains(md.Body.String(), `"resource":"http://example.com/mcp"`) {
+		t.Fatalf("unexpected resource metadata %s", md.Body.String())
+	}
+
+	res = post("reader", "", initializeBody)
+	session := res.Header().Get(SessionHeader)
+	if res.Code != http.StatusOK || session == "" {
+		t.Fatalf("initialize failed: %d %s", res.Code, res.Body.String())
+	}
+	if res := post("nobody", session, `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`); res.Code != http.StatusForbidden {
+		t.Fatalf("expected another caller to be refused the session, got %d", res.Code)
+	}
+
+	var resp Response
+	json.Unmarshal(post("reader", session, `{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"query_logs","arguments":{"service":"api","api_token":"x"}}}`).Body.Bytes(), &resp)
+	if resp.Error != nil {
+		t.Fatalf("reader should call query_logs: %+v", resp.Error)
+	}
+
+	json.Unmarshal(post("nobody", "", `{"jsonrpc":"2.0","id":4,"method":"tools/list"}`).Body.Bytes(), &resp)
+	if tools := resp.Result.(map[string]interface{})["tools"].([]interface{}); len(tools) != 0 {
+		t.Fatalf("expected tools to be hidden without scopes, got %v", tools)
+	}
+	json.Unmarshal(post("nobody", "", `{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"query_logs","arguments":{}}}`).Body.Bytes(), &resp)
+	if resp.Error == nil || resp.Error.Code != codeForbidden {
+		t.Fatalf("expected a forbidden tool call, got %+v", resp)
+	}
+	for _, method := range []string{"resources/read", "resources/subscribe"} {
+		resp = Response{}
+		json.Unmarshal(post("nobody", "", `{"jsonrpc":"2.0","id":6,"method":"`+method+`","params":{"uri":"xscope://logs"}}`).Body.Bytes(), &resp)
+		if resp.Error == nil || resp.Error.Code != codeForbidden {
+			t.Fatalf("expected %s to be forbidden without scopes, got %+v", method, resp)
+		}
+	}
+	resp = Response{}
+	json.Unmarshal(post("reader", session, `{"jsonrpc":"2.0","id":7,"method":"resources/read","params":{"uri":"xscope://logs"}}`).Body.Bytes(), &resp)
+	if resp.Error != nil {
+		t.Fatalf("reader should read xscope://logs: %+v", resp.Error)
+	}
+
+	var entries []audit.Entry
+	for _, line := range strings.Split(strings.TrimSpace(auditLog.String()), "\n") {
+		var e audit.Entry
+		json.Unmarshal([]byte(line), &e)
+		entries = append(entries, e)
+	}
+	if len(entries) != 2 || entries[0].Outcome != audit.OutcomeOK || entries[0].Subject != "grafana" || entries[0].Tenant != "acme" ||
+		entries[0].Arguments["api_token"] != "[redacted]" || entries[1].Outcome != audit.OutcomeDenied || entries[1].Subject != "intern" {
+		t.Fatalf("unexpected audit entries %+v", entries)
+	}
+}
@@ -0,0 +1,256 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/xscopehub/mcp-server/internal/auth"
+)
+
+const (
+	// SessionHeader carries the session ID assigned during initialize.
+	SessionHeader = "Mcp-Session-Id"
+	// ProtocolVersionHeader carries the negotiated protocol version on
+	// requests after initialize.
+	ProtocolVersionHeader = "Mcp-Protocol-Version"
+
+	// sseKeepAlive is the interval between comment lines on idle SSE streams.
+	sseKeepAlive = 30 * time.Second
+)
+
+// ServeHTTP implements the Streamable HTTP transport on /mcp: POST delivers
+// one JSON-RPC message, GET opens a server-sent events stream for
+// notifications and DELETE terminates the session.
+//
+// An initialize POST creates a session and returns its ID in Mcp-Session-Id.
+// POSTs without the header are served statelessly so single-shot clients keep
+// working.
+//
+// Requests with an Origin header not in Options.AllowedOrigins are refused.
+// When authentication is configured every request needs a bearer token, and
+// a session only accepts requests from the caller that opened it.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.auth != nil && strings.HasPrefix(r.URL.Path, resourceMetadataPath) {
+		s.serveResourceMetadata(w, r)
+		return
+	}
+	if r.URL.Path != "/mcp" {
+		http.NotFound(w, r)
+		return
+	}
+	if origin := r.Header.Get("Origin"); origin != "" && !s.origins[strings.ToLower(origin)] {
+		http.Error(w, fmt.Sprintf("origin %s is not allowed", origin), http.StatusForbidden)
+		return
+	}
+	if s.auth != nil {
+		p, err := s.auth.Authenticate(r.Context(), r.Header.Get("Authorization"))
+		if err != nil {
+			s.unauthorized(w, r, err)
+			return
+		}
+		r = r.WithContext(auth.WithPrincipal(r.Context(), p))
+	}
+	if v := r.Header.Get(ProtocolVersionHeader); v != "" && negotiateVersion(v) != v {
+		http.Error(w, fmt.Sprintf("unsupported protocol version %s", v), http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		s.servePost(w, r)
+	case http.MethodGet:
+		s.serveStream(w, r)
+	case http.MethodDelete:
+		s.serveDelete(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) servePost(w http.ResponseWriter, r *http.Request) {
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			log.Printf("failed to close body: %v", err)
+		}
+	}()
+
+	payload, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("read body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	sess, status := s.postSession(r, payload)
+	if sess == nil {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	if status == http.StatusCreated {
+		w.Header().Set(SessionHeader, sess.ID)
+	}
+	if messageMethod(payload) == "tools/call" {
+		// Tool calls may wait for the user to confirm through elicitation.
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	}
+
+	resp := s.Handle(r.Context(), sess, payload)
+	if resp == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if isParseError(resp) {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	if _, err := w.Write(append(resp, '\n')); err != nil {
+		log.Printf("write response: %v", err)
+	}
+}
+
+// postSession resolves the session a POST belongs to. It returns
+// http.StatusCreated when a new session was started for initialize, and a nil
+// session with an error status when the header names an unknown session or
+// too many sessions are open.
+func (s *Server) postSession(r *http.Request, payload []byte) (*Session, int) {
+	if id := r.Header.Get(SessionHeader); id != "" {
+		return s.requestSession(r, id)
+	}
+
+	if messageMethod(payload) == "initialize" {
+		p, _ := auth.FromContext(r.Context())
+		if sess := s.openSession(p.Subject); sess != nil {
+			return sess, http.StatusCreated
+		}
+		return nil, http.StatusServiceUnavailable
+	}
+	return newSession(""), http.StatusOK
+}
+
+// serveStream relays session notifications as server-sent events until the
+// client disconnects or the session is deleted.
+func (s *Server) serveStream(w http.ResponseWriter, r *http.Request) {
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		http.Error(w, "GET requires Accept: text/event-stream", http.StatusNotAcceptable)
+		return
+	}
+	sess, status := s.requestSession(r, r.Header.Get(SessionHeader))
+	if sess == nil {
+		http.Error(w, "unknown or missing session", status)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	// Streams outlive the server's write timeout.
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return
+		case <-sess.Done():
+			return
+		case msg := <-sess.Outbox():
+			sess.touch()
+			_, err = fmt.Fprintf(w, "event: message\ndata: %s\n\n", msg)
+		case <-keepAlive.C:
+			// An open stream keeps its session from expiring.
+			sess.touch()
+			_, err = io.WriteString(w, ": keep-alive\n\n")
+		}
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) serveDelete(w http.ResponseWriter, r *http.Request) {
+	id := r.Header.Get(SessionHeader)
+	if sess, status := s.requestSession(r, id); sess == nil {
+		http.Error(w, "unknown or missing session", status)
+		return
+	}
+	s.CloseSession(id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requestSession looks up the session a request names. Sessions opened by an
+// authenticated caller answer 403 to everybody else.
+func (s *Server) requestSession(r *http.Request, id string) (*Session, int) {
+	sess, ok := s.Session(id)
+	if !ok {
+		return nil, http.StatusNotFound
+	}
+	if p, ok := auth.FromContext(r.Context()); ok && sess.owner != "" && sess.owner != p.Subject {
+		return nil, http.StatusForbidden
+	}
+	return sess, http.StatusOK
+}
+
+// resourceMetadataPath serves the OAuth protected resource metadata. Clients
+// find it through the WWW-Authenticate header of a 401.
+const resourceMetadataPath = "/.well-known/oauth-protected-resource"
+
+func (s *Server) serveResourceMetadata(w http.ResponseWriter, r *http.Request) {
+	md := s.auth.Metadata()
+	if md.Resource == "" {
+		md.Resource = baseURL(r) + "/mcp"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(md)
+}
+
+// unauthorized answers a request whose credentials were refused, pointing
+// the client at the resource metadata as the MCP authorization spec asks.
+func (s *Server) unauthorized(w http.ResponseWriter, r *http.Request, err error) {
+	if !errors.Is(err, auth.ErrNoCredentials) && !errors.Is(err, auth.ErrInvalidToken) {
+		log.Printf("authenticate request: %v", err)
+		http.Error(w, "authorization server unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	challenge := fmt.Sprintf(`Bearer resource_metadata="%s%s"`, baseURL(r), resourceMetadataPath)
+	if errors.Is(err, auth.ErrInvalidToken) {
+		challenge += `, error="invalid_token"`
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	http.Error(w, err.Error(), http.StatusUnauthorized)
+}
+
+// baseURL reconstructs the scheme and host the client used.
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host
+}
+
+func isParseError(resp []byte) bool {
+	var probe struct {
+		Error *Error `json:"error"`
+	}
+	return json.Unmarshal(resp, &probe) == nil && probe.Error != nil && probe.Error.Code == codeParseError
+}
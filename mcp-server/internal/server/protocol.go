@@ -0,0 +1,116 @@
+package server
+
+import "encoding/json"
+
+// LatestProtocolVersion is the newest MCP revision the server implements.
+const LatestProtocolVersion = "2025-06-18"
+
+// supportedProtocolVersions lists the MCP revisions the server can speak,
+// newest first.
+var supportedProtocolVersions = []string{LatestProtocolVersion, "2025-03-26", "2024-11-05"}
+
+// JSON-RPC error codes used by the server.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+	// codeResourceNotFound is the MCP specific code for unknown resource URIs.
+	codeResourceNotFound = -32002
+	// codeForbidden reports a tool call the caller lacks the scopes for.
+	codeForbidden = -32003
+)
+
+// negotiateVersion returns the requested protocol version when supported and
+// the latest supported version otherwise, as the specification requires.
+func negotiateVersion(requested string) string {
+	for _, v := range supportedProtocolVersions {
+		if v == requested {
+			return v
+		}
+	}
+	return LatestProtocolVersion
+}
+
+// Implementation identifies an MCP client or server.
+type Implementation struct {
+	Name    string `json:"name"`
+	Title   string `json:"title,omitempty"`
+	Version string `json:"version"`
+}
+
+// InitializeParams is sent by the client to open a session.
+type InitializeParams struct {
+	ProtocolVersion string          `json:"protocolVersion"`
+	Capabilities    json.RawMessage `json:"capabilities"`
+	ClientInfo      Implementation  `json:"clientInfo"`
+}
+
+// ServerCapabilities advertises the features the server supports.
+type ServerCapabilities struct {
+	Resources *ResourcesCapability   `json:"resources,omitempty"`
+	Tools     *ListChangedCapability `json:"tools,omitempty"`
+	Prompts   *ListChangedCapability `json:"prompts,omitempty"`
+}
+
+// ResourcesCapability describes optional resource features.
+type ResourcesCapability struct {
+	Subscribe   bool `json:"subscribe,omitempty"`
+	ListChanged bool `json:"listChanged,omitempty"`
+}
+
+// ListChangedCapability describes whether list change notifications are sent.
+type ListChangedCapability struct {
+	ListChanged bool `json:"listChanged,omitempty"`
+}
+
+// InitializeResult answers the initialize request.
+type InitializeResult struct {
+	ProtocolVersion string             `json:"protocolVersion"`
+	Capabilities    ServerCapabilities `json:"capabilities"`
+	ServerInfo      Implementation     `json:"serverInfo"`
+	Instructions    string             `json:"instructions,omitempty"`
+}
+
+// ResourceContents is one entry of a resources/read result.
+type ResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text"`
+}
+
+// Content is a content block returned by tools/call.
+type Content struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+}
+
+// CallToolResult is the result of tools/call. Tool failures are reported with
+// IsError rather than as JSON-RPC errors so the model can see them.
+type CallToolResult struct {
+	Content           []Content   `json:"content"`
+	StructuredContent interface{} `json:"structuredContent,omitempty"`
+	IsError           bool        `json:"isError,omitempty"`
+}
+
+// ElicitRequestParams asks the user for structured input through the client.
+type ElicitRequestParams struct {
+	Message         string          `json:"message"`
+	RequestedSchema json.RawMessage `json:"requestedSchema"`
+}
+
+// ElicitResult is the user's answer to an elicitation/create request. Action
+// is "accept", "decline" or "cancel".
+type ElicitResult struct {
+	Action  string                 `json:"action"`
+	Content map[string]interface{} `json:"content,omitempty"`
+}
+
+// ProgressParams is the payload of notifications/progress.
+type ProgressParams struct {
+	ProgressToken interface{} `json:"progressToken"`
+	Progress      float64     `json:"progress"`
+	Total         float64     `json:"total,omitempty"`
+	Message       string      `json:"message,omitempty"`
+}
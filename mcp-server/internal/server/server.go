@@ -1,74 +1,142 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/xscopehub/mcp-server/internal/audit"
+	"github.com/xscopehub/mcp-server/internal/auth"
 	"github.com/xscopehub/mcp-server/internal/registry"
 	"github.com/xscopehub/mcp-server/internal/types"
 	"github.com/xscopehub/mcp-server/pkg/manifest"
 )
 
-// Options configures the MCP HTTP server.
+// Options configures the MCP server.
 type Options struct {
 	Manifest     manifest.Manifest
 	Registry     *registry.Registry
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
+	// SessionTTL drops Streamable HTTP sessions idle for longer than this.
+	// Zero keeps sessions until the client deletes them. ExpireSessions does
+	// the dropping.
+	SessionTTL time.Duration
+	// MaxSessions bounds the Streamable HTTP sessions open at once; further
+	// initialize requests get 503. Zero means unlimited.
+	MaxSessions int
+	// AllowedOrigins are the browser origins allowed to call the Streamable
+	// HTTP endpoint. Requests carrying any other Origin header are refused,
+	// so a page reaching the server through DNS rebinding cannot use it.
+	AllowedOrigins []string
+	// Auth authenticates HTTP requests; nil leaves the server open. stdio
+	// sessions are trusted and never authenticated.
+	Auth *auth.Authenticator
+	// Audit records every tools/call; nil disables the audit log.
+	Audit *audit.Logger
 }
 
-// Server implements http.Handler for MCP JSON-RPC.
+// Server implements MCP JSON-RPC independently of the transport. ServeHTTP
+// and ServeStdio feed messages to Handle.
 type Server struct {
+	registry    *registry.Registry
+	sessionTTL  time.Duration
+	maxSessions int
+	origins     map[string]bool
+	auth        *auth.Authenticator
+	audit       *audit.Logger
+
+	mu       sync.RWMutex
 	manifest manifest.Manifest
-	registry *registry.Registry
+	sessions map[string]*Session
 }
 
-// New creates a new MCP server instance.
+// New creates a new MCP server instance. Connected sessions are notified
+// whenever the registry's tool, resource or prompt lists change.
 func New(opts Options) *Server {
-	return &Server{
-		manifest: opts.Manifest,
-		registry: opts.Registry,
+	s := &Server{
+		manifest:    opts.Manifest,
+		registry:    opts.Registry,
+		sessionTTL:  opts.SessionTTL,
+		maxSessions: opts.MaxSessions,
+		origins:     make(map[string]bool, len(opts.AllowedOrigins)),
+		auth:        opts.Auth,
+		audit:       opts.Audit,
+		sessions:    make(map[string]*Session),
 	}
-}
-
-// ServeHTTP implements http.Handler.
-func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if r.URL.Path != "/mcp" {
-		http.NotFound(w, r)
-		return
+	for _, origin := range opts.AllowedOrigins {
+		s.origins[strings.ToLower(strings.TrimRight(origin, "/"))] = true
 	}
-	if r.Method != http.MethodPost {
-		http.Error(w, "only POST supported", http.StatusMethodNotAllowed)
-		return
+	if s.registry != nil {
+		s.registry.OnChange(s.listChanged)
 	}
+	return s
+}
 
-	defer func() {
-		if err := r.Body.Close(); err != nil {
-			log.Printf("failed to close body: %v", err)
-		}
-	}()
+// SetManifest replaces the manifest; sessions initialized afterwards see the
+// new server info and instructions.
+func (s *Server) SetManifest(mf manifest.Manifest) {
+	s.mu.Lock()
+	s.manifest = mf
+	s.mu.Unlock()
+}
 
-	payload, err := io.ReadAll(r.Body)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("read body: %v", err), http.StatusBadRequest)
-		return
+func (s *Server) listChanged(c registry.Change) {
+	if c&registry.ToolsChanged != 0 {
+		s.Broadcast("notifications/tools/list_changed", nil)
 	}
+	if c&registry.ResourcesChanged != 0 {
+		s.Broadcast("notifications/resources/list_changed", nil)
+	}
+	if c&registry.PromptsChanged != 0 {
+		s.Broadcast("notifications/prompts/list_changed", nil)
+	}
+}
+
+// Handle processes one JSON-RPC message for a session and returns the encoded
+// response, or nil when the message does not warrant one.
+func (s *Server) Handle(ctx context.Context, sess *Session, payload []byte) []byte {
+	sess.touch()
 
 	var req Request
+	var resp *Response
 	if err := json.Unmarshal(payload, &req); err != nil {
-		http.Error(w, fmt.Sprintf("decode request: %v", err), http.StatusBadRequest)
-		return
+		r := errorResponse(nil, codeParseError, fmt.Sprintf("decode request: %v", err))
+		resp = &r
+	} else if req.Method == "" && req.ID != nil {
+		// A response to a server initiated request such as elicitation/create.
+		var r reply
+		if err := json.Unmarshal(payload, &r); err == nil {
+			sess.deliver(req.ID, r)
+		}
+	} else {
+		resp = s.handleRequest(ctx, sess, req)
+	}
+	if resp == nil {
+		return nil
 	}
 
-	resp := s.handleRequest(req)
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(resp); err != nil {
+	out, err := json.Marshal(resp)
+	if err != nil {
 		log.Printf("encode response: %v", err)
+		out, _ = json.Marshal(errorResponse(resp.ID, codeInternalError, "encode response failed"))
 	}
+	return out
+}
+
+// messageMethod peeks at the method of an encoded JSON-RPC message. It is
+// empty for responses and undecodable payloads.
+func messageMethod(payload []byte) string {
+	var probe struct {
+		Method string `json:"method"`
+	}
+	_ = json.Unmarshal(payload, &probe)
+	return probe.Method
 }
 
 // Request represents an MCP JSON-RPC request.
@@ -89,96 +157,415 @@ type Response struct {
 
 // Error wraps JSON-RPC error payload.
 type Error struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// handleRequest dispatches a JSON-RPC message. Notifications, which carry no
+// ID, never produce a response and nil is returned for them.
+func (s *Server) handleRequest(ctx context.Context, sess *Session, req Request) *Response {
+	if req.ID == nil {
+		s.handleNotification(sess, req)
+		return nil
+	}
+
+	ctx, finish := sess.startRequest(ctx, req.ID)
+	var resp Response
+	switch {
+	case req.JSONRPC != "2.0":
+		resp = errorResponse(req.ID, codeInvalidRequest, "jsonrpc must be \"2.0\"")
+	case req.Method == "initialize":
+		resp = s.handleInitialize(sess, req)
+	case req.Method == "ping":
+		resp = result(req.ID, struct{}{})
+	case req.Method == "resources/list":
+		resp = s.handleResourcesList(req)
+	case req.Method == "resources/templates/list":
+		resp = result(req.ID, map[string]interface{}{"resourceTemplates": s.registry.ListTemplates()})
+	case req.Method == "resources/read":
+		resp = s.handleResourcesRead(ctx, req)
+	case req.Method == "resources/subscribe":
+		resp = s.handleResourcesSubscribe(ctx, sess, req)
+	case req.Method == "resources/unsubscribe":
+		resp = s.handleResourcesUnsubscribe(sess, req)
+	case req.Method == "tools/list":
+		resp = s.handleToolsList(ctx, req)
+	case req.Method == "tools/call":
+		resp = s.handleToolsCall(ctx, sess, req)
+	case req.Method == "prompts/list":
+		resp = result(req.ID, map[string]interface{}{"prompts": s.registry.ListPrompts()})
+	case req.Method == "prompts/get":
+		resp = s.handlePromptsGet(ctx, req)
+	default:
+		resp = errorResponse(req.ID, codeMethodNotFound, fmt.Sprintf("method %s not found", req.Method))
+	}
+	if finish() {
+		// The client cancelled the request and expects no response.
+		return nil
+	}
+	return &resp
 }
 
-func (s *Server) handleRequest(req Request) Response {
+func (s *Server) handleNotification(sess *Session, req Request) {
 	switch req.Method {
-	case "resources/list":
-		return s.handleResourcesList(req)
-	case "resources/get":
-		return s.handleResourceGet(req)
-	case "tools/list":
-		return s.handleToolsList(req)
-	case "tools/call":
-		return s.handleToolsCall(req)
-	case "manifest/get":
-		return s.handleManifest(req)
+	case "notifications/initialized":
+		sess.mu.Lock()
+		sess.initialized = true
+		sess.mu.Unlock()
+	case "notifications/cancelled":
+		var params struct {
+			RequestID interface{} `json:"requestId"`
+			Reason    string      `json:"reason"`
+		}
+		if req.Params == nil || json.Unmarshal(*req.Params, &params) != nil || params.RequestID == nil {
+			return
+		}
+		if sess.cancelRequest(params.RequestID) && params.Reason != "" {
+			log.Printf("request %v cancelled: %s", params.RequestID, params.Reason)
+		}
 	default:
-		return errorResponse(req.ID, -32601, fmt.Sprintf("method %s not found", req.Method))
+		log.Printf("ignoring notification %s", req.Method)
 	}
 }
 
+func (s *Server) handleInitialize(sess *Session, req Request) Response {
+	var params InitializeParams
+	if err := decodeParams(req.Params, &params); err != nil {
+		return errorResponse(req.ID, err.Code, err.Message)
+	}
+
+	var caps struct {
+		Elicitation *json.RawMessage `json:"elicitation"`
+	}
+	_ = json.Unmarshal(params.Capabilities, &caps)
+
+	version := negotiateVersion(params.ProtocolVersion)
+	sess.mu.Lock()
+	sess.protocolVersion = version
+	sess.client = params.ClientInfo
+	sess.elicitation = caps.Elicitation != nil
+	sess.mu.Unlock()
+
+	s.mu.RLock()
+	mf := s.manifest
+	s.mu.RUnlock()
+
+	return result(req.ID, InitializeResult{
+		ProtocolVersion: version,
+		Capabilities: ServerCapabilities{
+			Resources: &ResourcesCapability{Subscribe: true, ListChanged: true},
+			Tools:     &ListChangedCapability{ListChanged: true},
+			Prompts:   &ListChangedCapability{ListChanged: true},
+		},
+		ServerInfo: Implementation{
+			Name:    mf.Name,
+			Version: mf.Version,
+		},
+		Instructions: mf.Description,
+	})
+}
+
 func (s *Server) handleResourcesList(req Request) Response {
-	resources := s.registry.ListResources()
-	return Response{JSONRPC: "2.0", ID: req.ID, Result: resources}
+	return result(req.ID, map[string]interface{}{"resources": s.registry.ListResources()})
 }
 
-func (s *Server) handleResourceGet(req Request) Response {
+func (s *Server) handleResourcesRead(ctx context.Context, req Request) Response {
 	var params struct {
-		Name string `json:"name"`
+		URI string `json:"uri"`
 	}
 	if err := decodeParams(req.Params, &params); err != nil {
 		return errorResponse(req.ID, err.Code, err.Message)
 	}
+	if resp, denied := s.checkResourceScopes(ctx, req, params.URI); denied {
+		return resp
+	}
 
-	payload, err := s.registry.Resource(params.Name)
+	payload, err := s.registry.ReadResource(ctx, params.URI)
+	if errors.Is(err, registry.ErrResourceNotFound) {
+		return errorResponse(req.ID, codeResourceNotFound, err.Error())
+	}
 	if err != nil {
-		return errorResponse(req.ID, -32000, err.Error())
+		return errorResponse(req.ID, codeInternalError, err.Error())
+	}
+	text, err := json.Marshal(payload.Data)
+	if err != nil {
+		return errorResponse(req.ID, codeInternalError, fmt.Sprintf("encode resource: %v", err))
+	}
+	contents := ResourceContents{URI: payload.URI, MimeType: payload.MimeType, Text: string(text)}
+	if contents.URI == "" {
+		contents.URI = params.URI
+	}
+	if contents.MimeType == "" {
+		contents.MimeType = "application/json"
+	}
+	return result(req.ID, map[string]interface{}{"contents": []ResourceContents{contents}})
+}
+
+func (s *Server) handlePromptsGet(ctx context.Context, req Request) Response {
+	var params struct {
+		Name      string            `json:"name"`
+		Arguments map[string]string `json:"arguments"`
+	}
+	if err := decodeParams(req.Params, &params); err != nil {
+		return errorResponse(req.ID, err.Code, err.Message)
+	}
+
+	res, err := s.registry.GetPrompt(ctx, params.Name, params.Arguments)
+	var invalid *registry.ValidationError
+	switch {
+	case errors.Is(err, registry.ErrPromptNotFound):
+		return errorResponse(req.ID, codeInvalidParams, err.Error())
+	case errors.As(err, &invalid):
+		resp := errorResponse(req.ID, codeInvalidParams, invalid.Error())
+		resp.Error.Data = map[string]interface{}{"prompt": params.Name, "errors": invalid.Errors}
+		return resp
+	case err != nil:
+		return errorResponse(req.ID, codeInternalError, err.Error())
+	}
+	return result(req.ID, res)
+}
+
+// handleResourcesSubscribe records that the session wants
+// notifications/resources/updated for a URI; see ResourceUpdated.
+func (s *Server) handleResourcesSubscribe(ctx context.Context, sess *Session, req Request) Response {
+	var params struct {
+		URI string `json:"uri"`
+	}
+	if err := decodeParams(req.Params, &params); err != nil {
+		return errorResponse(req.ID, err.Code, err.Message)
+	}
+	if !s.registry.HasResource(params.URI) {
+		return errorResponse(req.ID, codeResourceNotFound, fmt.Sprintf("%v: %s", registry.ErrResourceNotFound, params.URI))
+	}
+	if resp, denied := s.checkResourceScopes(ctx, req, params.URI); denied {
+		return resp
+	}
+	// An authenticated caller may only watch what it can read; another
+	// tenant's case is not found.
+	var subscriber *auth.Principal
+	if p, ok := auth.FromContext(ctx); ok {
+		if _, err := s.registry.ReadResource(ctx, params.URI); errors.Is(err, registry.ErrResourceNotFound) {
+			return errorResponse(req.ID, codeResourceNotFound, err.Error())
+		} else if err != nil {
+			return errorResponse(req.ID, codeInternalError, err.Error())
+		}
+		subscriber = &p
+	}
+	if err := sess.subscribe(params.URI, subscriber); err != nil {
+		return errorResponse(req.ID, codeInvalidParams, err.Error())
+	}
+	return result(req.ID, struct{}{})
+}
+
+// checkResourceScopes refuses an authenticated caller who lacks the scopes
+// of the resource at uri, the same way tools/call does.
+func (s *Server) checkResourceScopes(ctx context.Context, req Request, uri string) (Response, bool) {
+	p, ok := auth.FromContext(ctx)
+	scopes := s.registry.ResourceScopes(uri)
+	if !ok || p.HasScopes(scopes) {
+		return Response{}, false
 	}
-	return Response{JSONRPC: "2.0", ID: req.ID, Result: payload}
+	resp := errorResponse(req.ID, codeForbidden, fmt.Sprintf("%s requires scopes %s", uri, strings.Join(scopes, " ")))
+	resp.Error.Data = map[string]interface{}{"uri": uri, "required_scopes": scopes}
+	return resp, true
 }
 
-func (s *Server) handleToolsList(req Request) Response {
+func (s *Server) handleResourcesUnsubscribe(sess *Session, req Request) Response {
+	var params struct {
+		URI string `json:"uri"`
+	}
+	if err := decodeParams(req.Params, &params); err != nil {
+		return errorResponse(req.ID, err.Code, err.Message)
+	}
+	sess.unsubscribe(params.URI)
+	return result(req.ID, struct{}{})
+}
+
+// handleToolsList lists the tools the caller has the scopes to call.
+func (s *Server) handleToolsList(ctx context.Context, req Request) Response {
 	tools := s.registry.ListTools()
-	return Response{JSONRPC: "2.0", ID: req.ID, Result: tools}
+	if p, ok := auth.FromContext(ctx); ok {
+		allowed := tools[:0]
+		for _, tool := range tools {
+			if p.HasScopes(s.registry.ToolScopes(tool.Name)) {
+				allowed = append(allowed, tool)
+			}
+		}
+		tools = allowed
+	}
+	return result(req.ID, map[string]interface{}{"tools": tools})
 }
 
-func (s *Server) handleToolsCall(req Request) Response {
+func (s *Server) handleToolsCall(ctx context.Context, sess *Session, req Request) Response {
 	var params struct {
 		Name      string                 `json:"name"`
 		Arguments map[string]interface{} `json:"arguments"`
+		Meta      struct {
+			ProgressToken interface{} `json:"progressToken"`
+		} `json:"_meta"`
 	}
 	if err := decodeParams(req.Params, &params); err != nil {
 		return errorResponse(req.ID, err.Code, err.Message)
 	}
-
 	if params.Arguments == nil {
 		params.Arguments = map[string]interface{}{}
 	}
 
-	result, err := s.registry.InvokeTool(params.Name, params.Arguments)
-	if err != nil {
-		return errorResponse(req.ID, -32000, err.Error())
+	entry := audit.Entry{Session: sess.ID, Tool: params.Name, Arguments: params.Arguments}
+	principal, authenticated := auth.FromContext(ctx)
+	if authenticated {
+		entry.Subject, entry.Tenant = principal.Subject, principal.Tenant
 	}
-	return Response{JSONRPC: "2.0", ID: req.ID, Result: result}
+	start := time.Now()
+	record := func(outcome string, err error) {
+		entry.Outcome, entry.Duration = outcome, time.Since(start)
+		if err != nil {
+			entry.Error = err.Error()
+		}
+		s.audit.Log(entry)
+	}
+
+	if scopes := s.registry.ToolScopes(params.Name); authenticated && !principal.HasScopes(scopes) {
+		err := fmt.Errorf("%s requires scopes %s", params.Name, strings.Join(scopes, " "))
+		record(audit.OutcomeDenied, err)
+		resp := errorResponse(req.ID, codeForbidden, err.Error())
+		resp.Error.Data = map[string]interface{}{"tool": params.Name, "required_scopes": scopes}
+		return resp
+	}
+	if token := params.Meta.ProgressToken; token != nil {
+		ctx = registry.WithProgress(ctx, progressNotifier(sess, token))
+	}
+
+	res, err := s.registry.InvokeTool(ctx, params.Name, params.Arguments)
+	var confirm *registry.ConfirmationError
+	if errors.As(err, &confirm) && sess.canElicit() {
+		res, err = s.invokeConfirmed(ctx, sess, params.Name, params.Arguments, confirm)
+	}
+	var invalid *registry.ValidationError
+	switch {
+	case errors.Is(err, registry.ErrToolNotFound):
+		record(audit.OutcomeInvalid, err)
+		return errorResponse(req.ID, codeInvalidParams, err.Error())
+	case errors.As(err, &invalid):
+		record(audit.OutcomeInvalid, err)
+		resp := errorResponse(req.ID, codeInvalidParams, err.Error())
+		resp.Error.Data = map[string]interface{}{"tool": invalid.Name, "errors": invalid.Errors}
+		return resp
+	case errors.As(err, &confirm):
+		record(audit.OutcomeConfirmationRequired, nil)
+		return result(req.ID, confirmationContent(confirm))
+	case err != nil && errors.Is(ctx.Err(), context.Canceled):
+		record(audit.OutcomeCancelled, err)
+	case err != nil:
+		record(audit.OutcomeError, err)
+	default:
+		record(audit.OutcomeOK, nil)
+		return result(req.ID, toolContent(res))
+	}
+	return result(req.ID, CallToolResult{
+		Content: []Content{{Type: "text", Text: err.Error()}},
+		IsError: true,
+	})
 }
 
-func (s *Server) handleManifest(req Request) Response {
-	meta := struct {
-		Manifest  manifest.Manifest          `json:"manifest"`
-		Resources []types.ResourceDescriptor `json:"resources"`
-		Tools     []types.ToolDescriptor     `json:"tools"`
-	}{
-		Manifest:  s.manifest,
-		Resources: s.registry.ListResources(),
-		Tools:     s.registry.ListTools(),
+// elicitationTimeout bounds how long a tool call waits for the user to
+// answer a confirmation prompt.
+const elicitationTimeout = 5 * time.Minute
+
+// approvalSchema is the form shown to the user for tool confirmations.
+var approvalSchema = json.RawMessage(`{"type":"object","properties":{"approve":{"type":"boolean","title":"Approve","description":"Run the action described above."}},"required":["approve"]}`)
+
+// invokeConfirmed asks the user to approve a tool call through elicitation
+// and, once approved, calls the tool again with the confirmation attached to
+// its context. When the client cannot answer, the original confirmation error
+// is returned.
+func (s *Server) invokeConfirmed(ctx context.Context, sess *Session, name string, args map[string]interface{}, confirm *registry.ConfirmationError) (types.ToolResult, error) {
+	message := confirm.Message
+	if confirm.Preview != nil {
+		if preview, err := json.MarshalIndent(confirm.Preview, "", "  "); err == nil {
+			message += "\n\n" + string(preview)
+		}
 	}
 
-	return Response{JSONRPC: "2.0", ID: req.ID, Result: meta}
+	ctx, cancel := context.WithTimeout(ctx, elicitationTimeout)
+	defer cancel()
+	var answer ElicitResult
+	if err := sess.request(ctx, "elicitation/create", ElicitRequestParams{Message: message, RequestedSchema: approvalSchema}, &answer); err != nil {
+		log.Printf("elicit confirmation for %s: %v", name, err)
+		return types.ToolResult{}, confirm
+	}
+	if approved, _ := answer.Content["approve"].(bool); answer.Action != "accept" || !approved {
+		return types.ToolResult{}, fmt.Errorf("%s was not approved by the user", name)
+	}
+
+	return s.registry.InvokeTool(registry.WithConfirmation(ctx, confirm.Token), name, args)
+}
+
+// progressNotifier sends notifications/progress for a request that carried a
+// progress token. Progress must increase, so stale updates are dropped.
+func progressNotifier(sess *Session, token interface{}) registry.ProgressFunc {
+	var mu sync.Mutex
+	last := -1.0
+	return func(progress, total float64, message string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if progress <= last {
+			return
+		}
+		last = progress
+		sess.Notify("notifications/progress", ProgressParams{
+			ProgressToken: token,
+			Progress:      progress,
+			Total:         total,
+			Message:       message,
+		})
+	}
+}
+
+// confirmationContent tells the model that a tool call is waiting for human
+// approval, with the preview to show the user. The token stays on the server
+// so the model cannot approve the call itself.
+func confirmationContent(confirm *registry.ConfirmationError) CallToolResult {
+	out := CallToolResult{Content: []Content{{Type: "text", Text: confirm.Error()}}, IsError: true}
+	if raw, err := json.Marshal(map[string]interface{}{"preview": confirm.Preview}); err == nil {
+		out.Content = append(out.Content, Content{Type: "text", Text: string(raw)})
+		out.StructuredContent = json.RawMessage(raw)
+	}
+	return out
+}
+
+// toolContent renders tool output as a text block. JSON objects are also
+// returned as structured content.
+func toolContent(res types.ToolResult) CallToolResult {
+	if text, ok := res.Output.(string); ok {
+		return CallToolResult{Content: []Content{{Type: "text", Text: text}}}
+	}
+	raw, err := json.Marshal(res.Output)
+	if err != nil {
+		return CallToolResult{Content: []Content{{Type: "text", Text: fmt.Sprintf("encode output: %v", err)}}, IsError: true}
+	}
+	out := CallToolResult{Content: []Content{{Type: "text", Text: string(raw)}}}
+	if len(raw) > 0 && raw[0] == '{' {
+		out.StructuredContent = json.RawMessage(raw)
+	}
+	return out
 }
 
 func decodeParams(raw *json.RawMessage, v interface{}) *Error {
 	if raw == nil {
-		return &Error{Code: -32602, Message: "missing params"}
+		return &Error{Code: codeInvalidParams, Message: "missing params"}
 	}
 	if err := json.Unmarshal(*raw, v); err != nil {
-		return &Error{Code: -32602, Message: fmt.Sprintf("invalid params: %v", err)}
+		return &Error{Code: codeInvalidParams, Message: fmt.Sprintf("invalid params: %v", err)}
 	}
 	return nil
 }
 
+func result(id interface{}, v interface{}) Response {
+	return Response{JSONRPC: "2.0", ID: id, Result: v}
+}
+
 func errorResponse(id interface{}, code int, message string) Response {
 	return Response{
 		JSONRPC: "2.0",
@@ -2,12 +2,17 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/xscopehub/mcp-server/internal/auth"
 	"github.com/xscopehub/mcp-server/internal/registry"
+	"github.com/xscopehub/mcp-server/internal/types"
 	"github.com/xscopehub/mcp-server/pkg/manifest"
 )
 
@@ -47,3 +52,323 @@ func TestServeHTTPResourcesList(t *testing.T) {
 		t.Fatalf("expected result payload")
 	}
 }
+
+func newTestServer() *Server {
+	reg := registry.New()
+	reg.RegisterResources(registry.StaticResources(), auth.ScopeTelemetryRead)
+	reg.RegisterTools(registry.StaticTools())
+	return New(Options{Manifest: manifest.Manifest{Name: "xscopehub", Version: "1.0.0"}, Registry: reg})
+}
+
+func call(t *testing.T, srv *Server, body string) (*httptest.ResponseRecorder, Response) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/mcp", bytes.NewReader([]byte(body)))
+	res := httptest.NewRecorder()
+	srv.ServeHTTP(res, req)
+
+	var resp Response
+	if res.Body.Len() > 0 {
+		if err := json.Unmarshal(res.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+	}
+	return res, resp
+}
+
+func TestInitializeNegotiatesVersion(t *testing.T) {
+	srv := newTestServer()
+	cases := map[string]string{
+		"2025-03-26": "2025-03-26",
+		"1999-01-01": LatestProtocolVersion,
+	}
+	for requested, want := range cases {
+		_, resp := call(t, srv, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"`+requested+`","capabilities":{},"clientInfo":{"name":"test","version":"0"}}}`)
+		if resp.Error != nil {
+			t.Fatalf("unexpected error: %+v", resp.Error)
+		}
+		result := resp.Result.(map[string]interface{})
+		if result["protocolVersion"] != want {
+			t.Fatalf("requested %s: expected %s, got %v", requested, want, result["protocolVersion"])
+		}
+		if _, ok := result["capabilities"].(map[string]interface{})["tools"]; !ok {
+			t.Fatalf("tools capability not advertised: %v", result["capabilities"])
+		}
+	}
+
+	res, _ := call(t, srv, `{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+	if res.Code != http.StatusAccepted || res.Body.Len() != 0 {
+		t.Fatalf("notification must not be answered, got %d %q", res.Code, res.Body.String())
+	}
+}
+
+func TestToolsCallAndResourcesRead(t *testing.T) {
+	srv := newTestServer()
+
+	_, resp := call(t, srv, `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	tools := resp.Result.(map[string]interface{})["tools"].([]interface{})
+	for _, tool := range tools {
+		if _, ok := tool.(map[string]interface{})["inputSchema"].(map[string]interface{}); !ok {
+			t.Fatalf("inputSchema must be an object: %v", tool)
+		}
+	}
+
+	_, resp = call(t, srv, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"query_logs","arguments":{"service":"api"}}}`)
+	content := resp.Result.(map[string]interface{})["content"].([]interface{})
+	if len(content) != 1 || content[0].(map[string]interface{})["type"] != "text" {
+		t.Fatalf("unexpected content %v", content)
+	}
+
+	_, resp = call(t, srv, `{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"missing"}}`)
+	if resp.Error == nil || resp.Error.Code != codeInvalidParams {
+		t.Fatalf("expected invalid params for unknown tool, got %+v", resp)
+	}
+
+	_, resp = call(t, srv, `{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"query_logs","arguments":{"service":{"name":"api"}}}}`)
+	if resp.Error == nil || resp.Error.Code != codeInvalidParams {
+		t.Fatalf("expected invalid params for bad arguments, got %+v", resp)
+	}
+	errs := resp.Error.Data.(map[string]interface{})["errors"].([]interface{})
+	if len(errs) != 1 || errs[0].(map[string]interface{})["path"] != "service" {
+		t.Fatalf("unexpected field errors %v", errs)
+	}
+
+	_, resp = call(t, srv, `{"jsonrpc":"2.0","id":4,"method":"resources/read","params":{"uri":"xscope://metrics"}}`)
+	contents := resp.Result.(map[string]interface{})["contents"].([]interface{})
+	if len(contents) != 1 || contents[0].(map[string]interface{})["uri"] != "xscope://metrics" {
+		t.Fatalf("unexpected contents %v", contents)
+	}
+}
+
+func TestToolCallProgressAndCancellation(t *testing.T) {
+	srv := newTestServer()
+	started := make(chan struct{})
+	srv.registry.RegisterTool(types.ToolDescriptor{Name: "slow"}, func(ctx context.Context, args map[string]interface{}) (types.ToolResult, error) {
+		registry.ReportProgress(ctx, 1, 4, "scanning")
+		close(started)
+		<-ctx.Done()
+		return types.ToolResult{}, ctx.Err()
+	})
+	sess := srv.NewSession()
+
+	resp := make(chan []byte, 1)
+	go func() {
+		resp <- srv.Handle(context.Background(), sess, []byte(`{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"slow","arguments":{},"_meta":{"progressToken":"p1"}}}`))
+	}()
+	<-started
+	var progress struct {
+		Method string         `json:"method"`
+		Params ProgressParams `json:"params"`
+	}
+	if err := json.Unmarshal(<-sess.Outbox(), &progress); err != nil || progress.Method != "notifications/progress" ||
+		progress.Params.ProgressToken != "p1" || progress.Params.Progress != 1 || progress.Params.Total != 4 {
+		t.Fatalf("unexpected progress notification %+v (%v)", progress, err)
+	}
+
+	srv.Handle(context.Background(), sess, []byte(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":7,"reason":"user abort"}}`))
+	select {
+	case out := <-resp:
+		if out != nil {
+			t.Fatalf("expected no response to a cancelled request, got %s", out)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("tool was not cancelled")
+	}
+}
+
+func TestToolCallTimeoutAndPanic(t *testing.T) {
+	srv := newTestServer()
+	srv.registry.AddTool(registry.Tool{
+		Descriptor: types.ToolDescriptor{Name: "stuck"},
+		Timeout:    10 * time.Millisecond,
+		Func: func(ctx context.Context, args map[string]interface{}) (types.ToolResult, error) {
+			select {}
+		},
+	})
+	srv.registry.RegisterTool(types.ToolDescriptor{Name: "broken"}, func(ctx context.Context, args map[string]interface{}) (types.ToolResult, error) {
+		var m map[string]int
+		m["boom"]++
+		return types.ToolResult{}, nil
+	})
+
+	for name, want := range map[string]string{"stuck": "timed out after 10ms", "broken": "tool panicked"} {
+		_, resp := call(t, srv, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"`+name+`","arguments":{}}}`)
+		res, _ := resp.Result.(map[string]interface{})
+		if res["isError"] != true || !strings.Contains(res["content"].([]interface{})[0].(map[string]interface{})["text"].(string), want) {
+			t.Fatalf("%s: expected an error result containing %q, got %+v", name, want, resp)
+		}
+	}
+}
+
+func TestRegistryChangesNotifySessions(t *testing.T) {
+	srv := newTestServer()
+	sess := srv.NewSession()
+	srv.Handle(context.Background(), sess, []byte(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"test"}}}`))
+	srv.Handle(context.Background(), sess, []byte(`{"jsonrpc":"2.0","method":"notifications/initialized"}`))
+
+	next := func() string {
+		select {
+		case msg := <-sess.Outbox():
+			var n Notification
+			json.Unmarshal(msg, &n)
+			return n.Method
+		case <-time.After(time.Second):
+			return ""
+		}
+	}
+
+	srv.registry.RegisterTool(types.ToolDescriptor{Name: "aaa_first"}, func(ctx context.Context, args map[string]interface{}) (types.ToolResult, error) {
+		return types.ToolResult{}, nil
+	})
+	if m := next(); m != "notifications/tools/list_changed" {
+		t.Fatalf("expected tools/list_changed, got %q", m)
+	}
+	srv.registry.ReplaceSource("plugins", registry.Set{Resources: []types.ResourcePayload{{Name: "runbooks", Data: "restart the pod"}}})
+	if m := next(); m != "notifications/resources/list_changed" {
+		t.Fatalf("expected resources/list_changed, got %q", m)
+	}
+
+	_, resp := call(t, srv, `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)
+	tools := resp.Result.(map[string]interface{})["tools"].([]interface{})
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool.(map[string]interface{})["name"].(string))
+	}
+	if strings.Join(names, ",") != "aaa_first,query_logs,summarize_alerts" {
+		t.Fatalf("expected tools sorted by name, got %v", names)
+	}
+}
+
+func TestPromptsListAndGet(t *testing.T) {
+	srv := newTestServer()
+	err := srv.registry.AddPrompts(registry.Prompt{
+		Descriptor: types.PromptDescriptor{
+			Name:      "triage_service",
+			Arguments: []types.PromptArgument{{Name: "service", Required: true}},
+		},
+		Func: func(ctx context.Context, args map[string]string) ([]types.PromptMessage, error) {
+			return []types.PromptMessage{{Role: "user", Content: types.PromptContent{Type: "text", Text: "Triage " + args["service"]}}}, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, resp := call(t, srv, `{"jsonrpc":"2.0","id":1,"method":"prompts/list"}`)
+	prompts := resp.Result.(map[string]interface{})["prompts"].([]interface{})
+	if len(prompts) != 1 || prompts[0].(map[string]interface{})["name"] != "triage_service" {
+		t.Fatalf("unexpected prompts %v", prompts)
+	}
+
+	_, resp = call(t, srv, `{"jsonrpc":"2.0","id":2,"method":"prompts/get","params":{"name":"triage_service","arguments":{"service":"api"}}}`)
+	messages := resp.Result.(map[string]interface{})["messages"].([]interface{})
+	content := messages[0].(map[string]interface{})["content"].(map[string]interface{})
+	if content["text"] != "Triage api" {
+		t.Fatalf("unexpected messages %v", messages)
+	}
+
+	_, resp = call(t, srv, `{"jsonrpc":"2.0","id":3,"method":"prompts/get","params":{"name":"triage_service","arguments":{}}}`)
+	if resp.Error == nil || resp.Error.Code != codeInvalidParams {
+		t.Fatalf("expected invalid params for a missing argument, got %+v", resp)
+	}
+	errs := resp.Error.Data.(map[string]interface{})["errors"].([]interface{})
+	if len(errs) != 1 || errs[0].(map[string]interface{})["path"] != "service" {
+		t.Fatalf("unexpected field errors %v", errs)
+	}
+
+	_, resp = call(t, srv, `{"jsonrpc":"2.0","id":4,"method":"prompts/get","params":{"name":"missing"}}`)
+	if resp.Error == nil || resp.Error.Code != codeInvalidParams {
+		t.Fatalf("expected invalid params for an unknown prompt, got %+v", resp)
+	}
+}
+
+func TestResourceSubscriptions(t *testing.T) {
+	srv := newTestServer()
+	subscriber, bystander := srv.NewSession(), srv.NewSession()
+	for _, sess := range []*Session{subscriber, bystander} {
+		srv.Handle(context.Background(), sess, []byte(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"test"}}}`))
+	}
+
+	handle := func(sess *Session, body string) Response {
+		var resp Response
+		json.Unmarshal(srv.Handle(context.Background(), sess, []byte(body)), &resp)
+		return resp
+	}
+	if resp := handle(subscriber, `{"jsonrpc":"2.0","id":2,"method":"resources/subscribe","params":{"uri":"xscope://logs"}}`); resp.Error != nil {
+		t.Fatalf("subscribe: %+v", resp.Error)
+	}
+	if resp := handle(subscriber, `{"jsonrpc":"2.0","id":3,"method":"resources/subscribe","params":{"uri":"xscope://missing"}}`); resp.Error == nil || resp.Error.Code != codeResourceNotFound {
+		t.Fatalf("expected an unknown resource to be refused, got %+v", resp)
+	}
+
+	srv.ResourceUpdated("xscope://logs")
+	srv.ResourceUpdated("xscope://metrics")
+	select {
+	case msg := <-subscriber.Outbox():
+		var n struct {
+			Method string            `json:"method"`
+			Params map[string]string `json:"params"`
+		}
+		json.Unmarshal(msg, &n)
+		if n.Method != "notifications/resources/updated" || n.Params["uri"] != "xscope://logs" {
+			t.Fatalf("unexpected notification %s", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected an update notification")
+	}
+	if len(subscriber.Outbox()) != 0 || len(bystander.Outbox()) != 0 {
+		t.Fatalf("only subscribed resources and sessions must be notified")
+	}
+
+	handle(subscriber, `{"jsonrpc":"2.0","id":4,"method":"resources/unsubscribe","params":{"uri":"xscope://logs"}}`)
+	srv.ResourceUpdated("xscope://logs")
+	if len(subscriber.Outbox()) != 0 {
+		t.Fatalf("unsubscribed session was notified")
+	}
+}
+
+func TestSubscriptionsFollowWhoCanRead(t *testing.T) {
+	srv := newTestServer()
+	// Cases are readable by stdio sessions and by their owner only.
+	owner := "acme"
+	err := srv.registry.RegisterTemplate(types.ResourceTemplate{URITemplate: "xscope://cases/{case_id}", Name: "case"},
+		func(ctx context.Context, uri string, vars map[string]string) (types.ResourcePayload, error) {
+			if tenant := auth.TenantFromContext(ctx); tenant != "" && tenant != owner {
+				return types.ResourcePayload{}, registry.ErrResourceNotFound
+			}
+			return types.ResourcePayload{URI: uri, Data: vars}, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	acme := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "alice", Tenant: "acme"})
+	globex := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "bob", Tenant: "globex"})
+	alice, bob, local := srv.NewSession(), srv.NewSession(), srv.NewSession()
+	subscribe := func(ctx context.Context, sess *Session) Response {
+		var resp Response
+		json.Unmarshal(srv.Handle(ctx, sess, []byte(`{"jsonrpc":"2.0","id":1,"method":"resources/subscribe","params":{"uri":"xscope://cases/7"}}`)), &resp)
+		return resp
+	}
+
+	if resp := subscribe(globex, bob); resp.Error == nil || resp.Error.Code != codeResourceNotFound {
+		t.Fatalf("expected another tenant's case to be not found, got %+v", resp)
+	}
+	for ctx, sess := range map[context.Context]*Session{acme: alice, context.Background(): local} {
+		if resp := subscribe(ctx, sess); resp.Error != nil {
+			t.Fatalf("subscribe: %+v", resp.Error)
+		}
+	}
+
+	srv.ResourceUpdated("xscope://cases/7")
+	if len(alice.Outbox()) != 1 || len(local.Outbox()) != 1 || len(bob.Outbox()) != 0 {
+		t.Fatalf("expected the owner and the stdio session to be notified")
+	}
+	<-alice.Outbox()
+	<-local.Outbox()
+
+	// Once the case is no longer acme's, alice stops hearing about it.
+	owner = "globex"
+	srv.ResourceUpdated("xscope://cases/7")
+	if len(alice.Outbox()) != 0 || len(local.Outbox()) != 1 {
+		t.Fatalf("expected only readers of the case to be notified")
+	}
+}
@@ -0,0 +1,435 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/xscopehub/mcp-server/internal/auth"
+)
+
+// sessionOutboxSize bounds the notifications queued for a session that has
+// no stream attached.
+const sessionOutboxSize = 64
+
+// maxSubscriptions bounds the resources one session can subscribe to.
+const maxSubscriptions = 256
+
+// sessionSweepInterval is how often idle sessions are looked for, unless
+// the session TTL is shorter.
+const sessionSweepInterval = time.Minute
+
+// resourceCheckTimeout bounds the read that checks an authenticated
+// subscriber may still see an updated resource.
+const resourceCheckTimeout = 10 * time.Second
+
+// Session is the state of one MCP connection: a stdio process or a
+// Streamable HTTP client identified by its Mcp-Session-Id.
+type Session struct {
+	ID string
+	// owner is the subject that opened the session when authentication is
+	// enabled; it never changes.
+	owner string
+
+	mu              sync.Mutex
+	protocolVersion string
+	initialized     bool
+	client          Implementation
+	elicitation     bool
+	lastSeen        time.Time
+
+	nextRequestID int64
+	pending       map[string]chan reply
+	inflight      map[string]*inflightRequest
+	// subscriptions are the resource URIs the client asked to be told about
+	// with notifications/resources/updated, with the caller who subscribed;
+	// nil for stdio and unauthenticated sessions. They go away with the
+	// session.
+	subscriptions map[string]*auth.Principal
+
+	outbox chan []byte
+	done   chan struct{}
+	once   sync.Once
+}
+
+func newSession(id string) *Session {
+	return &Session{
+		ID:       id,
+		lastSeen: time.Now(),
+		outbox:   make(chan []byte, sessionOutboxSize),
+		done:     make(chan struct{}),
+	}
+}
+
+// ProtocolVersion returns the version negotiated during initialize.
+func (sess *Session) ProtocolVersion() string {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return sess.protocolVersion
+}
+
+// Initialized reports whether the client sent notifications/initialized.
+func (sess *Session) Initialized() bool {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return sess.initialized
+}
+
+func (sess *Session) touch() {
+	sess.mu.Lock()
+	sess.lastSeen = time.Now()
+	sess.mu.Unlock()
+}
+
+func (sess *Session) idleSince() time.Time {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return sess.lastSeen
+}
+
+// Notify queues a JSON-RPC notification for delivery on the session's
+// stream. Notifications are dropped when the queue is full so a client that
+// never reads cannot block the server.
+func (sess *Session) Notify(method string, params interface{}) {
+	msg, err := json.Marshal(Notification{JSONRPC: "2.0", Method: method, Params: params})
+	if err != nil {
+		log.Printf("encode notification %s: %v", method, err)
+		return
+	}
+	select {
+	case <-sess.done:
+	case sess.outbox <- msg:
+	default:
+		log.Printf("session %s: dropping notification %s, outbox full", sess.ID, method)
+	}
+}
+
+// Outbox returns the channel of queued notifications for transports to drain.
+func (sess *Session) Outbox() <-chan []byte {
+	return sess.outbox
+}
+
+// Done is closed when the session is terminated.
+func (sess *Session) Done() <-chan struct{} {
+	return sess.done
+}
+
+func (sess *Session) close() {
+	sess.once.Do(func() { close(sess.done) })
+}
+
+func (sess *Session) subscribe(uri string, p *auth.Principal) error {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if sess.subscriptions == nil {
+		sess.subscriptions = make(map[string]*auth.Principal)
+	}
+	if _, ok := sess.subscriptions[uri]; !ok && len(sess.subscriptions) >= maxSubscriptions {
+		return fmt.Errorf("at most %d resource subscriptions per session", maxSubscriptions)
+	}
+	sess.subscriptions[uri] = p
+	return nil
+}
+
+func (sess *Session) unsubscribe(uri string) {
+	sess.mu.Lock()
+	delete(sess.subscriptions, uri)
+	sess.mu.Unlock()
+}
+
+// subscriber returns who subscribed the session to uri.
+func (sess *Session) subscriber(uri string) (p *auth.Principal, ok bool) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	p, ok = sess.subscriptions[uri]
+	return p, ok
+}
+
+// canElicit reports whether the client declared the elicitation capability.
+func (sess *Session) canElicit() bool {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return sess.elicitation
+}
+
+// inflightRequest is a client request still being handled.
+type inflightRequest struct {
+	cancel    context.CancelFunc
+	cancelled bool
+}
+
+// startRequest tracks a client request so notifications/cancelled can stop
+// it. The returned finish func forgets the request and reports whether the
+// client cancelled it, in which case no response is sent.
+func (sess *Session) startRequest(ctx context.Context, id interface{}) (context.Context, func() bool) {
+	ctx, cancel := context.WithCancel(ctx)
+	key := fmt.Sprint(id)
+	req := &inflightRequest{cancel: cancel}
+	sess.mu.Lock()
+	if sess.inflight == nil {
+		sess.inflight = make(map[string]*inflightRequest)
+	}
+	sess.inflight[key] = req
+	sess.mu.Unlock()
+
+	return ctx, func() bool {
+		cancel()
+		sess.mu.Lock()
+		defer sess.mu.Unlock()
+		if sess.inflight[key] == req {
+			delete(sess.inflight, key)
+		}
+		return req.cancelled
+	}
+}
+
+// cancelRequest stops an in-flight request on behalf of the client.
+func (sess *Session) cancelRequest(id interface{}) bool {
+	sess.mu.Lock()
+	req, ok := sess.inflight[fmt.Sprint(id)]
+	if ok {
+		req.cancelled = true
+	}
+	sess.mu.Unlock()
+	if ok {
+		req.cancel()
+	}
+	return ok
+}
+
+// errSessionClosed is returned to server initiated requests whose session
+// ends before the client answers.
+var errSessionClosed = errors.New("session closed")
+
+// reply is the client's answer to a server initiated request.
+type reply struct {
+	Result json.RawMessage `json:"result"`
+	Error  *Error          `json:"error"`
+}
+
+// request sends a server initiated JSON-RPC request on the session's stream
+// and decodes the client's result into out.
+func (sess *Session) request(ctx context.Context, method string, params, out interface{}) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("encode %s: %w", method, err)
+	}
+	rawParams := json.RawMessage(raw)
+
+	sess.mu.Lock()
+	sess.nextRequestID++
+	id := fmt.Sprintf("srv-%d", sess.nextRequestID)
+	ch := make(chan reply, 1)
+	if sess.pending == nil {
+		sess.pending = make(map[string]chan reply)
+	}
+	sess.pending[id] = ch
+	sess.mu.Unlock()
+	defer func() {
+		sess.mu.Lock()
+		delete(sess.pending, id)
+		sess.mu.Unlock()
+	}()
+
+	msg, err := json.Marshal(Request{JSONRPC: "2.0", ID: id, Method: method, Params: &rawParams})
+	if err != nil {
+		return fmt.Errorf("encode %s: %w", method, err)
+	}
+	select {
+	case sess.outbox <- msg:
+	case <-sess.done:
+		return errSessionClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case r := <-ch:
+		if r.Error != nil {
+			return fmt.Errorf("%s: client returned %d: %s", method, r.Error.Code, r.Error.Message)
+		}
+		return json.Unmarshal(r.Result, out)
+	case <-sess.done:
+		return errSessionClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// deliver hands a client response to the request waiting for it. Responses
+// nobody waits for any more are dropped.
+func (sess *Session) deliver(id interface{}, r reply) {
+	key := fmt.Sprint(id)
+	sess.mu.Lock()
+	ch, ok := sess.pending[key]
+	delete(sess.pending, key)
+	sess.mu.Unlock()
+	if ok {
+		ch <- r
+	}
+}
+
+// Notification is a JSON-RPC message without an ID.
+type Notification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// NewSession creates and tracks a session. Sessions idle for longer than the
+// configured TTL are dropped on the way.
+func (s *Server) NewSession() *Session {
+	sess := newSession(newSessionID())
+	s.mu.Lock()
+	s.sessions[sess.ID] = sess
+	s.mu.Unlock()
+	return sess
+}
+
+// openSession starts a Streamable HTTP session for owner. It returns nil when
+// Options.MaxSessions sessions are already open.
+func (s *Server) openSession(owner string) *Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.maxSessions > 0 && len(s.sessions) >= s.maxSessions {
+		return nil
+	}
+	sess := newSession(newSessionID())
+	sess.owner = owner
+	s.sessions[sess.ID] = sess
+	return sess
+}
+
+// ExpireSessions closes sessions idle for longer than Options.SessionTTL
+// until ctx is done. It returns at once when sessions never expire.
+func (s *Server) ExpireSessions(ctx context.Context) {
+	if s.sessionTTL <= 0 {
+		return
+	}
+	ticker := time.NewTicker(min(s.sessionTTL, sessionSweepInterval))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.expireSessions(now)
+		}
+	}
+}
+
+func (s *Server) expireSessions(now time.Time) {
+	cutoff := now.Add(-s.sessionTTL)
+	var expired []*Session
+	s.mu.Lock()
+	for id, sess := range s.sessions {
+		if sess.idleSince().Before(cutoff) {
+			expired = append(expired, sess)
+			delete(s.sessions, id)
+		}
+	}
+	s.mu.Unlock()
+	for _, sess := range expired {
+		sess.close()
+	}
+}
+
+// Session looks up a tracked session.
+func (s *Server) Session(id string) (*Session, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sess, ok := s.sessions[id]
+	return sess, ok
+}
+
+// CloseSession terminates and forgets a session.
+func (s *Server) CloseSession(id string) {
+	s.mu.Lock()
+	sess, ok := s.sessions[id]
+	delete(s.sessions, id)
+	s.mu.Unlock()
+	if ok {
+		sess.close()
+	}
+}
+
+// Close terminates every session, ending their open streams.
+func (s *Server) Close() {
+	s.mu.Lock()
+	sessions := s.sessions
+	s.sessions = make(map[string]*Session)
+	s.mu.Unlock()
+	for _, sess := range sessions {
+		sess.close()
+	}
+}
+
+// Broadcast sends a notification to every initialized session.
+func (s *Server) Broadcast(method string, params interface{}) {
+	s.mu.RLock()
+	sessions := make([]*Session, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		sessions = append(sessions, sess)
+	}
+	s.mu.RUnlock()
+
+	for _, sess := range sessions {
+		if sess.Initialized() {
+			sess.Notify(method, params)
+		}
+	}
+}
+
+// ResourceUpdated notifies the sessions subscribed to uri that it changed.
+// An authenticated subscriber is only notified while it can still read the
+// resource, so it learns nothing about resources it may not see, such as
+// another tenant's case.
+func (s *Server) ResourceUpdated(uri string) {
+	type watcher struct {
+		sess *Session
+		p    *auth.Principal
+	}
+	s.mu.RLock()
+	var watchers []watcher
+	for _, sess := range s.sessions {
+		if p, ok := sess.subscriber(uri); ok {
+			watchers = append(watchers, watcher{sess, p})
+		}
+	}
+	s.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, w := range watchers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if w.p != nil && !s.canRead(auth.WithPrincipal(context.Background(), *w.p), uri) {
+				return
+			}
+			w.sess.Notify("notifications/resources/updated", map[string]string{"uri": uri})
+		}()
+	}
+	wg.Wait()
+}
+
+// canRead reports whether the caller in ctx can read uri.
+func (s *Server) canRead(ctx context.Context, uri string) bool {
+	ctx, cancel := context.WithTimeout(ctx, resourceCheckTimeout)
+	defer cancel()
+	_, err := s.registry.ReadResource(ctx, uri)
+	return err == nil
+}
+
+func newSessionID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b[:])
+}
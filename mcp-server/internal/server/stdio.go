@@ -0,0 +1,102 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"sync"
+)
+
+// maxStdioMessage bounds a single newline-delimited message read from stdin.
+const maxStdioMessage = 4 << 20
+
+// ServeStdio runs one MCP session over newline-delimited JSON-RPC on in and
+// out, as used by hosts that launch the server as a subprocess. It returns
+// when in reaches EOF or ctx is cancelled.
+func (s *Server) ServeStdio(ctx context.Context, in io.Reader, out io.Writer) error {
+	sess := s.NewSession()
+	defer s.CloseSession(sess.ID)
+
+	var mu sync.Mutex
+	write := func(msg []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		_, err := out.Write(append(msg, '\n'))
+		return err
+	}
+
+	// Tool calls run concurrently so the client's answers to elicitation and
+	// its further requests are still read while a tool waits. They finish
+	// before the session closes.
+	var calls sync.WaitGroup
+	defer calls.Wait()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg := <-sess.Outbox():
+				if err := write(msg); err != nil {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		scanner := bufio.NewScanner(in)
+		scanner.Buffer(make([]byte, 64*1024), maxStdioMessage)
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			select {
+			case lines <- append([]byte(nil), line...):
+			case <-ctx.Done():
+				return
+			}
+		}
+		readErr <- scanner.Err()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := ctx.Err(); !errors.Is(err, context.Canceled) {
+				return err
+			}
+			return nil
+		case err := <-readErr:
+			return err
+		case line := <-lines:
+			if messageMethod(line) == "tools/call" {
+				calls.Add(1)
+				go func() {
+					defer calls.Done()
+					if resp := s.Handle(ctx, sess, line); resp != nil {
+						if err := write(resp); err != nil {
+							log.Printf("write tool response: %v", err)
+							cancel()
+						}
+					}
+				}()
+				continue
+			}
+			if resp := s.Handle(ctx, sess, line); resp != nil {
+				if err := write(resp); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
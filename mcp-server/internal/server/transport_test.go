@@ -0,0 +1,237 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xscopehub/mcp-server/internal/registry"
+	"github.com/xscopehub/mcp-server/internal/types"
+)
+
+const initializeBody = `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"test","version":"0"}}}`
+
+func TestServeStdio(t *testing.T) {
+	srv := newTestServer()
+	in := strings.NewReader(initializeBody + "\n" +
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}` + "\n\n" +
+		`{"jsonrpc":"2.0","id":2,"method":"ping"}` + "\n")
+	var out bytes.Buffer
+
+	if err := srv.ServeStdio(context.Background(), in, &out); err != nil {
+		t.Fatalf("serve stdio: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected two responses, got %q", out.String())
+	}
+	var resp Response
+	if err := json.Unmarshal([]byte(lines[1]), &resp); err != nil || resp.Error != nil || resp.ID != float64(2) {
+		t.Fatalf("unexpected ping response %q (%v)", lines[1], err)
+	}
+}
+
+func TestStreamableHTTPSession(t *testing.T) {
+	srv := newTestServer()
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/mcp", "application/json", strings.NewReader(initializeBody))
+	if err != nil {
+		t.Fatalf("initialize: %v", err)
+	}
+	resp.Body.Close()
+	id := resp.Header.Get(SessionHeader)
+	if id == "" {
+		t.Fatalf("initialize did not assign a session")
+	}
+
+	post := func(sessionID, body string) *http.Response {
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+"/mcp", strings.NewReader(body))
+		req.Header.Set(SessionHeader, sessionID)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("post: %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+	if got := post(id, `{"jsonrpc":"2.0","method":"notifications/initialized"}`).StatusCode; got != http.StatusAccepted {
+		t.Fatalf("expected 202 for notification, got %d", got)
+	}
+	if got := post("unknown", `{"jsonrpc":"2.0","id":2,"method":"ping"}`).StatusCode; got != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown session, got %d", got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/mcp", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set(SessionHeader, id)
+	stream, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("open stream: %v", err)
+	}
+	defer stream.Body.Close()
+
+	srv.Broadcast("notifications/tools/list_changed", nil)
+	reader := bufio.NewReader(stream.Body)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read stream: %v", err)
+		}
+		if strings.HasPrefix(line, "data: ") {
+			if !strings.Contains(line, "notifications/tools/list_changed") {
+				t.Fatalf("unexpected event %q", line)
+			}
+			break
+		}
+	}
+
+	del, _ := http.NewRequest(http.MethodDelete, ts.URL+"/mcp", nil)
+	del.Header.Set(SessionHeader, id)
+	delResp, err := http.DefaultClient.Do(del)
+	if err != nil || delResp.StatusCode != http.StatusNoContent {
+		t.Fatalf("delete session: %v %v", delResp, err)
+	}
+	if _, err := io.ReadAll(stream.Body); err != nil {
+		t.Fatalf("stream did not end cleanly: %v", err)
+	}
+}
+
+func TestStreamableHTTPChecksOrigin(t *testing.T) {
+	reg := registry.New()
+	reg.RegisterTools(registry.StaticTools())
+	srv := New(Options{Registry: reg, AllowedOrigins: []string{"https://Console.example.com/"}})
+
+	cases := map[string]int{
+		"":                            http.StatusOK,
+		"https://console.example.com": http.StatusOK,
+		"http://console.example.com":  http.StatusForbidden,
+		"http://attacker.example":     http.StatusForbidden,
+		"null":                        http.StatusForbidden,
+	}
+	for origin, want := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(initializeBody))
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		res := httptest.NewRecorder()
+		srv.ServeHTTP(res, req)
+		if res.Code != want {
+			t.Fatalf("origin %q: expected %d, got %d", origin, want, res.Code)
+		}
+	}
+}
+
+func TestSessionsAreCappedAndExpire(t *testing.T) {
+	reg := registry.New()
+	srv := New(Options{Registry: reg, SessionTTL: time.Minute, MaxSessions: 2})
+	initialize := func() *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		srv.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(initializeBody)))
+		return res
+	}
+
+	idle, active := initialize(), initialize()
+	if idle.Code != http.StatusOK || active.Code != http.StatusOK {
+		t.Fatalf("initialize failed: %d %d", idle.Code, active.Code)
+	}
+	if res := initialize(); res.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 past the session limit, got %d", res.Code)
+	}
+
+	sess, _ := srv.Session(idle.Header().Get(SessionHeader))
+	sess.lastSeen = time.Now().Add(-2 * time.Minute)
+	srv.expireSessions(time.Now())
+	if _, ok := srv.Session(idle.Header().Get(SessionHeader)); ok {
+		t.Fatalf("idle session was not expired")
+	}
+	if _, ok := srv.Session(active.Header().Get(SessionHeader)); !ok {
+		t.Fatalf("active session was expired")
+	}
+	if res := initialize(); res.Code != http.StatusOK {
+		t.Fatalf("expected a new session once one expired, got %d", res.Code)
+	}
+}
+
+func TestStdioElicitsToolConfirmation(t *testing.T) {
+	srv := newTestServer()
+	srv.registry.RegisterTool(types.ToolDescriptor{Name: "restart"}, func(ctx context.Context, args map[string]interface{}) (types.ToolResult, error) {
+		if registry.Confirmed(ctx) != "tok" {
+			return types.ToolResult{}, &registry.ConfirmationError{Message: "restart checkout?", Preview: map[string]string{"service": "checkout"}, Token: "tok"}
+		}
+		return types.ToolResult{Name: "restart", Output: "restarted"}, nil
+	})
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	done := make(chan error, 1)
+	go func() { done <- srv.ServeStdio(context.Background(), inR, outW) }()
+	lines := bufio.NewScanner(outR)
+	next := func() map[string]interface{} {
+		t.Helper()
+		if !lines.Scan() {
+			t.Fatalf("stdio output ended: %v", lines.Err())
+		}
+		var msg map[string]interface{}
+		if err := json.Unmarshal(lines.Bytes(), &msg); err != nil {
+			t.Fatalf("decode %q: %v", lines.Text(), err)
+		}
+		return msg
+	}
+	send := func(msg string) {
+		if _, err := io.WriteString(inW, msg+"\n"); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	send(strings.Replace(initializeBody, `"capabilities":{}`, `"capabilities":{"elicitation":{}}`, 1))
+	next()
+	send(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"restart","arguments":{}}}`)
+	elicit := next()
+	if elicit["method"] != "elicitation/create" || !strings.Contains(elicit["params"].(map[string]interface{})["message"].(string), "checkout") {
+		t.Fatalf("expected an elicitation request, got %v", elicit)
+	}
+	// Other requests are still answered while the tool waits for the user.
+	send(`{"jsonrpc":"2.0","id":3,"method":"ping"}`)
+	if pong := next(); pong["id"] != float64(3) {
+		t.Fatalf("expected ping response, got %v", pong)
+	}
+	id, _ := json.Marshal(elicit["id"])
+	send(`{"jsonrpc":"2.0","id":` + string(id) + `,"result":{"action":"accept","content":{"approve":true}}}`)
+	res := next()["result"].(map[string]interface{})
+	if res["isError"] == true || res["content"].([]interface{})[0].(map[string]interface{})["text"] != "restarted" {
+		t.Fatalf("expected the confirmed tool to run, got %v", res)
+	}
+
+	inW.Close()
+	if err := <-done; err != nil {
+		t.Fatalf("serve stdio: %v", err)
+	}
+}
+
+func TestToolConfirmationWithoutElicitation(t *testing.T) {
+	srv := newTestServer()
+	srv.registry.RegisterTool(types.ToolDescriptor{Name: "restart"}, func(ctx context.Context, args map[string]interface{}) (types.ToolResult, error) {
+		return types.ToolResult{}, &registry.ConfirmationError{Message: "restart checkout?", Token: "s3cr3t"}
+	})
+
+	_, resp := call(t, srv, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"restart","arguments":{}}}`)
+	res := resp.Result.(map[string]interface{})
+	if res["isError"] != true || res["structuredContent"] == nil {
+		t.Fatalf("expected the confirmation to be returned to the model, got %v", res)
+	}
+	if raw, _ := json.Marshal(res); strings.Contains(string(raw), "s3cr3t") {
+		t.Fatalf("confirmation token returned to the model: %s", raw)
+	}
+}
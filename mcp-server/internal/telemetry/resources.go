@@ -0,0 +1,488 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xscopehub/mcp-server/internal/auth"
+	"github.com/xscopehub/mcp-server/internal/gateway"
+	"github.com/xscopehub/mcp-server/internal/registry"
+	"github.com/xscopehub/mcp-server/internal/types"
+)
+
+// maxSince caps the look-back window a resource URI may request.
+const maxSince = 24 * time.Hour
+
+// errorLinePattern selects log lines that look like failures.
+const errorLinePattern = `(?i)(error|exception|fatal|panic)`
+
+// Options tunes the live telemetry resources and tools.
+type Options struct {
+	// CacheTTL is how long gateway results are reused. Defaults to 30s.
+	CacheTTL time.Duration
+	// PageSize is the maximum number of items per resource page. Defaults to 50.
+	PageSize int
+	// MaxBytes bounds the encoded items of a page so it fits an LLM context
+	// window. Defaults to 32 KiB. A page always holds at least one item.
+	MaxBytes int
+	// DefaultSince is the look-back window when a URI has no since parameter.
+	// Defaults to 15m.
+	DefaultSince time.Duration
+	// TraceStream is the OpenObserve trace stream queried for slow traces.
+	// Defaults to "default".
+	TraceStream string
+	// SlowTraceMS is the duration above which a trace counts as slow when the
+	// URI has no min_duration_ms parameter. Defaults to 500.
+	SlowTraceMS int
+}
+
+// Provider serves logs, metrics, traces and topology resources and the query
+// tools from observe-gateway.
+type Provider struct {
+	client *gateway.Client
+	opts   Options
+	now    func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	at    time.Time
+	items []interface{}
+}
+
+// NewProvider builds the providers. It returns nil when client is nil so
+// callers can fall back to the static sample resources.
+func NewProvider(client *gateway.Client, opts Options) *Provider {
+	if client == nil {
+		return nil
+	}
+	if opts.CacheTTL <= 0 {
+		opts.CacheTTL = 30 * time.Second
+	}
+	if opts.PageSize <= 0 {
+		opts.PageSize = 50
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = 32 << 10
+	}
+	if opts.DefaultSince <= 0 {
+		opts.DefaultSince = 15 * time.Minute
+	}
+	if opts.TraceStream == "" {
+		opts.TraceStream = "default"
+	}
+	if opts.SlowTraceMS <= 0 {
+		opts.SlowTraceMS = 500
+	}
+	return &Provider{client: client, opts: opts, now: time.Now, cache: map[string]cacheEntry{}}
+}
+
+// kind is one family of live resources.
+type kind struct {
+	name        string
+	description string
+	params      string
+	fetch       func(p *Provider, ctx context.Context, service string, since time.Duration, vars map[string]string) ([]interface{}, error)
+}
+
+var kinds = []kind{
+	{
+		name:        "logs",
+		description: "Recent error log lines",
+		params:      "since,cursor",
+		fetch:       (*Provider).errorLogs,
+	},
+	{
+		name:        "metrics",
+		description: "Request rate, error rate and p95 latency per service",
+		params:      "since",
+		fetch:       (*Provider).redMetrics,
+	},
+	{
+		name:        "traces",
+		description: "Slowest recent traces",
+		params:      "since,min_duration_ms,cursor",
+		fetch:       (*Provider).slowTraces,
+	},
+	{
+		name:        "topology",
+		description: "Service call graph with request rates",
+		params:      "since,cursor",
+		fetch:       (*Provider).topology,
+	},
+}
+
+// Register adds every live resource, its templates and the query tools to
+// reg, replacing the static samples of the same URI or name.
+func (p *Provider) Register(reg *registry.Registry) error {
+	for _, k := range kinds {
+		k := k
+		fn := func(ctx context.Context, uri string, vars map[string]string) (types.ResourcePayload, error) {
+			return p.read(ctx, k, uri, vars)
+		}
+		reg.RegisterResourceFunc(types.ResourceDescriptor{
+			URI:         registry.ResourceURIPrefix + k.name,
+			Name:        k.name,
+			Title:       strings.Title(k.name),
+			Description: k.description + " across all services (last " + p.opts.DefaultSince.String() + ").",
+			MimeType:    "application/json",
+		}, fn, auth.ScopeTelemetryRead)
+
+		templates := []types.ResourceTemplate{
+			{
+				URITemplate: registry.ResourceURIPrefix + k.name + "{?" + k.params + "}",
+				Name:        k.name,
+				Description: k.description + " across all services.",
+			},
+			{
+				URITemplate: registry.ResourceURIPrefix + k.name + "/{service}{?" + k.params + "}",
+				Name:        k.name + "_by_service",
+				Description: k.description + " for one service.",
+			},
+		}
+		for _, t := range templates {
+			t.Title = strings.Title(strings.ReplaceAll(t.Name, "_", " "))
+			t.MimeType = "application/json"
+			if err := reg.RegisterTemplate(t, fn, auth.ScopeTelemetryRead); err != nil {
+				return err
+			}
+		}
+	}
+	reg.RegisterTools(p.Tools())
+	return nil
+}
+
+// page is the data of a live resource read.
+type page struct {
+	Service    string        `json:"service,omitempty"`
+	Since      string        `json:"since"`
+	Total      int           `json:"total"`
+	Items      []interface{} `json:"items"`
+	NextCursor int           `json:"next_cursor,omitempty"`
+	NextURI    string        `json:"next_uri,omitempty"`
+}
+
+func (p *Provider) read(ctx context.Context, k kind, uri string, vars map[string]string) (types.ResourcePayload, error) {
+	since := p.opts.DefaultSince
+	if raw := vars["since"]; raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 || d > maxSince {
+			return types.ResourcePayload{}, fmt.Errorf("since must be a duration between 0 and %s", maxSince)
+		}
+		since = d
+	}
+	cursor := 0
+	if raw := vars["cursor"]; raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			return types.ResourcePayload{}, fmt.Errorf("cursor must be a non-negative integer")
+		}
+		cursor = n
+	}
+	service := vars["service"]
+
+	items, err := p.cached(auth.TenantFromContext(ctx)+"|"+cacheKey(uri), func() ([]interface{}, error) {
+		return k.fetch(p, ctx, service, since, vars)
+	})
+	if err != nil {
+		return types.ResourcePayload{}, err
+	}
+
+	pg := paginate(items, cursor, p.opts.PageSize, p.opts.MaxBytes)
+	pg.Service, pg.Since = service, since.String()
+	if pg.NextCursor > 0 {
+		pg.NextURI = withCursor(uri, pg.NextCursor)
+	}
+	return types.ResourcePayload{
+		URI:         uri,
+		Name:        k.name,
+		Description: k.description,
+		MimeType:    "application/json",
+		Data:        pg,
+	}, nil
+}
+
+func (p *Provider) cached(key string, fetch func() ([]interface{}, error)) ([]interface{}, error) {
+	now := p.now()
+	p.mu.Lock()
+	entry, ok := p.cache[key]
+	p.mu.Unlock()
+	if ok && now.Sub(entry.at) < p.opts.CacheTTL {
+		return entry.items, nil
+	}
+
+	items, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for k, e := range p.cache {
+		if now.Sub(e.at) >= p.opts.CacheTTL {
+			delete(p.cache, k)
+		}
+	}
+	p.cache[key] = cacheEntry{at: now, items: items}
+	return items, nil
+}
+
+// cacheKey drops the cursor so every page of a result shares one entry.
+func cacheKey(uri string) string {
+	base, rawQuery, ok := strings.Cut(uri, "?")
+	if !ok {
+		return uri
+	}
+	q, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return uri
+	}
+	q.Del("cursor")
+	if len(q) == 0 {
+		return base
+	}
+	return base + "?" + q.Encode()
+}
+
+func withCursor(uri string, cursor int) string {
+	base, rawQuery, _ := strings.Cut(uri, "?")
+	q, _ := url.ParseQuery(rawQuery)
+	q.Set("cursor", strconv.Itoa(cursor))
+	return base + "?" + q.Encode()
+}
+
+// paginate returns up to pageSize items starting at cursor whose encoded
+// size stays within maxBytes.
+func paginate(items []interface{}, cursor, pageSize, maxBytes int) page {
+	pg := page{Total: len(items), Items: []interface{}{}}
+	if cursor >= len(items) {
+		return pg
+	}
+	size := 0
+	for i := cursor; i < len(items) && len(pg.Items) < pageSize; i++ {
+		raw, _ := json.Marshal(items[i])
+		if len(pg.Items) > 0 && size+len(raw) > maxBytes {
+			break
+		}
+		size += len(raw)
+		pg.Items = append(pg.Items, items[i])
+	}
+	if next := cursor + len(pg.Items); next < len(items) {
+		pg.NextCursor = next
+	}
+	return pg
+}
+
+func (p *Provider) errorLogs(ctx context.Context, service string, since time.Duration, _ map[string]string) ([]interface{}, error) {
+	q := "|~ " + gateway.QuoteLabelValue(errorLinePattern)
+	if service != "" {
+		q = "{service=" + gateway.QuoteLabelValue(service) + "} " + q
+	}
+	end := p.now()
+	resp, err := p.client.Query(ctx, gateway.Request{Lang: "logql", Query: q, Start: end.Add(-since), End: end})
+	if err != nil {
+		return nil, err
+	}
+	hits, err := gateway.Hits(resp.Result)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]interface{}, len(hits))
+	for i, h := range hits {
+		items[i] = h
+	}
+	return items, nil
+}
+
+func (p *Provider) slowTraces(ctx context.Context, service string, since time.Duration, vars map[string]string) ([]interface{}, error) {
+	minMS := p.opts.SlowTraceMS
+	if raw := vars["min_duration_ms"]; raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("min_duration_ms must be a non-negative integer")
+		}
+		minMS = n
+	}
+
+	// OpenObserve records span durations in microseconds.
+	q := fmt.Sprintf("FROM %s WHERE duration >= %d", gateway.QuoteLabelValue(p.opts.TraceStream), minMS*1000)
+	if service != "" {
+		q += " AND service_name = " + gateway.QuoteLabelValue(service)
+	}
+	end := p.now()
+	resp, err := p.client.Query(ctx, gateway.Request{Lang: "traceql", Query: q, Start: end.Add(-since), End: end})
+	if err != nil {
+		return nil, err
+	}
+	hits, err := gateway.Hits(resp.Result)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		return number(hits[i]["duration"]) > number(hits[j]["duration"])
+	})
+	items := make([]interface{}, len(hits))
+	for i, h := range hits {
+		items[i] = SummarizeSpan(h)
+	}
+	return items, nil
+}
+
+func (p *Provider) redMetrics(ctx context.Context, service string, since time.Duration, _ map[string]string) ([]interface{}, error) {
+	window := promDuration(since)
+	selector, by := "", " by (service_name)"
+	if service != "" {
+		selector = "service_name=" + gateway.QuoteLabelValue(service)
+	}
+	withSel := func(extra string) string {
+		parts := []string{}
+		for _, s := range []string{selector, extra} {
+			if s != "" {
+				parts = append(parts, s)
+			}
+		}
+		if len(parts) == 0 {
+			return ""
+		}
+		return "{" + strings.Join(parts, ",") + "}"
+	}
+	queries := map[string]string{
+		"rate":       "sum" + by + " (rate(calls_total" + withSel("") + "[" + window + "]))",
+		"error_rate": "sum" + by + " (rate(calls_total" + withSel(`status_code="STATUS_CODE_ERROR"`) + "[" + window + "]))",
+		"p95_ms":     "histogram_quantile(0.95, sum by (le, service_name) (rate(duration_milliseconds_bucket" + withSel("") + "[" + window + "])))",
+	}
+
+	byService := map[string]map[string]interface{}{}
+	for _, name := range []string{"rate", "error_rate", "p95_ms"} {
+		resp, err := p.client.Query(ctx, gateway.Request{Lang: "promql", Query: queries[name]})
+		if err != nil {
+			return nil, err
+		}
+		series, err := gateway.PromSeries(resp.Result)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range series {
+			svc := s.Metric["service_name"]
+			row := byService[svc]
+			if row == nil {
+				row = map[string]interface{}{"service": svc}
+				byService[svc] = row
+			}
+			if len(s.Value) == 2 {
+				row[name] = sampleValue(s.Value[1])
+			}
+		}
+	}
+
+	names := make([]string, 0, len(byService))
+	for svc := range byService {
+		names = append(names, svc)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return number(byService[names[i]]["rate"]) > number(byService[names[j]]["rate"])
+	})
+	items := make([]interface{}, len(names))
+	for i, svc := range names {
+		items[i] = byService[svc]
+	}
+	return items, nil
+}
+
+func (p *Provider) topology(ctx context.Context, service string, since time.Duration, _ map[string]string) ([]interface{}, error) {
+	window := promDuration(since)
+	edges := func(sel string) string {
+		return "sum by (client, server) (rate(traces_service_graph_request_total" + sel + "[" + window + "]))"
+	}
+	query := edges("")
+	if service != "" {
+		// Edges touching the service in either direction.
+		q := gateway.QuoteLabelValue(service)
+		query = edges("{client="+q+"}") + " or " + edges("{server="+q+"}")
+	}
+
+	resp, err := p.client.Query(ctx, gateway.Request{Lang: "promql", Query: query})
+	if err != nil {
+		return nil, err
+	}
+	series, err := gateway.PromSeries(resp.Result)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]interface{}, 0, len(series))
+	for _, s := range series {
+		edge := map[string]interface{}{"from": s.Metric["client"], "to": s.Metric["server"], "edge": "CALLS"}
+		if len(s.Value) == 2 {
+			edge["rps"] = sampleValue(s.Value[1])
+		}
+		items = append(items, edge)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return number(items[i].(map[string]interface{})["rps"]) > number(items[j].(map[string]interface{})["rps"])
+	})
+	return items, nil
+}
+
+// SummarizeSpan keeps the fields of a span useful to an assistant.
+func SummarizeSpan(h map[string]interface{}) map[string]interface{} {
+	out := map[string]interface{}{}
+	for _, key := range []string{"trace_id", "span_id", "service_name", "operation_name", "span_status", "start_time", "duration"} {
+		if v, ok := h[key]; ok && v != nil {
+			out[key] = v
+		}
+	}
+	if d, ok := out["duration"]; ok {
+		out["duration_ms"] = number(d) / 1000
+		delete(out, "duration")
+	}
+	return out
+}
+
+// promDuration renders d in PromQL duration syntax.
+func promDuration(d time.Duration) string {
+	if d%time.Hour == 0 {
+		return strconv.Itoa(int(d/time.Hour)) + "h"
+	}
+	if d%time.Minute == 0 {
+		return strconv.Itoa(int(d/time.Minute)) + "m"
+	}
+	return strconv.Itoa(int(d.Round(time.Second)/time.Second)) + "s"
+}
+
+// sampleValue converts a Prometheus sample value string to a number when possible.
+func sampleValue(v interface{}) interface{} {
+	s, ok := v.(string)
+	if !ok {
+		return v
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return s
+	}
+	return f
+}
+
+func number(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case int:
+		return float64(n)
+	case string:
+		f, _ := strconv.ParseFloat(n, 64)
+		return f
+	default:
+		return 0
+	}
+}
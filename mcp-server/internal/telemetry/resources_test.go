@@ -0,0 +1,81 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/xscopehub/mcp-server/internal/gateway"
+	"github.com/xscopehub/mcp-server/internal/registry"
+)
+
+func TestLogResourcePagesAndCaches(t *testing.T) {
+	var calls int32
+	var got gateway.Request
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.Header.Get("X-Tenant") != "acme" || r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		hits := make([]map[string]interface{}, 5)
+		for i := range hits {
+			hits[i] = map[string]interface{}{"message": fmt.Sprintf("error %d", i)}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"lang": "logql", "result": map[string]interface{}{"hits": hits}})
+	}))
+	defer ts.Close()
+
+	reg := registry.New()
+	live := NewProvider(gateway.New(gateway.Config{BaseURL: ts.URL, Tenant: "acme", Token: "secret"}), Options{PageSize: 2})
+	if err := live.Register(reg); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	payload, err := reg.ReadResource(context.Background(), "xscope://logs/check%20out?since=1h")
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if got.Lang != "logql" || !strings.HasPrefix(got.Query, `{service="check out"} |~`) {
+		t.Fatalf("unexpected gateway request %+v", got)
+	}
+	if d := got.End.Sub(got.Start); d.Hours() != 1 {
+		t.Fatalf("unexpected range %s", d)
+	}
+	pg := payload.Data.(page)
+	if pg.Total != 5 || len(pg.Items) != 2 || pg.NextCursor != 2 {
+		t.Fatalf("unexpected first page %+v", pg)
+	}
+
+	payload, err = reg.ReadResource(context.Background(), pg.NextURI)
+	if err != nil {
+		t.Fatalf("read next page: %v", err)
+	}
+	if pg = payload.Data.(page); pg.Items[0].(map[string]interface{})["message"] != "error 2" {
+		t.Fatalf("unexpected second page %+v", pg)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("expected pages to share a cached result, got %d gateway calls", n)
+	}
+
+	if _, err := reg.ReadResource(context.Background(), "xscope://logs?since=48h"); err == nil {
+		t.Fatalf("expected since beyond the limit to be rejected")
+	}
+}
+
+func TestPaginateRespectsByteBudget(t *testing.T) {
+	items := []interface{}{strings.Repeat("a", 40), strings.Repeat("b", 40), "c"}
+	pg := paginate(items, 0, 10, 50)
+	if len(pg.Items) != 1 || pg.NextCursor != 1 {
+		t.Fatalf("expected a single item within the byte budget, got %+v", pg)
+	}
+	if pg = paginate(items, 3, 10, 50); len(pg.Items) != 0 || pg.NextCursor != 0 {
+		t.Fatalf("expected an empty final page, got %+v", pg)
+	}
+}
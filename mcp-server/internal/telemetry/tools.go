@@ -0,0 +1,527 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/xscopehub/mcp-server/internal/auth"
+	"github.com/xscopehub/mcp-server/internal/gateway"
+	"github.com/xscopehub/mcp-server/internal/registry"
+	"github.com/xscopehub/mcp-server/internal/types"
+)
+
+const (
+	defaultToolLimit  = 20
+	maxToolLimit      = 200
+	defaultMaxPoints  = 60
+	defaultMaxSeries  = 10
+	maxMessageLength  = 500
+	defaultToolWindow = 15 * time.Minute
+)
+
+// timeRangeSchema is shared by every query tool.
+const timeRangeSchema = `
+		"since": {"type": "string", "description": "Look-back window ending now, e.g. 15m or 2h. Ignored when start is set.", "default": "15m"},
+		"start": {"type": "string", "format": "date-time", "description": "RFC 3339 start of the range."},
+		"end": {"type": "string", "format": "date-time", "description": "RFC 3339 end of the range, defaults to now."}`
+
+var (
+	queryLogsSchema = json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		"service": {"type": "string", "description": "Service label to select."},
+		"level": {"type": "string", "description": "Log level label to select, e.g. error."},
+		"contains": {"type": "string", "description": "Case-insensitive substring the line must contain."},
+		"regex": {"type": "string", "description": "Regular expression the line must match."},
+		"labels": {"type": "object", "additionalProperties": {"type": "string"}, "description": "Additional label equality filters."},
+		"query": {"type": "string", "description": "Raw LogQL; overrides service, level, labels, contains and regex."},` + timeRangeSchema + `,
+		"limit": {"type": "integer", "minimum": 1, "maximum": 200, "default": 20}
+	}
+}`)
+
+	queryMetricsSchema = json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		"metric": {"type": "string", "enum": ["rate", "errors", "latency_p95"], "description": "RED preset to chart for the service."},
+		"service": {"type": "string", "description": "Service the preset is scoped to."},
+		"labels": {"type": "object", "additionalProperties": {"type": "string"}, "description": "Additional label equality filters for the preset."},
+		"query": {"type": "string", "description": "Raw PromQL; overrides metric, service and labels."},` + timeRangeSchema + `,
+		"step": {"type": "string", "description": "Query resolution, e.g. 30s. Derived from max_points when omitted."},
+		"max_points": {"type": "integer", "minimum": 2, "maximum": 500, "default": 60},
+		"max_series": {"type": "integer", "minimum": 1, "maximum": 100, "default": 10}
+	}
+}`)
+
+	queryTracesSchema = json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		"service": {"type": "string", "description": "Service name of the spans."},
+		"operation": {"type": "string", "description": "Span operation name."},
+		"min_duration_ms": {"type": "integer", "minimum": 0, "description": "Only spans at least this slow."},
+		"errors_only": {"type": "boolean", "description": "Only spans with an error status."},
+		"attributes": {"type": "object", "additionalProperties": {"type": "string"}, "description": "Additional span attribute equality filters."},` + timeRangeSchema + `,
+		"limit": {"type": "integer", "minimum": 1, "maximum": 200, "default": 20}
+	}
+}`)
+)
+
+// Tools returns the query tools backed by observe-gateway. They replace the
+// static query_logs sample when registered.
+func (p *Provider) Tools() map[string]registry.Tool {
+	return map[string]registry.Tool{
+		"query_logs": {
+			Descriptor: types.ToolDescriptor{
+				Name:        "query_logs",
+				Title:       "Query logs",
+				Description: "Search log lines through observe-gateway with LogQL and return the newest matches.",
+				InputSchema: queryLogsSchema,
+			},
+			Func:   p.queryLogs,
+			Scopes: []string{auth.ScopeTelemetryRead},
+		},
+		"query_metrics": {
+			Descriptor: types.ToolDescriptor{
+				Name:        "query_metrics",
+				Title:       "Query metrics",
+				Description: "Run a PromQL range query through observe-gateway and return downsampled series.",
+				InputSchema: queryMetricsSchema,
+			},
+			Func:   p.queryMetrics,
+			Scopes: []string{auth.ScopeTelemetryRead},
+		},
+		"query_traces": {
+			Descriptor: types.ToolDescriptor{
+				Name:        "query_traces",
+				Title:       "Query traces",
+				Description: "Find spans through observe-gateway and return per-trace summaries, slowest first.",
+				InputSchema: queryTracesSchema,
+			},
+			Func:   p.queryTraces,
+			Scopes: []string{auth.ScopeTelemetryRead},
+		},
+	}
+}
+
+func (p *Provider) queryLogs(ctx context.Context, args map[string]interface{}) (types.ToolResult, error) {
+	start, end, err := timeRange(args, p.now())
+	if err != nil {
+		return types.ToolResult{}, err
+	}
+	limit := intArg(args, "limit", defaultToolLimit, maxToolLimit)
+
+	q := stringArg(args, "query")
+	if q == "" {
+		matchers, err := labelMatchers(args, "labels")
+		if err != nil {
+			return types.ToolResult{}, err
+		}
+		if svc := stringArg(args, "service"); svc != "" {
+			matchers = append([]string{"service=" + gateway.QuoteLabelValue(svc)}, matchers...)
+		}
+		if lvl := stringArg(args, "level"); lvl != "" {
+			matchers = append(matchers, "level="+gateway.QuoteLabelValue(lvl))
+		}
+		var parts []string
+		if len(matchers) > 0 {
+			parts = append(parts, "{"+strings.Join(matchers, ", ")+"}")
+		}
+		if s := stringArg(args, "contains"); s != "" {
+			parts = append(parts, "|= "+gateway.QuoteLabelValue(s))
+		}
+		if s := stringArg(args, "regex"); s != "" {
+			parts = append(parts, "|~ "+gateway.QuoteLabelValue(s))
+		}
+		if len(parts) == 0 {
+			return types.ToolResult{}, fmt.Errorf("one of query, service, level, labels, contains or regex is required")
+		}
+		q = strings.Join(parts, " ")
+	}
+
+	resp, err := p.query(ctx, gateway.Request{Lang: "logql", Query: q, Start: start, End: end})
+	if err != nil {
+		return types.ToolResult{}, err
+	}
+	hits, err := gateway.Hits(resp.Result)
+	if err != nil {
+		return types.ToolResult{}, err
+	}
+
+	lines := make([]map[string]interface{}, 0, min(limit, len(hits)))
+	for _, h := range hits[:min(limit, len(hits))] {
+		lines = append(lines, compactLogLine(h))
+	}
+	return types.ToolResult{Name: "query_logs", Output: map[string]interface{}{
+		"query":    q,
+		"start":    start,
+		"end":      end,
+		"total":    len(hits),
+		"returned": len(lines),
+		"lines":    lines,
+	}}, nil
+}
+
+func (p *Provider) queryMetrics(ctx context.Context, args map[string]interface{}) (types.ToolResult, error) {
+	start, end, err := timeRange(args, p.now())
+	if err != nil {
+		return types.ToolResult{}, err
+	}
+	maxPoints := intArg(args, "max_points", defaultMaxPoints, 500)
+	maxSeries := intArg(args, "max_series", defaultMaxSeries, 100)
+
+	q := stringArg(args, "query")
+	if q == "" {
+		if q, err = redPreset(args, end.Sub(start)); err != nil {
+			return types.ToolResult{}, err
+		}
+	}
+	step := stringArg(args, "step")
+	if step == "" {
+		step = promDuration(max(time.Second, (end.Sub(start) / time.Duration(maxPoints)).Round(time.Second)))
+	}
+
+	resp, err := p.query(ctx, gateway.Request{Lang: "promql", Query: q, Start: start, End: end, Step: step})
+	if err != nil {
+		return types.ToolResult{}, err
+	}
+	series, err := gateway.PromSeries(resp.Result)
+	if err != nil {
+		return types.ToolResult{}, err
+	}
+
+	out := make([]map[string]interface{}, 0, min(maxSeries, len(series)))
+	for _, s := range series[:min(maxSeries, len(series))] {
+		out = append(out, summarizeSeries(s, maxPoints))
+	}
+	return types.ToolResult{Name: "query_metrics", Output: map[string]interface{}{
+		"query":        q,
+		"start":        start,
+		"end":          end,
+		"step":         step,
+		"total_series": len(series),
+		"series":       out,
+	}}, nil
+}
+
+func (p *Provider) queryTraces(ctx context.Context, args map[string]interface{}) (types.ToolResult, error) {
+	start, end, err := timeRange(args, p.now())
+	if err != nil {
+		return types.ToolResult{}, err
+	}
+	limit := intArg(args, "limit", defaultToolLimit, maxToolLimit)
+
+	var conds []string
+	if svc := stringArg(args, "service"); svc != "" {
+		conds = append(conds, "service_name = "+gateway.QuoteLabelValue(svc))
+	}
+	if op := stringArg(args, "operation"); op != "" {
+		conds = append(conds, "operation_name = "+gateway.QuoteLabelValue(op))
+	}
+	if ms := intArg(args, "min_duration_ms", 0, 1<<31-1); ms > 0 {
+		// OpenObserve records span durations in microseconds.
+		conds = append(conds, fmt.Sprintf("duration >= %d", ms*1000))
+	}
+	if errorsOnly, _ := args["errors_only"].(bool); errorsOnly {
+		conds = append(conds, `span_status = "ERROR"`)
+	}
+	attrs, err := sortedStringMap(args, "attributes")
+	if err != nil {
+		return types.ToolResult{}, err
+	}
+	for _, m := range attrs {
+		conds = append(conds, m[0]+" = "+gateway.QuoteLabelValue(m[1]))
+	}
+
+	q := "FROM " + gateway.QuoteLabelValue(p.opts.TraceStream)
+	if len(conds) > 0 {
+		q += " WHERE " + strings.Join(conds, " AND ")
+	}
+	resp, err := p.query(ctx, gateway.Request{Lang: "traceql", Query: q, Start: start, End: end})
+	if err != nil {
+		return types.ToolResult{}, err
+	}
+	hits, err := gateway.Hits(resp.Result)
+	if err != nil {
+		return types.ToolResult{}, err
+	}
+
+	traces := summarizeTraces(hits)
+	return types.ToolResult{Name: "query_traces", Output: map[string]interface{}{
+		"query":        q,
+		"start":        start,
+		"end":          end,
+		"total_spans":  len(hits),
+		"total_traces": len(traces),
+		"traces":       traces[:min(limit, len(traces))],
+	}}, nil
+}
+
+// redPreset builds the PromQL for a RED metric preset.
+func redPreset(args map[string]interface{}, window time.Duration) (string, error) {
+	matchers, err := labelMatchers(args, "labels")
+	if err != nil {
+		return "", err
+	}
+	if svc := stringArg(args, "service"); svc != "" {
+		matchers = append([]string{"service_name=" + gateway.QuoteLabelValue(svc)}, matchers...)
+	}
+	rateWindow := promDuration(min(5*time.Minute, max(time.Minute, window)))
+	sel := func(extra ...string) string {
+		all := append(append([]string{}, matchers...), extra...)
+		if len(all) == 0 {
+			return ""
+		}
+		return "{" + strings.Join(all, ",") + "}"
+	}
+
+	switch stringArg(args, "metric") {
+	case "rate":
+		return "sum by (service_name) (rate(calls_total" + sel() + "[" + rateWindow + "]))", nil
+	case "errors":
+		return "sum by (service_name) (rate(calls_total" + sel(`status_code="STATUS_CODE_ERROR"`) + "[" + rateWindow + "]))", nil
+	case "latency_p95":
+		return "histogram_quantile(0.95, sum by (le, service_name) (rate(duration_milliseconds_bucket" + sel() + "[" + rateWindow + "])))", nil
+	case "":
+		return "", fmt.Errorf("query or metric is required")
+	default:
+		return "", fmt.Errorf("metric must be one of rate, errors, latency_p95")
+	}
+}
+
+// summarizeSeries downsamples a series to at most maxPoints by averaging
+// consecutive buckets and adds min, max and last values.
+func summarizeSeries(s gateway.Series, maxPoints int) map[string]interface{} {
+	type point struct{ ts, v float64 }
+	var points []point
+	raw := s.Values
+	if len(raw) == 0 && len(s.Value) == 2 {
+		raw = [][]interface{}{s.Value}
+	}
+	for _, p := range raw {
+		if len(p) != 2 {
+			continue
+		}
+		v, ok := sampleValue(p[1]).(float64)
+		if !ok {
+			continue
+		}
+		points = append(points, point{ts: number(p[0]), v: v})
+	}
+
+	out := map[string]interface{}{"metric": s.Metric, "samples": len(points)}
+	if len(points) == 0 {
+		out["points"] = [][2]float64{}
+		return out
+	}
+
+	lo, hi := points[0].v, points[0].v
+	for _, p := range points {
+		lo, hi = min(lo, p.v), max(hi, p.v)
+	}
+	out["min"], out["max"], out["last"] = lo, hi, points[len(points)-1].v
+
+	buckets := min(maxPoints, len(points))
+	down := make([][2]float64, 0, buckets)
+	for b := 0; b < buckets; b++ {
+		from, to := b*len(points)/buckets, (b+1)*len(points)/buckets
+		sum := 0.0
+		for _, p := range points[from:to] {
+			sum += p.v
+		}
+		down = append(down, [2]float64{points[to-1].ts, sum / float64(to-from)})
+	}
+	out["points"] = down
+	return out
+}
+
+// summarizeTraces groups spans by trace and orders traces by their slowest span.
+func summarizeTraces(hits []map[string]interface{}) []map[string]interface{} {
+	type trace struct {
+		id       string
+		services map[string]struct{}
+		spans    int
+		errors   int
+		slowest  map[string]interface{}
+		duration float64
+		start    float64
+	}
+	byID := map[string]*trace{}
+	var order []string
+	for _, h := range hits {
+		id, _ := h["trace_id"].(string)
+		t := byID[id]
+		if t == nil {
+			t = &trace{id: id, services: map[string]struct{}{}, start: number(h["start_time"])}
+			byID[id] = t
+			order = append(order, id)
+		}
+		t.spans++
+		if svc, ok := h["service_name"].(string); ok {
+			t.services[svc] = struct{}{}
+		}
+		if status, _ := h["span_status"].(string); strings.EqualFold(status, "error") {
+			t.errors++
+		}
+		if d := number(h["duration"]); t.slowest == nil || d > t.duration {
+			t.duration, t.slowest = d, h
+		}
+		if st := number(h["start_time"]); st > 0 && (t.start == 0 || st < t.start) {
+			t.start = st
+		}
+	}
+
+	sort.SliceStable(order, func(i, j int) bool { return byID[order[i]].duration > byID[order[j]].duration })
+	out := make([]map[string]interface{}, 0, len(order))
+	for _, id := range order {
+		t := byID[id]
+		services := make([]string, 0, len(t.services))
+		for svc := range t.services {
+			services = append(services, svc)
+		}
+		sort.Strings(services)
+		out = append(out, map[string]interface{}{
+			"trace_id":     t.id,
+			"services":     services,
+			"spans":        t.spans,
+			"error_spans":  t.errors,
+			"slowest_span": SummarizeSpan(t.slowest),
+			"start_time":   t.start,
+		})
+	}
+	return out
+}
+
+// compactLogLine keeps the timestamp, common labels and a truncated message.
+func compactLogLine(h map[string]interface{}) map[string]interface{} {
+	out := map[string]interface{}{}
+	for _, key := range []string{"_timestamp", "service", "service_name", "level", "trace_id"} {
+		if v, ok := h[key]; ok && v != nil {
+			out[key] = v
+		}
+	}
+	msg, _ := h["message"].(string)
+	if msg == "" {
+		msg, _ = h["log"].(string)
+	}
+	out["message"] = truncate(msg, maxMessageLength)
+	return out
+}
+
+// timeRange resolves start/end/since arguments into an absolute range.
+func timeRange(args map[string]interface{}, now time.Time) (time.Time, time.Time, error) {
+	end := now
+	if raw := stringArg(args, "end"); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("end: %w", err)
+		}
+		end = t
+	}
+	if raw := stringArg(args, "start"); raw != "" {
+		start, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("start: %w", err)
+		}
+		if !start.Before(end) {
+			return time.Time{}, time.Time{}, fmt.Errorf("start must be before end")
+		}
+		return start, end, nil
+	}
+
+	since := defaultToolWindow
+	if raw := stringArg(args, "since"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 || d > maxSince {
+			return time.Time{}, time.Time{}, fmt.Errorf("since must be a duration between 0 and %s", maxSince)
+		}
+		since = d
+	}
+	return end.Add(-since), end, nil
+}
+
+// query runs a gateway query for a tool and reports progress around the
+// round trip, which can take a while over long ranges.
+func (p *Provider) query(ctx context.Context, req gateway.Request) (gateway.Response, error) {
+	registry.ReportProgress(ctx, 0, 2, "querying observe-gateway")
+	resp, err := p.client.Query(ctx, req)
+	if err == nil {
+		registry.ReportProgress(ctx, 1, 2, "summarizing results")
+	}
+	return resp, err
+}
+
+func stringArg(args map[string]interface{}, key string) string {
+	s, _ := args[key].(string)
+	return strings.TrimSpace(s)
+}
+
+// intArg reads a JSON number argument, falling back to def and capping at max.
+func intArg(args map[string]interface{}, key string, def, limit int) int {
+	var n int
+	switch v := args[key].(type) {
+	case float64:
+		n = int(v)
+	case int:
+		n = v
+	case string:
+		n, _ = strconv.Atoi(v)
+	}
+	if n <= 0 {
+		return def
+	}
+	return min(n, limit)
+}
+
+// fieldNameRegex restricts label and attribute names so they cannot break
+// out of the generated queries.
+var fieldNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// sortedStringMap returns the entries of an object argument sorted by key.
+func sortedStringMap(args map[string]interface{}, key string) ([][2]string, error) {
+	m, _ := args[key].(map[string]interface{})
+	out := make([][2]string, 0, len(m))
+	for k, v := range m {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s.%s must be a string", key, k)
+		}
+		if !fieldNameRegex.MatchString(k) {
+			return nil, fmt.Errorf("%s: invalid name %q", key, k)
+		}
+		out = append(out, [2]string{k, s})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i][0] < out[j][0] })
+	return out, nil
+}
+
+func labelMatchers(args map[string]interface{}, key string) ([]string, error) {
+	entries, err := sortedStringMap(args, key)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, kv := range entries {
+		if strings.Contains(kv[0], ".") {
+			return nil, fmt.Errorf("%s: invalid label name %q", key, kv[0])
+		}
+		out = append(out, kv[0]+"="+gateway.QuoteLabelValue(kv[1]))
+	}
+	return out, nil
+}
+
+// truncate shortens s to at most n bytes without splitting a UTF-8 sequence.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + "…"
+}
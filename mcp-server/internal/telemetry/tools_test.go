@@ -0,0 +1,97 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xscopehub/mcp-server/internal/gateway"
+)
+
+func newToolProvider(t *testing.T, handler http.HandlerFunc) *Provider {
+	t.Helper()
+	ts := httptest.NewServer(handler)
+	t.Cleanup(ts.Close)
+	p := NewProvider(gateway.New(gateway.Config{BaseURL: ts.URL}), Options{})
+	p.now = func() time.Time { return time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC) }
+	return p
+}
+
+func TestQueryMetricsDownsamples(t *testing.T) {
+	var got gateway.Request
+	p := newToolProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		values := make([][]interface{}, 100)
+		for i := range values {
+			values[i] = []interface{}{float64(1714550000 + i*15), "2"}
+		}
+		values[99][1] = "4"
+		json.NewEncoder(w).Encode(map[string]interface{}{"result": map[string]interface{}{
+			"status": "success",
+			"data": map[string]interface{}{"resultType": "matrix", "result": []interface{}{
+				map[string]interface{}{"metric": map[string]string{"service_name": "api"}, "values": values},
+			}},
+		}})
+	})
+
+	res, err := p.queryMetrics(context.Background(), map[string]interface{}{"metric": "errors", "service": "api", "since": "1h", "max_points": float64(10)})
+	if err != nil {
+		t.Fatalf("query metrics: %v", err)
+	}
+	if !strings.Contains(got.Query, `calls_total{service_name="api",status_code="STATUS_CODE_ERROR"}[5m]`) || got.Step != "6m" {
+		t.Fatalf("unexpected gateway request %+v", got)
+	}
+	series := res.Output.(map[string]interface{})["series"].([]map[string]interface{})
+	points := series[0]["points"].([][2]float64)
+	if len(points) != 10 || series[0]["max"] != 4.0 || series[0]["last"] != 4.0 {
+		t.Fatalf("unexpected series summary %+v", series[0])
+	}
+	if points[9][1] != 2.2 {
+		t.Fatalf("expected the last bucket to average its samples, got %v", points[9])
+	}
+
+	if _, err := p.queryMetrics(context.Background(), map[string]interface{}{"metric": "rate", "labels": map[string]interface{}{`x"}`: "y"}}); err == nil {
+		t.Fatalf("expected invalid label names to be rejected")
+	}
+}
+
+func TestQueryTracesSummarizes(t *testing.T) {
+	var got gateway.Request
+	p := newToolProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"result":{"hits":[
+			{"trace_id":"a","service_name":"api","duration":1000,"start_time":5},
+			{"trace_id":"b","service_name":"api","duration":9000,"span_status":"ERROR","start_time":7},
+			{"trace_id":"a","service_name":"db","duration":3000,"start_time":4}
+		]}}`))
+	})
+
+	res, err := p.queryTraces(context.Background(), map[string]interface{}{"service": "api", "min_duration_ms": float64(1), "errors_only": true})
+	if err != nil {
+		t.Fatalf("query traces: %v", err)
+	}
+	if want := `FROM "default" WHERE service_name = "api" AND duration >= 1000 AND span_status = "ERROR"`; got.Query != want {
+		t.Fatalf("unexpected traceql %q", got.Query)
+	}
+	traces := res.Output.(map[string]interface{})["traces"].([]map[string]interface{})
+	if len(traces) != 2 || traces[0]["trace_id"] != "b" || traces[1]["spans"] != 2 || traces[1]["start_time"] != 4.0 {
+		t.Fatalf("unexpected summaries %+v", traces)
+	}
+}
+
+func TestGatewayErrorsSurface(t *testing.T) {
+	p := newToolProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"logql requires start and end"}`, http.StatusBadRequest)
+	})
+
+	_, err := p.queryLogs(context.Background(), map[string]interface{}{"service": "api"})
+	var gwErr *gateway.Error
+	if !errors.As(err, &gwErr) || gwErr.Status != http.StatusBadRequest || gwErr.Message != "logql requires start and end" {
+		t.Fatalf("expected gateway error, got %v", err)
+	}
+}
@@ -0,0 +1,273 @@
+// Package toolplugin runs tools declared in the manifest or a plugin file
+// outside the server process, so tools such as kubectl-read or git-blame can
+// be added without a rebuild.
+//
+// Both kinds of plugin speak the same JSON: each call sends a Request and
+// expects a Response. An exec plugin is started once per call, reads the
+// request from stdin and writes the response to stdout; it is killed when the
+// call's timeout expires or the client cancels. An HTTP plugin receives the
+// request as a POST body.
+package toolplugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/xscopehub/mcp-server/internal/auth"
+	"github.com/xscopehub/mcp-server/internal/registry"
+	"github.com/xscopehub/mcp-server/internal/types"
+	"github.com/xscopehub/mcp-server/pkg/manifest"
+)
+
+const (
+	// maxOutput bounds a plugin's response.
+	maxOutput = 4 << 20
+	// maxStderr bounds how much of an exec plugin's stderr ends up in errors.
+	maxStderr = 4 << 10
+	// killGrace is how long an exec plugin may take to exit after it has
+	// been killed before its pipes are closed.
+	killGrace = 2 * time.Second
+)
+
+// Request is what a plugin receives for each call. Arguments have already
+// been validated against the declared input schema. Tenant and Subject
+// identify the authenticated caller, if any.
+type Request struct {
+	Tool      string                 `json:"tool"`
+	Arguments map[string]interface{} `json:"arguments"`
+	Tenant    string                 `json:"tenant,omitempty"`
+	Subject   string                 `json:"subject,omitempty"`
+}
+
+// Response is what a plugin answers. A non-empty Error fails the call; the
+// message is shown to the client as the tool's error.
+type Response struct {
+	Output interface{} `json:"output"`
+	Error  string      `json:"error,omitempty"`
+}
+
+var client = &http.Client{}
+
+// Tools builds registry tools from declarations. dir is the directory of the
+// file that declared them; relative commands and working directories are
+// resolved against it.
+func Tools(dir string, specs []manifest.ToolPlugin) ([]registry.Tool, error) {
+	out := make([]registry.Tool, 0, len(specs))
+	for _, spec := range specs {
+		tool, err := Tool(dir, spec)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, tool)
+	}
+	return out, nil
+}
+
+// Tool builds one registry tool from its declaration.
+func Tool(dir string, spec manifest.ToolPlugin) (registry.Tool, error) {
+	if spec.Name == "" {
+		return registry.Tool{}, errors.New("tool plugin without a name")
+	}
+	tool := registry.Tool{
+		Descriptor: types.ToolDescriptor{
+			Name:        spec.Name,
+			Title:       spec.Title,
+			Description: spec.Description,
+			InputSchema: spec.InputSchema,
+		},
+		MaxConcurrent: spec.MaxConcurrent,
+		Scopes:        spec.Scopes,
+	}
+	if spec.Timeout != "" {
+		timeout, err := time.ParseDuration(spec.Timeout)
+		if err != nil || timeout <= 0 {
+			return registry.Tool{}, fmt.Errorf("tool plugin %s: invalid timeout %q", spec.Name, spec.Timeout)
+		}
+		tool.Timeout = timeout
+	}
+
+	var err error
+	switch {
+	case spec.Exec != nil && spec.HTTP == nil:
+		tool.Func, err = execFunc(dir, spec.Name, *spec.Exec)
+	case spec.HTTP != nil && spec.Exec == nil:
+		tool.Func, err = httpFunc(spec.Name, *spec.HTTP)
+	default:
+		err = errors.New("set exactly one of exec and http")
+	}
+	if err != nil {
+		return registry.Tool{}, fmt.Errorf("tool plugin %s: %w", spec.Name, err)
+	}
+	return tool, nil
+}
+
+func newRequest(ctx context.Context, name string, args map[string]interface{}) ([]byte, error) {
+	req := Request{Tool: name, Arguments: args}
+	if p, ok := auth.FromContext(ctx); ok {
+		req.Tenant, req.Subject = p.Tenant, p.Subject
+	}
+	return json.Marshal(req)
+}
+
+func decodeResponse(name string, raw []byte) (types.ToolResult, error) {
+	var resp Response
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return types.ToolResult{}, fmt.Errorf("decode %s response: %w", name, err)
+	}
+	if resp.Error != "" {
+		return types.ToolResult{}, errors.New(resp.Error)
+	}
+	return types.ToolResult{Name: name, Output: resp.Output}, nil
+}
+
+func execFunc(dir, name string, spec manifest.ExecTool) (registry.ToolFunc, error) {
+	if len(spec.Command) == 0 || spec.Command[0] == "" {
+		return nil, errors.New("exec.command is required")
+	}
+	program := spec.Command[0]
+	if local := filepath.Join(dir, program); !filepath.IsAbs(program) && (strings.ContainsRune(program, filepath.Separator) || isExecutable(local)) {
+		program = local
+	}
+	workDir := dir
+	if spec.Dir != "" {
+		workDir = spec.Dir
+		if !filepath.IsAbs(workDir) {
+			workDir = filepath.Join(dir, workDir)
+		}
+	}
+
+	return func(ctx context.Context, args map[string]interface{}) (types.ToolResult, error) {
+		body, err := newRequest(ctx, name, args)
+		if err != nil {
+			return types.ToolResult{}, err
+		}
+		cmd := exec.CommandContext(ctx, program, spec.Command[1:]...)
+		cmd.Dir = workDir
+		cmd.Env = environ(spec)
+		cmd.WaitDelay = killGrace
+		cmd.Stdin = bytes.NewReader(body)
+		stdout := &limitedBuffer{limit: maxOutput}
+		stderr := &limitedBuffer{limit: maxStderr, keepTail: true}
+		cmd.Stdout, cmd.Stderr = stdout, stderr
+
+		if err := cmd.Run(); err != nil {
+			if ctx.Err() != nil {
+				return types.ToolResult{}, ctx.Err()
+			}
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return types.ToolResult{}, fmt.Errorf("%s: %w: %s", name, err, msg)
+			}
+			return types.ToolResult{}, fmt.Errorf("%s: %w", name, err)
+		}
+		if stdout.truncated {
+			return types.ToolResult{}, fmt.Errorf("%s: response larger than %d bytes", name, maxOutput)
+		}
+		return decodeResponse(name, stdout.Bytes())
+	}, nil
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir() && info.Mode()&0o111 != 0
+}
+
+// environ builds a plugin's environment from scratch so server secrets are
+// only passed on when the declaration asks for them.
+func environ(spec manifest.ExecTool) []string {
+	var env []string
+	for _, key := range append([]string{"PATH", "HOME"}, spec.InheritEnv...) {
+		if v, ok := os.LookupEnv(key); ok {
+			env = append(env, key+"="+v)
+		}
+	}
+	for key, v := range spec.Env {
+		env = append(env, key+"="+os.ExpandEnv(v))
+	}
+	return env
+}
+
+func httpFunc(name string, spec manifest.HTTPTool) (registry.ToolFunc, error) {
+	u, err := url.Parse(spec.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("http.url must be an http or https URL, got %q", spec.URL)
+	}
+
+	return func(ctx context.Context, args map[string]interface{}) (types.ToolResult, error) {
+		body, err := newRequest(ctx, name, args)
+		if err != nil {
+			return types.ToolResult{}, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, spec.URL, bytes.NewReader(body))
+		if err != nil {
+			return types.ToolResult{}, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for key, v := range spec.Headers {
+			req.Header.Set(key, os.ExpandEnv(v))
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return types.ToolResult{}, fmt.Errorf("%s: %w", name, err)
+		}
+		defer resp.Body.Close()
+
+		raw, err := io.ReadAll(io.LimitReader(resp.Body, maxOutput+1))
+		if err != nil {
+			return types.ToolResult{}, fmt.Errorf("%s: read response: %w", name, err)
+		}
+		if len(raw) > maxOutput {
+			return types.ToolResult{}, fmt.Errorf("%s: response larger than %d bytes", name, maxOutput)
+		}
+		if resp.StatusCode/100 != 2 {
+			// A plugin may still explain the failure in a Response.
+			var failure Response
+			if json.Unmarshal(raw, &failure) == nil && failure.Error != "" {
+				return types.ToolResult{}, fmt.Errorf("%s returned %d: %s", name, resp.StatusCode, failure.Error)
+			}
+			if len(raw) > maxStderr {
+				raw = raw[:maxStderr]
+			}
+			return types.ToolResult{}, fmt.Errorf("%s returned %d: %s", name, resp.StatusCode, strings.TrimSpace(string(raw)))
+		}
+		return decodeResponse(name, raw)
+	}, nil
+}
+
+// limitedBuffer keeps at most limit bytes of what is written to it: the
+// beginning, or the end when keepTail is set. It never fails a write so a
+// chatty plugin is not killed by a broken pipe.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit     int
+	keepTail  bool
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if b.keepTail {
+		b.Buffer.Write(p)
+		if extra := b.Len() - b.limit; extra > 0 {
+			b.Next(extra)
+			b.truncated = true
+		}
+		return n, nil
+	}
+	if room := b.limit - b.Len(); len(p) > room {
+		p = p[:room]
+		b.truncated = true
+	}
+	b.Buffer.Write(p)
+	return n, nil
+}
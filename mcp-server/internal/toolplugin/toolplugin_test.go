@@ -0,0 +1,127 @@
+package toolplugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xscopehub/mcp-server/internal/auth"
+	"github.com/xscopehub/mcp-server/internal/registry"
+	"github.com/xscopehub/mcp-server/pkg/manifest"
+)
+
+func writeScript(t *testing.T, dir, name, body string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExecPlugin(t *testing.T) {
+	dir := t.TempDir()
+	// The script echoes its request and environment back as the output.
+	writeScript(t, dir, "echo.sh", `req=$(cat)
+printf '{"output": {"request": %s, "region": "%s", "secret": "%s"}}' "$req" "$REGION" "$PLUGIN_TEST_SECRET"
+`)
+	writeScript(t, dir, "fail.sh", "echo 'kubeconfig not found' >&2\nexit 3\n")
+	writeScript(t, dir, "refuse.sh", `cat >/dev/null; echo '{"error": "namespace is not allowed"}'`)
+	writeScript(t, dir, "slow.sh", "sleep 5\n")
+	t.Setenv("PLUGIN_TEST_SECRET", "s3cret")
+	t.Setenv("PLUGIN_TEST_REGION", "eu-west-1")
+
+	tools, err := Tools(dir, []manifest.ToolPlugin{
+		{Name: "echo", InputSchema: json.RawMessage(`{"type":"object","properties":{"namespace":{"type":"string"}},"required":["namespace"]}`),
+			Exec: &manifest.ExecTool{Command: []string{"echo.sh"}, Env: map[string]string{"REGION": "$PLUGIN_TEST_REGION"}}},
+		{Name: "fail", Exec: &manifest.ExecTool{Command: []string{"./fail.sh"}}},
+		{Name: "refuse", Exec: &manifest.ExecTool{Command: []string{"sh", "refuse.sh"}}},
+		{Name: "slow", Timeout: "100ms", Exec: &manifest.ExecTool{Command: []string{"slow.sh"}}},
+	})
+	if err != nil {
+		t.Fatalf("tools: %v", err)
+	}
+	reg := registry.New()
+	if err := reg.ReplaceSource("plugins", registry.Set{Tools: tools}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "alice", Tenant: "acme"})
+	res, err := reg.InvokeTool(ctx, "echo", map[string]interface{}{"namespace": "payments"})
+	if err != nil {
+		t.Fatalf("echo: %v", err)
+	}
+	out := res.Output.(map[string]interface{})
+	req := out["request"].(map[string]interface{})
+	if req["tool"] != "echo" || req["tenant"] != "acme" || req["subject"] != "alice" || req["arguments"].(map[string]interface{})["namespace"] != "payments" {
+		t.Fatalf("unexpected request %v", req)
+	}
+	if out["region"] != "eu-west-1" || out["secret"] != "" {
+		t.Fatalf("environment must be built from the declaration only, got %v", out)
+	}
+
+	if _, err := reg.InvokeTool(ctx, "echo", nil); err == nil {
+		t.Fatalf("expected the declared schema to be enforced")
+	}
+	if _, err := reg.InvokeTool(ctx, "fail", nil); err == nil || !strings.Contains(err.Error(), "kubeconfig not found") {
+		t.Fatalf("expected stderr in the error, got %v", err)
+	}
+	if _, err := reg.InvokeTool(ctx, "refuse", nil); err == nil || err.Error() != "namespace is not allowed" {
+		t.Fatalf("expected the plugin's error, got %v", err)
+	}
+	start := time.Now()
+	if _, err := reg.InvokeTool(ctx, "slow", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+	if time.Since(start) > 3*time.Second {
+		t.Fatalf("a timed out plugin must be killed")
+	}
+}
+
+func TestHTTPPlugin(t *testing.T) {
+	t.Setenv("PLUGIN_TEST_TOKEN", "abc")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer abc" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": "bad token"}`))
+			return
+		}
+		var req Request
+		json.NewDecoder(r.Body).Decode(&req)
+		json.NewEncoder(w).Encode(Response{Output: map[string]interface{}{"runbook": "restart " + req.Arguments["service"].(string)}})
+	}))
+	defer srv.Close()
+
+	good, err := Tool("", manifest.ToolPlugin{Name: "runbook_lookup", HTTP: &manifest.HTTPTool{URL: srv.URL, Headers: map[string]string{"Authorization": "Bearer ${PLUGIN_TEST_TOKEN}"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := good.Func(context.Background(), map[string]interface{}{"service": "api"})
+	if err != nil || res.Output.(map[string]interface{})["runbook"] != "restart api" {
+		t.Fatalf("unexpected result %v, %v", res, err)
+	}
+
+	bad, _ := Tool("", manifest.ToolPlugin{Name: "runbook_lookup", HTTP: &manifest.HTTPTool{URL: srv.URL}})
+	if _, err := bad.Func(context.Background(), map[string]interface{}{}); err == nil || !strings.Contains(err.Error(), "401: bad token") {
+		t.Fatalf("expected the status and error, got %v", err)
+	}
+}
+
+func TestToolRejectsBadDeclarations(t *testing.T) {
+	for _, spec := range []manifest.ToolPlugin{
+		{Name: "none"},
+		{Name: "both", Exec: &manifest.ExecTool{Command: []string{"true"}}, HTTP: &manifest.HTTPTool{URL: "http://localhost"}},
+		{Name: "no_command", Exec: &manifest.ExecTool{}},
+		{Name: "bad_url", HTTP: &manifest.HTTPTool{URL: "localhost:8080"}},
+		{Name: "bad_timeout", Timeout: "soon", Exec: &manifest.ExecTool{Command: []string{"true"}}},
+	} {
+		if _, err := Tool("", spec); err == nil {
+			t.Errorf("%s: expected an error", spec.Name)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package topology
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// maxLinks bounds the edges one walk reads.
+const maxLinks = 2000
+
+// link is a directed edge with the labels of its ends: the vertex labels in
+// AGE, the resource types in dim_resource.
+type link struct {
+	from, fromLabel string
+	relation        string
+	to, toLabel     string
+}
+
+// walk asks for the edges within depth hops of start.
+type walk struct {
+	start string
+	// up follows edges backwards, towards what depends on start.
+	up    bool
+	depth int
+	// relation restricts the walk to one edge type; empty follows all.
+	relation string
+	// at reads the topology valid at that time from topo_edge_time instead
+	// of the current graph.
+	at *time.Time
+	// tenant limits the walk to one tenant's resources; empty reads all.
+	tenant string
+}
+
+// backend reads topology edges; pgStore in production.
+type backend interface {
+	links(ctx context.Context, w walk) ([]link, error)
+	// source names where edges come from, for tool output.
+	source(w walk) string
+}
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// pgStore reads the AGE graph and topo_edge_time.
+type pgStore struct {
+	pool  *pgxpool.Pool
+	graph string
+}
+
+func newPGStore(databaseURL, graph string) (*pgStore, error) {
+	if !identifier.MatchString(graph) {
+		return nil, fmt.Errorf("invalid graph name %q", graph)
+	}
+	cfg, err := pgxpool.ParseConfig(databaseURL)
+	if err != nil {
+		return nil, err
+	}
+	cfg.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+		// LOAD needs privileges the server may not grant; it is not
+		// needed when age is in shared_preload_libraries, and cypher()
+		// reports a missing library clearly enough otherwise.
+		_, _ = conn.Exec(ctx, `LOAD 'age'`)
+		_, err := conn.Exec(ctx, `SET search_path = ag_catalog, "$user", public`)
+		return err
+	}
+	pool, err := pgxpool.NewWithConfig(context.Background(), cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &pgStore{pool: pool, graph: graph}, nil
+}
+
+func (s *pgStore) source(w walk) string {
+	if w.at != nil {
+		return "topo_edge_time"
+	}
+	return "age:" + s.graph
+}
+
+func (s *pgStore) links(ctx context.Context, w walk) ([]link, error) {
+	if w.at != nil {
+		return s.historicLinks(ctx, w)
+	}
+	return s.graphLinks(ctx, w)
+}
+
+// cypherQuery builds the Cypher reading every edge on a path of at most
+// w.depth hops from or to the start vertex. Vertices are matched by name
+// whatever their label, so both the Service vertices of graph.DAO and the
+// Resource vertices of the ops graph are found. With a tenant, only vertices
+// whose tenant property is that tenant are matched and only edges between
+// two of them are returned; edges through another tenant's vertices are
+// dropped, so nothing beyond them is reachable either.
+func cypherQuery(graph string, w walk) string {
+	rel := ""
+	if w.relation != "" {
+		rel = ":" + w.relation
+	}
+	start, where := "{name: $name}", ""
+	if w.tenant != "" {
+		start, where = "{name: $name, tenant: $tenant}", "\n    WHERE a.tenant = $tenant AND b.tenant = $tenant"
+	}
+	pattern := fmt.Sprintf("(s %s)-[%s*1..%d]->(t)", start, rel, w.depth)
+	if w.up {
+		pattern = fmt.Sprintf("(t)-[%s*1..%d]->(s %s)", rel, w.depth, start)
+	}
+	return fmt.Sprintf(`SELECT * FROM cypher('%s', $$
+    MATCH p = %s
+    UNWIND relationships(p) AS r
+    WITH r, startNode(r) AS a, endNode(r) AS b%s
+    RETURN DISTINCT a.name, label(a), type(r), b.name, label(b)
+    LIMIT %d
+$$, $1) AS (src agtype, src_label agtype, relation agtype, dst agtype, dst_label agtype)`, graph, pattern, where, maxLinks)
+}
+
+func (s *pgStore) graphLinks(ctx context.Context, w walk) ([]link, error) {
+	if w.relation != "" && !identifier.MatchString(w.relation) {
+		return nil, fmt.Errorf("invalid relation %q", w.relation)
+	}
+	params, err := json.Marshal(map[string]string{"name": w.start, "tenant": w.tenant})
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.pool.Query(ctx, cypherQuery(s.graph, w), string(params))
+	if err != nil {
+		return nil, fmt.Errorf("query graph %s: %w", s.graph, err)
+	}
+	defer rows.Close()
+
+	var out []link
+	for rows.Next() {
+		var cols [5]string
+		if err := rows.Scan(&cols[0], &cols[1], &cols[2], &cols[3], &cols[4]); err != nil {
+			return nil, fmt.Errorf("query graph %s: %w", s.graph, err)
+		}
+		out = append(out, link{
+			from: agString(cols[0]), fromLabel: agString(cols[1]),
+			relation: agString(cols[2]),
+			to:       agString(cols[3]), toLabel: agString(cols[4]),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query graph %s: %w", s.graph, err)
+	}
+	return out, nil
+}
+
+// agString decodes an agtype scalar such as "checkout" or 42 in its text
+// form.
+func agString(v string) string {
+	var s string
+	if json.Unmarshal([]byte(v), &s) == nil {
+		return s
+	}
+	return v
+}
+
+// historicQuery walks topo_edge_time from the dim_resource named $1,
+// keeping edges valid at $2. Unless $6 is empty, edges and the start are
+// limited to the dim_tenant with code $6; resource names are only unique
+// within a tenant. near and far are the edge ends the walk moves from and
+// to.
+func historicQuery(up bool) string {
+	near, far := "src_resource_id", "dst_resource_id"
+	if up {
+		near, far = far, near
+	}
+	return fmt.Sprintf(`
+WITH RECURSIVE tenant AS (
+    SELECT tenant_id FROM dim_tenant WHERE code = $6
+), edge AS (
+    SELECT src_resource_id, dst_resource_id, relation
+    FROM topo_edge_time
+    WHERE valid @> $2::timestamptz AND ($3 = '' OR relation = $3)
+      AND ($6 = '' OR tenant_id IN (SELECT tenant_id FROM tenant))
+), reach(id, depth) AS (
+    SELECT resource_id, 0 FROM dim_resource
+    WHERE name = $1 AND ($6 = '' OR tenant_id IN (SELECT tenant_id FROM tenant))
+    UNION
+    SELECT e.%[2]s, r.depth + 1
+    FROM reach r JOIN edge e ON e.%[1]s = r.id
+    WHERE r.depth < $4
+)
+SELECT DISTINCT src.name, src.type, e.relation, dst.name, dst.type
+FROM edge e
+JOIN (SELECT id, min(depth) AS depth FROM reach GROUP BY id) r ON e.%[1]s = r.id AND r.depth < $4
+JOIN dim_resource src ON src.resource_id = e.src_resource_id
+JOIN dim_resource dst ON dst.resource_id = e.dst_resource_id
+LIMIT $5`, near, far)
+}
+
+func (s *pgStore) historicLinks(ctx context.Context, w walk) ([]link, error) {
+	rows, err := s.pool.Query(ctx, historicQuery(w.up), w.start, *w.at, w.relation, w.depth, maxLinks, w.tenant)
+	if err != nil {
+		return nil, fmt.Errorf("query topo_edge_time: %w", err)
+	}
+	defer rows.Close()
+
+	var out []link
+	for rows.Next() {
+		var l link
+		if err := rows.Scan(&l.from, &l.fromLabel, &l.relation, &l.to, &l.toLabel); err != nil {
+			return nil, fmt.Errorf("query topo_edge_time: %w", err)
+		}
+		out = append(out, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query topo_edge_time: %w", err)
+	}
+	return out, nil
+}
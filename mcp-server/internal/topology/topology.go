@@ -0,0 +1,279 @@
+// Package topology answers dependency questions over the service graph
+// observe-bridge keeps in PostgreSQL: the Apache AGE graph its graph.DAO
+// writes CALLS edges into for the current topology, and topo_edge_time for
+// the topology at a past instant, e.g. during an incident.
+package topology
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xscopehub/mcp-server/internal/auth"
+	"github.com/xscopehub/mcp-server/internal/registry"
+	"github.com/xscopehub/mcp-server/internal/types"
+)
+
+// relationCalls is the edge type between services.
+const relationCalls = "CALLS"
+
+// Config holds the database settings.
+type Config struct {
+	// DatabaseURL is the PostgreSQL database with the AGE graph and
+	// topo_edge_time. The tools are disabled when it is empty.
+	DatabaseURL string
+	// Graph is the AGE graph queried for the current topology.
+	Graph string
+}
+
+// Provider serves the topology tools.
+type Provider struct {
+	store backend
+}
+
+// New connects to the database lazily and returns the provider. It returns
+// nil when no database is configured.
+func New(cfg Config) (*Provider, error) {
+	if cfg.DatabaseURL == "" {
+		return nil, nil
+	}
+	store, err := newPGStore(cfg.DatabaseURL, cfg.Graph)
+	if err != nil {
+		return nil, fmt.Errorf("topology database: %w", err)
+	}
+	return &Provider{store: store}, nil
+}
+
+// Node is a service or resource reached by a walk. Depth is the fewest hops
+// from the start.
+type Node struct {
+	Name  string `json:"name"`
+	Label string `json:"label,omitempty"`
+	Depth int    `json:"depth"`
+}
+
+// Edge is a directed edge: From depends on To.
+type Edge struct {
+	From     string `json:"from"`
+	Relation string `json:"relation"`
+	To       string `json:"to"`
+}
+
+type walkArgs struct {
+	Service string `json:"service" jsonschema:"minLength=1"`
+	Depth   int    `json:"depth" jsonschema:"minimum=1,maximum=5,default=3"`
+	At      string `json:"at,omitempty" description:"Read the topology as it was at this time instead of now." jsonschema:"format=date-time"`
+}
+
+type blastArgs struct {
+	Resource string `json:"resource" description:"Service, host or other resource that fails." jsonschema:"minLength=1"`
+	Depth    int    `json:"depth" jsonschema:"minimum=1,maximum=5,default=3"`
+	At       string `json:"at,omitempty" description:"Read the topology as it was at this time instead of now." jsonschema:"format=date-time"`
+}
+
+type pathArgs struct {
+	From     string `json:"from" jsonschema:"minLength=1"`
+	To       string `json:"to" jsonschema:"minLength=1"`
+	MaxDepth int    `json:"max_depth" jsonschema:"minimum=1,maximum=6,default=4"`
+	At       string `json:"at,omitempty" description:"Read the topology as it was at this time instead of now." jsonschema:"format=date-time"`
+}
+
+// Register adds the topology tools to reg.
+func (p *Provider) Register(reg *registry.Registry) {
+	reg.RegisterTools(p.Tools())
+}
+
+// Tools returns get_dependencies, get_dependents, blast_radius and
+// path_between.
+func (p *Provider) Tools() map[string]registry.Tool {
+	return map[string]registry.Tool{
+		"get_dependencies": registry.TypedTool(types.ToolDescriptor{
+			Name:        "get_dependencies",
+			Title:       "Get service dependencies",
+			Description: "List the services a service calls, directly or through up to depth hops, with the CALLS edges between them.",
+		}, p.getDependencies).WithScopes(auth.ScopeTelemetryRead),
+		"get_dependents": registry.TypedTool(types.ToolDescriptor{
+			Name:        "get_dependents",
+			Title:       "Get service dependents",
+			Description: "List the services that call a service, directly or through up to depth hops, with the CALLS edges between them.",
+		}, p.getDependents).WithScopes(auth.ScopeTelemetryRead),
+		"blast_radius": registry.TypedTool(types.ToolDescriptor{
+			Name:        "blast_radius",
+			Title:       "Blast radius",
+			Description: "List everything that depends on a resource through any relation (calls, runs on, ...) within depth hops, i.e. what is affected when it fails.",
+		}, p.blastRadius).WithScopes(auth.ScopeTelemetryRead),
+		"path_between": registry.TypedTool(types.ToolDescriptor{
+			Name:        "path_between",
+			Title:       "Path between resources",
+			Description: "Find the shortest dependency path from one service or resource to another, in either direction.",
+		}, p.pathBetween).WithScopes(auth.ScopeTelemetryRead),
+	}
+}
+
+func (p *Provider) getDependencies(ctx context.Context, args walkArgs) (types.ToolResult, error) {
+	return p.reach(ctx, "get_dependencies", args.Service, args.Depth, args.At, false, relationCalls)
+}
+
+func (p *Provider) getDependents(ctx context.Context, args walkArgs) (types.ToolResult, error) {
+	return p.reach(ctx, "get_dependents", args.Service, args.Depth, args.At, true, relationCalls)
+}
+
+func (p *Provider) blastRadius(ctx context.Context, args blastArgs) (types.ToolResult, error) {
+	return p.reach(ctx, "blast_radius", args.Resource, args.Depth, args.At, true, "")
+}
+
+func (p *Provider) reach(ctx context.Context, tool, start string, depth int, at string, up bool, relation string) (types.ToolResult, error) {
+	w, err := newWalk(ctx, start, depth, at, up, relation)
+	if err != nil {
+		return types.ToolResult{}, err
+	}
+	links, err := p.store.links(ctx, w)
+	if err != nil {
+		return types.ToolResult{}, err
+	}
+	nodes, edges := reachable(w.start, links, up)
+	out := map[string]interface{}{
+		"start":  w.start,
+		"depth":  w.depth,
+		"source": p.store.source(w),
+		"nodes":  nodes,
+		"edges":  edges,
+	}
+	if w.at != nil {
+		out["at"] = w.at.Format(time.RFC3339)
+	}
+	if len(links) >= maxLinks {
+		out["truncated"] = true
+	}
+	return types.ToolResult{Name: tool, Output: out}, nil
+}
+
+func (p *Provider) pathBetween(ctx context.Context, args pathArgs) (types.ToolResult, error) {
+	from, to := strings.TrimSpace(args.From), strings.TrimSpace(args.To)
+	if from == to {
+		return types.ToolResult{}, errors.New("from and to must differ")
+	}
+	out := map[string]interface{}{"from": from, "to": to, "found": false}
+	// Try from -> to first; a path the other way still relates them.
+	for _, ends := range [][2]string{{from, to}, {to, from}} {
+		w, err := newWalk(ctx, ends[0], args.MaxDepth, args.At, false, "")
+		if err != nil {
+			return types.ToolResult{}, err
+		}
+		links, err := p.store.links(ctx, w)
+		if err != nil {
+			return types.ToolResult{}, err
+		}
+		out["source"] = p.store.source(w)
+		if w.at != nil {
+			out["at"] = w.at.Format(time.RFC3339)
+		}
+		if path := shortestPath(ends[0], ends[1], links); path != nil {
+			out["found"], out["path"], out["hops"] = true, path, len(path)
+			out["direction"] = ends[0] + " depends on " + ends[1]
+			break
+		}
+	}
+	return types.ToolResult{Name: "path_between", Output: out}, nil
+}
+
+func newWalk(ctx context.Context, start string, depth int, at string, up bool, relation string) (walk, error) {
+	w := walk{start: strings.TrimSpace(start), depth: depth, up: up, relation: relation}
+	if w.start == "" {
+		return walk{}, errors.New("a start service or resource is required")
+	}
+	tenant, err := auth.CallerTenant(ctx)
+	if err != nil {
+		return walk{}, err
+	}
+	w.tenant = tenant
+	if at != "" {
+		t, err := time.Parse(time.RFC3339, at)
+		if err != nil {
+			return walk{}, fmt.Errorf("at must be an RFC 3339 time: %w", err)
+		}
+		w.at = &t
+	}
+	return w, nil
+}
+
+// reachable orders the nodes links reach from start by depth, following
+// edges backwards when up is set.
+func reachable(start string, links []link, up bool) ([]Node, []Edge) {
+	next := map[string][]string{}
+	labels := map[string]string{}
+	edges := make([]Edge, 0, len(links))
+	for _, l := range links {
+		labels[l.from], labels[l.to] = l.fromLabel, l.toLabel
+		if up {
+			next[l.to] = append(next[l.to], l.from)
+		} else {
+			next[l.from] = append(next[l.from], l.to)
+		}
+		edges = append(edges, Edge{From: l.from, Relation: l.relation, To: l.to})
+	}
+
+	depth := map[string]int{start: 0}
+	queue := []string{start}
+	nodes := []Node{}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, n := range next[cur] {
+			if _, seen := depth[n]; seen {
+				continue
+			}
+			depth[n] = depth[cur] + 1
+			nodes = append(nodes, Node{Name: n, Label: labels[n], Depth: depth[n]})
+			queue = append(queue, n)
+		}
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if nodes[i].Depth != nodes[j].Depth {
+			return nodes[i].Depth < nodes[j].Depth
+		}
+		return nodes[i].Name < nodes[j].Name
+	})
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		return edges[i].To < edges[j].To
+	})
+	return nodes, edges
+}
+
+// shortestPath finds the fewest edges leading from one end to the other, or
+// nil.
+func shortestPath(from, to string, links []link) []Edge {
+	out := map[string][]link{}
+	for _, l := range links {
+		out[l.from] = append(out[l.from], l)
+	}
+	via := map[string]link{}
+	seen := map[string]bool{from: true}
+	queue := []string{from}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		if cur == to {
+			var path []Edge
+			for n := to; n != from; n = via[n].from {
+				l := via[n]
+				path = append([]Edge{{From: l.from, Relation: l.relation, To: l.to}}, path...)
+			}
+			return path
+		}
+		for _, l := range out[cur] {
+			if !seen[l.to] {
+				seen[l.to] = true
+				via[l.to] = l
+				queue = append(queue, l.to)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package topology
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xscopehub/mcp-server/internal/auth"
+	"github.com/xscopehub/mcp-server/internal/registry"
+)
+
+// fakeStore serves a fixed graph: checkout calls payment and cart, payment
+// calls ledger, and payment and ledger run on host worker-a1. Like pgStore
+// it drops edges touching a vertex of another tenant than the walk's, for
+// the vertices listed in tenants.
+type fakeStore struct {
+	walks   []walk
+	extra   []link
+	tenants map[string]string
+}
+
+var graphLinks = []link{
+	{"checkout", "Service", "CALLS", "payment", "Service"},
+	{"checkout", "Service", "CALLS", "cart", "Service"},
+	{"payment", "Service", "CALLS", "ledger", "Service"},
+	{"payment", "Service", "RUNS_ON", "worker-a1", "Host"},
+	{"ledger", "Service", "RUNS_ON", "worker-a1", "Host"},
+}
+
+func (f *fakeStore) links(ctx context.Context, w walk) ([]link, error) {
+	f.walks = append(f.walks, w)
+	var out []link
+	for _, l := range append(graphLinks, f.extra...) {
+		if w.tenant != "" && (f.tenants[l.from] != w.tenant || f.tenants[l.to] != w.tenant) {
+			continue
+		}
+		if w.relation == "" || l.relation == w.relation {
+			out = append(out, l)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeStore) source(w walk) string {
+	if w.at != nil {
+		return "topo_edge_time"
+	}
+	return "age:test"
+}
+
+func invoke(t *testing.T, store *fakeStore, tool string, args map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	return invokeAs(t, context.Background(), store, tool, args)
+}
+
+func invokeAs(t *testing.T, ctx context.Context, store *fakeStore, tool string, args map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	reg := registry.New()
+	(&Provider{store: store}).Register(reg)
+	res, err := reg.InvokeTool(ctx, tool, args)
+	if err != nil {
+		t.Fatalf("%s: %v", tool, err)
+	}
+	return res.Output.(map[string]interface{})
+}
+
+func TestDependenciesAndDependents(t *testing.T) {
+	store := &fakeStore{}
+	out := invoke(t, store, "get_dependencies", map[string]interface{}{"service": "checkout"})
+	want := []Node{{"cart", "Service", 1}, {"payment", "Service", 1}, {"ledger", "Service", 2}}
+	if !reflect.DeepEqual(out["nodes"], want) || out["source"] != "age:test" || out["depth"] != 3 {
+		t.Fatalf("unexpected dependencies %+v", out)
+	}
+	if w := store.walks[0]; w.up || w.relation != relationCalls || w.at != nil {
+		t.Fatalf("unexpected walk %+v", w)
+	}
+
+	out = invoke(t, store, "get_dependents", map[string]interface{}{"service": "ledger", "depth": 2.0, "at": "2024-01-01T00:10:00Z"})
+	want = []Node{{"payment", "Service", 1}, {"checkout", "Service", 2}}
+	if !reflect.DeepEqual(out["nodes"], want) || out["source"] != "topo_edge_time" || out["at"] != "2024-01-01T00:10:00Z" {
+		t.Fatalf("unexpected dependents %+v", out)
+	}
+	if w := store.walks[1]; !w.up || w.depth != 2 || !w.at.Equal(time.Date(2024, 1, 1, 0, 10, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected walk %+v", w)
+	}
+}
+
+func TestBlastRadiusFollowsEveryRelation(t *testing.T) {
+	out := invoke(t, &fakeStore{}, "blast_radius", map[string]interface{}{"resource": "worker-a1"})
+	want := []Node{{"ledger", "Service", 1}, {"payment", "Service", 1}, {"checkout", "Service", 2}}
+	if !reflect.DeepEqual(out["nodes"], want) {
+		t.Fatalf("unexpected blast radius %+v", out["nodes"])
+	}
+}
+
+func TestPathBetween(t *testing.T) {
+	out := invoke(t, &fakeStore{}, "path_between", map[string]interface{}{"from": "checkout", "to": "worker-a1"})
+	want := []Edge{{"checkout", "CALLS", "payment"}, {"payment", "RUNS_ON", "worker-a1"}}
+	if out["found"] != true || !reflect.DeepEqual(out["path"], want) {
+		t.Fatalf("unexpected path %+v", out)
+	}
+
+	out = invoke(t, &fakeStore{}, "path_between", map[string]interface{}{"from": "ledger", "to": "checkout"})
+	if out["found"] != true || out["direction"] != "checkout depends on ledger" || out["hops"] != 2 {
+		t.Fatalf("expected the reverse path, got %+v", out)
+	}
+
+	out = invoke(t, &fakeStore{}, "path_between", map[string]interface{}{"from": "cart", "to": "ledger"})
+	if out["found"] != false || out["path"] != nil {
+		t.Fatalf("expected no path, got %+v", out)
+	}
+}
+
+func TestWalksStayInCallerTenant(t *testing.T) {
+	store := &fakeStore{
+		// globex's checkout shares the name of acme's and calls its own
+		// fraud service, which calls acme's ledger.
+		extra: []link{
+			{"checkout", "Service", "CALLS", "fraud", "Service"},
+			{"fraud", "Service", "CALLS", "ledger", "Service"},
+		},
+		tenants: map[string]string{"checkout": "acme", "cart": "acme", "payment": "acme", "ledger": "acme", "fraud": "globex"},
+	}
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "grafana", Tenant: "acme"})
+	out := invokeAs(t, ctx, store, "get_dependencies", map[string]interface{}{"service": "checkout"})
+	want := []Node{{"cart", "Service", 1}, {"payment", "Service", 1}, {"ledger", "Service", 2}}
+	if !reflect.DeepEqual(out["nodes"], want) || store.walks[0].tenant != "acme" {
+		t.Fatalf("expected globex's edges to be excluded, got %+v", out)
+	}
+
+	reg := registry.New()
+	(&Provider{store: store}).Register(reg)
+	noTenant := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "intern"})
+	if _, err := reg.InvokeTool(noTenant, "blast_radius", map[string]interface{}{"resource": "worker-a1"}); !errors.Is(err, auth.ErrNoTenant) {
+		t.Fatalf("expected a caller without a tenant to be refused, got %v", err)
+	}
+
+	q := cypherQuery("xinsight", walk{start: "checkout", depth: 2, tenant: "acme"})
+	if !strings.Contains(q, "(s {name: $name, tenant: $tenant})") || !strings.Contains(q, "WHERE a.tenant = $tenant AND b.tenant = $tenant") {
+		t.Fatalf("expected the cypher to be limited to the tenant, got %s", q)
+	}
+	if q := historicQuery(false); !strings.Contains(q, "AND ($6 = '' OR tenant_id IN (SELECT tenant_id FROM tenant))") ||
+		!strings.Contains(q, "WHERE name = $1 AND ($6 = '' OR tenant_id IN (SELECT tenant_id FROM tenant))") {
+		t.Fatalf("expected topo_edge_time and dim_resource to be limited to the tenant, got %s", q)
+	}
+}
+
+func TestQueries(t *testing.T) {
+	q := cypherQuery("xinsight", walk{start: "checkout", up: true, depth: 2, relation: relationCalls})
+	if !strings.Contains(q, "cypher('xinsight'") || !strings.Contains(q, "(t)-[:CALLS*1..2]->(s {name: $name})") {
+		t.Fatalf("unexpected cypher %s", q)
+	}
+	if q := historicQuery(true); !strings.Contains(q, "SELECT e.src_resource_id, r.depth + 1") || !strings.Contains(q, "JOIN edge e ON e.dst_resource_id = r.id") {
+		t.Fatalf("unexpected upward walk %s", q)
+	}
+	if _, err := newPGStore("postgres://localhost/xscope", "x'); DROP TABLE t; --"); err == nil {
+		t.Fatalf("expected an invalid graph name to be refused")
+	}
+}
@@ -1,24 +1,41 @@
 package types
 
+import "encoding/json"
+
 // ResourceDescriptor describes a resource exposed by the MCP server.
 type ResourceDescriptor struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// ResourceTemplate describes a family of resources addressed by an RFC 6570
+// URI template.
+type ResourceTemplate struct {
+	URITemplate string `json:"uriTemplate"`
 	Name        string `json:"name"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
 }
 
 // ResourcePayload represents resource data returned to clients.
 type ResourcePayload struct {
+	URI         string      `json:"uri"`
 	Name        string      `json:"name"`
 	Description string      `json:"description"`
+	MimeType    string      `json:"mimeType,omitempty"`
 	Data        interface{} `json:"data"`
 }
 
 // ToolDescriptor describes a tool callable via the MCP server.
 type ToolDescriptor struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	InputSchema string `json:"input_schema"`
+	Name        string          `json:"name"`
+	Title       string          `json:"title,omitempty"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"inputSchema"`
 }
 
 // ToolResult encapsulates tool execution output.
@@ -26,3 +43,42 @@ type ToolResult struct {
 	Name   string      `json:"name"`
 	Output interface{} `json:"output"`
 }
+
+// PromptDescriptor describes a prompt template exposed via prompts/list.
+type PromptDescriptor struct {
+	Name        string           `json:"name"`
+	Title       string           `json:"title,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Arguments   []PromptArgument `json:"arguments,omitempty"`
+}
+
+// PromptArgument describes one argument a prompt accepts.
+type PromptArgument struct {
+	Name        string `json:"name"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// PromptMessage is one message of a rendered prompt.
+type PromptMessage struct {
+	Role    string        `json:"role"`
+	Content PromptContent `json:"content"`
+}
+
+// PromptContent is either text or a resource_link pointing the model at a
+// resource it can read.
+type PromptContent struct {
+	Type        string `json:"type"`
+	Text        string `json:"text,omitempty"`
+	URI         string `json:"uri,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// PromptResult is a rendered prompt returned by prompts/get.
+type PromptResult struct {
+	Description string          `json:"description,omitempty"`
+	Messages    []PromptMessage `json:"messages"`
+}
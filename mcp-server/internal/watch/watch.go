@@ -0,0 +1,101 @@
+// Package watch polls files and directories for changes. Changes are
+// detected by comparing size and modification time, which also works on the
+// network and overlay filesystems manifests are often mounted from.
+package watch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Watcher calls a function whenever one of its paths changes. Directories
+// are watched one level deep: adding, removing or modifying a file in them
+// counts as a change. A missing path is not an error; it changes when it
+// appears.
+type Watcher struct {
+	paths    func() []string
+	interval time.Duration
+	onChange func()
+}
+
+// New creates a watcher polling paths every interval. It returns nil when
+// interval is not positive or there is nothing to watch.
+func New(interval time.Duration, onChange func(), paths ...string) *Watcher {
+	if len(paths) == 0 {
+		return nil
+	}
+	return NewFunc(interval, onChange, func() []string { return paths })
+}
+
+// NewFunc is like New but asks paths for the watched paths at every poll,
+// for sets that change with the configuration being watched. It returns nil
+// when interval is not positive.
+func NewFunc(interval time.Duration, onChange func(), paths func() []string) *Watcher {
+	if interval <= 0 {
+		return nil
+	}
+	return &Watcher{paths: paths, interval: interval, onChange: onChange}
+}
+
+// Run polls until ctx is done. The state at the first poll is the baseline;
+// onChange runs on Run's goroutine, so a slow reload delays the next poll
+// rather than overlapping it.
+func (w *Watcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	last := w.snapshot()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if next := w.snapshot(); next != last {
+				last = next
+				w.onChange()
+			}
+		}
+	}
+}
+
+// snapshot fingerprints the watched paths.
+func (w *Watcher) snapshot() string {
+	var b strings.Builder
+	for _, path := range w.paths() {
+		info, err := os.Stat(path)
+		if err != nil {
+			fmt.Fprintf(&b, "%s missing\n", path)
+			continue
+		}
+		writeInfo(&b, path, info)
+		if !info.IsDir() {
+			continue
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			fmt.Fprintf(&b, "%s unreadable\n", path)
+			continue
+		}
+		names := make([]string, 0, len(entries))
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			child := filepath.Join(path, name)
+			if info, err := os.Stat(child); err == nil {
+				writeInfo(&b, child, info)
+			}
+		}
+	}
+	return b.String()
+}
+
+func writeInfo(b *strings.Builder, path string, info os.FileInfo) {
+	fmt.Fprintf(b, "%s %d %d\n", path, info.Size(), info.ModTime().UnixNano())
+}
@@ -14,6 +14,52 @@ type Manifest struct {
 	EntryPoint  string   `json:"entry_point"`
 	Resources   []string `json:"resources"`
 	Tools       []string `json:"tools"`
+	// Prompts lists prompt files, as paths or glob patterns relative to the
+	// manifest.
+	Prompts []string `json:"prompts,omitempty"`
+	// ToolPlugins are tools implemented outside the server.
+	ToolPlugins []ToolPlugin `json:"tool_plugins,omitempty"`
+}
+
+// ToolPlugin declares a tool implemented by an executable or an HTTP
+// endpoint. Exactly one of Exec and HTTP is set.
+type ToolPlugin struct {
+	Name        string          `json:"name"`
+	Title       string          `json:"title,omitempty"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"input_schema,omitempty"`
+	Scopes      []string        `json:"scopes,omitempty"`
+	// Timeout bounds one call, e.g. "10s". Empty uses the server default.
+	Timeout       string `json:"timeout,omitempty"`
+	MaxConcurrent int    `json:"max_concurrent,omitempty"`
+
+	Exec *ExecTool `json:"exec,omitempty"`
+	HTTP *HTTPTool `json:"http,omitempty"`
+}
+
+// ExecTool runs an executable per call, writing the request as JSON to its
+// stdin and reading the response from its stdout.
+type ExecTool struct {
+	// Command is the program and its arguments. A relative program path is
+	// resolved against the declaring file's directory when it exists there,
+	// and looked up in PATH otherwise.
+	Command []string `json:"command"`
+	// Dir is the working directory, relative to the declaring file.
+	Dir string `json:"dir,omitempty"`
+	// Env sets environment variables. Values may refer to the server's
+	// environment as $VAR or ${VAR}.
+	Env map[string]string `json:"env,omitempty"`
+	// InheritEnv names server environment variables passed through as is.
+	// Nothing else is inherited except PATH and HOME.
+	InheritEnv []string `json:"inherit_env,omitempty"`
+}
+
+// HTTPTool POSTs the request as JSON to URL and reads the response body.
+type HTTPTool struct {
+	URL string `json:"url"`
+	// Headers are sent with every call. Values may refer to the server's
+	// environment as $VAR or ${VAR}.
+	Headers map[string]string `json:"headers,omitempty"`
 }
 
 // Load reads a manifest from disk.
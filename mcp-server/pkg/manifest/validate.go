@@ -0,0 +1,136 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// Severities of a Problem.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Problem is one issue found while validating a manifest.
+type Problem struct {
+	Severity string `json:"severity"`
+	Field    string `json:"field"`
+	Message  string `json:"message"`
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s: %s", p.Severity, p.Field, p.Message)
+}
+
+// HasErrors reports whether any problem is an error rather than a warning.
+func HasErrors(problems []Problem) bool {
+	for _, p := range problems {
+		if p.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// semver matches MAJOR.MINOR.PATCH with optional pre-release and build.
+var semver = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+
+// namePattern is what MCP clients accept as tool names.
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,128}$`)
+
+// LoadStrict is Load, but fields the manifest does not define are an error
+// so typos such as "tool_plugin" do not go unnoticed.
+func LoadStrict(path string) (Manifest, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("read manifest: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	var mf Manifest
+	if err := dec.Decode(&mf); err != nil {
+		return Manifest{}, fmt.Errorf("decode manifest: %w", err)
+	}
+	return mf, nil
+}
+
+// Validate checks the manifest on its own: required fields, the version,
+// the entry point, names and duplicates, prompt patterns and tool plugin
+// declarations. Whether the listed resources and tools exist is checked
+// against a registry by the conformance package.
+func (m Manifest) Validate() []Problem {
+	var problems []Problem
+	add := func(severity, field, format string, args ...interface{}) {
+		problems = append(problems, Problem{Severity: severity, Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if m.Name == "" {
+		add(SeverityError, "name", "is required")
+	}
+	if !semver.MatchString(m.Version) {
+		add(SeverityError, "version", "%q is not a semantic version such as 1.2.0", m.Version)
+	}
+	if m.Description == "" {
+		add(SeverityWarning, "description", "is empty; clients show it as the server's instructions")
+	}
+	if m.EntryPoint != "" {
+		if u, err := url.Parse(m.EntryPoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add(SeverityError, "entry_point", "%q is not an http or https URL", m.EntryPoint)
+		}
+	}
+
+	checkNames := func(field string, names []string) {
+		seen := map[string]bool{}
+		for i, name := range names {
+			f := fmt.Sprintf("%s[%d]", field, i)
+			switch {
+			case !namePattern.MatchString(name):
+				add(SeverityError, f, "%q is not a valid name", name)
+			case seen[name]:
+				add(SeverityError, f, "duplicate name %s", name)
+			}
+			seen[name] = true
+		}
+	}
+	checkNames("resources", m.Resources)
+	checkNames("tools", m.Tools)
+
+	for i, pattern := range m.Prompts {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			add(SeverityError, fmt.Sprintf("prompts[%d]", i), "bad pattern %q: %v", pattern, err)
+		}
+	}
+
+	names := make([]string, len(m.ToolPlugins))
+	for i, tp := range m.ToolPlugins {
+		names[i] = tp.Name
+		field := fmt.Sprintf("tool_plugins[%d]", i)
+		if (tp.Exec == nil) == (tp.HTTP == nil) {
+			add(SeverityError, field, "set exactly one of exec and http")
+		}
+		if tp.Exec != nil && (len(tp.Exec.Command) == 0 || tp.Exec.Command[0] == "") {
+			add(SeverityError, field+".exec.command", "is required")
+		}
+		if tp.HTTP != nil {
+			if u, err := url.Parse(tp.HTTP.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				add(SeverityError, field+".http.url", "%q is not an http or https URL", tp.HTTP.URL)
+			}
+		}
+		if tp.Timeout != "" {
+			if d, err := time.ParseDuration(tp.Timeout); err != nil || d <= 0 {
+				add(SeverityError, field+".timeout", "%q is not a positive duration", tp.Timeout)
+			}
+		}
+		if tp.Description == "" {
+			add(SeverityWarning, field+".description", "is empty; models pick tools by their description")
+		}
+	}
+	checkNames("tool_plugins", names)
+	return problems
+}
@@ -0,0 +1,49 @@
+package manifest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	mf := Manifest{
+		Name:       "xscopehub",
+		Version:    "1.0",
+		EntryPoint: "localhost:8000",
+		Resources:  []string{"logs", "logs"},
+		Tools:      []string{"query logs"},
+		Prompts:    []string{"prompts/[.json"},
+		ToolPlugins: []ToolPlugin{
+			{Name: "kubectl_get", Description: "Read objects", Timeout: "soon", Exec: &ExecTool{}},
+			{Name: "kubectl_get", HTTP: &HTTPTool{URL: "http://runbooks"}, Exec: &ExecTool{Command: []string{"x"}}},
+		},
+	}
+	var got []string
+	for _, p := range mf.Validate() {
+		got = append(got, p.String())
+	}
+	want := []string{
+		`error: version: "1.0" is not a semantic version such as 1.2.0`,
+		"warning: description: is empty; clients show it as the server's instructions",
+		`error: entry_point: "localhost:8000" is not an http or https URL`,
+		"error: resources[1]: duplicate name logs",
+		`error: tools[0]: "query logs" is not a valid name`,
+		`error: prompts[0]: bad pattern "prompts/[.json": syntax error in pattern`,
+		"error: tool_plugins[0].exec.command: is required",
+		`error: tool_plugins[0].timeout: "soon" is not a positive duration`,
+		"error: tool_plugins[1]: set exactly one of exec and http",
+		"warning: tool_plugins[1].description: is empty; models pick tools by their description",
+		"error: tool_plugins[1]: duplicate name kubectl_get",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected problems:\n%s", strings.Join(got, "\n"))
+	}
+	if !HasErrors(mf.Validate()) {
+		t.Fatalf("expected errors")
+	}
+
+	ok := Manifest{Name: "xscopehub", Version: "1.2.0-rc.1", Description: "d", EntryPoint: "http://localhost:8000/mcp"}
+	if problems := ok.Validate(); len(problems) != 0 {
+		t.Fatalf("unexpected problems %v", problems)
+	}
+}
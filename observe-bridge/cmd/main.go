@@ -111,7 +111,10 @@ func main() {
 				}
 			}
 
-			srv := etl.NewServer(cfg)
+			srv, err := etl.NewServer(cfg)
+			if err != nil {
+				return err
+			}
 			return srv.Run()
 		},
 	}
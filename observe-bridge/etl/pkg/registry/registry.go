@@ -1,12 +1,120 @@
+// Package registry holds the ETL jobs and the dependencies between them. The
+// dependencies must form a DAG; Order checks that and returns the jobs
+// upstream first.
 package registry
 
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
 // Job represents a registered ETL job.
 type Job struct {
 	Name string
+	// DependsOn names the jobs that must complete a tenant's window before
+	// this job processes it.
+	DependsOn []string
+}
+
+// Registry is a set of jobs.
+type Registry struct {
+	jobs map[string]Job
+}
+
+// New returns an empty registry.
+func New() *Registry {
+	return &Registry{jobs: map[string]Job{}}
 }
 
 // Register adds a job to the registry.
-func Register(job Job) error {
-	// TODO: implement job registration
+func (r *Registry) Register(job Job) error {
+	if job.Name == "" {
+		return fmt.Errorf("job without a name")
+	}
+	if _, ok := r.jobs[job.Name]; ok {
+		return fmt.Errorf("job %s registered twice", job.Name)
+	}
+	job.DependsOn = append([]string(nil), job.DependsOn...)
+	r.jobs[job.Name] = job
 	return nil
 }
+
+// Get returns the named job.
+func (r *Registry) Get(name string) (Job, bool) {
+	j, ok := r.jobs[name]
+	return j, ok
+}
+
+// Downstream returns the jobs that depend directly on name, sorted.
+func (r *Registry) Downstream(name string) []string {
+	var out []string
+	for _, j := range r.jobs {
+		for _, dep := range j.DependsOn {
+			if dep == name {
+				out = append(out, j.Name)
+				break
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Order validates the dependencies and returns the jobs so that every job
+// comes after the jobs it depends on; independent jobs are sorted by name.
+// It fails on a dependency that is not registered and on a cycle, naming
+// the jobs in it.
+func (r *Registry) Order() ([]Job, error) {
+	names := make([]string, 0, len(r.jobs))
+	for name, j := range r.jobs {
+		for _, dep := range j.DependsOn {
+			if _, ok := r.jobs[dep]; !ok {
+				return nil, fmt.Errorf("job %s depends on unknown job %s", name, dep)
+			}
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[string]int{}
+	var order []Job
+	var path []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			for i, n := range path {
+				if n == name {
+					return fmt.Errorf("dependency cycle: %s", strings.Join(append(path[i:], name), " -> "))
+				}
+			}
+		}
+		state[name] = visiting
+		path = append(path, name)
+		deps := append([]string(nil), r.jobs[name].DependsOn...)
+		sort.Strings(deps)
+		for _, dep := range deps {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		order = append(order, r.jobs[name])
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
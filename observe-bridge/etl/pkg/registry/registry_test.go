@@ -0,0 +1,57 @@
+package registry
+
+import (
+	"strings"
+	"testing"
+)
+
+func names(jobs []Job) string {
+	var out []string
+	for _, j := range jobs {
+		out = append(out, j.Name)
+	}
+	return strings.Join(out, ",")
+}
+
+func TestOrderPutsUpstreamFirst(t *testing.T) {
+	r := New()
+	for _, j := range []Job{
+		{Name: "age-refresh", DependsOn: []string{"oo-agg"}},
+		{Name: "topo-ansible"},
+		{Name: "oo-agg"},
+		{Name: "alerts", DependsOn: []string{"age-refresh", "topo-ansible"}},
+	} {
+		if err := r.Register(j); err != nil {
+			t.Fatal(err)
+		}
+	}
+	order, err := r.Order()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(order); got != "oo-agg,age-refresh,topo-ansible,alerts" {
+		t.Fatalf("order %s", got)
+	}
+	if got := r.Downstream("oo-agg"); len(got) != 1 || got[0] != "age-refresh" {
+		t.Fatalf("downstream %v", got)
+	}
+	if err := r.Register(Job{Name: "oo-agg"}); err == nil {
+		t.Fatal("expected an error registering oo-agg twice")
+	}
+}
+
+func TestOrderRejectsCyclesAndUnknownJobs(t *testing.T) {
+	r := New()
+	r.Register(Job{Name: "a", DependsOn: []string{"b"}})
+	r.Register(Job{Name: "b", DependsOn: []string{"c"}})
+	r.Register(Job{Name: "c", DependsOn: []string{"a"}})
+	if _, err := r.Order(); err == nil || !strings.Contains(err.Error(), "a -> b -> c -> a") {
+		t.Fatalf("expected the cycle to be named, got %v", err)
+	}
+
+	r = New()
+	r.Register(Job{Name: "age-refresh", DependsOn: []string{"oo-agg"}})
+	if _, err := r.Order(); err == nil || !strings.Contains(err.Error(), "unknown job oo-agg") {
+		t.Fatalf("expected an unknown job error, got %v", err)
+	}
+}
@@ -1,7 +1,352 @@
+// Package runner executes queued job runs. Each run gets a deadline; failed
+// runs are retried with exponential backoff until their attempts run out and
+// then dead-lettered; each job runs at most its configured number of windows
+// at once. A job with dependencies processes a tenant's window only once its
+// upstream jobs have completed runs covering it.
 package runner
 
-// Run executes the given job function with retry logic.
-func Run(name string, fn func() error) error {
-	// TODO: implement job runner with retry
-	return nil
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xscopehub/xscopehub/etl/pkg/store"
+	"github.com/xscopehub/xscopehub/etl/pkg/window"
+)
+
+// Defaults for unset Policy fields.
+const (
+	DefaultTimeout     = 10 * time.Minute
+	DefaultMaxAttempts = 5
+	DefaultBaseDelay   = 2 * time.Second
+	DefaultMaxDelay    = 5 * time.Minute
+)
+
+const (
+	// pollInterval is how often the queue is checked for runs.
+	pollInterval = time.Second
+	// leaseTTL is how long a lease lasts. A running run renews it every
+	// third of that, so the run of a crashed worker is taken over within
+	// leaseTTL however long its timeout.
+	leaseTTL = time.Minute
+	// recordTimeout bounds writing an outcome.
+	recordTimeout = 10 * time.Second
+	// waitingDelay is how soon a run waiting for its upstream jobs is
+	// checked again; blockedDelay the same for a run whose upstream failed,
+	// which needs the upstream run retried first.
+	waitingDelay = 15 * time.Second
+	blockedDelay = time.Minute
+)
+
+// Func processes one tenant's window.
+type Func func(ctx context.Context, tenant string, w window.Window) error
+
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying: the run fails at once instead
+// of backing off. Errors are retryable unless marked.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err}
+}
+
+// IsPermanent reports whether err was marked with Permanent.
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// Policy is how a job's runs are executed and retried.
+type Policy struct {
+	// Timeout is the deadline of one attempt.
+	Timeout time.Duration
+	// MaxAttempts is how many attempts a run gets before it is dead-lettered.
+	MaxAttempts int
+	// BaseDelay is the backoff after the first failure; it doubles with each
+	// further failure up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+func (p Policy) withDefaults() Policy {
+	if p.Timeout <= 0 {
+		p.Timeout = DefaultTimeout
+	}
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DefaultMaxAttempts
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = DefaultBaseDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = DefaultMaxDelay
+	}
+	return p
+}
+
+// Backoff is the delay before retrying a run that failed its attempt-th
+// attempt.
+func (p Policy) Backoff(attempt int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < attempt && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return d
+}
+
+// Job is a job the runner executes.
+type Job struct {
+	Name string
+	Fn   Func
+	// Concurrency is how many of the job's runs execute at once in this
+	// process. Defaults to one.
+	Concurrency int
+	Policy      Policy
+	// DependsOn names the jobs that must complete a tenant's window first.
+	DependsOn []string
+}
+
+// Queue hands out runs and records their outcome. store.Postgres
+// implements it.
+type Queue interface {
+	Lease(ctx context.Context, job, owner string, ttl time.Duration, limit int) ([]store.Job, error)
+	MarkDone(ctx context.Context, id int64, owner string) error
+	Retry(ctx context.Context, id int64, owner string, delay time.Duration, msg, stack string) error
+	MarkFailed(ctx context.Context, id int64, owner, msg, stack string) error
+	MarkDead(ctx context.Context, id int64, owner, msg, stack string) error
+	Defer(ctx context.Context, id int64, owner string, state store.State, delay time.Duration, reason string) error
+	Extend(ctx context.Context, id int64, owner string, ttl time.Duration) error
+	Runs(ctx context.Context, f store.Filter) ([]store.Job, error)
+}
+
+// Runner leases runs from a queue and executes them.
+type Runner struct {
+	queue Queue
+	owner string
+	jobs  []Job
+	lease time.Duration
+	// slots limits the runs in flight per job.
+	slots map[string]chan struct{}
+	wg    sync.WaitGroup
+}
+
+// New returns a runner that leases runs of jobs as owner, which should be
+// unique per process. Jobs are polled in the order given, so passing them
+// upstream first lets a window flow through the DAG in one poll.
+func New(queue Queue, owner string, jobs []Job) *Runner {
+	r := &Runner{queue: queue, owner: owner, lease: leaseTTL, slots: map[string]chan struct{}{}}
+	for _, j := range jobs {
+		if j.Concurrency <= 0 {
+			j.Concurrency = 1
+		}
+		j.Policy = j.Policy.withDefaults()
+		r.jobs = append(r.jobs, j)
+		r.slots[j.Name] = make(chan struct{}, j.Concurrency)
+	}
+	return r
+}
+
+// Loop executes runs as they become available until ctx is done, then waits
+// for the runs in flight.
+func (r *Runner) Loop(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		r.poll(ctx)
+		select {
+		case <-ctx.Done():
+			r.wg.Wait()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll leases as many runs of each job as it has free slots and starts them.
+func (r *Runner) poll(ctx context.Context) {
+	for _, j := range r.jobs {
+		slots := r.slots[j.Name]
+		free := cap(slots) - len(slots)
+		if free == 0 || ctx.Err() != nil {
+			continue
+		}
+		runs, err := r.queue.Lease(ctx, j.Name, r.owner, r.lease, free)
+		if err != nil {
+			log.Printf("ERROR: lease %s: %v", j.Name, err)
+			continue
+		}
+		for _, run := range runs {
+			slots <- struct{}{}
+			r.wg.Add(1)
+			go func(j Job, run store.Job) {
+				defer r.wg.Done()
+				defer func() { <-slots }()
+				r.execute(ctx, j, run)
+			}(j, run)
+		}
+	}
+}
+
+// execute runs one leased run and records its outcome.
+func (r *Runner) execute(ctx context.Context, j Job, run store.Job) {
+	// A run leased more often than allowed was abandoned by crashed
+	// workers; running it again would not end differently.
+	if run.Attempts > j.Policy.MaxAttempts {
+		msg := fmt.Sprintf("abandoned after %d attempts", run.Attempts-1)
+		if run.LastError != "" {
+			msg += "; last error: " + run.LastError
+		}
+		rec, cancel := context.WithTimeout(context.WithoutCancel(ctx), recordTimeout)
+		defer cancel()
+		r.record(j, run, store.StateDead, r.queue.MarkDead(rec, run.ID, r.owner, msg, run.ErrorStack))
+		return
+	}
+
+	if state, reason := r.upstream(ctx, j, run); state != "" {
+		rec, cancel := context.WithTimeout(context.WithoutCancel(ctx), recordTimeout)
+		defer cancel()
+		delay := waitingDelay
+		if state == store.StateBlocked {
+			delay = blockedDelay
+		}
+		r.record(j, run, state, r.queue.Defer(rec, run.ID, r.owner, state, delay, reason))
+		return
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	stop := r.heartbeat(runCtx, j, run, cancel)
+	stack, err := call(runCtx, j, run)
+	stop()
+	cancel()
+	// The outcome is recorded even when the runner is shutting down.
+	rec, cancel := context.WithTimeout(context.WithoutCancel(ctx), recordTimeout)
+	defer cancel()
+	switch {
+	case err == nil:
+		r.record(j, run, store.StateDone, r.queue.MarkDone(rec, run.ID, r.owner))
+	case ctx.Err() != nil:
+		// Shutting down: hand the run back without waiting for backoff.
+		r.record(j, run, store.StateQueued, r.queue.Retry(rec, run.ID, r.owner, 0, "interrupted: "+err.Error(), stack))
+	case IsPermanent(err):
+		log.Printf("ERROR: %s %s %s failed: %v", j.Name, run.Tenant, run.Window, err)
+		r.record(j, run, store.StateFailed, r.queue.MarkFailed(rec, run.ID, r.owner, err.Error(), stack))
+	case run.Attempts >= j.Policy.MaxAttempts:
+		log.Printf("ERROR: %s %s %s dead after %d attempts: %v", j.Name, run.Tenant, run.Window, run.Attempts, err)
+		r.record(j, run, store.StateDead, r.queue.MarkDead(rec, run.ID, r.owner, err.Error(), stack))
+	default:
+		delay := j.Policy.Backoff(run.Attempts)
+		log.Printf("WARN: %s %s %s attempt %d failed, retrying in %s: %v", j.Name, run.Tenant, run.Window, run.Attempts, delay, err)
+		r.record(j, run, store.StateQueued, r.queue.Retry(rec, run.ID, r.owner, delay, err.Error(), stack))
+	}
+}
+
+// heartbeat renews the run's lease until the returned stop is called. When
+// the lease is lost another worker has taken the run over, so lost is called
+// to abandon this attempt.
+func (r *Runner) heartbeat(ctx context.Context, j Job, run store.Job, lost context.CancelFunc) (stop func()) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(r.lease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			err := r.queue.Extend(ctx, run.ID, r.owner, r.lease)
+			switch {
+			case err == nil:
+			case errors.Is(err, store.ErrLeaseLost):
+				log.Printf("WARN: %s run %d: lease lost, abandoning the attempt", j.Name, run.ID)
+				lost()
+				return
+			default:
+				log.Printf("ERROR: %s run %d: extend lease: %v", j.Name, run.ID, err)
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// upstream checks the job's dependencies for the run's tenant and window.
+// It returns StateBlocked when an upstream run overlapping the window failed,
+// StateWaiting when the upstream runs completed so far do not cover the
+// window, and an empty state when the run may proceed.
+func (r *Runner) upstream(ctx context.Context, j Job, run store.Job) (store.State, string) {
+	var waiting []string
+	for _, dep := range j.DependsOn {
+		runs, err := r.queue.Runs(ctx, store.Filter{Job: dep, Tenant: run.Tenant, From: run.Window.From, To: run.Window.To})
+		if err != nil {
+			return store.StateWaiting, fmt.Sprintf("check upstream %s: %v", dep, err)
+		}
+		var done []window.Window
+		for _, u := range runs {
+			switch u.State {
+			case store.StateDone:
+				done = append(done, u.Window)
+			case store.StateFailed, store.StateDead, store.StateBlocked:
+				return store.StateBlocked, fmt.Sprintf("upstream %s %s is %s", dep, u.Window, u.State)
+			}
+		}
+		if !window.Covers(done, run.Window) {
+			waiting = append(waiting, dep)
+		}
+	}
+	if len(waiting) > 0 {
+		return store.StateWaiting, fmt.Sprintf("waiting for %s to cover %s", strings.Join(waiting, ", "), run.Window)
+	}
+	return "", ""
+}
+
+// call runs the job function under the job's deadline. A panic is turned
+// into a permanent error with the panicking goroutine's stack; for errors
+// the stack is whatever detail their %+v verb adds.
+func call(ctx context.Context, j Job, run store.Job) (stack string, err error) {
+	ctx, cancel := context.WithTimeout(ctx, j.Policy.Timeout)
+	defer cancel()
+	defer func() {
+		if p := recover(); p != nil {
+			stack, err = string(debug.Stack()), Permanent(fmt.Errorf("panic: %v", p))
+		}
+	}()
+	if err = j.Fn(ctx, run.Tenant, run.Window); err != nil {
+		if detail := fmt.Sprintf("%+v", err); detail != err.Error() {
+			stack = detail
+		}
+	}
+	return stack, err
+}
+
+// record logs a failure to store an outcome. A lost lease means another
+// worker took the run over, so its outcome is no longer ours to record.
+func (r *Runner) record(j Job, run store.Job, state store.State, err error) {
+	switch {
+	case err == nil:
+	case errors.Is(err, store.ErrLeaseLost):
+		log.Printf("WARN: %s run %d: lease lost before recording %s", j.Name, run.ID, state)
+	default:
+		log.Printf("ERROR: %s run %d: record %s: %v", j.Name, run.ID, state, err)
+	}
 }
@@ -0,0 +1,264 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xscopehub/xscopehub/etl/pkg/store"
+	"github.com/xscopehub/xscopehub/etl/pkg/window"
+)
+
+// fakeQueue records outcomes; Lease hands out the runs in pending and Runs
+// returns those in history.
+type fakeQueue struct {
+	mu       sync.Mutex
+	pending  []store.Job
+	history  []store.Job
+	outcomes map[int64]outcome
+	// extended counts lease renewals by run; leaseLost makes them fail.
+	extended  map[int64]int
+	leaseLost bool
+}
+
+type outcome struct {
+	state      store.State
+	delay      time.Duration
+	msg, stack string
+}
+
+func newFakeQueue(runs ...store.Job) *fakeQueue {
+	return &fakeQueue{pending: runs, outcomes: map[int64]outcome{}, extended: map[int64]int{}}
+}
+
+func (q *fakeQueue) Lease(ctx context.Context, job, owner string, ttl time.Duration, limit int) ([]store.Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var out, rest []store.Job
+	for _, r := range q.pending {
+		if r.Name == job && len(out) < limit {
+			out = append(out, r)
+		} else {
+			rest = append(rest, r)
+		}
+	}
+	q.pending = rest
+	return out, nil
+}
+
+func (q *fakeQueue) set(id int64, o outcome) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.outcomes[id] = o
+	return nil
+}
+
+func (q *fakeQueue) get(id int64) outcome {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.outcomes[id]
+}
+
+func (q *fakeQueue) MarkDone(ctx context.Context, id int64, owner string) error {
+	return q.set(id, outcome{state: store.StateDone})
+}
+
+func (q *fakeQueue) Retry(ctx context.Context, id int64, owner string, delay time.Duration, msg, stack string) error {
+	return q.set(id, outcome{store.StateQueued, delay, msg, stack})
+}
+
+func (q *fakeQueue) MarkFailed(ctx context.Context, id int64, owner, msg, stack string) error {
+	return q.set(id, outcome{state: store.StateFailed, msg: msg, stack: stack})
+}
+
+func (q *fakeQueue) MarkDead(ctx context.Context, id int64, owner, msg, stack string) error {
+	return q.set(id, outcome{state: store.StateDead, msg: msg, stack: stack})
+}
+
+func (q *fakeQueue) Defer(ctx context.Context, id int64, owner string, state store.State, delay time.Duration, reason string) error {
+	return q.set(id, outcome{state: state, delay: delay, msg: reason})
+}
+
+func (q *fakeQueue) Extend(ctx context.Context, id int64, owner string, ttl time.Duration) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.leaseLost {
+		return store.ErrLeaseLost
+	}
+	q.extended[id]++
+	return nil
+}
+
+func (q *fakeQueue) Runs(ctx context.Context, f store.Filter) ([]store.Job, error) {
+	var out []store.Job
+	for _, r := range q.history {
+		if r.Name == f.Job && r.Tenant == f.Tenant && r.Window.To.After(f.From) && r.Window.From.Before(f.To) {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func TestBackoffDoublesUpToCap(t *testing.T) {
+	p := Policy{}.withDefaults()
+	want := []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second}
+	for i, w := range want {
+		if got := p.Backoff(i + 1); got != w {
+			t.Fatalf("attempt %d: got %s, want %s", i+1, got, w)
+		}
+	}
+	if got := p.Backoff(20); got != 5*time.Minute {
+		t.Fatalf("attempt 20: got %s, want the 5m cap", got)
+	}
+}
+
+func TestExecuteClassifiesErrors(t *testing.T) {
+	errBoom := errors.New("boom")
+	cases := []struct {
+		name     string
+		attempts int
+		fn       Func
+		want     outcome
+	}{
+		{"done", 1, func(context.Context, string, window.Window) error { return nil }, outcome{state: store.StateDone}},
+		{"retry", 2, func(context.Context, string, window.Window) error { return errBoom }, outcome{state: store.StateQueued, delay: 4 * time.Second, msg: "boom"}},
+		{"permanent", 1, func(context.Context, string, window.Window) error { return Permanent(errBoom) }, outcome{state: store.StateFailed, msg: "boom"}},
+		{"exhausted", 3, func(context.Context, string, window.Window) error { return errBoom }, outcome{state: store.StateDead, msg: "boom"}},
+		{"abandoned", 4, func(context.Context, string, window.Window) error { t.Fatal("abandoned run executed"); return nil }, outcome{state: store.StateDead, msg: "abandoned after 3 attempts"}},
+		{"timeout", 1, func(ctx context.Context, _ string, _ window.Window) error { <-ctx.Done(); return ctx.Err() }, outcome{state: store.StateQueued, delay: 2 * time.Second, msg: "context deadline exceeded"}},
+	}
+	for i, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			q := newFakeQueue()
+			r := New(q, "test", nil)
+			j := Job{Name: "oo-agg", Fn: tc.fn, Policy: Policy{Timeout: 10 * time.Millisecond, MaxAttempts: 3}.withDefaults()}
+			r.execute(context.Background(), j, store.Job{ID: int64(i), Name: "oo-agg", Attempts: tc.attempts})
+			if got := q.get(int64(i)); got != tc.want {
+				t.Fatalf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestExecuteRecordsPanicStack(t *testing.T) {
+	q := newFakeQueue()
+	r := New(q, "test", nil)
+	j := Job{Name: "oo-agg", Fn: func(context.Context, string, window.Window) error { panic("bad window") }, Policy: Policy{}.withDefaults()}
+	r.execute(context.Background(), j, store.Job{ID: 1, Attempts: 1})
+	got := q.get(1)
+	if got.state != store.StateFailed || got.msg != "panic: bad window" || !strings.Contains(got.stack, "runtime/debug.Stack") {
+		t.Fatalf("unexpected outcome %+v", got)
+	}
+}
+
+func TestPollHonorsConcurrency(t *testing.T) {
+	var runs []store.Job
+	for i := 0; i < 5; i++ {
+		runs = append(runs, store.Job{ID: int64(i), Name: "oo-agg", Attempts: 1})
+	}
+	q := newFakeQueue(runs...)
+	release := make(chan struct{})
+	var mu sync.Mutex
+	running, peak := 0, 0
+	fn := func(context.Context, string, window.Window) error {
+		mu.Lock()
+		running++
+		if running > peak {
+			peak = running
+		}
+		mu.Unlock()
+		<-release
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	}
+	r := New(q, "test", []Job{{Name: "oo-agg", Fn: fn, Concurrency: 2}})
+	ctx := context.Background()
+	r.poll(ctx)
+	r.poll(ctx)
+	if left := len(q.pending); left != 3 {
+		t.Fatalf("leased %d runs with two slots", 5-left)
+	}
+	close(release)
+	for len(q.pending) > 0 {
+		r.wg.Wait()
+		r.poll(ctx)
+	}
+	r.wg.Wait()
+	if peak > 2 {
+		t.Fatalf("%d runs in flight, limit is 2", peak)
+	}
+	for i := range runs {
+		if q.get(int64(i)).state != store.StateDone {
+			t.Fatalf("run %d not done", i)
+		}
+	}
+}
+
+func TestExecuteWaitsForUpstream(t *testing.T) {
+	at := func(min int) time.Time { return time.Date(2024, 1, 1, 12, min, 0, 0, time.UTC) }
+	upstream := func(from, to int, state store.State) store.Job {
+		return store.Job{Name: "oo-agg", Tenant: "default", Window: window.Window{From: at(from), To: at(to)}, State: state}
+	}
+	executed := false
+	j := Job{Name: "age-refresh", DependsOn: []string{"oo-agg"}, Policy: Policy{}.withDefaults(),
+		Fn: func(context.Context, string, window.Window) error { executed = true; return nil }}
+	run := store.Job{ID: 1, Name: "age-refresh", Tenant: "default", Window: window.Window{From: at(0), To: at(2)}, Attempts: 1}
+
+	cases := []struct {
+		name    string
+		history []store.Job
+		want    store.State
+	}{
+		{"no upstream runs", nil, store.StateWaiting},
+		{"partly covered", []store.Job{upstream(0, 1, store.StateDone), upstream(1, 2, store.StateRunning)}, store.StateWaiting},
+		{"upstream failed", []store.Job{upstream(0, 1, store.StateDone), upstream(1, 2, store.StateDead)}, store.StateBlocked},
+		{"other tenant", []store.Job{{Name: "oo-agg", Tenant: "other", Window: run.Window, State: store.StateDone}}, store.StateWaiting},
+		{"covered", []store.Job{upstream(0, 1, store.StateDone), upstream(1, 2, store.StateDone)}, store.StateDone},
+	}
+	for _, tc := range cases {
+		q := newFakeQueue()
+		q.history = tc.history
+		executed = false
+		New(q, "test", nil).execute(context.Background(), j, run)
+		got := q.get(1)
+		if got.state != tc.want || executed != (tc.want == store.StateDone) {
+			t.Fatalf("%s: got %+v (executed %v), want %s", tc.name, got, executed, tc.want)
+		}
+	}
+}
+
+func TestExecuteRenewsLease(t *testing.T) {
+	q := newFakeQueue()
+	r := New(q, "test", nil)
+	r.lease = 30 * time.Millisecond
+	j := Job{Name: "oo-agg", Fn: func(ctx context.Context, _ string, _ window.Window) error {
+		time.Sleep(100 * time.Millisecond)
+		return nil
+	}, Policy: Policy{}.withDefaults()}
+	r.execute(context.Background(), j, store.Job{ID: 1, Attempts: 1})
+	if q.get(1).state != store.StateDone || q.extended[1] < 2 {
+		t.Fatalf("expected a renewed lease and a done run, got %+v after %d renewals", q.get(1), q.extended[1])
+	}
+
+	q = newFakeQueue()
+	q.leaseLost = true
+	r = New(q, "test", nil)
+	r.lease = 30 * time.Millisecond
+	j.Fn = func(ctx context.Context, _ string, _ window.Window) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+			return errors.New("attempt not abandoned")
+		}
+	}
+	r.execute(context.Background(), j, store.Job{ID: 2, Attempts: 1})
+	if got := q.get(2); got.msg != "context canceled" {
+		t.Fatalf("expected the attempt abandoned once the lease was lost, got %+v", got)
+	}
+}
@@ -1,7 +1,259 @@
+// Package scheduler turns the jobs configuration into runs: for every job and
+// tenant it computes the closed, aligned windows that are due and enqueues
+// them once.
 package scheduler
 
-// Tick processes pending windows and enqueues jobs.
-func Tick() error {
-	// TODO: implement scheduler tick
-	return nil
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/xscopehub/xscopehub/etl/pkg/window"
+)
+
+// maxWindowsPerTick bounds how many windows one job and tenant enqueue per
+// tick, so a long backfill is spread over several ticks.
+const maxWindowsPerTick = 500
+
+// minRetryBackoff is how long a job waits after its scheduling failed. It
+// doubles with every consecutive failure, up to the job's interval.
+const minRetryBackoff = 5 * time.Second
+
+// Job is the schedule of one job.
+type Job struct {
+	Name string
+	// Align is the window size; windows start on multiples of it.
+	Align time.Duration
+	// Delay holds a window back after it closes so late data can arrive.
+	Delay time.Duration
+	// Interval is how often the job is scheduled. Jobs with no interval
+	// only run when triggered.
+	Interval time.Duration
+	// Lookback is how far back a tenant without a watermark starts.
+	// Defaults to one window.
+	Lookback time.Duration
+}
+
+// Options applies to every job.
+type Options struct {
+	Tenants []string
+	// Jitter delays each scheduling pass by a random amount up to it so
+	// jobs due at the same time do not start together.
+	Jitter time.Duration
+	// MaxBackfill bounds how far behind a job may catch up. Older windows
+	// are skipped.
+	MaxBackfill time.Duration
+}
+
+// Store keeps the queue of runs and how far each job has been scheduled.
+type Store interface {
+	// Watermark returns the end of the last window scheduled for the job
+	// and tenant, or the zero time.
+	Watermark(ctx context.Context, job, tenant string) (time.Time, error)
+	// Enqueue queues a run unless one exists for the same window. It
+	// reports whether a run was created.
+	Enqueue(ctx context.Context, job, tenant string, w window.Window) (bool, error)
+	// Advance moves the watermark forward to to.
+	Advance(ctx context.Context, job, tenant string, to time.Time) error
+}
+
+// Run is a window the scheduler enqueued.
+type Run struct {
+	Job    string        `json:"job"`
+	Tenant string        `json:"tenant"`
+	Window window.Window `json:"window"`
+}
+
+// Scheduler enqueues due windows.
+type Scheduler struct {
+	jobs  []Job
+	opts  Options
+	store Store
+	now   func() time.Time
+
+	mu sync.Mutex
+	// next is when each job is due again.
+	next map[string]time.Time
+	// failures counts each job's consecutive failed scheduling passes.
+	failures map[string]int
+}
+
+// New validates the schedules and returns a scheduler.
+func New(jobs []Job, opts Options, store Store) (*Scheduler, error) {
+	jobs = append([]Job(nil), jobs...)
+	for i, j := range jobs {
+		if j.Interval <= 0 {
+			continue
+		}
+		if j.Align <= 0 {
+			jobs[i].Align = j.Interval
+		}
+		if j.Delay < 0 || j.Lookback < 0 {
+			return nil, fmt.Errorf("job %s: negative delay or lookback", j.Name)
+		}
+	}
+	if len(opts.Tenants) == 0 {
+		return nil, fmt.Errorf("no tenants configured")
+	}
+	sort.Slice(jobs, func(i, k int) bool { return jobs[i].Name < jobs[k].Name })
+	return &Scheduler{jobs: jobs, opts: opts, store: store, now: time.Now, next: map[string]time.Time{}, failures: map[string]int{}}, nil
+}
+
+// Job returns the schedule of the named job with defaults applied.
+func (s *Scheduler) Job(name string) (Job, bool) {
+	for _, j := range s.jobs {
+		if j.Name == name {
+			return j, true
+		}
+	}
+	return Job{}, false
+}
+
+// Tick enqueues the due windows of every scheduled job, whether or not the
+// job's interval has elapsed, and returns the runs created.
+func (s *Scheduler) Tick(ctx context.Context) ([]Run, error) {
+	return s.tick(ctx, s.now(), true)
+}
+
+// tick schedules the due jobs for every tenant. A job or tenant that fails
+// is logged and does not hold up the others; the job is tried again after a
+// backoff. The failures are returned together.
+func (s *Scheduler) tick(ctx context.Context, now time.Time, all bool) ([]Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var runs []Run
+	var errs []error
+	for _, j := range s.jobs {
+		if j.Interval <= 0 || (!all && now.Before(s.next[j.Name])) {
+			continue
+		}
+		failed := false
+		for _, tenant := range s.opts.Tenants {
+			created, err := s.schedule(ctx, j, tenant, now)
+			runs = append(runs, created...)
+			if err != nil {
+				err = fmt.Errorf("schedule %s for %s: %w", j.Name, tenant, err)
+				log.Printf("ERROR: scheduler: %v", err)
+				errs = append(errs, err)
+				failed = true
+			}
+		}
+		if failed {
+			s.next[j.Name] = s.retryAt(j, now)
+			continue
+		}
+		delete(s.failures, j.Name)
+		s.next[j.Name] = s.due(j, now)
+	}
+	return runs, errors.Join(errs...)
+}
+
+// retryAt is when a job whose scheduling just failed is tried again.
+func (s *Scheduler) retryAt(j Job, now time.Time) time.Time {
+	s.failures[j.Name]++
+	backoff := minRetryBackoff << min(s.failures[j.Name]-1, 16)
+	if backoff > j.Interval {
+		backoff = j.Interval
+	}
+	return now.Add(backoff)
+}
+
+// schedule enqueues the closed windows of one job and tenant past its
+// watermark.
+func (s *Scheduler) schedule(ctx context.Context, j Job, tenant string, now time.Time) ([]Run, error) {
+	upper := window.Floor(now.Add(-j.Delay), j.Align)
+	start, err := s.store.Watermark(ctx, j.Name, tenant)
+	if err != nil {
+		return nil, err
+	}
+	if start.IsZero() {
+		lookback := j.Lookback
+		if lookback < j.Align {
+			lookback = j.Align
+		}
+		start = window.Floor(upper.Add(-lookback), j.Align)
+	}
+	if s.opts.MaxBackfill > 0 {
+		if oldest := window.Ceil(upper.Add(-s.opts.MaxBackfill), j.Align); start.Before(oldest) {
+			log.Printf("WARN: %s/%s is behind by more than %s; skipping %s to %s", j.Name, tenant, s.opts.MaxBackfill, start.Format(time.RFC3339), oldest.Format(time.RFC3339))
+			start = oldest
+		}
+	}
+
+	windows := window.Split(start, upper, j.Align)
+	if len(windows) > maxWindowsPerTick {
+		windows = windows[:maxWindowsPerTick]
+	}
+	var runs []Run
+	for _, w := range windows {
+		created, err := s.store.Enqueue(ctx, j.Name, tenant, w)
+		if err != nil {
+			return runs, err
+		}
+		if created {
+			runs = append(runs, Run{Job: j.Name, Tenant: tenant, Window: w})
+		}
+		if err := s.store.Advance(ctx, j.Name, tenant, w.To); err != nil {
+			return runs, err
+		}
+	}
+	return runs, nil
+}
+
+// due is when a job should be scheduled next: when its next window has
+// closed and the delay has passed, but not sooner than one interval from
+// now.
+func (s *Scheduler) due(j Job, now time.Time) time.Time {
+	next := window.Floor(now.Add(-j.Delay), j.Align).Add(j.Align + j.Delay)
+	if earliest := now.Add(j.Interval); next.Before(earliest) {
+		next = earliest
+	}
+	if s.opts.Jitter > 0 {
+		next = next.Add(time.Duration(rand.Int63n(int64(s.opts.Jitter))))
+	}
+	return next
+}
+
+// Loop schedules jobs as they become due until ctx is done.
+func (s *Scheduler) Loop(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		// tick logs the jobs that failed.
+		runs, _ := s.tick(ctx, s.now(), false)
+		if len(runs) > 0 {
+			log.Printf("INFO: scheduler enqueued %d runs", len(runs))
+		}
+		timer.Reset(s.wait())
+	}
+}
+
+// wait is how long until the earliest job is due, at least a second.
+func (s *Scheduler) wait() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	wait := time.Minute
+	for _, j := range s.jobs {
+		if j.Interval <= 0 {
+			continue
+		}
+		if d := time.Until(s.next[j.Name]); d < wait {
+			wait = d
+		}
+	}
+	if wait < time.Second {
+		wait = time.Second
+	}
+	return wait
 }
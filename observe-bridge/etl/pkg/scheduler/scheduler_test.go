@@ -0,0 +1,136 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/xscopehub/xscopehub/etl/pkg/store"
+)
+
+func TestTickEnqueuesClosedAlignedWindows(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 7, 30, 0, time.UTC)
+	jobs := []Job{
+		{Name: "oo-agg", Align: time.Minute, Delay: 2 * time.Minute, Interval: time.Minute, Lookback: 5 * time.Minute},
+		{Name: "topo-iac", Align: 15 * time.Minute, Delay: time.Minute, Interval: 15 * time.Minute},
+		{Name: "manual"},
+	}
+	s, err := New(jobs, Options{Tenants: []string{"default"}}, store.NewMemory())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = func() time.Time { return now }
+
+	runs, err := s.Tick(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// oo-agg: windows up to floor(12:05:30, 1m) = 12:05, five minutes back.
+	// topo-iac: one window before floor(12:06:30, 15m) = 12:00.
+	want := []string{
+		"oo-agg 2024-01-01T12:00:00Z/2024-01-01T12:01:00Z",
+		"oo-agg 2024-01-01T12:01:00Z/2024-01-01T12:02:00Z",
+		"oo-agg 2024-01-01T12:02:00Z/2024-01-01T12:03:00Z",
+		"oo-agg 2024-01-01T12:03:00Z/2024-01-01T12:04:00Z",
+		"oo-agg 2024-01-01T12:04:00Z/2024-01-01T12:05:00Z",
+		"topo-iac 2024-01-01T11:45:00Z/2024-01-01T12:00:00Z",
+	}
+	if len(runs) != len(want) {
+		t.Fatalf("got %d runs %v, want %d", len(runs), runs, len(want))
+	}
+	for i, r := range runs {
+		if got := r.Job + " " + r.Window.String(); got != want[i] {
+			t.Fatalf("run %d: got %s, want %s", i, got, want[i])
+		}
+	}
+
+	// The same instant enqueues nothing new; a minute later one window more.
+	if runs, _ := s.Tick(context.Background()); len(runs) != 0 {
+		t.Fatalf("expected no new runs, got %v", runs)
+	}
+	now = now.Add(time.Minute)
+	runs, _ = s.Tick(context.Background())
+	if len(runs) != 1 || runs[0].Window.String() != "2024-01-01T12:05:00Z/2024-01-01T12:06:00Z" {
+		t.Fatalf("unexpected runs %v", runs)
+	}
+}
+
+func TestTickSkipsWindowsBeyondMaxBackfill(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	st := store.NewMemory()
+	st.Advance(context.Background(), "oo-agg", "default", now.Add(-48*time.Hour))
+	s, err := New([]Job{{Name: "oo-agg", Align: time.Minute, Interval: time.Minute}}, Options{Tenants: []string{"default"}, MaxBackfill: 3 * time.Minute}, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = func() time.Time { return now }
+
+	runs, err := s.Tick(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 3 || !runs[0].Window.From.Equal(now.Add(-3*time.Minute)) {
+		t.Fatalf("expected the last three windows, got %v", runs)
+	}
+	if wm, _ := st.Watermark(context.Background(), "oo-agg", "default"); !wm.Equal(now) {
+		t.Fatalf("watermark %s, want %s", wm, now)
+	}
+}
+
+func TestDueWaitsForDelayAndJitter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 7, 30, 0, time.UTC)
+	s, _ := New(nil, Options{Tenants: []string{"default"}, Jitter: 3 * time.Second}, store.NewMemory())
+	j := Job{Name: "oo-agg", Align: time.Minute, Delay: 2 * time.Minute, Interval: time.Minute}
+	for i := 0; i < 20; i++ {
+		// The next window closes at 12:06 and is due after the 2m delay,
+		// but not before one interval from now: 12:08:30 plus jitter.
+		next := s.due(j, now)
+		if next.Before(now.Add(time.Minute)) || !next.Before(now.Add(time.Minute+3*time.Second)) {
+			t.Fatalf("due at %s", next)
+		}
+	}
+}
+
+// failingStore fails every call for one job.
+type failingStore struct {
+	*store.Memory
+	job string
+}
+
+func (f failingStore) Watermark(ctx context.Context, job, tenant string) (time.Time, error) {
+	if job == f.job {
+		return time.Time{}, errors.New("connection refused")
+	}
+	return f.Memory.Watermark(ctx, job, tenant)
+}
+
+func TestTickContinuesPastFailingJobsAndBacksOff(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	jobs := []Job{
+		{Name: "age-refresh", Align: time.Minute, Interval: time.Minute},
+		{Name: "oo-agg", Align: time.Minute, Interval: time.Minute},
+	}
+	s, err := New(jobs, Options{Tenants: []string{"acme", "globex"}}, failingStore{store.NewMemory(), "age-refresh"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runs, err := s.tick(context.Background(), now, false)
+	if err == nil || len(runs) != 2 || runs[0].Job != "oo-agg" || runs[1].Tenant != "globex" {
+		t.Fatalf("expected oo-agg to be scheduled for both tenants despite age-refresh failing, got %v %v", runs, err)
+	}
+	if got := s.next["age-refresh"]; !got.Equal(now.Add(minRetryBackoff)) {
+		t.Fatalf("age-refresh retries at %s, want %s", got, now.Add(minRetryBackoff))
+	}
+	s.tick(context.Background(), now.Add(minRetryBackoff), false)
+	if got, want := s.next["age-refresh"], now.Add(3*minRetryBackoff); !got.Equal(want) {
+		t.Fatalf("age-refresh retries at %s, want %s after a second failure", got, want)
+	}
+	for i := 0; i < 5; i++ {
+		s.tick(context.Background(), now, true)
+	}
+	if got := s.next["age-refresh"]; !got.Equal(now.Add(time.Minute)) {
+		t.Fatalf("backoff should stop at the interval, retries at %s", got)
+	}
+}
@@ -0,0 +1,59 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/xscopehub/xscopehub/etl/pkg/window"
+)
+
+type key struct {
+	job, tenant string
+}
+
+// Memory keeps the queue and watermarks in process. Nothing survives a
+// restart, so it is meant for tests and single runs.
+type Memory struct {
+	mu         sync.Mutex
+	queued     map[key]map[window.Window]bool
+	watermarks map[key]time.Time
+}
+
+// NewMemory returns an empty in-process store.
+func NewMemory() *Memory {
+	return &Memory{queued: map[key]map[window.Window]bool{}, watermarks: map[key]time.Time{}}
+}
+
+// Watermark returns the end of the last window scheduled for job and tenant.
+func (m *Memory) Watermark(ctx context.Context, job, tenant string) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.watermarks[key{job, tenant}], nil
+}
+
+// Enqueue records the window unless it was enqueued before.
+func (m *Memory) Enqueue(ctx context.Context, job, tenant string, w window.Window) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k := key{job, tenant}
+	if m.queued[k] == nil {
+		m.queued[k] = map[window.Window]bool{}
+	}
+	if m.queued[k][w] {
+		return false, nil
+	}
+	m.queued[k][w] = true
+	return true, nil
+}
+
+// Advance moves the watermark of job and tenant forward.
+func (m *Memory) Advance(ctx context.Context, job, tenant string, to time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k := key{job, tenant}
+	if to.After(m.watermarks[k]) {
+		m.watermarks[k] = to
+	}
+	return nil
+}
@@ -1,16 +1,369 @@
+// Package store keeps the ETL job queue and scheduling watermarks in
+// Postgres (migrations/postgres/0005_etl_jobs.sql). Each job, tenant and
+// window has one etl_job_run row, so a window is enqueued and completed once
+// however often it is scheduled.
 package store
 
-// Job represents a queued job.
-type Job struct{}
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
 
-// EnqueueOnce enqueues a job if it has not been enqueued before.
-func EnqueueOnce(job Job) error {
-	// TODO: implement enqueue-once logic
-	return nil
+	"github.com/xscopehub/xscopehub/etl/pkg/window"
+)
+
+// State is where a run is in its lifecycle.
+type State string
+
+const (
+	StateQueued State = "queued"
+	// StateWaiting is a run whose upstream jobs have not completed its
+	// window yet.
+	StateWaiting State = "waiting"
+	// StateBlocked is a run whose upstream jobs failed its window.
+	StateBlocked State = "blocked"
+	StateRunning State = "running"
+	StateDone    State = "done"
+	// StateFailed is a run stopped by a permanent error.
+	StateFailed State = "failed"
+	// StateDead is a run whose retries ran out.
+	StateDead State = "dead"
+)
+
+// ErrLeaseLost is returned when a run is no longer leased by the caller,
+// typically because its lease expired and another worker took it over.
+var ErrLeaseLost = errors.New("lease lost")
+
+// ErrNotFound is returned for a run that does not exist.
+var ErrNotFound = errors.New("run not found")
+
+// ErrNotRetryable is returned when requeueing a run that has not failed.
+var ErrNotRetryable = errors.New("run is not failed or dead")
+
+// Job is one queued run of a job over a tenant's window.
+type Job struct {
+	ID             int64         `json:"id"`
+	Name           string        `json:"job"`
+	Tenant         string        `json:"tenant"`
+	Window         window.Window `json:"window"`
+	State          State         `json:"state"`
+	Attempts       int           `json:"attempts"`
+	RunAfter       time.Time     `json:"run_after"`
+	LastError      string        `json:"last_error,omitempty"`
+	ErrorStack     string        `json:"error_stack,omitempty"`
+	LeaseOwner     string        `json:"lease_owner,omitempty"`
+	LeaseExpiresAt *time.Time    `json:"lease_expires_at,omitempty"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+	StartedAt      *time.Time    `json:"started_at,omitempty"`
+	FinishedAt     *time.Time    `json:"finished_at,omitempty"`
+}
+
+const jobColumns = `id, job, tenant, window_from, window_to, state, attempts,
+	run_after, coalesce(last_error, ''), coalesce(error_stack, ''),
+	coalesce(lease_owner, ''), lease_expires_at, created_at, updated_at, started_at, finished_at`
+
+func scanJob(row interface{ Scan(...any) error }) (Job, error) {
+	var j Job
+	err := row.Scan(&j.ID, &j.Name, &j.Tenant, &j.Window.From, &j.Window.To, &j.State, &j.Attempts,
+		&j.RunAfter, &j.LastError, &j.ErrorStack,
+		&j.LeaseOwner, &j.LeaseExpiresAt, &j.CreatedAt, &j.UpdatedAt, &j.StartedAt, &j.FinishedAt)
+	return j, err
+}
+
+func scanJobs(rows *sql.Rows, err error) ([]Job, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Job
+	for rows.Next() {
+		j, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, j)
+	}
+	return out, rows.Err()
+}
+
+// Postgres is the durable store.
+type Postgres struct {
+	db *sql.DB
+}
+
+// NewPostgres returns a store over db.
+func NewPostgres(db *sql.DB) *Postgres {
+	return &Postgres{db: db}
+}
+
+// Watermark returns the end of the last window scheduled for job and
+// tenant, or the zero time.
+func (p *Postgres) Watermark(ctx context.Context, job, tenant string) (time.Time, error) {
+	var t time.Time
+	err := p.db.QueryRowContext(ctx,
+		`SELECT scheduled_to FROM etl_watermark WHERE job = $1 AND tenant = $2`, job, tenant).Scan(&t)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	return t, err
+}
+
+// Advance moves the watermark of job and tenant forward to to. It never
+// moves backwards.
+func (p *Postgres) Advance(ctx context.Context, job, tenant string, to time.Time) error {
+	_, err := p.db.ExecContext(ctx, `
+		INSERT INTO etl_watermark (job, tenant, scheduled_to) VALUES ($1, $2, $3)
+		ON CONFLICT (job, tenant) DO UPDATE
+		SET scheduled_to = GREATEST(etl_watermark.scheduled_to, EXCLUDED.scheduled_to), updated_at = now()`,
+		job, tenant, to)
+	return err
+}
+
+// Enqueue queues a run of job over the tenant's window unless one exists.
+// It reports whether a run was created.
+func (p *Postgres) Enqueue(ctx context.Context, job, tenant string, w window.Window) (bool, error) {
+	res, err := p.db.ExecContext(ctx, `
+		INSERT INTO etl_job_run (job, tenant, window_from, window_to) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (job, tenant, window_from, window_to) DO NOTHING`,
+		job, tenant, w.From, w.To)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// Lease hands owner up to limit runs of job, oldest window first, for ttl.
+// Queued, waiting and blocked runs whose run_after has passed are taken as
+// well as running ones whose lease has expired, which recovers runs of a
+// worker that crashed. Rows leased by other workers are skipped rather than
+// waited for.
+func (p *Postgres) Lease(ctx context.Context, job, owner string, ttl time.Duration, limit int) ([]Job, error) {
+	return scanJobs(p.db.QueryContext(ctx, `
+		UPDATE etl_job_run SET
+			state = 'running',
+			attempts = attempts + 1,
+			lease_owner = $2,
+			lease_expires_at = now() + make_interval(secs => $3),
+			started_at = coalesce(started_at, now()),
+			updated_at = now()
+		WHERE id IN (
+			SELECT id FROM etl_job_run
+			WHERE job = $1
+			  AND ((state IN ('queued', 'waiting', 'blocked') AND run_after <= now())
+			    OR (state = 'running' AND lease_expires_at < now()))
+			ORDER BY window_from, id
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+jobColumns,
+		job, owner, ttl.Seconds(), limit))
+}
+
+// Extend renews owner's lease on run id for another ttl.
+func (p *Postgres) Extend(ctx context.Context, id int64, owner string, ttl time.Duration) error {
+	return p.finish(ctx, `
+		UPDATE etl_job_run SET lease_expires_at = now() + make_interval(secs => $3), updated_at = now()
+		WHERE id = $1 AND lease_owner = $2 AND state = 'running'`,
+		id, owner, ttl.Seconds())
+}
+
+// MarkDone completes run id. Only the current lease owner can complete it,
+// so a worker whose lease expired cannot record a window twice.
+func (p *Postgres) MarkDone(ctx context.Context, id int64, owner string) error {
+	return p.finish(ctx, `
+		UPDATE etl_job_run SET state = 'done', finished_at = now(), updated_at = now(),
+			lease_owner = NULL, lease_expires_at = NULL
+		WHERE id = $1 AND lease_owner = $2 AND state = 'running'`,
+		id, owner)
+}
+
+// Retry puts run id back in the queue after delay and records the error.
+func (p *Postgres) Retry(ctx context.Context, id int64, owner string, delay time.Duration, msg, stack string) error {
+	return p.finish(ctx, `
+		UPDATE etl_job_run SET state = 'queued', run_after = now() + make_interval(secs => $3),
+			last_error = $4, error_stack = nullif($5, ''), updated_at = now(),
+			lease_owner = NULL, lease_expires_at = NULL
+		WHERE id = $1 AND lease_owner = $2 AND state = 'running'`,
+		id, owner, delay.Seconds(), msg, stack)
+}
+
+// Defer puts run id back as waiting or blocked until after delay, with
+// reason shown as its last error. The lease does not count as an attempt.
+func (p *Postgres) Defer(ctx context.Context, id int64, owner string, state State, delay time.Duration, reason string) error {
+	return p.finish(ctx, `
+		UPDATE etl_job_run SET state = $3, run_after = now() + make_interval(secs => $4),
+			attempts = attempts - 1, last_error = $5, error_stack = NULL, updated_at = now(),
+			lease_owner = NULL, lease_expires_at = NULL
+		WHERE id = $1 AND lease_owner = $2 AND state = 'running'`,
+		id, owner, state, delay.Seconds(), reason)
+}
+
+// MarkFailed stops run id for good after a permanent error.
+func (p *Postgres) MarkFailed(ctx context.Context, id int64, owner, msg, stack string) error {
+	return p.stop(ctx, id, owner, StateFailed, msg, stack)
 }
 
-// MarkDone marks the job as completed.
-func MarkDone(job Job) error {
-	// TODO: implement job completion mark
+// MarkDead moves run id to the dead letters once its retries ran out.
+func (p *Postgres) MarkDead(ctx context.Context, id int64, owner, msg, stack string) error {
+	return p.stop(ctx, id, owner, StateDead, msg, stack)
+}
+
+func (p *Postgres) stop(ctx context.Context, id int64, owner string, state State, msg, stack string) error {
+	return p.finish(ctx, `
+		UPDATE etl_job_run SET state = $3, last_error = $4, error_stack = nullif($5, ''),
+			finished_at = now(), updated_at = now(), lease_owner = NULL, lease_expires_at = NULL
+		WHERE id = $1 AND lease_owner = $2 AND state = 'running'`,
+		id, owner, state, msg, stack)
+}
+
+// finish runs an update guarded by the lease and reports ErrLeaseLost when
+// it matched nothing.
+func (p *Postgres) finish(ctx context.Context, query string, args ...any) error {
+	res, err := p.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrLeaseLost
+	}
 	return nil
 }
+
+// Filter selects runs. Zero fields match everything; From and To select
+// runs whose window overlaps [From, To).
+type Filter struct {
+	Job    string
+	Tenant string
+	From   time.Time
+	To     time.Time
+	Limit  int
+}
+
+// Runs returns the runs matching f, oldest window first.
+func (p *Postgres) Runs(ctx context.Context, f Filter) ([]Job, error) {
+	query, args := runsQuery(f)
+	return scanJobs(p.db.QueryContext(ctx, query, args...))
+}
+
+// runsQuery builds the query of Runs and its arguments.
+func runsQuery(f Filter) (string, []any) {
+	query := `SELECT ` + jobColumns + ` FROM etl_job_run WHERE true`
+	var args []any
+	add := func(cond string, v any) {
+		args = append(args, v)
+		query += fmt.Sprintf(" AND "+cond, len(args))
+	}
+	if f.Job != "" {
+		add("job = $%d", f.Job)
+	}
+	if f.Tenant != "" {
+		add("tenant = $%d", f.Tenant)
+	}
+	if !f.From.IsZero() {
+		add("window_to > $%d", f.From)
+	}
+	if !f.To.IsZero() {
+		add("window_from < $%d", f.To)
+	}
+	query += " ORDER BY window_from, id"
+	if f.Limit > 0 {
+		args = append(args, f.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	return query, args
+}
+
+// Requeue puts a failed or dead run of job back in the queue with its
+// attempts reset. Its last error is kept until the next attempt.
+func (p *Postgres) Requeue(ctx context.Context, job string, id int64) (Job, error) {
+	j, err := scanJob(p.db.QueryRowContext(ctx, `
+		UPDATE etl_job_run SET state = 'queued', attempts = 0, run_after = now(),
+			finished_at = NULL, updated_at = now()
+		WHERE id = $1 AND job = $2 AND state IN ('failed', 'dead')
+		RETURNING `+jobColumns, id, job))
+	if !errors.Is(err, sql.ErrNoRows) {
+		return j, err
+	}
+	var state State
+	err = p.db.QueryRowContext(ctx, `SELECT state FROM etl_job_run WHERE id = $1 AND job = $2`, id, job).Scan(&state)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Job{}, ErrNotFound
+	}
+	if err != nil {
+		return Job{}, err
+	}
+	return Job{}, fmt.Errorf("%w: run %d is %s", ErrNotRetryable, id, state)
+}
+
+// Watermark is how far a job has been scheduled for a tenant.
+type Watermark struct {
+	Tenant      string    `json:"tenant"`
+	ScheduledTo time.Time `json:"scheduled_to"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Watermarks returns the watermarks of job by tenant.
+func (p *Postgres) Watermarks(ctx context.Context, job string) ([]Watermark, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT tenant, scheduled_to, updated_at FROM etl_watermark WHERE job = $1 ORDER BY tenant`, job)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Watermark
+	for rows.Next() {
+		var w Watermark
+		if err := rows.Scan(&w.Tenant, &w.ScheduledTo, &w.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, w)
+	}
+	return out, rows.Err()
+}
+
+// Summary counts a job's runs by state.
+type Summary struct {
+	Runs map[State]int `json:"runs"`
+	// LastDone is the end of the latest window completed for any tenant.
+	LastDone *time.Time `json:"last_done,omitempty"`
+}
+
+// Summaries returns the summary of every job with runs, by job name.
+func (p *Postgres) Summaries(ctx context.Context) (map[string]Summary, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT job, state, count(*), max(window_to) FROM etl_job_run GROUP BY job, state`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]Summary{}
+	for rows.Next() {
+		var (
+			job   string
+			state State
+			n     int
+			last  time.Time
+		)
+		if err := rows.Scan(&job, &state, &n, &last); err != nil {
+			return nil, err
+		}
+		sum, ok := out[job]
+		if !ok {
+			sum = Summary{Runs: map[State]int{}}
+		}
+		sum.Runs[state] = n
+		if state == StateDone {
+			sum.LastDone = &last
+		}
+		out[job] = sum
+	}
+	return out, rows.Err()
+}
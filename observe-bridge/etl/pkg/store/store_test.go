@@ -0,0 +1,40 @@
+package store
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunsQueryNumbersPlaceholders(t *testing.T) {
+	from := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+	cases := []struct {
+		name  string
+		f     Filter
+		where string
+		args  []any
+	}{
+		{"all", Filter{}, " ORDER BY window_from, id", nil},
+		{"limit only", Filter{Limit: 10}, " ORDER BY window_from, id LIMIT $1", []any{10}},
+		{"job and tenant", Filter{Job: "ooagg", Tenant: "t1"}, " AND job = $1 AND tenant = $2 ORDER BY window_from, id", []any{"ooagg", "t1"}},
+		{"tenant and window", Filter{Tenant: "t1", From: from, To: to, Limit: 5},
+			" AND tenant = $1 AND window_to > $2 AND window_from < $3 ORDER BY window_from, id LIMIT $4", []any{"t1", from, to, 5}},
+		{"everything", Filter{Job: "ooagg", Tenant: "t1", From: from, To: to, Limit: 5},
+			" AND job = $1 AND tenant = $2 AND window_to > $3 AND window_from < $4 ORDER BY window_from, id LIMIT $5", []any{"ooagg", "t1", from, to, 5}},
+		{"to only", Filter{To: to}, " AND window_from < $1 ORDER BY window_from, id", []any{to}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			query, args := runsQuery(tc.f)
+			where, ok := strings.CutPrefix(query, "SELECT "+jobColumns+" FROM etl_job_run WHERE true")
+			if !ok || where != tc.where {
+				t.Fatalf("got query %q, want conditions %q", query, tc.where)
+			}
+			if !reflect.DeepEqual(args, tc.args) {
+				t.Fatalf("got args %v, want %v", args, tc.args)
+			}
+		})
+	}
+}
@@ -1,9 +1,69 @@
 package window
 
-import "time"
+import (
+	"fmt"
+	"sort"
+	"time"
+)
 
-// Window represents a time range for ETL operations.
+// Window represents a time range for ETL operations. A record belongs to
+// the window when From <= ts < To.
 type Window struct {
-	From time.Time
-	To   time.Time
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// Floor aligns t down to a multiple of d counted from the Unix epoch, so
+// 1m, 15m and 1h windows start on the minute, quarter and hour in UTC.
+func Floor(t time.Time, d time.Duration) time.Time {
+	if d <= 0 {
+		return t.UTC()
+	}
+	return t.UTC().Truncate(d)
+}
+
+// Ceil aligns t up to a multiple of d.
+func Ceil(t time.Time, d time.Duration) time.Time {
+	f := Floor(t, d)
+	if f.Before(t) {
+		return f.Add(d)
+	}
+	return f
+}
+
+// Split cuts [from, to) into consecutive windows of size d. from is expected
+// to be aligned; a trailing partial window is left out.
+func Split(from, to time.Time, d time.Duration) []Window {
+	if d <= 0 {
+		return nil
+	}
+	var out []Window
+	for start := from; !start.Add(d).After(to); start = start.Add(d) {
+		out = append(out, Window{From: start, To: start.Add(d)})
+	}
+	return out
+}
+
+// Covers reports whether the union of ws spans all of w without gaps. The
+// windows may overlap and need not be sorted.
+func Covers(ws []Window, w Window) bool {
+	ws = append([]Window(nil), ws...)
+	sort.Slice(ws, func(i, k int) bool { return ws[i].From.Before(ws[k].From) })
+	reached := w.From
+	for _, c := range ws {
+		if !reached.Before(w.To) {
+			break
+		}
+		if c.From.After(reached) {
+			return false
+		}
+		if c.To.After(reached) {
+			reached = c.To
+		}
+	}
+	return !reached.Before(w.To)
+}
+
+func (w Window) String() string {
+	return fmt.Sprintf("%s/%s", w.From.UTC().Format(time.RFC3339), w.To.UTC().Format(time.RFC3339))
 }
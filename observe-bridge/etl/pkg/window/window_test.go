@@ -0,0 +1,30 @@
+package window
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCovers(t *testing.T) {
+	at := func(min int) time.Time { return time.Date(2024, 1, 1, 12, min, 0, 0, time.UTC) }
+	w := Window{From: at(0), To: at(15)}
+	cases := []struct {
+		name string
+		ws   []Window
+		want bool
+	}{
+		{"same", []Window{w}, true},
+		{"larger", []Window{{at(0).Add(-time.Hour), at(30)}}, true},
+		{"pieces out of order", []Window{{at(5), at(15)}, {at(0), at(5)}}, true},
+		{"overlapping", []Window{{at(0), at(10)}, {at(3), at(15)}}, true},
+		{"gap", []Window{{at(0), at(5)}, {at(6), at(15)}}, false},
+		{"short", []Window{{at(0), at(14)}}, false},
+		{"late start", []Window{{at(1), at(15)}}, false},
+		{"none", nil, false},
+	}
+	for _, tc := range cases {
+		if got := Covers(tc.ws, w); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -54,27 +54,33 @@ type Config struct {
 			ID   int    `yaml:"id"`
 		} `yaml:"list"`
 	} `yaml:"tenants"`
-	Jobs map[string]struct {
-		Enabled     bool     `yaml:"enabled"`
-		Align       string   `yaml:"align,omitempty"`
-		Delay       string   `yaml:"delay,omitempty"`
-		Interval    string   `yaml:"interval,omitempty"`
-		Concurrency int      `yaml:"concurrency,omitempty"`
-		DependsOn   []string `yaml:"depends_on,omitempty"`
-		Graph       struct {
-			Name    string `yaml:"name"`
-			SQLFile string `yaml:"sql_file"`
-		} `yaml:"graph,omitempty"`
-		StatusRef      string `yaml:"status_ref,omitempty"`
-		FullSyncOnBoot bool   `yaml:"full_sync_on_boot,omitempty"`
-		DriftDetection struct {
-			Enabled          bool   `yaml:"enabled"`
-			EmitEvent        bool   `yaml:"emit_event"`
-			SeverityOnChange string `yaml:"severity_on_change"`
-		} `yaml:"drift_detection,omitempty"`
-		RepoRef      string `yaml:"repo_ref,omitempty"`
-		ChangeWindow string `yaml:"change_window,omitempty"`
-	} `yaml:"jobs"`
+	Jobs map[string]JobConfig `yaml:"jobs"`
+}
+
+// JobConfig is the schedule and settings of one ETL job. Durations use Go
+// syntax such as "1m" or "240h".
+type JobConfig struct {
+	Enabled     bool     `yaml:"enabled"`
+	Align       string   `yaml:"align,omitempty"`
+	Delay       string   `yaml:"delay,omitempty"`
+	Interval    string   `yaml:"interval,omitempty"`
+	Concurrency int      `yaml:"concurrency,omitempty"`
+	Timeout     string   `yaml:"timeout,omitempty"`
+	MaxAttempts int      `yaml:"max_attempts,omitempty"`
+	DependsOn   []string `yaml:"depends_on,omitempty"`
+	Graph       struct {
+		Name    string `yaml:"name"`
+		SQLFile string `yaml:"sql_file"`
+	} `yaml:"graph,omitempty"`
+	StatusRef      string `yaml:"status_ref,omitempty"`
+	FullSyncOnBoot bool   `yaml:"full_sync_on_boot,omitempty"`
+	DriftDetection struct {
+		Enabled          bool   `yaml:"enabled"`
+		EmitEvent        bool   `yaml:"emit_event"`
+		SeverityOnChange string `yaml:"severity_on_change"`
+	} `yaml:"drift_detection,omitempty"`
+	RepoRef      string `yaml:"repo_ref,omitempty"`
+	ChangeWindow string `yaml:"change_window,omitempty"`
 }
 
 // Load reads configuration from the given file path and decodes it.
@@ -0,0 +1,368 @@
+package etl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/xscopehub/xscopehub/etl/pkg/registry"
+	"github.com/xscopehub/xscopehub/etl/pkg/store"
+	"github.com/xscopehub/xscopehub/etl/pkg/window"
+)
+
+const (
+	defaultRunsLimit = 100
+	maxRunsLimit     = 1000
+	// maxBackfillWindows bounds one backfill request.
+	maxBackfillWindows = 10000
+)
+
+// jobConfig is the configuration of a job as listed by GET /jobs.
+type jobConfig struct {
+	Align       string `json:"align,omitempty"`
+	Delay       string `json:"delay,omitempty"`
+	Interval    string `json:"interval,omitempty"`
+	Concurrency int    `json:"concurrency,omitempty"`
+	Timeout     string `json:"timeout,omitempty"`
+	MaxAttempts int    `json:"max_attempts,omitempty"`
+}
+
+// jobHealth summarizes a job's runs: "failing" when runs failed or are
+// dead, "blocked" when runs wait on failed upstream windows, else "ok".
+type jobHealth struct {
+	Status string `json:"status"`
+	store.Summary
+}
+
+type jobInfo struct {
+	Name       string     `json:"name"`
+	Config     jobConfig  `json:"config"`
+	DependsOn  []string   `json:"depends_on"`
+	Downstream []string   `json:"downstream"`
+	Health     *jobHealth `json:"health,omitempty"`
+}
+
+// handleJobRun queues a run of a job for the tenant and window of the
+// request and answers with it; the runner executes it like any scheduled
+// run. The window must be exactly one of the job's aligned windows that the
+// scheduler may already enqueue, so it is the same row as the scheduled run
+// and is never processed twice. A failed or dead run of the window is
+// requeued, any other run of it is returned as it is.
+func (s *Server) handleJobRun(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := s.registry.Get(name); !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("job %s is not enabled", name)})
+			return
+		}
+		tenant := c.Query("tenant")
+		if tenant == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing tenant"})
+			return
+		}
+		if !s.knownTenant(tenant) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown tenant %s", tenant)})
+			return
+		}
+		w, err := parseWindowParams(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		windows, err := s.jobWindows(name, w.From, w.To, time.Now())
+		if err == nil && (len(windows) != 1 || !windows[0].From.Equal(w.From) || !windows[0].To.Equal(w.To)) {
+			err = fmt.Errorf("window %s is not one aligned window of job %s; use backfill for a range", w, name)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		w = windows[0]
+		if !s.requireQueue(c) {
+			return
+		}
+		ctx := c.Request.Context()
+		if _, err := s.queue.Enqueue(ctx, name, tenant, w); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		run, err := s.windowRun(ctx, name, tenant, w)
+		if err == nil && (run.State == store.StateFailed || run.State == store.StateDead) {
+			run, err = s.queue.Requeue(ctx, name, run.ID)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, run)
+	}
+}
+
+// knownTenant reports whether tenant is one of the configured tenants.
+func (s *Server) knownTenant(tenant string) bool {
+	for _, t := range tenantCodes(s.cfg) {
+		if t == tenant {
+			return true
+		}
+	}
+	return false
+}
+
+// windowRun returns the run of job for tenant's window w.
+func (s *Server) windowRun(ctx context.Context, job, tenant string, w window.Window) (store.Job, error) {
+	runs, err := s.queue.Runs(ctx, store.Filter{Job: job, Tenant: tenant, From: w.From, To: w.To})
+	if err != nil {
+		return store.Job{}, err
+	}
+	for _, r := range runs {
+		if r.Window.From.Equal(w.From) && r.Window.To.Equal(w.To) {
+			return r, nil
+		}
+	}
+	return store.Job{}, fmt.Errorf("%w: %s %s %s", store.ErrNotFound, job, tenant, w)
+}
+
+// job resolves the :name parameter to a registered job, answering 404
+// otherwise.
+func (s *Server) job(c *gin.Context) (registry.Job, bool) {
+	j, ok := s.registry.Get(c.Param("name"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("unknown job %s", c.Param("name"))})
+	}
+	return j, ok
+}
+
+// requireQueue answers 503 when runs are not kept in Postgres.
+func (s *Server) requireQueue(c *gin.Context) bool {
+	if s.queue == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "job queue not configured (outputs.postgres.url)"})
+		return false
+	}
+	return true
+}
+
+func (s *Server) handleJobs(c *gin.Context) {
+	order, err := s.registry.Order()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var summaries map[string]store.Summary
+	if s.queue != nil {
+		if summaries, err = s.queue.Summaries(c.Request.Context()); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	out := make([]jobInfo, 0, len(order))
+	for _, j := range order {
+		jc := s.cfg.Jobs[j.Name]
+		info := jobInfo{
+			Name: j.Name,
+			Config: jobConfig{
+				Align:       jc.Align,
+				Delay:       jc.Delay,
+				Interval:    jc.Interval,
+				Concurrency: jc.Concurrency,
+				Timeout:     jc.Timeout,
+				MaxAttempts: jc.MaxAttempts,
+			},
+			DependsOn:  append([]string{}, j.DependsOn...),
+			Downstream: append([]string{}, s.registry.Downstream(j.Name)...),
+		}
+		if s.queue != nil {
+			sum, ok := summaries[j.Name]
+			if !ok {
+				sum = store.Summary{Runs: map[store.State]int{}}
+			}
+			h := &jobHealth{Status: "ok", Summary: sum}
+			switch {
+			case sum.Runs[store.StateFailed]+sum.Runs[store.StateDead] > 0:
+				h.Status = "failing"
+			case sum.Runs[store.StateBlocked] > 0:
+				h.Status = "blocked"
+			}
+			info.Health = h
+		}
+		out = append(out, info)
+	}
+	c.JSON(http.StatusOK, gin.H{"jobs": out})
+}
+
+func (s *Server) handleJobRuns(c *gin.Context) {
+	j, ok := s.job(c)
+	if !ok || !s.requireQueue(c) {
+		return
+	}
+	f := store.Filter{Job: j.Name, Tenant: c.Query("tenant"), Limit: defaultRunsLimit}
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"from", &f.From}, {"to", &f.To}} {
+		if v := c.Query(p.name); v != "" {
+			t, err := parseTime(v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			*p.dst = t
+		}
+	}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxRunsLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxRunsLimit)})
+			return
+		}
+		f.Limit = n
+	}
+	runs, err := s.queue.Runs(c.Request.Context(), f)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if runs == nil {
+		runs = []store.Job{}
+	}
+	c.JSON(http.StatusOK, gin.H{"runs": runs})
+}
+
+func (s *Server) handleJobWatermarks(c *gin.Context) {
+	j, ok := s.job(c)
+	if !ok || !s.requireQueue(c) {
+		return
+	}
+	wms, err := s.queue.Watermarks(c.Request.Context(), j.Name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if wms == nil {
+		wms = []store.Watermark{}
+	}
+	c.JSON(http.StatusOK, gin.H{"watermarks": wms})
+}
+
+type backfillRequest struct {
+	// Tenant defaults to every configured tenant.
+	Tenant string `json:"tenant"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+}
+
+// handleJobBackfill enqueues the aligned windows covering [from, to) for one
+// tenant or all of them. Windows already enqueued are left as they are, so
+// repeating a backfill is harmless.
+func (s *Server) handleJobBackfill(c *gin.Context) {
+	j, ok := s.job(c)
+	if !ok {
+		return
+	}
+	var req backfillRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	windows, err := s.backfillWindows(j.Name, req, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	tenants := tenantCodes(s.cfg)
+	if req.Tenant != "" {
+		if !s.knownTenant(req.Tenant) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown tenant %s", req.Tenant)})
+			return
+		}
+		tenants = []string{req.Tenant}
+	}
+	if len(windows)*len(tenants) > maxBackfillWindows {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("backfill of %d windows exceeds %d", len(windows)*len(tenants), maxBackfillWindows)})
+		return
+	}
+	if !s.requireQueue(c) {
+		return
+	}
+	enqueued := 0
+	for _, tenant := range tenants {
+		for _, w := range windows {
+			created, err := s.queue.Enqueue(c.Request.Context(), j.Name, tenant, w)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "enqueued": enqueued})
+				return
+			}
+			if created {
+				enqueued++
+			}
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"job":      j.Name,
+		"tenants":  tenants,
+		"windows":  len(windows) * len(tenants),
+		"enqueued": enqueued,
+		"existing": len(windows)*len(tenants) - enqueued,
+	})
+}
+
+// backfillWindows parses the requested range and splits it with
+// jobWindows.
+func (s *Server) backfillWindows(name string, req backfillRequest, now time.Time) ([]window.Window, error) {
+	if req.From == "" || req.To == "" {
+		return nil, fmt.Errorf("missing from/to")
+	}
+	from, err := parseTime(req.From)
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseTime(req.To)
+	if err != nil {
+		return nil, err
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("from must be before to")
+	}
+	return s.jobWindows(name, from, to, now)
+}
+
+// jobWindows aligns [from, to) outwards to the job's windows and splits it.
+// The aligned range may not reach past the last window the scheduler would
+// enqueue at now, which is still open or held back by the job's delay.
+func (s *Server) jobWindows(name string, from, to, now time.Time) ([]window.Window, error) {
+	sj, ok := s.scheduler.Job(name)
+	if !ok || sj.Align <= 0 {
+		return nil, fmt.Errorf("job %s has no align or interval to split windows by", name)
+	}
+	end := window.Ceil(to, sj.Align)
+	if upper := window.Floor(now.Add(-sj.Delay), sj.Align); end.After(upper) {
+		return nil, fmt.Errorf("to aligns to %s, past %s, the end of the last window closed for the job's delay", end.Format(time.RFC3339), upper.Format(time.RFC3339))
+	}
+	return window.Split(window.Floor(from, sj.Align), end, sj.Align), nil
+}
+
+func (s *Server) handleJobRetry(c *gin.Context) {
+	j, ok := s.job(c)
+	if !ok || !s.requireQueue(c) {
+		return
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid run id"})
+		return
+	}
+	run, err := s.queue.Requeue(c.Request.Context(), j.Name, id)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, store.ErrNotRetryable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, run)
+	}
+}
@@ -0,0 +1,123 @@
+package etl
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/xscopehub/xscopehub/internal/etl/config"
+)
+
+func testConfig() *config.Config {
+	cfg := &config.Config{Jobs: map[string]config.JobConfig{
+		"oo-agg":      {Enabled: true, Align: "1m", Delay: "2m", Interval: "1m", Concurrency: 2},
+		"age-refresh": {Enabled: true, Interval: "1m", DependsOn: []string{"oo-agg"}},
+		"topo-iac":    {Enabled: false, Interval: "15m"},
+	}}
+	cfg.Server.API.Listen = ":0"
+	return cfg
+}
+
+func serve(t *testing.T, s *Server, method, path, body string) (int, map[string]any) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	s.engine.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+	var out map[string]any
+	json.Unmarshal(rec.Body.Bytes(), &out)
+	return rec.Code, out
+}
+
+func TestJobsAPIWithoutQueue(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	s, err := NewServer(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, out := serve(t, s, http.MethodGet, "/jobs", "")
+	jobs, _ := out["jobs"].([]any)
+	if code != http.StatusOK || len(jobs) != 2 {
+		t.Fatalf("GET /jobs: %d %v", code, out)
+	}
+	first := jobs[0].(map[string]any)
+	if first["name"] != "oo-agg" || first["downstream"].([]any)[0] != "age-refresh" || first["health"] != nil {
+		t.Fatalf("unexpected first job %v", first)
+	}
+
+	for _, tc := range []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodGet, "/jobs/oo-agg/runs?tenant=default", http.StatusServiceUnavailable},
+		{http.MethodGet, "/jobs/topo-iac/runs", http.StatusNotFound},
+		{http.MethodGet, "/jobs/oo-agg/watermarks", http.StatusServiceUnavailable},
+		{http.MethodPost, "/jobs/oo-agg/runs/1/retry", http.StatusServiceUnavailable},
+		{http.MethodPost, "/scheduler/tick", http.StatusServiceUnavailable},
+		// Manual runs go through the queue like any other run.
+		{http.MethodPost, "/jobs/ooagg/run?tenant=default&window=2024-01-01T12:00:00Z/2024-01-01T12:01:00Z", http.StatusServiceUnavailable},
+		{http.MethodPost, "/jobs/topo/iac/run?tenant=default&from=1704110400&to=1704111300", http.StatusNotFound},
+		// Manual runs must be one aligned window the scheduler may enqueue,
+		// of a configured tenant.
+		{http.MethodPost, "/jobs/ooagg/run?tenant=globex&window=2024-01-01T12:00:00Z/2024-01-01T12:01:00Z", http.StatusBadRequest},
+		{http.MethodPost, "/jobs/ooagg/run?tenant=default&window=2024-01-01T12:00:30Z/2024-01-01T12:01:00Z", http.StatusBadRequest},
+		{http.MethodPost, "/jobs/ooagg/run?tenant=default&window=2024-01-01T12:00:00Z/2024-01-01T12:02:00Z", http.StatusBadRequest},
+		{http.MethodPost, "/jobs/ooagg/run?tenant=default&window=2999-01-01T12:00:00Z/2999-01-01T12:01:00Z", http.StatusBadRequest},
+	} {
+		if code, out := serve(t, s, tc.method, tc.path, ""); code != tc.want {
+			t.Errorf("%s %s: got %d %v, want %d", tc.method, tc.path, code, out, tc.want)
+		}
+	}
+	for body, want := range map[string]int{
+		`{"tenant":"default","from":"2024-01-01T12:00:00Z","to":"2024-01-01T12:04:00Z"}`: http.StatusServiceUnavailable,
+		`{"tenant":"globex","from":"2024-01-01T12:00:00Z","to":"2024-01-01T12:04:00Z"}`:  http.StatusBadRequest,
+	} {
+		if code, out := serve(t, s, http.MethodPost, "/jobs/oo-agg/backfill", body); code != want {
+			t.Errorf("backfill %s: got %d %v, want %d", body, code, out, want)
+		}
+	}
+}
+
+func TestBackfillWindowsAlignOutwards(t *testing.T) {
+	s, err := NewServer(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// oo-agg has 1m windows held back 2m, so at 12:06:30 the last window
+	// closed for scheduling ends at 12:04.
+	now := time.Date(2024, 1, 1, 12, 6, 30, 0, time.UTC)
+	ws, err := s.backfillWindows("oo-agg", backfillRequest{From: "2024-01-01T12:00:30Z", To: "2024-01-01T12:03:10Z"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ws) != 4 || ws[0].String() != "2024-01-01T12:00:00Z/2024-01-01T12:01:00Z" || ws[3].String() != "2024-01-01T12:03:00Z/2024-01-01T12:04:00Z" {
+		t.Fatalf("unexpected windows %v", ws)
+	}
+	// 12:04:10 is in the past but rounds up to 12:05, a window the delay
+	// still holds back.
+	for _, to := range []string{"2024-01-01T12:04:10Z", "2024-01-01T12:06:00Z", "2999-01-01T00:00:00Z"} {
+		if _, err := s.backfillWindows("oo-agg", backfillRequest{From: "2024-01-01T12:00:00Z", To: to}, now); err == nil {
+			t.Fatalf("expected an error for to=%s", to)
+		}
+	}
+	if ws, err := s.backfillWindows("oo-agg", backfillRequest{From: "2024-01-01T12:00:00Z", To: "2024-01-01T12:04:00Z"}, now); err != nil || len(ws) != 4 {
+		t.Fatalf("expected the windows up to 12:04, got %v %v", ws, err)
+	}
+}
+
+func TestNewServerRejectsDependencyCycles(t *testing.T) {
+	cfg := testConfig()
+	cfg.Jobs["oo-agg"] = config.JobConfig{Enabled: true, Interval: "1m", DependsOn: []string{"age-refresh"}}
+	if _, err := NewServer(cfg); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Fatalf("expected a cycle error, got %v", err)
+	}
+	cfg = testConfig()
+	cfg.Jobs["age-refresh"] = config.JobConfig{Enabled: true, DependsOn: []string{"topo-iac"}}
+	if _, err := NewServer(cfg); err == nil || !strings.Contains(err.Error(), "disabled") {
+		t.Fatalf("expected a disabled dependency error, got %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package etl
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/xscopehub/xscopehub/etl/jobs"
+	"github.com/xscopehub/xscopehub/etl/pkg/registry"
+	"github.com/xscopehub/xscopehub/etl/pkg/runner"
+	"github.com/xscopehub/xscopehub/internal/etl/config"
+)
+
+// jobFuncs are the job implementations by configured job name.
+var jobFuncs = map[string]runner.Func{
+	"oo-agg":       jobs.RunOOAgg,
+	"age-refresh":  jobs.RunAGERefresh,
+	"topo-iac":     jobs.RunTopoIAC,
+	"topo-ansible": jobs.RunTopoAnsible,
+}
+
+// jobRegistry registers the enabled jobs of cfg with their dependencies and
+// checks that they form a DAG. A dependency on a disabled job is an error,
+// since its dependents would wait forever.
+func jobRegistry(cfg *config.Config) (*registry.Registry, error) {
+	reg := registry.New()
+	for name, jc := range cfg.Jobs {
+		if !jc.Enabled {
+			continue
+		}
+		for _, dep := range jc.DependsOn {
+			if up, ok := cfg.Jobs[dep]; ok && !up.Enabled {
+				return nil, fmt.Errorf("jobs.%s depends on disabled job %s", name, dep)
+			}
+		}
+		if err := reg.Register(registry.Job{Name: name, DependsOn: jc.DependsOn}); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := reg.Order(); err != nil {
+		return nil, fmt.Errorf("jobs: %w", err)
+	}
+	return reg, nil
+}
+
+// runnerJobs reads the execution settings of the registered jobs from cfg,
+// upstream jobs first. Jobs without an implementation are left out.
+func runnerJobs(cfg *config.Config, reg *registry.Registry) ([]runner.Job, error) {
+	order, err := reg.Order()
+	if err != nil {
+		return nil, err
+	}
+	var out []runner.Job
+	for _, rj := range order {
+		name := rj.Name
+		jc := cfg.Jobs[name]
+		fn, ok := jobFuncs[name]
+		if !ok {
+			log.Printf("WARN: job %s has no implementation; its runs stay queued", name)
+			continue
+		}
+		if jc.Concurrency < 0 || jc.MaxAttempts < 0 {
+			return nil, fmt.Errorf("jobs.%s: negative concurrency or max_attempts", name)
+		}
+		timeout, err := parseDuration("jobs."+name+".timeout", jc.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, runner.Job{
+			Name:        name,
+			Fn:          fn,
+			Concurrency: jc.Concurrency,
+			Policy:      runner.Policy{Timeout: timeout, MaxAttempts: jc.MaxAttempts},
+			DependsOn:   rj.DependsOn,
+		})
+	}
+	return out, nil
+}
+
+// leaseOwner identifies this process on the runs it leases.
+func leaseOwner() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
@@ -0,0 +1,81 @@
+package etl
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/xscopehub/xscopehub/etl/pkg/scheduler"
+	"github.com/xscopehub/xscopehub/internal/etl/config"
+)
+
+// defaultTenant is scheduled when tenants.list is empty.
+const defaultTenant = "default"
+
+// parseDuration reads an optional duration setting; empty means zero.
+func parseDuration(field, v string) (time.Duration, error) {
+	if v == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("%s: invalid duration %q", field, v)
+	}
+	return d, nil
+}
+
+// tenantCodes returns the configured tenants, or the default tenant.
+func tenantCodes(cfg *config.Config) []string {
+	var out []string
+	for _, t := range cfg.Tenants.List {
+		out = append(out, t.Code)
+	}
+	if len(out) == 0 {
+		out = []string{defaultTenant}
+	}
+	return out
+}
+
+// schedules reads the job schedules and scheduler options from cfg.
+// Disabled jobs are left out.
+func schedules(cfg *config.Config) ([]scheduler.Job, scheduler.Options, error) {
+	var opts scheduler.Options
+	var err error
+	if opts.Jitter, err = parseDuration("scheduler.jitter", cfg.Scheduler.Jitter); err != nil {
+		return nil, opts, err
+	}
+	if opts.MaxBackfill, err = parseDuration("scheduler.max_backfill", cfg.Scheduler.MaxBackfill); err != nil {
+		return nil, opts, err
+	}
+	opts.Tenants = tenantCodes(cfg)
+
+	names := make([]string, 0, len(cfg.Jobs))
+	for name := range cfg.Jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var jobs []scheduler.Job
+	for _, name := range names {
+		jc := cfg.Jobs[name]
+		if !jc.Enabled {
+			continue
+		}
+		j := scheduler.Job{Name: name}
+		prefix := "jobs." + name + "."
+		if j.Align, err = parseDuration(prefix+"align", jc.Align); err != nil {
+			return nil, opts, err
+		}
+		if j.Delay, err = parseDuration(prefix+"delay", jc.Delay); err != nil {
+			return nil, opts, err
+		}
+		if j.Interval, err = parseDuration(prefix+"interval", jc.Interval); err != nil {
+			return nil, opts, err
+		}
+		if j.Lookback, err = parseDuration("tenants.initial_lookback."+name, cfg.Tenants.InitialLookback[name]); err != nil {
+			return nil, opts, err
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, opts, nil
+}
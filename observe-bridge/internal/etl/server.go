@@ -1,36 +1,81 @@
 package etl
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
-	"github.com/xscopehub/xscopehub/etl/jobs"
 	"github.com/xscopehub/xscopehub/etl/pkg/ansible"
 	"github.com/xscopehub/xscopehub/etl/pkg/events"
 	"github.com/xscopehub/xscopehub/etl/pkg/iac"
 	"github.com/xscopehub/xscopehub/etl/pkg/oo"
 	"github.com/xscopehub/xscopehub/etl/pkg/pgw"
+	"github.com/xscopehub/xscopehub/etl/pkg/registry"
+	"github.com/xscopehub/xscopehub/etl/pkg/runner"
 	"github.com/xscopehub/xscopehub/etl/pkg/scheduler"
+	"github.com/xscopehub/xscopehub/etl/pkg/store"
 	"github.com/xscopehub/xscopehub/etl/pkg/window"
 	"github.com/xscopehub/xscopehub/internal/etl/config"
+
+	_ "github.com/lib/pq"
 )
 
 // Server wraps the HTTP engine and configuration.
 type Server struct {
-	engine *gin.Engine
-	cfg    *config.Config
+	engine    *gin.Engine
+	cfg       *config.Config
+	scheduler *scheduler.Scheduler
+	registry  *registry.Registry
+	// queue is the durable job store; nil when outputs.postgres.url is
+	// not set, and then jobs are neither scheduled nor run.
+	queue *store.Postgres
+	// runner executes queued runs; nil without a durable queue.
+	runner *runner.Runner
 }
 
-// NewServer creates a server with basic health and metrics endpoints.
-func NewServer(cfg *config.Config) *Server {
-	s := &Server{engine: gin.New(), cfg: cfg}
+// NewServer creates a server with basic health and metrics endpoints and the
+// job scheduler.
+func NewServer(cfg *config.Config) (*Server, error) {
+	jobs, opts, err := schedules(cfg)
+	if err != nil {
+		return nil, err
+	}
+	reg, err := jobRegistry(cfg)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{engine: gin.New(), cfg: cfg, registry: reg}
+	// Without Postgres the scheduler only describes the jobs' windows to
+	// the API; it is never run, so this store stays empty.
+	var st scheduler.Store = store.NewMemory()
+	if url := cfg.Outputs.Postgres.URL; url != "" {
+		db, err := sql.Open("postgres", url)
+		if err != nil {
+			return nil, err
+		}
+		s.queue = store.NewPostgres(db)
+		st = s.queue
+		rjobs, err := runnerJobs(cfg, reg)
+		if err != nil {
+			return nil, err
+		}
+		s.runner = runner.New(s.queue, leaseOwner(), rjobs)
+	} else {
+		log.Printf("WARN: outputs.postgres.url not set; jobs are not scheduled or executed")
+	}
+	if s.scheduler, err = scheduler.New(jobs, opts, st); err != nil {
+		return nil, err
+	}
 	r := s.engine
 	r.Use(gin.Logger())
 	r.GET("/healthz", func(c *gin.Context) {
@@ -45,45 +90,63 @@ func NewServer(cfg *config.Config) *Server {
 	r.POST("/pgw/topo/edges", handlePGWTopoEdges)
 
 	// Jobs
-	r.POST("/jobs/ooagg/run", handleJobOOAgg)
-	r.POST("/jobs/age_refresh/run", handleJobAGERefresh)
-	r.POST("/jobs/topo/iac/run", handleJobTopoIAC)
-	r.POST("/jobs/topo/ansible/run", handleJobTopoAnsible)
+	r.POST("/jobs/ooagg/run", s.handleJobRun("oo-agg"))
+	r.POST("/jobs/age_refresh/run", s.handleJobRun("age-refresh"))
+	r.POST("/jobs/topo/iac/run", s.handleJobRun("topo-iac"))
+	r.POST("/jobs/topo/ansible/run", s.handleJobRun("topo-ansible"))
+	r.GET("/jobs", s.handleJobs)
+	r.GET("/jobs/:name/runs", s.handleJobRuns)
+	r.GET("/jobs/:name/watermarks", s.handleJobWatermarks)
+	r.POST("/jobs/:name/backfill", s.handleJobBackfill)
+	r.POST("/jobs/:name/runs/:id/retry", s.handleJobRetry)
 
 	// Events and scheduler
 	r.POST("/events/enqueue", handleEventsEnqueue)
-	r.POST("/scheduler/tick", handleSchedulerTick)
+	r.POST("/scheduler/tick", s.handleSchedulerTick)
 
 	// Topology discovery
 	r.GET("/topo/iac/discover", handleIACDiscover)
 	r.GET("/topo/ansible/extract", handleAnsibleExtract)
 
-	return s
+	return s, nil
+}
+
+// parseTime accepts RFC3339 or Unix seconds.
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(i, 0).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %s", s)
 }
 
+// parseWindowParams reads the window from the window query parameter, as
+// "from/to", or from the from and to parameters.
 func parseWindowParams(c *gin.Context) (window.Window, error) {
 	fromStr := c.Query("from")
 	toStr := c.Query("to")
+	if w := c.Query("window"); w != "" {
+		var ok bool
+		if fromStr, toStr, ok = strings.Cut(w, "/"); !ok {
+			return window.Window{}, fmt.Errorf("invalid window %s, want from/to", w)
+		}
+	}
 	if fromStr == "" || toStr == "" {
 		return window.Window{}, fmt.Errorf("missing from/to")
 	}
-	parse := func(s string) (time.Time, error) {
-		if t, err := time.Parse(time.RFC3339, s); err == nil {
-			return t, nil
-		}
-		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
-			return time.Unix(i, 0).UTC(), nil
-		}
-		return time.Time{}, fmt.Errorf("invalid time %s", s)
-	}
-	from, err := parse(fromStr)
+	from, err := parseTime(fromStr)
 	if err != nil {
 		return window.Window{}, err
 	}
-	to, err := parse(toStr)
+	to, err := parseTime(toStr)
 	if err != nil {
 		return window.Window{}, err
 	}
+	if !from.Before(to) {
+		return window.Window{}, fmt.Errorf("from must be before to")
+	}
 	return window.Window{From: from, To: to}, nil
 }
 
@@ -133,46 +196,6 @@ func handlePGWTopoEdges(c *gin.Context) {
 	c.Status(http.StatusOK)
 }
 
-func handleJobOOAgg(c *gin.Context) {
-	tenant := c.Query("tenant")
-	w := window.Window{}
-	if err := jobs.RunOOAgg(c.Request.Context(), tenant, w); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-	c.Status(http.StatusOK)
-}
-
-func handleJobAGERefresh(c *gin.Context) {
-	tenant := c.Query("tenant")
-	w := window.Window{}
-	if err := jobs.RunAGERefresh(c.Request.Context(), tenant, w); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-	c.Status(http.StatusOK)
-}
-
-func handleJobTopoIAC(c *gin.Context) {
-	tenant := c.Query("tenant")
-	w := window.Window{}
-	if err := jobs.RunTopoIAC(c.Request.Context(), tenant, w); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-	c.Status(http.StatusOK)
-}
-
-func handleJobTopoAnsible(c *gin.Context) {
-	tenant := c.Query("tenant")
-	w := window.Window{}
-	if err := jobs.RunTopoAnsible(c.Request.Context(), tenant, w); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-	c.Status(http.StatusOK)
-}
-
 func handleEventsEnqueue(c *gin.Context) {
 	body, _ := io.ReadAll(c.Request.Body)
 	if err := events.Enqueue(c.Request.Context(), body); err != nil {
@@ -182,12 +205,19 @@ func handleEventsEnqueue(c *gin.Context) {
 	c.Status(http.StatusOK)
 }
 
-func handleSchedulerTick(c *gin.Context) {
-	if err := scheduler.Tick(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+func (s *Server) handleSchedulerTick(c *gin.Context) {
+	if !s.requireQueue(c) {
 		return
 	}
-	c.Status(http.StatusOK)
+	runs, err := s.scheduler.Tick(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "enqueued": runs})
+		return
+	}
+	if runs == nil {
+		runs = []scheduler.Run{}
+	}
+	c.JSON(http.StatusOK, gin.H{"enqueued": runs})
 }
 
 func handleIACDiscover(c *gin.Context) {
@@ -210,10 +240,19 @@ func handleAnsibleExtract(c *gin.Context) {
 	c.JSON(http.StatusOK, edges)
 }
 
-// Run starts the HTTP server using the configured listen address.
+// Run starts the scheduler, the runner and the HTTP server using the configured listen
+// address.
 func (s *Server) Run() error {
 	if s.cfg == nil || s.cfg.Server.API.Listen == "" {
 		return fmt.Errorf("server listen address not configured")
 	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// Without a durable queue nothing would execute the scheduled runs,
+	// which would only pile up in memory.
+	if s.queue != nil {
+		go s.scheduler.Loop(ctx)
+		go s.runner.Loop(ctx)
+	}
 	return s.engine.Run(s.cfg.Server.API.Listen)
 }
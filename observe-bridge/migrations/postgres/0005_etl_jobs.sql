@@ -0,0 +1,37 @@
+-- ETL 任务队列：每个 (job, tenant, window) 只有一行，保证窗口只处理一次
+--   state: queued → running → done | failed
+--   lease_*: 执行者租约；租约过期的 running 任务会被重新领取（崩溃恢复）
+CREATE TABLE IF NOT EXISTS etl_job_run (
+  id               BIGSERIAL PRIMARY KEY,
+  job              TEXT NOT NULL,
+  tenant           TEXT NOT NULL,
+  window_from      TIMESTAMPTZ NOT NULL,
+  window_to        TIMESTAMPTZ NOT NULL,
+  state            TEXT NOT NULL DEFAULT 'queued'
+                   CHECK (state IN ('queued', 'running', 'done', 'failed')),
+  attempts         INT NOT NULL DEFAULT 0,
+  lease_owner      TEXT,
+  lease_expires_at TIMESTAMPTZ,
+  created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+  started_at       TIMESTAMPTZ,
+  finished_at      TIMESTAMPTZ,
+  CHECK (window_from < window_to)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS ux_job_once
+  ON etl_job_run (job, tenant, window_from, window_to);
+
+-- 领取队列：按 job 取最早的待处理窗口
+CREATE INDEX IF NOT EXISTS idx_job_run_pending
+  ON etl_job_run (job, window_from)
+  WHERE state IN ('queued', 'running');
+
+-- 调度水位：每个 job/租户已入队到的窗口上界
+CREATE TABLE IF NOT EXISTS etl_watermark (
+  job          TEXT NOT NULL,
+  tenant       TEXT NOT NULL,
+  scheduled_to TIMESTAMPTZ NOT NULL,
+  updated_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (job, tenant)
+);
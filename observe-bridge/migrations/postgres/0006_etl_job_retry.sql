@@ -0,0 +1,17 @@
+-- ETL 任务重试与死信
+--   run_after: 重试退避，未到时间的任务不会被领取
+--   last_error/error_stack: 最近一次失败的错误与堆栈
+--   state 新增 dead：可重试错误用尽次数后进入死信；failed 表示不可重试错误
+ALTER TABLE etl_job_run
+  ADD COLUMN IF NOT EXISTS run_after   TIMESTAMPTZ NOT NULL DEFAULT now(),
+  ADD COLUMN IF NOT EXISTS last_error  TEXT,
+  ADD COLUMN IF NOT EXISTS error_stack TEXT;
+
+ALTER TABLE etl_job_run DROP CONSTRAINT IF EXISTS etl_job_run_state_check;
+ALTER TABLE etl_job_run ADD CONSTRAINT etl_job_run_state_check
+  CHECK (state IN ('queued', 'running', 'done', 'failed', 'dead'));
+
+DROP INDEX IF EXISTS idx_job_run_pending;
+CREATE INDEX IF NOT EXISTS idx_job_run_pending
+  ON etl_job_run (job, run_after, window_from)
+  WHERE state IN ('queued', 'running');
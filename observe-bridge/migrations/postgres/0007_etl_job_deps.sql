@@ -0,0 +1,15 @@
+-- ETL 任务依赖：下游窗口等待上游覆盖同一窗口
+--   waiting: 上游尚未完成覆盖该窗口的任务，稍后重新检查
+--   blocked: 上游覆盖该窗口的任务失败（failed/dead），上游重试成功后自动解除
+ALTER TABLE etl_job_run DROP CONSTRAINT IF EXISTS etl_job_run_state_check;
+ALTER TABLE etl_job_run ADD CONSTRAINT etl_job_run_state_check
+  CHECK (state IN ('queued', 'waiting', 'blocked', 'running', 'done', 'failed', 'dead'));
+
+DROP INDEX IF EXISTS idx_job_run_pending;
+CREATE INDEX IF NOT EXISTS idx_job_run_pending
+  ON etl_job_run (job, run_after, window_from)
+  WHERE state IN ('queued', 'waiting', 'blocked', 'running');
+
+-- 按租户与时间范围查询任务（依赖检查与 /jobs/{name}/runs）
+CREATE INDEX IF NOT EXISTS idx_job_run_tenant_window
+  ON etl_job_run (job, tenant, window_from);
@@ -21,6 +21,8 @@ import (
 	"github.com/xscopehub/observe-gateway/internal/cache"
 	"github.com/xscopehub/observe-gateway/internal/config"
 	"github.com/xscopehub/observe-gateway/internal/limiter"
+	"github.com/xscopehub/observe-gateway/internal/queries"
+	"github.com/xscopehub/observe-gateway/internal/recording"
 	"github.com/xscopehub/observe-gateway/internal/server"
 )
 
@@ -79,7 +81,24 @@ func main() {
 
 	auditLogger := audit.New(cfg.Audit.Enabled, os.Stdout)
 
-	srv := server.New(cfg, authenticator, backendClient, cacheStore, limit, auditLogger)
+	queryStore, err := queries.New(ctx, cfg.Queries)
+	if err != nil {
+		log.Fatalf("init query store: %v", err)
+	}
+	if queryStore != nil {
+		defer queryStore.Close()
+		auditLogger.AddSink(queryStore)
+	}
+
+	rules, err := recording.New(cfg.Recording, backendClient)
+	if err != nil {
+		log.Fatalf("init recording rules: %v", err)
+	}
+	if rules != nil {
+		go rules.Run(ctx)
+	}
+
+	srv := server.New(cfg, authenticator, backendClient, cacheStore, limit, auditLogger, queryStore, rules)
 
 	log.Printf("query gateway listening on %s", cfg.Server.Address)
 	if err := srv.Run(ctx); err != nil {
@@ -14,12 +14,23 @@ type Entry struct {
 	User     string        `json:"user"`
 	Lang     string        `json:"lang"`
 	Query    string        `json:"query"`
+	Start    time.Time     `json:"start,omitzero"`
+	End      time.Time     `json:"end,omitzero"`
+	Step     string        `json:"step,omitempty"`
 	Cost     int64         `json:"cost"`
 	Duration time.Duration `json:"duration"`
 	Cached   bool          `json:"cached"`
 	Backend  string        `json:"backend"`
 	Error    string        `json:"error,omitempty"`
 	Time     time.Time     `json:"time"`
+	// Explain marks entries for plans, which do not run the query.
+	Explain bool `json:"explain,omitempty"`
+}
+
+// Sink receives every audit entry, independently of whether JSON output is
+// enabled. Implementations must not block.
+type Sink interface {
+	Record(Entry)
 }
 
 // Logger emits audit entries in JSON format.
@@ -27,6 +38,7 @@ type Logger struct {
 	enabled bool
 	mu      sync.Mutex
 	out     io.Writer
+	sinks   []Sink
 }
 
 // New creates a new audit logger writing to the provided writer.
@@ -37,12 +49,30 @@ func New(enabled bool, out io.Writer) *Logger {
 	return &Logger{enabled: enabled, out: out}
 }
 
-// Log writes an audit entry if enabled.
+// AddSink registers an additional consumer of audit entries.
+func (l *Logger) AddSink(sink Sink) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sinks = append(l.sinks, sink)
+}
+
+// Log writes an audit entry if enabled and forwards it to registered sinks.
 func (l *Logger) Log(entry Entry) {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	entry.Time = entry.Time.UTC()
+
+	l.mu.Lock()
+	sinks := l.sinks
+	l.mu.Unlock()
+	for _, sink := range sinks {
+		sink.Record(entry)
+	}
+
 	if !l.enabled {
 		return
 	}
-	entry.Time = entry.Time.UTC()
 	data, err := json.Marshal(entry)
 	if err != nil {
 		return
@@ -69,11 +69,17 @@ func (a *Authenticator) Enabled() bool {
 
 // Verify extracts tenant and user information from the request.
 func (a *Authenticator) Verify(r *http.Request) (tenant, user string, err error) {
+	return a.VerifyCredentials(r.Context(), r.Header.Get("Authorization"), r.Header.Get("X-Tenant"), r.Header.Get("X-User"))
+}
+
+// VerifyCredentials validates an Authorization header value and extracts the
+// tenant and user claims, falling back to the supplied tenant and user
+// headers. It lets non-HTTP transports such as gRPC share the same checks.
+func (a *Authenticator) VerifyCredentials(ctx context.Context, header, headerTenant, headerUser string) (tenant, user string, err error) {
 	if a == nil || !a.enabled {
-		return r.Header.Get("X-Tenant"), r.Header.Get("X-User"), nil
+		return headerTenant, headerUser, nil
 	}
 
-	header := r.Header.Get("Authorization")
 	if header == "" {
 		return "", "", errors.New("authorization header required")
 	}
@@ -86,7 +92,7 @@ func (a *Authenticator) Verify(r *http.Request) (tenant, user string, err error)
 		return "", "", errors.New("empty bearer token")
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	set, err := a.getKeySet(ctx)
@@ -116,10 +122,10 @@ func (a *Authenticator) Verify(r *http.Request) (tenant, user string, err error)
 	user = claimAsString(token, a.cfg.UserClaim, "sub")
 
 	if tenant == "" {
-		tenant = r.Header.Get("X-Tenant")
+		tenant = headerTenant
 	}
 	if user == "" {
-		user = r.Header.Get("X-User")
+		user = headerUser
 	}
 
 	return tenant, user, nil
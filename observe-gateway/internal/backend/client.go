@@ -10,7 +10,6 @@ import (
 	"net/http"
 	"net/url"
 	"path"
-	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -106,19 +105,7 @@ func (c *Client) QueryLogQL(ctx context.Context, tenant string, req query.Reques
 		return Result{}, err
 	}
 
-	body := map[string]any{
-		"sql":    sql,
-		"start":  req.Start,
-		"end":    req.End,
-		"tenant": tenant,
-	}
-
-	payload, err := json.Marshal(body)
-	if err != nil {
-		return Result{}, err
-	}
-
-	res, err := c.oo.postJSON(ctx, tenant, c.oo.logSearchURL(meta.Org), payload)
+	res, err := c.oo.searchSQL(ctx, tenant, c.oo.logSearchURL(meta.Org), sql, req.Start, req.End)
 	if err != nil {
 		return Result{}, err
 	}
@@ -139,19 +126,7 @@ func (c *Client) QueryTraceQL(ctx context.Context, tenant string, req query.Requ
 		return Result{}, err
 	}
 
-	body := map[string]any{
-		"sql":    sql,
-		"start":  req.Start,
-		"end":    req.End,
-		"tenant": tenant,
-	}
-
-	payload, err := json.Marshal(body)
-	if err != nil {
-		return Result{}, err
-	}
-
-	res, err := c.oo.postJSON(ctx, tenant, c.oo.traceSearchURL(meta.Org), payload)
+	res, err := c.oo.searchSQL(ctx, tenant, c.oo.traceSearchURL(meta.Org), sql, req.Start, req.End)
 	if err != nil {
 		return Result{}, err
 	}
@@ -210,6 +185,7 @@ type openObserveClient struct {
 	promRange   string
 	logSearch   string
 	traceSearch string
+	remoteWrite string
 }
 
 func newOpenObserveClient(cfg config.OpenObserveConfig) (*openObserveClient, error) {
@@ -236,6 +212,7 @@ func newOpenObserveClient(cfg config.OpenObserveConfig) (*openObserveClient, err
 		promRange:   cfg.PromRangeEndpoint,
 		logSearch:   cfg.LogSearchEndpoint,
 		traceSearch: cfg.TraceSearchEndpoint,
+		remoteWrite: cfg.RemoteWriteEndpoint,
 	}, nil
 }
 
@@ -273,6 +250,18 @@ func (c *openObserveClient) traceSearchURL(org string) string {
 	return c.resolve(endpoint)
 }
 
+func (c *openObserveClient) remoteWriteURL(org string) (string, error) {
+	endpoint := c.remoteWrite
+	if endpoint == "" {
+		return "", fmt.Errorf("remote write endpoint not configured")
+	}
+	resolvedOrg := c.resolveOrg(org)
+	if strings.Contains(endpoint, "%s") {
+		endpoint = fmt.Sprintf(endpoint, resolvedOrg)
+	}
+	return c.resolve(endpoint), nil
+}
+
 func (c *openObserveClient) resolveOrg(org string) string {
 	if org != "" {
 		return org
@@ -344,6 +333,23 @@ func (c *openObserveClient) queryPromQL(ctx context.Context, org, tenant string,
 	}, nil
 }
 
+// searchSQL runs a generated SQL statement against an OpenObserve search endpoint.
+func (c *openObserveClient) searchSQL(ctx context.Context, tenant, url, sql string, start, end time.Time) (Result, error) {
+	body := map[string]any{
+		"sql":    sql,
+		"start":  start,
+		"end":    end,
+		"tenant": tenant,
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return c.postJSON(ctx, tenant, url, payload)
+}
+
 func (c *openObserveClient) postJSON(ctx context.Context, tenant, url string, payload []byte) (Result, error) {
 	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
 	if err != nil {
@@ -496,141 +502,3 @@ func parseCost(h http.Header) int64 {
 	}
 	return cost
 }
-
-// --- Translators ---
-
-func translateLogQL(q, table string) (string, error) {
-	q = strings.TrimSpace(q)
-	if q == "" {
-		return "", fmt.Errorf("empty logql")
-	}
-
-	table = sanitizeSQLIdentifier(table)
-	if table == "" {
-		table = "logs"
-	}
-
-	var conditions []string
-	if strings.HasPrefix(q, "{") {
-		idx := strings.Index(q, "}")
-		if idx == -1 {
-			return "", fmt.Errorf("invalid logql selector")
-		}
-		selector := q[1:idx]
-		q = strings.TrimSpace(q[idx+1:])
-		if selector != "" {
-			parts := strings.Split(selector, ",")
-			for _, part := range parts {
-				part = strings.TrimSpace(part)
-				if part == "" {
-					continue
-				}
-				switch {
-				case strings.Contains(part, "!="):
-					kv := strings.SplitN(part, "!=", 2)
-					if len(kv) != 2 {
-						continue
-					}
-					conditions = append(conditions, fmt.Sprintf("labels->>'%s' <> '%s'", sanitizeSQLIdentifier(kv[0]), escapeSQLValue(kv[1])))
-				case strings.Contains(part, "="):
-					kv := strings.SplitN(part, "=", 2)
-					if len(kv) != 2 {
-						continue
-					}
-					conditions = append(conditions, fmt.Sprintf("labels->>'%s' = '%s'", sanitizeSQLIdentifier(kv[0]), escapeSQLValue(kv[1])))
-				}
-			}
-		}
-	}
-
-	matches := logPipelineRegex.FindAllStringSubmatch(q, -1)
-	for _, m := range matches {
-		op := m[1]
-		val := escapeSQLValue(m[2])
-		switch op {
-		case "=":
-			conditions = append(conditions, fmt.Sprintf("message ILIKE '%%%s%%'", val))
-		case "!=":
-			conditions = append(conditions, fmt.Sprintf("message NOT ILIKE '%%%s%%'", val))
-		case "~":
-			conditions = append(conditions, fmt.Sprintf("message ~ '%s'", val))
-		case "!~":
-			conditions = append(conditions, fmt.Sprintf("message !~ '%s'", val))
-		}
-	}
-
-	if len(conditions) == 0 {
-		conditions = append(conditions, "1=1")
-	}
-
-	return fmt.Sprintf("SELECT * FROM %s WHERE %s", table, strings.Join(conditions, " AND ")), nil
-}
-
-func translateTraceQL(q, table string) (string, error) {
-	q = strings.TrimSpace(q)
-	if q == "" {
-		return "", fmt.Errorf("empty traceql")
-	}
-
-	table = sanitizeSQLIdentifier(table)
-	if table == "" {
-		table = "traces"
-	}
-
-	lower := strings.ToLower(q)
-	if !strings.HasPrefix(lower, "from") {
-		return "", fmt.Errorf("traceql must start with FROM")
-	}
-
-	tokens := strings.Fields(q)
-	if len(tokens) < 2 {
-		return "", fmt.Errorf("traceql missing stream")
-	}
-	stream := tokens[1]
-	conditions := []string{fmt.Sprintf("trace_stream = '%s'", escapeSQLValue(stream))}
-
-	whereIdx := strings.Index(lower, " where ")
-	if whereIdx != -1 {
-		condExpr := strings.TrimSpace(q[whereIdx+7:])
-		if condExpr != "" {
-			parts := strings.Split(condExpr, " and ")
-			for _, part := range parts {
-				part = strings.TrimSpace(part)
-				if part == "" {
-					continue
-				}
-				switch {
-				case strings.Contains(part, "!="):
-					kv := strings.SplitN(part, "!=", 2)
-					conditions = append(conditions, fmt.Sprintf("attributes->>'%s' <> '%s'", sanitizeSQLIdentifier(kv[0]), escapeSQLValue(kv[1])))
-				case strings.Contains(part, "="):
-					kv := strings.SplitN(part, "=", 2)
-					conditions = append(conditions, fmt.Sprintf("attributes->>'%s' = '%s'", sanitizeSQLIdentifier(kv[0]), escapeSQLValue(kv[1])))
-				case strings.Contains(part, ">"):
-					kv := strings.SplitN(part, ">", 2)
-					conditions = append(conditions, fmt.Sprintf("%s > %s", sanitizeSQLIdentifier(kv[0]), strings.TrimSpace(kv[1])))
-				case strings.Contains(part, "<"):
-					kv := strings.SplitN(part, "<", 2)
-					conditions = append(conditions, fmt.Sprintf("%s < %s", sanitizeSQLIdentifier(kv[0]), strings.TrimSpace(kv[1])))
-				}
-			}
-		}
-	}
-
-	return fmt.Sprintf("SELECT * FROM %s WHERE %s", table, strings.Join(conditions, " AND ")), nil
-}
-
-func sanitizeSQLIdentifier(in string) string {
-	in = strings.TrimSpace(in)
-	in = strings.Trim(in, "\"`'")
-	in = strings.ReplaceAll(in, " ", "_")
-	return in
-}
-
-func escapeSQLValue(in string) string {
-	in = strings.TrimSpace(in)
-	in = strings.Trim(in, "\"`'")
-	return strings.ReplaceAll(in, "'", "''")
-}
-
-var logPipelineRegex = regexp.MustCompile(`\|([=!~]{1,2})\s*"([^"]*)"`)
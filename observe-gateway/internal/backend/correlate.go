@@ -0,0 +1,244 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xscopehub/observe-gateway/internal/query"
+)
+
+var (
+	// ErrTraceNotFound indicates no spans matched the requested trace ID.
+	ErrTraceNotFound = errors.New("trace not found")
+	// ErrInvalidTraceID indicates the trace ID is not 16 to 32 hex characters.
+	ErrInvalidTraceID = errors.New("trace_id must be 16 to 32 hex characters")
+)
+
+var traceIDRegex = regexp.MustCompile(`^[0-9A-Fa-f]{16,32}$`)
+
+// CorrelateOptions controls how a trace is expanded into logs and metrics.
+type CorrelateOptions struct {
+	// Start and End bound the search for the trace's spans.
+	Start time.Time
+	End   time.Time
+	// Padding widens the window derived from the spans before querying logs and metrics.
+	Padding time.Duration
+	// MaxServices caps how many services get RED metric queries.
+	MaxServices int
+	// REDQueries maps a metric name to a PromQL template containing ${service}.
+	REDQueries map[string]string
+}
+
+// Correlation bundles the trace together with the logs and RED metrics of the
+// services involved in it.
+type Correlation struct {
+	TraceID  string                                `json:"trace_id"`
+	Services []string                              `json:"services"`
+	Start    time.Time                             `json:"start"`
+	End      time.Time                             `json:"end"`
+	Trace    json.RawMessage                       `json:"trace"`
+	Logs     json.RawMessage                       `json:"logs,omitempty"`
+	Metrics  map[string]map[string]json.RawMessage `json:"metrics,omitempty"`
+	Errors   []string                              `json:"errors,omitempty"`
+	Cost     int64                                 `json:"-"`
+}
+
+// Correlate looks up the spans of traceID, derives the services and time
+// bounds involved and fetches logs carrying the same trace ID plus RED
+// metrics for those services in that window. Failures of the log or metric
+// lookups are reported in Correlation.Errors rather than failing the bundle.
+func (c *Client) Correlate(ctx context.Context, tenant, traceID string, opts CorrelateOptions) (Correlation, error) {
+	if !traceIDRegex.MatchString(traceID) {
+		return Correlation{}, ErrInvalidTraceID
+	}
+
+	meta, err := c.resolveTenantMetadata(ctx, tenant)
+	if err != nil {
+		return Correlation{}, err
+	}
+
+	traceSQL, err := newTraceIDQuery(meta.TraceTable, traceID)
+	if err != nil {
+		return Correlation{}, err
+	}
+	traceRes, err := c.oo.searchSQL(ctx, tenant, c.oo.traceSearchURL(meta.Org), traceSQL, opts.Start, opts.End)
+	if err != nil {
+		return Correlation{}, err
+	}
+
+	spans := extractHits(traceRes.Payload)
+	services, first, last := summarizeSpans(spans)
+	if len(spans) == 0 || first.IsZero() {
+		return Correlation{}, ErrTraceNotFound
+	}
+
+	out := Correlation{
+		TraceID:  traceID,
+		Services: services,
+		Start:    first.Add(-opts.Padding),
+		End:      last.Add(opts.Padding),
+		Trace:    traceRes.Payload,
+		Metrics:  map[string]map[string]json.RawMessage{},
+		Cost:     traceRes.Cost,
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	record := func(fn func()) {
+		mu.Lock()
+		defer mu.Unlock()
+		fn()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		logSQL, err := newTraceIDQuery(meta.LogTable, traceID)
+		if err == nil {
+			var res Result
+			res, err = c.oo.searchSQL(ctx, tenant, c.oo.logSearchURL(meta.Org), logSQL, out.Start, out.End)
+			if err == nil {
+				record(func() { out.Logs, out.Cost = res.Payload, out.Cost+res.Cost })
+				return
+			}
+		}
+		record(func() { out.Errors = append(out.Errors, fmt.Sprintf("logs: %v", err)) })
+	}()
+
+	metricServices := services
+	if opts.MaxServices > 0 && len(metricServices) > opts.MaxServices {
+		metricServices = metricServices[:opts.MaxServices]
+	}
+	step := correlationStep(out.End.Sub(out.Start))
+	for _, svc := range metricServices {
+		for name, tmpl := range opts.REDQueries {
+			if strings.TrimSpace(tmpl) == "" {
+				continue
+			}
+			wg.Add(1)
+			go func(svc, name, tmpl string) {
+				defer wg.Done()
+				req := query.Request{
+					Lang:  "promql",
+					Query: strings.ReplaceAll(tmpl, "${service}", escapePromLabelValue(svc)),
+					Start: out.Start,
+					End:   out.End,
+					Step:  step.String(),
+				}
+				res, err := c.QueryPromQL(ctx, tenant, req)
+				record(func() {
+					if err != nil {
+						out.Errors = append(out.Errors, fmt.Sprintf("metrics %s/%s: %v", svc, name, err))
+						return
+					}
+					if out.Metrics[svc] == nil {
+						out.Metrics[svc] = map[string]json.RawMessage{}
+					}
+					out.Metrics[svc][name] = res.Payload
+					out.Cost += res.Cost
+				})
+			}(svc, name, tmpl)
+		}
+	}
+	wg.Wait()
+
+	sort.Strings(out.Errors)
+	return out, nil
+}
+
+func newTraceIDQuery(table, traceID string) (string, error) {
+	sq := newSelectQuery(strings.TrimSpace(table))
+	sq.columnCompare("trace_id", sqlEq, quoteSQLString(traceID))
+	return sq.build()
+}
+
+// extractHits returns the rows of an OpenObserve search response.
+func extractHits(payload json.RawMessage) []map[string]any {
+	var body struct {
+		Hits []map[string]any `json:"hits"`
+	}
+	if err := json.Unmarshal(payload, &body); err != nil {
+		return nil
+	}
+	return body.Hits
+}
+
+// summarizeSpans returns the sorted distinct services and the earliest start
+// and latest end across spans.
+func summarizeSpans(spans []map[string]any) ([]string, time.Time, time.Time) {
+	seen := map[string]struct{}{}
+	var first, last time.Time
+	for _, span := range spans {
+		for _, key := range []string{"service_name", "service.name", "resource_service_name"} {
+			if v, ok := span[key].(string); ok && v != "" {
+				seen[v] = struct{}{}
+				break
+			}
+		}
+
+		start := spanTime(span, "start_time", "_timestamp")
+		end := spanTime(span, "end_time")
+		if end.IsZero() {
+			end = start
+		}
+		if !start.IsZero() && (first.IsZero() || start.Before(first)) {
+			first = start
+		}
+		if !end.IsZero() && end.After(last) {
+			last = end
+		}
+	}
+
+	services := make([]string, 0, len(seen))
+	for svc := range seen {
+		services = append(services, svc)
+	}
+	sort.Strings(services)
+	return services, first, last
+}
+
+// spanTime reads the first numeric timestamp found under keys, inferring the
+// unit from its magnitude since OpenObserve mixes nano- and microseconds.
+func spanTime(span map[string]any, keys ...string) time.Time {
+	for _, key := range keys {
+		v, ok := span[key].(float64)
+		if !ok || v <= 0 {
+			continue
+		}
+		switch {
+		case v > 1e17:
+			return time.Unix(0, int64(v)).UTC()
+		case v > 1e14:
+			return time.UnixMicro(int64(v)).UTC()
+		case v > 1e11:
+			return time.UnixMilli(int64(v)).UTC()
+		default:
+			return time.Unix(int64(v), 0).UTC()
+		}
+	}
+	return time.Time{}
+}
+
+// correlationStep picks a range query step that yields roughly 60 points.
+func correlationStep(window time.Duration) time.Duration {
+	step := (window / 60).Truncate(time.Second)
+	if step < 15*time.Second {
+		step = 15 * time.Second
+	}
+	return step
+}
+
+func escapePromLabelValue(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `"`, `\"`)
+	return strings.ReplaceAll(v, "\n", `\n`)
+}
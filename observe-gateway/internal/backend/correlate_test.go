@@ -0,0 +1,97 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xscopehub/observe-gateway/internal/config"
+)
+
+func TestCorrelate(t *testing.T) {
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	spanStart := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	var (
+		mu       sync.Mutex
+		logSQL   string
+		promQLs  []string
+		promStep string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/traces"):
+			json.NewEncoder(w).Encode(map[string]any{"hits": []map[string]any{
+				{"service_name": "frontend", "start_time": spanStart.UnixNano(), "end_time": spanStart.Add(2 * time.Second).UnixNano()},
+				{"service_name": "checkout", "start_time": spanStart.Add(time.Second).UnixNano(), "end_time": spanStart.Add(1500 * time.Millisecond).UnixNano()},
+			}})
+		case strings.HasSuffix(r.URL.Path, "/_search"):
+			var body map[string]any
+			json.NewDecoder(r.Body).Decode(&body)
+			mu.Lock()
+			logSQL, _ = body["sql"].(string)
+			mu.Unlock()
+			w.Write([]byte(`{"hits":[]}`))
+		case strings.Contains(r.URL.Path, "/promql/"):
+			mu.Lock()
+			promQLs = append(promQLs, r.URL.Query().Get("query"))
+			promStep = r.URL.Query().Get("step")
+			mu.Unlock()
+			w.Write([]byte(`{"status":"success"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	client, err := New(context.Background(), config.BackendConfig{OpenObserve: config.OpenObserveConfig{
+		BaseURL:             ts.URL,
+		Org:                 "default",
+		PromQueryEndpoint:   "/api/%s/promql/query",
+		PromRangeEndpoint:   "/api/%s/promql/query_range",
+		LogSearchEndpoint:   "/api/%s/_search",
+		TraceSearchEndpoint: "/api/%s/traces",
+	}})
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	bundle, err := client.Correlate(context.Background(), "acme", traceID, CorrelateOptions{
+		Start:      spanStart.Add(-time.Hour),
+		End:        spanStart.Add(time.Hour),
+		Padding:    time.Minute,
+		REDQueries: map[string]string{"rate": `sum(rate(calls_total{service_name="${service}"}[1m]))`},
+	})
+	if err != nil {
+		t.Fatalf("correlate: %v", err)
+	}
+
+	if got := strings.Join(bundle.Services, ","); got != "checkout,frontend" {
+		t.Fatalf("unexpected services %q", got)
+	}
+	if !bundle.Start.Equal(spanStart.Add(-time.Minute)) || !bundle.End.Equal(spanStart.Add(2*time.Second+time.Minute)) {
+		t.Fatalf("unexpected window %s - %s", bundle.Start, bundle.End)
+	}
+	if want := `SELECT * FROM logs WHERE trace_id = '` + traceID + `'`; logSQL != want {
+		t.Fatalf("unexpected log sql %q", logSQL)
+	}
+	if len(promQLs) != 2 || len(bundle.Metrics) != 2 || bundle.Metrics["checkout"]["rate"] == nil {
+		t.Fatalf("expected one RED query per service, got %v", promQLs)
+	}
+	if promStep != "15" {
+		t.Fatalf("unexpected step %q", promStep)
+	}
+	if len(bundle.Errors) != 0 {
+		t.Fatalf("unexpected errors %v", bundle.Errors)
+	}
+
+	if _, err := client.Correlate(context.Background(), "acme", "x' OR 1=1", CorrelateOptions{}); !errors.Is(err, ErrInvalidTraceID) {
+		t.Fatalf("expected invalid trace id error, got %v", err)
+	}
+}
@@ -0,0 +1,44 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/xscopehub/observe-gateway/internal/query"
+)
+
+// Plan describes how a query would be sent upstream.
+type Plan struct {
+	Backend   string
+	Statement string
+	Org       string
+	Table     string
+}
+
+// Explain resolves tenant metadata and translates the query without
+// contacting OpenObserve.
+func (c *Client) Explain(ctx context.Context, tenant string, req query.Request) (Plan, error) {
+	meta, err := c.resolveTenantMetadata(ctx, tenant)
+	if err != nil {
+		return Plan{}, err
+	}
+
+	switch req.Lang {
+	case "promql":
+		return Plan{Backend: "openobserve-promql", Statement: req.Query, Org: meta.Org}, nil
+	case "logql":
+		sql, err := translateLogQL(req.Query, meta.LogTable)
+		if err != nil {
+			return Plan{}, err
+		}
+		return Plan{Backend: "openobserve-logsql", Statement: sql, Org: meta.Org, Table: meta.LogTable}, nil
+	case "traceql":
+		sql, err := translateTraceQL(req.Query, meta.TraceTable)
+		if err != nil {
+			return Plan{}, err
+		}
+		return Plan{Backend: "openobserve-tracesql", Statement: sql, Org: meta.Org, Table: meta.TraceTable}, nil
+	default:
+		return Plan{}, fmt.Errorf("unsupported language: %s", req.Lang)
+	}
+}
@@ -0,0 +1,137 @@
+package backend
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// sqlOp enumerates the comparison operators the translators may emit. Only
+// values declared here ever reach the generated SQL.
+type sqlOp string
+
+const (
+	sqlEq       sqlOp = "="
+	sqlNeq      sqlOp = "<>"
+	sqlGt       sqlOp = ">"
+	sqlGte      sqlOp = ">="
+	sqlLt       sqlOp = "<"
+	sqlLte      sqlOp = "<="
+	sqlILike    sqlOp = "ILIKE"
+	sqlNotILike sqlOp = "NOT ILIKE"
+	sqlRegex    sqlOp = "~"
+	sqlNotRegex sqlOp = "!~"
+)
+
+var (
+	// sqlIdentRegex allowlists bare column and table identifiers.
+	sqlIdentRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	// sqlFieldKeyRegex allowlists keys looked up inside JSON columns, which
+	// may carry dotted OpenTelemetry attribute names such as service.name.
+	sqlFieldKeyRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+	// sqlNumberRegex matches numeric literals that may be emitted unquoted.
+	sqlNumberRegex = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+)
+
+// sqlIdent is an identifier that passed the allowlist.
+type sqlIdent string
+
+// sqlLiteral is a rendered SQL literal, either quoted text or a number.
+type sqlLiteral string
+
+func newSQLIdent(name string) (sqlIdent, error) {
+	if !sqlIdentRegex.MatchString(name) {
+		return "", fmt.Errorf("invalid identifier %q", name)
+	}
+	return sqlIdent(name), nil
+}
+
+// quoteSQLString renders a single-quoted literal. OpenObserve (DataFusion)
+// follows standard SQL: only quotes are doubled and backslashes are taken
+// literally, so regexes such as \d and Windows paths pass through intact.
+func quoteSQLString(v string) sqlLiteral {
+	return sqlLiteral("'" + strings.ReplaceAll(v, "'", "''") + "'")
+}
+
+// likeEscaper escapes the LIKE wildcards and the escape character itself.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// likeContains renders a LIKE pattern matching text anywhere in a value.
+// Wildcards in text are matched literally; the pattern must be used with
+// ESCAPE '\', which columnLike adds.
+func likeContains(text string) sqlLiteral {
+	return quoteSQLString("%" + likeEscaper.Replace(text) + "%")
+}
+
+// sqlValue renders numbers unquoted and everything else as a string literal.
+func sqlValue(v string) sqlLiteral {
+	if sqlNumberRegex.MatchString(v) {
+		return sqlLiteral(v)
+	}
+	return quoteSQLString(v)
+}
+
+// selectQuery builds a single-table SELECT with AND-joined predicates. The
+// first invalid input is remembered and reported by build.
+type selectQuery struct {
+	table sqlIdent
+	preds []string
+	err   error
+}
+
+func newSelectQuery(table string) *selectQuery {
+	q := &selectQuery{}
+	q.table, q.err = newSQLIdent(table)
+	return q
+}
+
+// fieldCompare adds `column->>'key' op value` for JSON-typed columns such as
+// labels and attributes.
+func (q *selectQuery) fieldCompare(column sqlIdent, key string, op sqlOp, value sqlLiteral) {
+	if q.err != nil {
+		return
+	}
+	if !sqlFieldKeyRegex.MatchString(key) {
+		q.err = fmt.Errorf("invalid field name %q", key)
+		return
+	}
+	q.preds = append(q.preds, fmt.Sprintf("%s->>%s %s %s", column, quoteSQLString(key), op, value))
+}
+
+// columnCompare adds `column op value` for a top-level column.
+func (q *selectQuery) columnCompare(column string, op sqlOp, value sqlLiteral) {
+	if q.err != nil {
+		return
+	}
+	ident, err := newSQLIdent(column)
+	if err != nil {
+		q.err = err
+		return
+	}
+	q.preds = append(q.preds, fmt.Sprintf("%s %s %s", ident, op, value))
+}
+
+// columnLike adds `column op pattern ESCAPE '\'` for ILIKE and NOT ILIKE
+// with a pattern from likeContains.
+func (q *selectQuery) columnLike(column string, op sqlOp, pattern sqlLiteral) {
+	if q.err != nil {
+		return
+	}
+	ident, err := newSQLIdent(column)
+	if err != nil {
+		q.err = err
+		return
+	}
+	q.preds = append(q.preds, fmt.Sprintf(`%s %s %s ESCAPE '\'`, ident, op, pattern))
+}
+
+func (q *selectQuery) build() (string, error) {
+	if q.err != nil {
+		return "", q.err
+	}
+	where := "1=1"
+	if len(q.preds) > 0 {
+		where = strings.Join(q.preds, " AND ")
+	}
+	return fmt.Sprintf("SELECT * FROM %s WHERE %s", q.table, where), nil
+}
@@ -0,0 +1,228 @@
+package backend
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	logMatcherRegex  = regexp.MustCompile(`^\s*([^\s=!~,{}]+)\s*(=~|!~|!=|=)\s*("(?:[^"\\]|\\.)*"|` + "`[^`]*`" + `|'[^']*'|[^,}\s]+)\s*`)
+	logPipelineRegex = regexp.MustCompile(`(\|=|\|?!=|\|~|\|?!~)\s*("(?:[^"\\]|\\.)*"|` + "`[^`]*`" + `)`)
+	traceCondRegex   = regexp.MustCompile(`^([^\s=!<>]+)\s*(!=|>=|<=|=|>|<)\s*(.*)$`)
+	traceWhereRegex  = regexp.MustCompile(`\s(?i:where)\s`)
+	traceAndRegex    = regexp.MustCompile(`^\s+(?i:and)\s+`)
+)
+
+// logMatcher is a single label matcher from a LogQL stream selector.
+type logMatcher struct {
+	name  string
+	op    sqlOp
+	value string
+}
+
+func translateLogQL(q, table string) (string, error) {
+	q = strings.TrimSpace(q)
+	if q == "" {
+		return "", fmt.Errorf("empty logql")
+	}
+
+	table = strings.TrimSpace(table)
+	if table == "" {
+		table = "logs"
+	}
+	sq := newSelectQuery(table)
+
+	if strings.HasPrefix(q, "{") {
+		matchers, rest, err := parseLogSelector(q)
+		if err != nil {
+			return "", err
+		}
+		for _, m := range matchers {
+			value := m.value
+			if m.op == sqlRegex || m.op == sqlNotRegex {
+				// LogQL regex matchers are fully anchored.
+				value = "^(?:" + value + ")$"
+			}
+			sq.fieldCompare("labels", m.name, m.op, quoteSQLString(value))
+		}
+		q = rest
+	}
+
+	for _, m := range logPipelineRegex.FindAllStringSubmatch(q, -1) {
+		val, err := unquoteQueryValue(m[2])
+		if err != nil {
+			return "", fmt.Errorf("invalid logql line filter %s: %w", m[0], err)
+		}
+		switch strings.TrimPrefix(m[1], "|") {
+		case "=":
+			sq.columnLike("message", sqlILike, likeContains(val))
+		case "!=":
+			sq.columnLike("message", sqlNotILike, likeContains(val))
+		case "~":
+			sq.columnCompare("message", sqlRegex, quoteSQLString(val))
+		case "!~":
+			sq.columnCompare("message", sqlNotRegex, quoteSQLString(val))
+		}
+	}
+
+	return sq.build()
+}
+
+// parseLogSelector parses the leading `{...}` stream selector and returns the
+// matchers together with the remainder of the query.
+func parseLogSelector(q string) ([]logMatcher, string, error) {
+	rest := q[1:]
+	var matchers []logMatcher
+	for {
+		rest = strings.TrimLeft(rest, " \t\n")
+		if strings.HasPrefix(rest, "}") {
+			return matchers, strings.TrimSpace(rest[1:]), nil
+		}
+		m := logMatcherRegex.FindStringSubmatch(rest)
+		if m == nil {
+			return nil, "", fmt.Errorf("invalid logql selector")
+		}
+		rest = rest[len(m[0]):]
+
+		value, err := unquoteQueryValue(m[3])
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid logql matcher %s: %w", strings.TrimSpace(m[0]), err)
+		}
+		matcher := logMatcher{name: m[1], value: value}
+		switch m[2] {
+		case "=":
+			matcher.op = sqlEq
+		case "!=":
+			matcher.op = sqlNeq
+		case "=~":
+			matcher.op = sqlRegex
+		case "!~":
+			matcher.op = sqlNotRegex
+		}
+		matchers = append(matchers, matcher)
+
+		if strings.HasPrefix(rest, ",") {
+			rest = rest[1:]
+		} else if !strings.HasPrefix(rest, "}") {
+			return nil, "", fmt.Errorf("invalid logql selector")
+		}
+	}
+}
+
+func translateTraceQL(q, table string) (string, error) {
+	q = strings.TrimSpace(q)
+	if q == "" {
+		return "", fmt.Errorf("empty traceql")
+	}
+
+	table = strings.TrimSpace(table)
+	if table == "" {
+		table = "traces"
+	}
+
+	if len(q) < 4 || !strings.EqualFold(q[:4], "from") {
+		return "", fmt.Errorf("traceql must start with FROM")
+	}
+
+	tokens := strings.Fields(q)
+	if len(tokens) < 2 {
+		return "", fmt.Errorf("traceql missing stream")
+	}
+	stream, err := unquoteQueryValue(tokens[1])
+	if err != nil {
+		return "", fmt.Errorf("invalid traceql stream: %w", err)
+	}
+
+	sq := newSelectQuery(table)
+	sq.columnCompare("trace_stream", sqlEq, quoteSQLString(stream))
+
+	// Locate WHERE on the original string; lower-casing can change byte
+	// offsets when the query is not valid UTF-8.
+	if loc := traceWhereRegex.FindStringIndex(q); loc != nil {
+		for _, part := range splitConjunction(q[loc[1]:]) {
+			m := traceCondRegex.FindStringSubmatch(part)
+			if m == nil {
+				return "", fmt.Errorf("invalid traceql condition %q", part)
+			}
+			field, raw := m[1], strings.TrimSpace(m[3])
+			value, err := unquoteQueryValue(raw)
+			if err != nil {
+				return "", fmt.Errorf("invalid traceql value %q: %w", raw, err)
+			}
+			switch m[2] {
+			case "=":
+				sq.fieldCompare("attributes", field, sqlEq, quoteSQLString(value))
+			case "!=":
+				sq.fieldCompare("attributes", field, sqlNeq, quoteSQLString(value))
+			default:
+				literal := sqlValue(value)
+				if value != raw {
+					// Explicitly quoted operands always compare as text.
+					literal = quoteSQLString(value)
+				}
+				sq.columnCompare(field, sqlOp(m[2]), literal)
+			}
+		}
+	}
+
+	return sq.build()
+}
+
+// splitConjunction splits a condition list on AND (case-insensitive) while
+// leaving quoted operands intact.
+func splitConjunction(expr string) []string {
+	var (
+		parts []string
+		quote byte
+		start int
+	)
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+		case c == ' ' || c == '\t' || c == '\n':
+			if loc := traceAndRegex.FindStringIndex(expr[i:]); loc != nil {
+				parts = append(parts, expr[start:i])
+				start = i + loc[1]
+				i = start - 1
+			}
+		}
+	}
+	parts = append(parts, expr[start:])
+
+	out := parts[:0]
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// unquoteQueryValue strips Go-style double or backtick quoting as used by
+// LogQL, or plain single quotes. Bare values are returned trimmed.
+func unquoteQueryValue(v string) (string, error) {
+	v = strings.TrimSpace(v)
+	if len(v) < 2 {
+		return v, nil
+	}
+	switch v[0] {
+	case '"', '`':
+		return strconv.Unquote(v)
+	case '\'':
+		if v[len(v)-1] != '\'' {
+			return "", fmt.Errorf("unterminated quote")
+		}
+		return v[1 : len(v)-1], nil
+	}
+	return v, nil
+}
@@ -0,0 +1,224 @@
+package backend
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestTranslateLogQL(t *testing.T) {
+	cases := []struct {
+		name  string
+		query string
+		table string
+		want  string
+		err   bool
+	}{
+		{
+			name:  "selector and line filter",
+			query: `{app="api", env!="dev"} |= "timeout"`,
+			table: "logs",
+			want:  `SELECT * FROM logs WHERE labels->>'app' = 'api' AND labels->>'env' <> 'dev' AND message ILIKE '%timeout%' ESCAPE '\'`,
+		},
+		{
+			name:  "regex matcher is anchored",
+			query: `{app=~"api|web"} !~ "health"`,
+			table: "logs",
+			want:  `SELECT * FROM logs WHERE labels->>'app' ~ '^(?:api|web)$' AND message !~ 'health'`,
+		},
+		{
+			name:  "quotes in values are escaped",
+			query: `{app="o'brien"} |= "it's"`,
+			table: "logs",
+			want:  `SELECT * FROM logs WHERE labels->>'app' = 'o''brien' AND message ILIKE '%it''s%' ESCAPE '\'`,
+		},
+		{
+			name:  "backslashes are literal",
+			query: `{path="C:\\logs"} |~ "\\d+ms"`,
+			table: "logs",
+			want:  `SELECT * FROM logs WHERE labels->>'path' = 'C:\logs' AND message ~ '\d+ms'`,
+		},
+		{
+			name:  "like wildcards in line filters are escaped",
+			query: `{app="api"} != "50%_off\\"`,
+			table: "logs",
+			want:  `SELECT * FROM logs WHERE labels->>'app' = 'api' AND message NOT ILIKE '%50\%\_off\\%' ESCAPE '\'`,
+		},
+		{
+			name:  "label name outside allowlist",
+			query: `{a'||(select 1)||'="x"}`,
+			table: "logs",
+			err:   true,
+		},
+		{
+			name:  "table name outside allowlist",
+			query: `{app="api"}`,
+			table: "logs; drop table logs",
+			err:   true,
+		},
+		{
+			name:  "empty selector",
+			query: `{}`,
+			table: "",
+			want:  `SELECT * FROM logs WHERE 1=1`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := translateLogQL(tc.query, tc.table)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("unexpected sql\n got: %s\nwant: %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTranslateTraceQL(t *testing.T) {
+	cases := []struct {
+		name  string
+		query string
+		want  string
+		err   bool
+	}{
+		{
+			name:  "attributes and comparisons",
+			query: `FROM default WHERE service.name = "checkout" AND duration >= 500`,
+			want:  `SELECT * FROM traces WHERE trace_stream = 'default' AND attributes->>'service.name' = 'checkout' AND duration >= 500`,
+		},
+		{
+			name:  "non numeric comparison operand is quoted",
+			query: `FROM default WHERE duration > 1 OR 1=1`,
+			want:  `SELECT * FROM traces WHERE trace_stream = 'default' AND duration > '1 OR 1=1'`,
+		},
+		{
+			name:  "column outside allowlist",
+			query: `FROM default WHERE (select 1) > 1`,
+			err:   true,
+		},
+		{
+			name:  "and inside quoted value",
+			query: `FROM default where http.route = "/a and b" and status_code != '500'`,
+			want:  `SELECT * FROM traces WHERE trace_stream = 'default' AND attributes->>'http.route' = '/a and b' AND attributes->>'status_code' <> '500'`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := translateTraceQL(tc.query, "traces")
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("unexpected sql\n got: %s\nwant: %s", got, tc.want)
+			}
+		})
+	}
+}
+
+// injectionCorpus seeds the fuzzers with attempts to escape the WHERE clause.
+var injectionCorpus = []string{
+	`{a'||(select password from users)||'="x"}`,
+	`{app="x' OR '1'='1"}`,
+	`{app="x\\' OR 1=1 --"}`,
+	`{app="x"} |= "' UNION SELECT * FROM secrets --"`,
+	`{app="x"} |~ "\\\\' OR 1=1; --"`,
+	"{app=`x' or ''='`} != `a\\`",
+	`{app=x'--}`,
+	`{"app"="x"}`,
+	`FROM default WHERE duration > 1; DROP TABLE traces`,
+	`FROM default WHERE a' = 'b`,
+	`FROM default WHERE duration < '1' OR '1'='1'`,
+	`FROM x'-- WHERE a = b`,
+	`FROM default WHERE service.name = "x\" OR 1=1 --"`,
+	`FROM default WHERE k = 'v\' AND 1=1 --'`,
+	"FROM default WHERE duration > 1\nAND\n1=1",
+}
+
+var (
+	skeletonPred  = `(?:(?:labels|attributes)->>\? (?:=|<>|~|!~) \?|[A-Za-z_][A-Za-z0-9_]* (?:NOT )?ILIKE \? ESCAPE \?|[A-Za-z_][A-Za-z0-9_]* (?:=|<>|>=|>|<=|<|~|!~) (?:\?|-?[0-9]+(?:\.[0-9]+)?(?:[eE][-+]?[0-9]+)?))`
+	skeletonRegex = regexp.MustCompile(`^SELECT \* FROM [A-Za-z_][A-Za-z0-9_]* WHERE (?:1=1|` + skeletonPred + `(?: AND ` + skeletonPred + `)*)$`)
+)
+
+func FuzzTranslateLogQL(f *testing.F) {
+	for _, seed := range injectionCorpus {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, q string) {
+		sql, err := translateLogQL(q, "logs")
+		if err != nil {
+			return
+		}
+		assertWhereContained(t, q, sql)
+	})
+}
+
+func FuzzTranslateTraceQL(f *testing.F) {
+	for _, seed := range injectionCorpus {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, q string) {
+		sql, err := translateTraceQL(q, "traces")
+		if err != nil {
+			return
+		}
+		assertWhereContained(t, q, sql)
+	})
+}
+
+// assertWhereContained checks that every literal in sql terminates where it
+// should under standard SQL string rules, which OpenObserve follows, and that
+// the SQL left after blanking literals only contains the predicate shapes the
+// translators are allowed to produce.
+func assertWhereContained(t *testing.T, q, sql string) {
+	t.Helper()
+	standard, ok := sqlSkeleton(sql)
+	if !ok {
+		t.Fatalf("unterminated literal for %q: %s", q, sql)
+	}
+	if !skeletonRegex.MatchString(standard) {
+		t.Fatalf("unexpected sql structure for %q: %s", q, sql)
+	}
+}
+
+// sqlSkeleton replaces each single-quoted literal with '?'.
+func sqlSkeleton(sql string) (string, bool) {
+	var b strings.Builder
+	for i := 0; i < len(sql); i++ {
+		if sql[i] != '\'' {
+			b.WriteByte(sql[i])
+			continue
+		}
+		closed := false
+		for i++; i < len(sql); i++ {
+			if sql[i] == '\'' {
+				if i+1 < len(sql) && sql[i+1] == '\'' {
+					i++
+					continue
+				}
+				closed = true
+				break
+			}
+		}
+		if !closed {
+			return "", false
+		}
+		b.WriteByte('?')
+	}
+	return b.String(), true
+}
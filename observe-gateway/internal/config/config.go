@@ -16,6 +16,9 @@ type Config struct {
 	Cache       CacheConfig       `yaml:"cache"`
 	Audit       AuditConfig       `yaml:"audit"`
 	Backends    BackendConfig     `yaml:"backends"`
+	Queries     QueriesConfig     `yaml:"queries"`
+	Correlate   CorrelateConfig   `yaml:"correlate"`
+	Recording   RecordingConfig   `yaml:"recording_rules"`
 }
 
 // ServerConfig controls HTTP server settings.
@@ -24,6 +27,10 @@ type ServerConfig struct {
 	ReadTimeout  time.Duration `yaml:"read_timeout"`
 	WriteTimeout time.Duration `yaml:"write_timeout"`
 	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+	// GRPCAddress enables the gRPC query API when set.
+	GRPCAddress string `yaml:"grpc_address"`
+	// FlightEnabled additionally serves Arrow Flight on the gRPC listener.
+	FlightEnabled bool `yaml:"flight_enabled"`
 }
 
 // AuthConfig configures JWT based authentication.
@@ -84,6 +91,7 @@ type OpenObserveConfig struct {
 	PromRangeEndpoint   string        `yaml:"prom_range_endpoint"`
 	LogSearchEndpoint   string        `yaml:"log_search_endpoint"`
 	TraceSearchEndpoint string        `yaml:"trace_search_endpoint"`
+	RemoteWriteEndpoint string        `yaml:"remote_write_endpoint"`
 	LogTable            string        `yaml:"log_table"`
 	TraceTable          string        `yaml:"trace_table"`
 }
@@ -107,6 +115,42 @@ type MetadataConfig struct {
 	TenantLookupQuery string        `yaml:"tenant_lookup_query"`
 }
 
+// QueriesConfig configures PostgreSQL storage for query history and saved queries.
+type QueriesConfig struct {
+	Enabled          bool          `yaml:"enabled"`
+	DSN              string        `yaml:"dsn"`
+	MaxConnections   int32         `yaml:"max_connections"`
+	MaxConnIdleTime  time.Duration `yaml:"max_conn_idle_time"`
+	HistoryLimit     int           `yaml:"history_limit"`
+	HistoryRetention time.Duration `yaml:"history_retention"`
+}
+
+// CorrelateConfig tunes the trace to logs and metrics correlation endpoint.
+type CorrelateConfig struct {
+	Lookback    time.Duration     `yaml:"lookback"`
+	Padding     time.Duration     `yaml:"padding"`
+	MaxServices int               `yaml:"max_services"`
+	REDQueries  map[string]string `yaml:"red_queries"`
+}
+
+// RecordingConfig configures gateway-side recording rules.
+type RecordingConfig struct {
+	Enabled  bool            `yaml:"enabled"`
+	Mode     string          `yaml:"mode"`
+	Interval time.Duration   `yaml:"interval"`
+	Tenants  []string        `yaml:"tenants"`
+	Rules    []RecordingRule `yaml:"rules"`
+}
+
+// RecordingRule precomputes a PromQL expression under a new metric name.
+type RecordingRule struct {
+	Record   string            `yaml:"record"`
+	Expr     string            `yaml:"expr"`
+	Interval time.Duration     `yaml:"interval"`
+	Tenants  []string          `yaml:"tenants"`
+	Labels   map[string]string `yaml:"labels"`
+}
+
 // Load reads configuration from the supplied path or returns defaults.
 func Load(path string) (Config, error) {
 	cfg := defaultConfig()
@@ -167,9 +211,30 @@ func defaultConfig() Config {
 				PromRangeEndpoint:   "/api/%s/promql/query_range",
 				LogSearchEndpoint:   "/api/%s/_search",
 				TraceSearchEndpoint: "/api/%s/traces",
+				RemoteWriteEndpoint: "/api/%s/prometheus/api/v1/write",
 				LogTable:            "logs",
 				TraceTable:          "traces",
 			},
 		},
+		Queries: QueriesConfig{
+			Enabled:          false,
+			HistoryLimit:     100,
+			HistoryRetention: 30 * 24 * time.Hour,
+		},
+		Correlate: CorrelateConfig{
+			Lookback:    24 * time.Hour,
+			Padding:     5 * time.Minute,
+			MaxServices: 10,
+			REDQueries: map[string]string{
+				"rate":     `sum(rate(calls_total{service_name="${service}"}[1m]))`,
+				"errors":   `sum(rate(calls_total{service_name="${service}",status_code="STATUS_CODE_ERROR"}[1m]))`,
+				"duration": `histogram_quantile(0.95, sum(rate(duration_milliseconds_bucket{service_name="${service}"}[1m])) by (le))`,
+			},
+		},
+		Recording: RecordingConfig{
+			Enabled:  false,
+			Mode:     "remote_write",
+			Interval: time.Minute,
+		},
 	}
 }
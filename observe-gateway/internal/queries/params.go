@@ -0,0 +1,225 @@
+package queries
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	// placeholderRegex matches ${name} variables inside saved query text.
+	placeholderRegex = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+	paramNameRegex   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// Parameter types. A string value is substituted into a quoted string in the
+// query and escaped for it; the other types may appear outside strings
+// because their values are restricted to a single token.
+const (
+	TypeString     = "string"
+	TypeDuration   = "duration"
+	TypeNumber     = "number"
+	TypeIdentifier = "identifier"
+)
+
+// typeRegexes restricts the values of the unquoted parameter types.
+var typeRegexes = map[string]*regexp.Regexp{
+	TypeDuration:   regexp.MustCompile(`^([0-9]+(ms|[smhdwy]))+$`),
+	TypeNumber:     regexp.MustCompile(`^[-+]?[0-9]+(\.[0-9]+)?([eE][-+]?[0-9]+)?$`),
+	TypeIdentifier: regexp.MustCompile(`^[A-Za-z_:][A-Za-z0-9_:]*$`),
+}
+
+// Parameter declares a variable that can be substituted into a saved query.
+type Parameter struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Type is one of the Type constants; empty means TypeString.
+	Type string `json:"type,omitempty"`
+	// Pattern, when set, is a regular expression every value must match in
+	// full.
+	Pattern  string `json:"pattern,omitempty"`
+	Default  string `json:"default,omitempty"`
+	Required bool   `json:"required,omitempty"`
+}
+
+func (p Parameter) typ() string {
+	if p.Type == "" {
+		return TypeString
+	}
+	return p.Type
+}
+
+// check reports whether v is an acceptable value of p.
+func (p Parameter) check(v string) error {
+	if re, ok := typeRegexes[p.typ()]; ok && !re.MatchString(v) {
+		return fmt.Errorf("%w: parameter %q must be a %s", ErrInvalid, p.Name, p.typ())
+	}
+	if p.Pattern != "" {
+		re, err := regexp.Compile(`^(?:` + p.Pattern + `)$`)
+		if err != nil {
+			return fmt.Errorf("%w: parameter %q: invalid pattern: %v", ErrInvalid, p.Name, err)
+		}
+		if !re.MatchString(v) {
+			return fmt.Errorf("%w: parameter %q does not match %s", ErrInvalid, p.Name, p.Pattern)
+		}
+	}
+	return nil
+}
+
+// validateParameters checks that every placeholder in text is declared and
+// that declarations are well formed.
+func validateParameters(text string, params []Parameter) error {
+	declared := make(map[string]Parameter, len(params))
+	for _, p := range params {
+		if !paramNameRegex.MatchString(p.Name) {
+			return fmt.Errorf("%w: invalid parameter name %q", ErrInvalid, p.Name)
+		}
+		if _, dup := declared[p.Name]; dup {
+			return fmt.Errorf("%w: duplicate parameter %q", ErrInvalid, p.Name)
+		}
+		if _, ok := typeRegexes[p.typ()]; !ok && p.typ() != TypeString {
+			return fmt.Errorf("%w: parameter %q has unknown type %q", ErrInvalid, p.Name, p.Type)
+		}
+		if _, err := regexp.Compile(p.Pattern); err != nil {
+			return fmt.Errorf("%w: parameter %q: invalid pattern: %v", ErrInvalid, p.Name, err)
+		}
+		if p.Default != "" {
+			if err := p.check(p.Default); err != nil {
+				return err
+			}
+		}
+		declared[p.Name] = p
+	}
+	for _, ph := range placeholders(text) {
+		p, ok := declared[ph.name]
+		if !ok {
+			return fmt.Errorf("%w: undeclared parameter %q", ErrInvalid, ph.name)
+		}
+		if p.typ() == TypeString && ph.quote == 0 {
+			return fmt.Errorf("%w: string parameter %q must be inside a quoted string", ErrInvalid, ph.name)
+		}
+	}
+	return nil
+}
+
+// Render substitutes values into the query text. Values fall back to the
+// declared default; required parameters without either are rejected, as are
+// values for parameters that were never declared and values that do not fit
+// their parameter's type and pattern. String values are escaped for the
+// quoted string they are placed in, so no value can change the query's
+// structure.
+func Render(text string, params []Parameter, values map[string]string) (string, error) {
+	byName := make(map[string]Parameter, len(params))
+	for _, p := range params {
+		byName[p.Name] = p
+	}
+
+	var unknown []string
+	for name := range values {
+		if _, ok := byName[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return "", fmt.Errorf("%w: unknown parameters %s", ErrInvalid, strings.Join(unknown, ", "))
+	}
+
+	resolved := make(map[string]string, len(params))
+	for _, p := range params {
+		v, ok := values[p.Name]
+		if !ok || v == "" {
+			v = p.Default
+		}
+		if v == "" && p.Required {
+			return "", fmt.Errorf("%w: parameter %q is required", ErrInvalid, p.Name)
+		}
+		if v != "" {
+			if err := p.check(v); err != nil {
+				return "", err
+			}
+		}
+		resolved[p.Name] = v
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, ph := range placeholders(text) {
+		p := byName[ph.name]
+		v := resolved[ph.name]
+		if p.typ() == TypeString {
+			// Queries saved before parameters were typed may still place
+			// a string outside quotes.
+			if ph.quote == 0 {
+				return "", fmt.Errorf("%w: string parameter %q must be inside a quoted string", ErrInvalid, ph.name)
+			}
+			v = escape(v, ph.quote)
+		}
+		b.WriteString(text[last:ph.start])
+		b.WriteString(v)
+		last = ph.end
+	}
+	b.WriteString(text[last:])
+	return b.String(), nil
+}
+
+// placeholder is one ${name} in a query and the quote of the string it is
+// in, or zero outside strings and in raw `...` strings.
+type placeholder struct {
+	name       string
+	start, end int
+	quote      byte
+}
+
+// placeholders finds the ${name} variables in text. PromQL, LogQL and
+// TraceQL share Go's string syntax: "..." and '...' strings with backslash
+// escapes, and raw `...` strings without.
+func placeholders(text string) []placeholder {
+	locs := placeholderRegex.FindAllStringSubmatchIndex(text, -1)
+	out := make([]placeholder, 0, len(locs))
+	var quote byte
+	k := 0
+	for i := 0; i < len(text) && k < len(locs); i++ {
+		// i passes the start only when the placeholder follows a
+		// backslash, which would escape the first character of its value.
+		if i >= locs[k][0] {
+			q := quote
+			if q == '`' || i > locs[k][0] {
+				q = 0
+			}
+			out = append(out, placeholder{name: text[locs[k][2]:locs[k][3]], start: locs[k][0], end: locs[k][1], quote: q})
+			i = locs[k][1] - 1
+			k++
+			continue
+		}
+		switch c := text[i]; {
+		case quote == 0 && (c == '"' || c == '\'' || c == '`'):
+			quote = c
+		case c == quote:
+			quote = 0
+		case c == '\\' && quote != 0 && quote != '`':
+			i++
+		}
+	}
+	return out
+}
+
+// escape makes v safe inside a string delimited by quote.
+func escape(v string, quote byte) string {
+	var b strings.Builder
+	for _, r := range v {
+		switch {
+		case r == '\\' || r == rune(quote):
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,100 @@
+package queries
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	params := []Parameter{
+		{Name: "service", Required: true},
+		{Name: "window", Type: TypeDuration, Default: "5m"},
+	}
+	text := `sum(rate(http_requests_total{service="${service}"}[${window}]))`
+
+	got, err := Render(text, params, map[string]string{"service": "checkout"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `sum(rate(http_requests_total{service="checkout"}[5m]))`
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	if _, err := Render(text, params, nil); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expected missing required parameter to fail, got %v", err)
+	}
+	if _, err := Render(text, params, map[string]string{"service": "a", "tenant": "b"}); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expected unknown parameter to fail, got %v", err)
+	}
+}
+
+func TestValidateParameters(t *testing.T) {
+	if err := validateParameters(`up{job="${job}"}`, nil); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expected undeclared parameter to fail, got %v", err)
+	}
+	if err := validateParameters(`up`, []Parameter{{Name: "a"}, {Name: "a"}}); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expected duplicate parameter to fail, got %v", err)
+	}
+	if err := validateParameters(`up`, []Parameter{{Name: "bad-name"}}); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expected invalid name to fail, got %v", err)
+	}
+	if err := validateParameters(`up{job="${job}"}`, []Parameter{{Name: "job"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := validateParameters(`up{job=${job}}`, []Parameter{{Name: "job"}}); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expected an unquoted string parameter to fail, got %v", err)
+	}
+	if err := validateParameters(`up[${w}]`, []Parameter{{Name: "w", Type: "interval"}}); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expected an unknown type to fail, got %v", err)
+	}
+	if err := validateParameters(`up[${w}]`, []Parameter{{Name: "w", Type: TypeDuration, Default: "soon"}}); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expected an invalid default to fail, got %v", err)
+	}
+	if err := validateParameters(`up{job="${job}"}`, []Parameter{{Name: "job", Pattern: "("}}); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expected an invalid pattern to fail, got %v", err)
+	}
+}
+
+func TestRenderKeepsValuesInTheirPlace(t *testing.T) {
+	params := []Parameter{
+		{Name: "service", Required: true},
+		{Name: "window", Type: TypeDuration, Default: "5m"},
+		{Name: "metric", Type: TypeIdentifier, Default: "http_requests_total"},
+		{Name: "threshold", Type: TypeNumber, Default: "0.5"},
+		{Name: "env", Pattern: `prod|staging`, Default: "prod"},
+	}
+	text := `rate(${metric}{service="${service}",env='${env}'}[${window}]) > ${threshold}`
+
+	got, err := Render(text, params, map[string]string{"service": `"} or vector(1) #`})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `rate(http_requests_total{service="\"} or vector(1) #",env='prod'}[5m]) > 0.5`
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	got, err = Render(text, params, map[string]string{"service": "a\\.b\n"})
+	if err != nil || got != `rate(http_requests_total{service="a\\.b\n",env='prod'}[5m]) > 0.5` {
+		t.Fatalf("expected backslashes escaped, got %q, %v", got, err)
+	}
+
+	for name, values := range map[string]map[string]string{
+		"duration":   {"service": "a", "window": "5m]) or vector(1"},
+		"identifier": {"service": "a", "metric": "up or vector(1)"},
+		"number":     {"service": "a", "threshold": "1 or vector(1)"},
+		"pattern":    {"service": "a", "env": "dev"},
+	} {
+		if _, err := Render(text, params, values); !errors.Is(err, ErrInvalid) {
+			t.Fatalf("%s: expected the value to be rejected, got %v", name, err)
+		}
+	}
+
+	// A string outside quotes, from a query saved before parameters were
+	// typed, or right after a backslash cannot be escaped.
+	for _, text := range []string{`up{service=${service}}`, `up{service="\${service}"}`, "{app=`${service}`}"} {
+		if _, err := Render(text, params[:1], map[string]string{"service": "a"}); !errors.Is(err, ErrInvalid) {
+			t.Fatalf("%s: expected an unquoted string parameter to fail, got %v", text, err)
+		}
+	}
+}
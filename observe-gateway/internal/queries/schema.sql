@@ -0,0 +1,37 @@
+CREATE TABLE IF NOT EXISTS gateway_query_history (
+    id          BIGSERIAL PRIMARY KEY,
+    tenant      TEXT        NOT NULL,
+    username    TEXT        NOT NULL DEFAULT '',
+    lang        TEXT        NOT NULL,
+    query       TEXT        NOT NULL,
+    range_start TIMESTAMPTZ,
+    range_end   TIMESTAMPTZ,
+    step        TEXT        NOT NULL DEFAULT '',
+    duration_ms BIGINT      NOT NULL DEFAULT 0,
+    cached      BOOLEAN     NOT NULL DEFAULT FALSE,
+    backend     TEXT        NOT NULL DEFAULT '',
+    error       TEXT        NOT NULL DEFAULT '',
+    executed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS gateway_query_history_user_idx
+    ON gateway_query_history (tenant, username, executed_at DESC);
+
+CREATE TABLE IF NOT EXISTS gateway_saved_queries (
+    id          BIGSERIAL PRIMARY KEY,
+    tenant      TEXT        NOT NULL,
+    owner       TEXT        NOT NULL DEFAULT '',
+    name        TEXT        NOT NULL,
+    description TEXT        NOT NULL DEFAULT '',
+    lang        TEXT        NOT NULL,
+    query       TEXT        NOT NULL,
+    step        TEXT        NOT NULL DEFAULT '',
+    parameters  JSONB       NOT NULL DEFAULT '[]'::jsonb,
+    shared      BOOLEAN     NOT NULL DEFAULT FALSE,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (tenant, owner, name)
+);
+
+CREATE INDEX IF NOT EXISTS gateway_saved_queries_shared_idx
+    ON gateway_saved_queries (tenant, shared);
@@ -0,0 +1,386 @@
+package queries
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/xscopehub/observe-gateway/internal/audit"
+	"github.com/xscopehub/observe-gateway/internal/config"
+)
+
+var (
+	// ErrNotFound indicates the saved query does not exist or is not visible to the caller.
+	ErrNotFound = errors.New("saved query not found")
+	// ErrInvalid indicates a saved query definition or run request was rejected.
+	ErrInvalid = errors.New("invalid saved query")
+)
+
+//go:embed schema.sql
+var schemaSQL string
+
+// HistoryEntry is a single executed query recorded from the audit path.
+type HistoryEntry struct {
+	ID         int64     `json:"id"`
+	Tenant     string    `json:"tenant"`
+	User       string    `json:"user"`
+	Lang       string    `json:"lang"`
+	Query      string    `json:"query"`
+	Start      time.Time `json:"start,omitzero"`
+	End        time.Time `json:"end,omitzero"`
+	Step       string    `json:"step,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+	Cached     bool      `json:"cached"`
+	Backend    string    `json:"backend"`
+	Error      string    `json:"error,omitempty"`
+	ExecutedAt time.Time `json:"executed_at"`
+}
+
+// SavedQuery is a named, parameterised query owned by a user within a tenant.
+type SavedQuery struct {
+	ID          int64       `json:"id"`
+	Tenant      string      `json:"tenant"`
+	Owner       string      `json:"owner"`
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Lang        string      `json:"lang"`
+	Query       string      `json:"query"`
+	Step        string      `json:"step,omitempty"`
+	Parameters  []Parameter `json:"parameters"`
+	Shared      bool        `json:"shared"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+// RunRequest carries the variables and time range used to execute a saved query.
+type RunRequest struct {
+	Params map[string]string `json:"params"`
+	Start  time.Time         `json:"start"`
+	End    time.Time         `json:"end"`
+	Step   string            `json:"step"`
+}
+
+// Store persists query history and saved queries in PostgreSQL.
+type Store struct {
+	pool         *pgxpool.Pool
+	historyLimit int
+	retention    time.Duration
+	history      chan HistoryEntry
+	done         chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// New connects to PostgreSQL, applies the schema and starts the history writer.
+// A nil store is returned when the feature is disabled.
+func New(ctx context.Context, cfg config.QueriesConfig) (*Store, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	if strings.TrimSpace(cfg.DSN) == "" {
+		return nil, fmt.Errorf("queries dsn required")
+	}
+
+	poolConfig, err := pgxpool.ParseConfig(cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("parse queries dsn: %w", err)
+	}
+	if cfg.MaxConnections > 0 {
+		poolConfig.MaxConns = cfg.MaxConnections
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = cfg.MaxConnIdleTime
+	}
+
+	connectCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	pool, err := pgxpool.NewWithConfig(connectCtx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("connect queries db: %w", err)
+	}
+	if _, err := pool.Exec(connectCtx, schemaSQL); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("apply queries schema: %w", err)
+	}
+
+	limit := cfg.HistoryLimit
+	if limit <= 0 {
+		limit = 100
+	}
+
+	s := &Store{
+		pool:         pool,
+		historyLimit: limit,
+		retention:    cfg.HistoryRetention,
+		history:      make(chan HistoryEntry, 256),
+		done:         make(chan struct{}),
+	}
+	go s.writeHistory()
+	return s, nil
+}
+
+// Close stops the history writer and releases the connection pool.
+func (s *Store) Close() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.history)
+	}
+	s.mu.Unlock()
+	<-s.done
+	s.pool.Close()
+}
+
+// Record implements audit.Sink. Entries are queued and written asynchronously
+// so the query path never waits on PostgreSQL; they are dropped when the
+// queue is full. Only executed queries that can be run again are kept:
+// Explain calls and other audited requests, such as correlations, are not
+// history.
+func (s *Store) Record(e audit.Entry) {
+	if e.Tenant == "" || e.Query == "" || e.Explain || !runnable(e.Lang) {
+		return
+	}
+	entry := HistoryEntry{
+		Tenant:     e.Tenant,
+		User:       e.User,
+		Lang:       e.Lang,
+		Query:      e.Query,
+		Start:      e.Start,
+		End:        e.End,
+		Step:       e.Step,
+		DurationMS: e.Duration.Milliseconds(),
+		Cached:     e.Cached,
+		Backend:    e.Backend,
+		Error:      e.Error,
+		ExecutedAt: e.Time,
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.history <- entry:
+	default:
+	}
+}
+
+func (s *Store) writeHistory() {
+	defer close(s.done)
+
+	prune := time.NewTicker(time.Hour)
+	defer prune.Stop()
+
+	for {
+		select {
+		case entry, ok := <-s.history:
+			if !ok {
+				return
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := s.insertHistory(ctx, entry); err != nil {
+				log.Printf("record query history: %v", err)
+			}
+			cancel()
+		case <-prune.C:
+			if s.retention <= 0 {
+				continue
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			if _, err := s.pool.Exec(ctx, `DELETE FROM gateway_query_history WHERE executed_at < $1`, time.Now().Add(-s.retention)); err != nil {
+				log.Printf("prune query history: %v", err)
+			}
+			cancel()
+		}
+	}
+}
+
+func (s *Store) insertHistory(ctx context.Context, e HistoryEntry) error {
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO gateway_query_history
+			(tenant, username, lang, query, range_start, range_end, step, duration_ms, cached, backend, error, executed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+		e.Tenant, e.User, e.Lang, e.Query, nullTime(e.Start), nullTime(e.End), e.Step,
+		e.DurationMS, e.Cached, e.Backend, e.Error, e.ExecutedAt)
+	return err
+}
+
+// History returns the most recent queries executed by user within tenant.
+func (s *Store) History(ctx context.Context, tenant, user string, limit int) ([]HistoryEntry, error) {
+	if limit <= 0 || limit > s.historyLimit {
+		limit = s.historyLimit
+	}
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, tenant, username, lang, query, range_start, range_end, step, duration_ms, cached, backend, error, executed_at
+		FROM gateway_query_history
+		WHERE tenant = $1 AND username = $2
+		ORDER BY executed_at DESC
+		LIMIT $3`, tenant, user, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []HistoryEntry{}
+	for rows.Next() {
+		var (
+			e          HistoryEntry
+			start, end *time.Time
+		)
+		if err := rows.Scan(&e.ID, &e.Tenant, &e.User, &e.Lang, &e.Query, &start, &end, &e.Step,
+			&e.DurationMS, &e.Cached, &e.Backend, &e.Error, &e.ExecutedAt); err != nil {
+			return nil, err
+		}
+		if start != nil {
+			e.Start = *start
+		}
+		if end != nil {
+			e.End = *end
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// runnable reports whether the query API can execute lang.
+func runnable(lang string) bool {
+	switch lang {
+	case "promql", "logql", "traceql":
+		return true
+	}
+	return false
+}
+
+// CreateSaved validates and stores a new saved query owned by q.Owner.
+func (s *Store) CreateSaved(ctx context.Context, q SavedQuery) (SavedQuery, error) {
+	q.Name = strings.TrimSpace(q.Name)
+	q.Lang = strings.ToLower(q.Lang)
+	if q.Name == "" {
+		return SavedQuery{}, fmt.Errorf("%w: name is required", ErrInvalid)
+	}
+	if strings.TrimSpace(q.Query) == "" {
+		return SavedQuery{}, fmt.Errorf("%w: query is required", ErrInvalid)
+	}
+	if !runnable(q.Lang) {
+		return SavedQuery{}, fmt.Errorf("%w: unsupported language: %s", ErrInvalid, q.Lang)
+	}
+	if q.Step != "" {
+		if _, err := time.ParseDuration(q.Step); err != nil {
+			return SavedQuery{}, fmt.Errorf("%w: invalid step duration", ErrInvalid)
+		}
+	}
+	if q.Parameters == nil {
+		q.Parameters = []Parameter{}
+	}
+	if err := validateParameters(q.Query, q.Parameters); err != nil {
+		return SavedQuery{}, err
+	}
+
+	params, err := json.Marshal(q.Parameters)
+	if err != nil {
+		return SavedQuery{}, err
+	}
+
+	row := s.pool.QueryRow(ctx, `
+		INSERT INTO gateway_saved_queries (tenant, owner, name, description, lang, query, step, parameters, shared)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (tenant, owner, name) DO NOTHING
+		RETURNING id, created_at, updated_at`,
+		q.Tenant, q.Owner, q.Name, q.Description, q.Lang, q.Query, q.Step, params, q.Shared)
+	if err := row.Scan(&q.ID, &q.CreatedAt, &q.UpdatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return SavedQuery{}, fmt.Errorf("%w: saved query %q already exists", ErrInvalid, q.Name)
+		}
+		return SavedQuery{}, err
+	}
+	return q, nil
+}
+
+// ListSaved returns the user's own saved queries plus those shared within the tenant.
+func (s *Store) ListSaved(ctx context.Context, tenant, user string) ([]SavedQuery, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+savedColumns+`
+		FROM gateway_saved_queries
+		WHERE tenant = $1 AND (owner = $2 OR shared)
+		ORDER BY name, owner`, tenant, user)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	saved := []SavedQuery{}
+	for rows.Next() {
+		q, err := scanSaved(rows)
+		if err != nil {
+			return nil, err
+		}
+		saved = append(saved, q)
+	}
+	return saved, rows.Err()
+}
+
+// GetSaved loads a saved query visible to user within tenant.
+func (s *Store) GetSaved(ctx context.Context, tenant, user string, id int64) (SavedQuery, error) {
+	row := s.pool.QueryRow(ctx, `
+		SELECT `+savedColumns+`
+		FROM gateway_saved_queries
+		WHERE id = $1 AND tenant = $2 AND (owner = $3 OR shared)`, id, tenant, user)
+	q, err := scanSaved(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return SavedQuery{}, ErrNotFound
+	}
+	return q, err
+}
+
+// DeleteSaved removes a saved query; only its owner may delete it.
+func (s *Store) DeleteSaved(ctx context.Context, tenant, user string, id int64) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM gateway_saved_queries WHERE id = $1 AND tenant = $2 AND owner = $3`, id, tenant, user)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+const savedColumns = `id, tenant, owner, name, description, lang, query, step, parameters, shared, created_at, updated_at`
+
+func scanSaved(row pgx.Row) (SavedQuery, error) {
+	var (
+		q      SavedQuery
+		params []byte
+	)
+	if err := row.Scan(&q.ID, &q.Tenant, &q.Owner, &q.Name, &q.Description, &q.Lang, &q.Query, &q.Step,
+		&params, &q.Shared, &q.CreatedAt, &q.UpdatedAt); err != nil {
+		return SavedQuery{}, err
+	}
+	if err := json.Unmarshal(params, &q.Parameters); err != nil {
+		return SavedQuery{}, fmt.Errorf("decode parameters: %w", err)
+	}
+	if q.Parameters == nil {
+		q.Parameters = []Parameter{}
+	}
+	return q, nil
+}
+
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
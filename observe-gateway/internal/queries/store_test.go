@@ -0,0 +1,52 @@
+package queries
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/xscopehub/observe-gateway/internal/audit"
+)
+
+func TestRecordKeepsOnlyExecutedQueries(t *testing.T) {
+	s := &Store{history: make(chan HistoryEntry, 8)}
+	entries := []audit.Entry{
+		{Tenant: "acme", User: "alice", Lang: "promql", Query: "up"},
+		{Tenant: "acme", User: "alice", Lang: "promql", Query: "up", Explain: true},
+		{Tenant: "acme", User: "alice", Lang: "correlate", Query: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{Tenant: "", User: "alice", Lang: "logql", Query: `{app="api"}`},
+		{Tenant: "acme", User: "alice", Lang: "logql", Query: `{app="api"}`},
+	}
+	for _, e := range entries {
+		s.Record(e)
+	}
+	close(s.history)
+
+	var langs []string
+	for entry := range s.history {
+		langs = append(langs, entry.Lang)
+	}
+	if len(langs) != 2 || langs[0] != "promql" || langs[1] != "logql" {
+		t.Fatalf("expected only the executed promql and logql queries, got %v", langs)
+	}
+}
+
+func TestCreateSavedValidates(t *testing.T) {
+	s := &Store{}
+	for name, q := range map[string]SavedQuery{
+		"no name":         {Lang: "promql", Query: "up"},
+		"no query":        {Name: "q", Lang: "promql"},
+		"language":        {Name: "q", Lang: "correlate", Query: "up"},
+		"step":            {Name: "q", Lang: "promql", Query: "up", Step: "often"},
+		"undeclared":      {Name: "q", Lang: "promql", Query: `up{job="${job}"}`},
+		"unquoted":        {Name: "q", Lang: "promql", Query: `up{job=${job}}`, Parameters: []Parameter{{Name: "job"}}},
+		"type":            {Name: "q", Lang: "promql", Query: `up[${w}]`, Parameters: []Parameter{{Name: "w", Type: "interval"}}},
+		"default":         {Name: "q", Lang: "promql", Query: `up[${w}]`, Parameters: []Parameter{{Name: "w", Type: TypeDuration, Default: "1 hour"}}},
+		"pattern":         {Name: "q", Lang: "promql", Query: `up{job="${job}"}`, Parameters: []Parameter{{Name: "job", Pattern: "[a-"}}},
+		"pattern default": {Name: "q", Lang: "promql", Query: `up{job="${job}"}`, Parameters: []Parameter{{Name: "job", Pattern: "api|web", Default: "db"}}},
+	} {
+		if _, err := s.CreateSaved(context.Background(), q); !errors.Is(err, ErrInvalid) {
+			t.Fatalf("%s: expected ErrInvalid, got %v", name, err)
+		}
+	}
+}
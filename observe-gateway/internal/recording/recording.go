@@ -0,0 +1,341 @@
+package recording
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xscopehub/observe-gateway/internal/backend"
+	"github.com/xscopehub/observe-gateway/internal/config"
+	"github.com/xscopehub/observe-gateway/internal/query"
+)
+
+const (
+	// ModeRemoteWrite writes rule results back to OpenObserve as new series and
+	// rewrites matching queries to select them.
+	ModeRemoteWrite = "remote_write"
+	// ModeCache keeps the latest rule result in memory and serves matching
+	// instant queries from it.
+	ModeCache = "cache"
+)
+
+// lookbackDelta is how far back a PromQL instant selector looks for a
+// sample. A recorded series evaluated less often than this has gaps where
+// it returns nothing, so rules with a longer interval are never used to
+// rewrite queries.
+const lookbackDelta = 5 * time.Minute
+
+var (
+	metricNameRegex = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNameRegex  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// Backend is the subset of backend.Client used to evaluate and store rules.
+type Backend interface {
+	QueryPromQL(ctx context.Context, tenant string, req query.Request) (backend.Result, error)
+	RemoteWrite(ctx context.Context, tenant string, samples []backend.Sample) error
+}
+
+type rule struct {
+	record   string
+	expr     string
+	norm     string
+	interval time.Duration
+	tenants  []string
+	labels   map[string]string
+}
+
+type state struct {
+	// since is when the rule first produced data for the tenant.
+	since     time.Time
+	updatedAt time.Time
+	payload   json.RawMessage
+}
+
+// Engine evaluates recording rules on a schedule and answers whether an
+// incoming PromQL query can use their precomputed results.
+type Engine struct {
+	backend Backend
+	mode    string
+	rules   []rule
+
+	mu     sync.RWMutex
+	states map[string]*state
+}
+
+// New validates the configured rules and builds an engine. A nil engine is
+// returned when recording rules are disabled.
+func New(cfg config.RecordingConfig, b Backend) (*Engine, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	mode := cfg.Mode
+	if mode == "" {
+		mode = ModeRemoteWrite
+	}
+	if mode != ModeRemoteWrite && mode != ModeCache {
+		return nil, fmt.Errorf("unsupported recording rule mode %q", cfg.Mode)
+	}
+
+	e := &Engine{backend: b, mode: mode, states: map[string]*state{}}
+	seen := map[string]struct{}{}
+	for _, rc := range cfg.Rules {
+		if !metricNameRegex.MatchString(rc.Record) {
+			return nil, fmt.Errorf("recording rule %q: invalid metric name", rc.Record)
+		}
+		if _, dup := seen[rc.Record]; dup {
+			return nil, fmt.Errorf("recording rule %q: duplicate record name", rc.Record)
+		}
+		seen[rc.Record] = struct{}{}
+
+		norm := normalize(rc.Expr)
+		if norm == "" {
+			return nil, fmt.Errorf("recording rule %q: expr is required", rc.Record)
+		}
+		interval := rc.Interval
+		if interval <= 0 {
+			interval = cfg.Interval
+		}
+		if interval <= 0 {
+			interval = time.Minute
+		}
+		tenants := rc.Tenants
+		if len(tenants) == 0 {
+			tenants = cfg.Tenants
+		}
+		if len(tenants) == 0 {
+			return nil, fmt.Errorf("recording rule %q: no tenants configured", rc.Record)
+		}
+		for name := range rc.Labels {
+			if name == "__name__" || !labelNameRegex.MatchString(name) {
+				return nil, fmt.Errorf("recording rule %q: invalid label %q", rc.Record, name)
+			}
+		}
+
+		e.rules = append(e.rules, rule{
+			record:   rc.Record,
+			expr:     rc.Expr,
+			norm:     norm,
+			interval: interval,
+			tenants:  tenants,
+			labels:   rc.Labels,
+		})
+	}
+	return e, nil
+}
+
+// Run evaluates every rule on its interval until ctx is cancelled.
+func (e *Engine) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, r := range e.rules {
+		wg.Add(1)
+		go func(r rule) {
+			defer wg.Done()
+			ticker := time.NewTicker(r.interval)
+			defer ticker.Stop()
+			for {
+				e.evaluateAll(ctx, r)
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}(r)
+	}
+	wg.Wait()
+}
+
+func (e *Engine) evaluateAll(ctx context.Context, r rule) {
+	for _, tenant := range r.tenants {
+		evalCtx, cancel := context.WithTimeout(ctx, r.interval)
+		if err := e.evaluate(evalCtx, r, tenant, time.Now()); err != nil && ctx.Err() == nil {
+			log.Printf("recording rule %s (tenant %s): %v", r.record, tenant, err)
+		}
+		cancel()
+	}
+}
+
+// evaluate runs r for tenant and records its result. A failed evaluation
+// forgets the rule's state, so queries fall back to the raw expression
+// until the rule produces data again, and range queries are only
+// rewritten once they start after the gap.
+func (e *Engine) evaluate(ctx context.Context, r rule, tenant string, now time.Time) error {
+	err := e.record(ctx, r, tenant, now)
+	if err != nil {
+		e.mu.Lock()
+		delete(e.states, stateKey(tenant, r.norm))
+		e.mu.Unlock()
+	}
+	return err
+}
+
+func (e *Engine) record(ctx context.Context, r rule, tenant string, now time.Time) error {
+	res, err := e.backend.QueryPromQL(ctx, tenant, query.Request{Lang: "promql", Query: r.expr})
+	if err != nil {
+		return err
+	}
+
+	if e.mode == ModeRemoteWrite {
+		samples, err := toSamples(res.Payload, r, now)
+		if err != nil {
+			return err
+		}
+		if len(samples) == 0 {
+			return nil
+		}
+		if err := e.backend.RemoteWrite(ctx, tenant, samples); err != nil {
+			return err
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	key := stateKey(tenant, r.norm)
+	st := e.states[key]
+	if st == nil {
+		st = &state{since: now}
+		e.states[key] = st
+	}
+	st.updatedAt = now
+	if e.mode == ModeCache {
+		st.payload = res.Payload
+	}
+	return nil
+}
+
+// Rewrite replaces a PromQL expression matching a rule with the rule's
+// recorded series. Range queries are only rewritten when the whole range
+// falls after the rule started producing data for the tenant. Rules that
+// add labels are never used: their series carry labels the expression does
+// not return, which would change aggregations and joins over the answer.
+// Neither are rules evaluated less often than the lookback delta, or whose
+// last successful evaluation is more than two intervals old.
+func (e *Engine) Rewrite(tenant string, req query.Request) (query.Request, bool) {
+	if e == nil || e.mode != ModeRemoteWrite {
+		return req, false
+	}
+	r, st := e.match(tenant, req.Query)
+	if st == nil || len(r.labels) > 0 || r.interval > lookbackDelta || e.stale(r, st) {
+		return req, false
+	}
+	if req.HasTimeRange() && req.Start.Before(st.since) {
+		return req, false
+	}
+	req.Query = r.record
+	return req, true
+}
+
+// Lookup returns the cached result of a rule matching an instant query, as
+// long as it is no older than two evaluation intervals.
+func (e *Engine) Lookup(tenant string, req query.Request) (backend.Result, bool) {
+	if e == nil || e.mode != ModeCache || req.HasTimeRange() {
+		return backend.Result{}, false
+	}
+	r, st := e.match(tenant, req.Query)
+	if st == nil || st.payload == nil || e.stale(r, st) {
+		return backend.Result{}, false
+	}
+	return backend.Result{Payload: st.payload, Backend: "recording-rule-cache"}, true
+}
+
+// stale reports whether the rule last produced data more than two
+// evaluation intervals ago.
+func (e *Engine) stale(r rule, st *state) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return time.Since(st.updatedAt) > 2*r.interval
+}
+
+func (e *Engine) match(tenant, expr string) (rule, *state) {
+	norm := normalize(expr)
+	for _, r := range e.rules {
+		if r.norm != norm {
+			continue
+		}
+		e.mu.RLock()
+		st := e.states[stateKey(tenant, norm)]
+		e.mu.RUnlock()
+		if st == nil {
+			return rule{}, nil
+		}
+		return r, st
+	}
+	return rule{}, nil
+}
+
+func stateKey(tenant, norm string) string {
+	return tenant + "|" + norm
+}
+
+// normalize collapses whitespace so formatting differences do not prevent a match.
+func normalize(expr string) string {
+	return strings.Join(strings.Fields(expr), " ")
+}
+
+// vectorPoint is one element of an instant vector in the Prometheus API.
+type vectorPoint struct {
+	Metric map[string]string `json:"metric"`
+	Value  [2]any            `json:"value"`
+}
+
+// toSamples converts an instant query response into samples named after the rule.
+func toSamples(payload json.RawMessage, r rule, now time.Time) ([]backend.Sample, error) {
+	var resp struct {
+		Status string `json:"status"`
+		Data   struct {
+			ResultType string          `json:"resultType"`
+			Result     json.RawMessage `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &resp); err != nil {
+		return nil, fmt.Errorf("decode promql response: %w", err)
+	}
+	if resp.Status != "" && resp.Status != "success" {
+		return nil, fmt.Errorf("promql status %q", resp.Status)
+	}
+
+	var points []vectorPoint
+	switch resp.Data.ResultType {
+	case "vector":
+		if err := json.Unmarshal(resp.Data.Result, &points); err != nil {
+			return nil, fmt.Errorf("decode vector: %w", err)
+		}
+	case "scalar":
+		points = make([]vectorPoint, 1)
+		if err := json.Unmarshal(resp.Data.Result, &points[0].Value); err != nil {
+			return nil, fmt.Errorf("decode scalar: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported result type %q", resp.Data.ResultType)
+	}
+
+	samples := make([]backend.Sample, 0, len(points))
+	for _, p := range points {
+		raw, ok := p.Value[1].(string)
+		if !ok {
+			continue
+		}
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			continue
+		}
+		labels := make(map[string]string, len(p.Metric)+len(r.labels)+1)
+		for k, val := range p.Metric {
+			labels[k] = val
+		}
+		for k, val := range r.labels {
+			labels[k] = val
+		}
+		labels["__name__"] = r.record
+		samples = append(samples, backend.Sample{Labels: labels, Value: v, Timestamp: now})
+	}
+	return samples, nil
+}
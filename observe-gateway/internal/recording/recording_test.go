@@ -0,0 +1,177 @@
+package recording
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/xscopehub/observe-gateway/internal/backend"
+	"github.com/xscopehub/observe-gateway/internal/config"
+	"github.com/xscopehub/observe-gateway/internal/query"
+)
+
+type fakeBackend struct {
+	payload string
+	err     error
+	written []backend.Sample
+}
+
+func (f *fakeBackend) QueryPromQL(context.Context, string, query.Request) (backend.Result, error) {
+	return backend.Result{Payload: json.RawMessage(f.payload)}, f.err
+}
+
+func (f *fakeBackend) RemoteWrite(_ context.Context, _ string, samples []backend.Sample) error {
+	f.written = append(f.written, samples...)
+	return nil
+}
+
+func TestRemoteWriteRewrite(t *testing.T) {
+	fb := &fakeBackend{payload: `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"job":"api"},"value":[1714557600,"4.5"]}]}}`}
+	e, err := New(config.RecordingConfig{
+		Enabled: true,
+		Tenants: []string{"acme"},
+		Rules: []config.RecordingRule{
+			{
+				Record: "job:http_requests:rate5m",
+				Expr:   "sum by (job) (rate(http_requests_total[5m]))",
+			},
+			{
+				Record: "job:http_requests:rate1m",
+				Expr:   "sum by (job) (rate(http_requests_total[1m]))",
+				Labels: map[string]string{"source": "gateway"},
+			},
+		},
+	}, fb)
+	if err != nil {
+		t.Fatalf("new engine: %v", err)
+	}
+
+	req := query.Request{Lang: "promql", Query: "sum  by (job)\n(rate(http_requests_total[5m]))"}
+	if _, ok := e.Rewrite("acme", req); ok {
+		t.Fatalf("query rewritten before the rule produced data")
+	}
+
+	now := time.Now()
+	if err := e.evaluate(context.Background(), e.rules[0], "acme", now); err != nil {
+		t.Fatalf("evaluate: %v", err)
+	}
+	if err := e.evaluate(context.Background(), e.rules[1], "acme", now); err != nil {
+		t.Fatalf("evaluate: %v", err)
+	}
+	if len(fb.written) != 2 {
+		t.Fatalf("expected two samples written, got %d", len(fb.written))
+	}
+	got := fb.written[0]
+	if got.Value != 4.5 || got.Labels["__name__"] != "job:http_requests:rate5m" || got.Labels["job"] != "api" {
+		t.Fatalf("unexpected sample %+v", got)
+	}
+	if got := fb.written[1]; got.Labels["__name__"] != "job:http_requests:rate1m" || got.Labels["source"] != "gateway" {
+		t.Fatalf("unexpected labelled sample %+v", got)
+	}
+	// The labelled series differs from the expression's answer.
+	if _, ok := e.Rewrite("acme", query.Request{Lang: "promql", Query: "sum by (job) (rate(http_requests_total[1m]))"}); ok {
+		t.Fatalf("query rewritten to a rule that adds labels")
+	}
+
+	rewritten, ok := e.Rewrite("acme", req)
+	if !ok || rewritten.Query != "job:http_requests:rate5m" {
+		t.Fatalf("expected rewrite, got %q (%v)", rewritten.Query, ok)
+	}
+	if _, ok := e.Rewrite("other", req); ok {
+		t.Fatalf("query rewritten for a tenant the rule does not cover")
+	}
+	req.Start, req.End = now.Add(-time.Hour), now
+	if _, ok := e.Rewrite("acme", req); ok {
+		t.Fatalf("range query starting before the rule was rewritten")
+	}
+}
+
+func TestRewriteNeedsFreshData(t *testing.T) {
+	fb := &fakeBackend{payload: `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"job":"api"},"value":[1714557600,"4.5"]}]}}`}
+	e, err := New(config.RecordingConfig{
+		Enabled:  true,
+		Tenants:  []string{"acme"},
+		Interval: time.Minute,
+		Rules: []config.RecordingRule{
+			{Record: "job:up:sum", Expr: "sum by (job) (up)"},
+			{Record: "job:up:count", Expr: "count by (job) (up)", Interval: 10 * time.Minute},
+		},
+	}, fb)
+	if err != nil {
+		t.Fatalf("new engine: %v", err)
+	}
+	req := query.Request{Lang: "promql", Query: "sum by (job) (up)"}
+	rewrite := func() bool {
+		_, ok := e.Rewrite("acme", req)
+		return ok
+	}
+
+	now := time.Now()
+	if err := e.evaluate(context.Background(), e.rules[0], "acme", now.Add(-3*time.Minute)); err != nil || rewrite() {
+		t.Fatalf("query rewritten to a series last recorded three intervals ago (%v)", err)
+	}
+	if err := e.evaluate(context.Background(), e.rules[0], "acme", now); err != nil || !rewrite() {
+		t.Fatalf("expected a rewrite to a fresh series (%v)", err)
+	}
+	fb.err = errors.New("openobserve unavailable")
+	if err := e.evaluate(context.Background(), e.rules[0], "acme", now); err == nil || rewrite() {
+		t.Fatalf("query rewritten after the rule failed (%v)", err)
+	}
+
+	// The series has a gap until the rule recovers; ranges over it are not
+	// rewritten.
+	fb.err = nil
+	if err := e.evaluate(context.Background(), e.rules[0], "acme", now); err != nil || !rewrite() {
+		t.Fatalf("expected instant queries to be rewritten again (%v)", err)
+	}
+	req.Start, req.End = now.Add(-time.Minute), now
+	if rewrite() {
+		t.Fatalf("range over the gap was rewritten")
+	}
+
+	if err := e.evaluate(context.Background(), e.rules[1], "acme", now); err != nil {
+		t.Fatalf("evaluate: %v", err)
+	}
+	if _, ok := e.Rewrite("acme", query.Request{Lang: "promql", Query: "count by (job) (up)"}); ok {
+		t.Fatalf("query rewritten to a rule evaluated less often than the lookback delta")
+	}
+}
+
+func TestCacheLookup(t *testing.T) {
+	payload := `{"status":"success","data":{"resultType":"scalar","result":[1714557600,"1"]}}`
+	e, err := New(config.RecordingConfig{
+		Enabled: true,
+		Mode:    ModeCache,
+		Tenants: []string{"acme"},
+		Rules:   []config.RecordingRule{{Record: "cluster:up", Expr: "sum(up)"}},
+	}, &fakeBackend{payload: payload})
+	if err != nil {
+		t.Fatalf("new engine: %v", err)
+	}
+	if err := e.evaluate(context.Background(), e.rules[0], "acme", time.Now()); err != nil {
+		t.Fatalf("evaluate: %v", err)
+	}
+
+	res, ok := e.Lookup("acme", query.Request{Lang: "promql", Query: "sum(up)"})
+	if !ok || string(res.Payload) != payload {
+		t.Fatalf("expected cached payload, got %s (%v)", res.Payload, ok)
+	}
+	if _, ok := e.Rewrite("acme", query.Request{Lang: "promql", Query: "sum(up)"}); ok {
+		t.Fatalf("cache mode must not rewrite queries")
+	}
+}
+
+func TestNewRejectsInvalidRules(t *testing.T) {
+	cases := []config.RecordingRule{
+		{Record: "bad name", Expr: "up"},
+		{Record: "ok", Expr: " "},
+		{Record: "ok", Expr: "up", Labels: map[string]string{"__name__": "x"}},
+	}
+	for _, rc := range cases {
+		if _, err := New(config.RecordingConfig{Enabled: true, Tenants: []string{"acme"}, Rules: []config.RecordingRule{rc}}, &fakeBackend{}); err == nil {
+			t.Fatalf("expected error for %+v", rc)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/xscopehub/observe-gateway/internal/audit"
+	"github.com/xscopehub/observe-gateway/internal/backend"
+	"github.com/xscopehub/observe-gateway/internal/limiter"
+)
+
+// handleCorrelate pivots from a trace ID to the trace's spans, the logs that
+// carry the same trace ID and RED metrics for every service involved.
+func (s *Server) handleCorrelate(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt64(&s.activeRequests, 1)
+	defer atomic.AddInt64(&s.activeRequests, -1)
+
+	start := time.Now()
+	traceID := strings.TrimSpace(r.URL.Query().Get("trace_id"))
+	entry := audit.Entry{Lang: "correlate", Query: traceID}
+
+	tenant, user, ok := s.identifyOrFail(w, r)
+	if !ok {
+		return
+	}
+	entry.Tenant, entry.User = tenant, user
+
+	fail := func(status int, msg string) {
+		s.writeError(w, status, msg)
+		entry.Duration, entry.Error = time.Since(start), msg
+		s.auditLog.Log(entry)
+	}
+
+	if traceID == "" {
+		fail(http.StatusBadRequest, "trace_id is required")
+		return
+	}
+
+	opts := backend.CorrelateOptions{
+		End:         time.Now().UTC(),
+		Padding:     s.cfg.Correlate.Padding,
+		MaxServices: s.cfg.Correlate.MaxServices,
+		REDQueries:  s.cfg.Correlate.REDQueries,
+	}
+	var err error
+	if opts.End, err = timeParam(r, "end", opts.End); err != nil {
+		fail(http.StatusBadRequest, err.Error())
+		return
+	}
+	if opts.Start, err = timeParam(r, "start", opts.End.Add(-s.cfg.Correlate.Lookback)); err != nil {
+		fail(http.StatusBadRequest, err.Error())
+		return
+	}
+	if opts.Start.After(opts.End) {
+		fail(http.StatusBadRequest, "start must be before end")
+		return
+	}
+	entry.Start, entry.End = opts.Start, opts.End
+
+	if s.limiter != nil {
+		if err := s.limiter.Allow(r.Context(), tenant); err != nil {
+			status := http.StatusTooManyRequests
+			if !errors.Is(err, limiter.ErrRateLimited) {
+				status = http.StatusInternalServerError
+			}
+			fail(status, err.Error())
+			return
+		}
+	}
+
+	bundle, err := s.backend.Correlate(r.Context(), tenant, traceID, opts)
+	if err != nil {
+		status := http.StatusBadGateway
+		switch {
+		case errors.Is(err, backend.ErrInvalidTraceID):
+			status = http.StatusBadRequest
+		case errors.Is(err, backend.ErrTraceNotFound):
+			status = http.StatusNotFound
+		}
+		fail(status, err.Error())
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]any{
+		"tenant":      tenant,
+		"correlation": bundle,
+		"stats": map[string]any{
+			"duration_ms": time.Since(start).Milliseconds(),
+			"cost":        bundle.Cost,
+		},
+	})
+
+	entry.Duration, entry.Cost, entry.Backend = time.Since(start), bundle.Cost, "openobserve-correlate"
+	s.auditLog.Log(entry)
+}
+
+// timeParam parses an optional RFC3339 query parameter.
+func timeParam(r *http.Request, key string, def time.Time) (time.Time, error) {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return def, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s: expected RFC3339", key)
+	}
+	return t, nil
+}
@@ -0,0 +1,200 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	queryv1 "github.com/xscopehub/observe-gateway/proto/query/v1"
+)
+
+// flightService serves log and trace results as Arrow record batches. Both
+// flight descriptors and tickets carry a serialised queryv1.QueryRequest.
+type flightService struct {
+	flight.BaseFlightServer
+	s   *Server
+	mem memory.Allocator
+}
+
+func registerFlight(gs *grpc.Server, s *Server) {
+	flight.RegisterFlightServiceServer(gs, &flightService{s: s, mem: memory.DefaultAllocator})
+}
+
+// GetFlightInfo validates the command and returns a single endpoint whose
+// ticket replays it. The schema depends on the result and is only known
+// once DoGet runs the query.
+func (f *flightService) GetFlightInfo(_ context.Context, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	if desc.GetType() != flight.DescriptorCMD {
+		return nil, status.Error(codes.InvalidArgument, "only command descriptors are supported")
+	}
+	if _, err := decodeFlightRequest(desc.GetCmd()); err != nil {
+		return nil, err
+	}
+	return &flight.FlightInfo{
+		FlightDescriptor: desc,
+		Endpoint:         []*flight.FlightEndpoint{{Ticket: &flight.Ticket{Ticket: desc.GetCmd()}}},
+		TotalRecords:     -1,
+		TotalBytes:       -1,
+	}, nil
+}
+
+// DoGet runs the query in the ticket and streams its hits as record batches.
+func (f *flightService) DoGet(tkt *flight.Ticket, stream flight.FlightService_DoGetServer) error {
+	in, err := decodeFlightRequest(tkt.GetTicket())
+	if err != nil {
+		return err
+	}
+	resp, err := f.s.runGRPC(stream.Context(), in)
+	if err != nil {
+		return err
+	}
+
+	rows, err := decodeHits(resultRows(resp.Lang, resp.Result))
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	schema := inferSchema(rows)
+	w := flight.NewRecordWriter(stream, ipc.WithSchema(schema), ipc.WithAllocator(f.mem))
+	defer w.Close()
+
+	for len(rows) > 0 {
+		n := min(streamBatchSize, len(rows))
+		rec := buildRecord(f.mem, schema, rows[:n])
+		err := w.Write(rec)
+		rec.Release()
+		if err != nil {
+			return err
+		}
+		rows = rows[n:]
+	}
+	return nil
+}
+
+func decodeFlightRequest(cmd []byte) (*queryv1.QueryRequest, error) {
+	var in queryv1.QueryRequest
+	if err := proto.Unmarshal(cmd, &in); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "ticket is not a QueryRequest")
+	}
+	if in.GetLang() != "logql" && in.GetLang() != "traceql" {
+		return nil, status.Error(codes.InvalidArgument, "flight only serves logql and traceql results")
+	}
+	return &in, nil
+}
+
+func decodeHits(raw []json.RawMessage) ([]map[string]any, error) {
+	rows := make([]map[string]any, 0, len(raw))
+	for _, r := range raw {
+		dec := json.NewDecoder(bytes.NewReader(r))
+		dec.UseNumber()
+		var row map[string]any
+		if err := dec.Decode(&row); err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// inferSchema derives one nullable column per key seen in the hits, sorted by
+// name. Columns whose values are all integers, all numbers or all booleans
+// get the matching Arrow type; anything else is a string column, with
+// objects and arrays JSON encoded.
+func inferSchema(rows []map[string]any) *arrow.Schema {
+	kinds := map[string]arrow.DataType{}
+	for _, row := range rows {
+		for k, v := range row {
+			kinds[k] = widen(kinds[k], v)
+		}
+	}
+
+	names := make([]string, 0, len(kinds))
+	for k := range kinds {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	fields := make([]arrow.Field, len(names))
+	for i, name := range names {
+		typ := kinds[name]
+		if typ == nil {
+			typ = arrow.BinaryTypes.String
+		}
+		fields[i] = arrow.Field{Name: name, Type: typ, Nullable: true}
+	}
+	return arrow.NewSchema(fields, nil)
+}
+
+// widen returns the narrowest type able to hold both prev and v.
+func widen(prev arrow.DataType, v any) arrow.DataType {
+	var typ arrow.DataType
+	switch val := v.(type) {
+	case nil:
+		return prev
+	case bool:
+		typ = arrow.FixedWidthTypes.Boolean
+	case json.Number:
+		typ = arrow.PrimitiveTypes.Int64
+		if _, err := val.Int64(); err != nil {
+			typ = arrow.PrimitiveTypes.Float64
+		}
+	default:
+		return arrow.BinaryTypes.String
+	}
+
+	switch {
+	case prev == nil || arrow.TypeEqual(prev, typ):
+		return typ
+	case isNumeric(prev) && isNumeric(typ):
+		return arrow.PrimitiveTypes.Float64
+	default:
+		return arrow.BinaryTypes.String
+	}
+}
+
+func isNumeric(t arrow.DataType) bool {
+	return t.ID() == arrow.INT64 || t.ID() == arrow.FLOAT64
+}
+
+func buildRecord(mem memory.Allocator, schema *arrow.Schema, rows []map[string]any) arrow.Record {
+	b := array.NewRecordBuilder(mem, schema)
+	defer b.Release()
+
+	for i, field := range schema.Fields() {
+		for _, row := range rows {
+			v, ok := row[field.Name]
+			if !ok || v == nil {
+				b.Field(i).AppendNull()
+				continue
+			}
+			switch fb := b.Field(i).(type) {
+			case *array.BooleanBuilder:
+				fb.Append(v.(bool))
+			case *array.Int64Builder:
+				n, _ := v.(json.Number).Int64()
+				fb.Append(n)
+			case *array.Float64Builder:
+				n, _ := v.(json.Number).Float64()
+				fb.Append(n)
+			case *array.StringBuilder:
+				if s, ok := v.(string); ok {
+					fb.Append(s)
+				} else {
+					enc, _ := json.Marshal(v)
+					fb.Append(string(enc))
+				}
+			}
+		}
+	}
+	return b.NewRecord()
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestHitsToRecord(t *testing.T) {
+	raw := resultRows("logql", json.RawMessage(`{"hits":[
+		{"_timestamp":1714557600000000,"level":"error","latency":1,"ok":true,"attrs":{"k":"v"}},
+		{"_timestamp":1714557601000000,"level":"info","latency":2.5,"ok":null,"extra":"x"}
+	]}`))
+	rows, err := decodeHits(raw)
+	if err != nil {
+		t.Fatalf("decode hits: %v", err)
+	}
+
+	schema := inferSchema(rows)
+	want := map[string]arrow.Type{
+		"_timestamp": arrow.INT64,
+		"attrs":      arrow.STRING,
+		"extra":      arrow.STRING,
+		"latency":    arrow.FLOAT64,
+		"level":      arrow.STRING,
+		"ok":         arrow.BOOL,
+	}
+	if schema.NumFields() != len(want) {
+		t.Fatalf("unexpected schema %s", schema)
+	}
+	for _, f := range schema.Fields() {
+		if want[f.Name] != f.Type.ID() {
+			t.Fatalf("column %s: expected %s, got %s", f.Name, want[f.Name], f.Type)
+		}
+	}
+
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+	rec := buildRecord(mem, schema, rows)
+	defer rec.Release()
+
+	if rec.NumRows() != 2 {
+		t.Fatalf("expected 2 rows, got %d", rec.NumRows())
+	}
+	idx := schema.FieldIndices("attrs")[0]
+	if got := rec.Column(idx).(*array.String).Value(0); got != `{"k":"v"}` {
+		t.Fatalf("unexpected attrs %q", got)
+	}
+	if !rec.Column(idx).IsNull(1) {
+		t.Fatalf("expected missing attrs to be null")
+	}
+	idx = schema.FieldIndices("latency")[0]
+	if got := rec.Column(idx).(*array.Float64).Value(0); got != 1 {
+		t.Fatalf("unexpected latency %v", got)
+	}
+}
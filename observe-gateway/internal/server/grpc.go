@@ -0,0 +1,270 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/xscopehub/observe-gateway/internal/auth"
+	"github.com/xscopehub/observe-gateway/internal/query"
+	queryv1 "github.com/xscopehub/observe-gateway/proto/query/v1"
+)
+
+// streamBatchSize is the number of result rows sent per QueryStream chunk
+// and per Arrow record batch.
+const streamBatchSize = 500
+
+// grpcService implements queryv1.QueryServiceServer on top of the same
+// pipeline as POST /api/query.
+type grpcService struct {
+	queryv1.UnimplementedQueryServiceServer
+	s *Server
+}
+
+// newGRPCServer builds the gRPC server with authentication interceptors and
+// the query service, plus Arrow Flight when enabled.
+func (s *Server) newGRPCServer() *grpc.Server {
+	gs := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(s.unaryAuth),
+		grpc.ChainStreamInterceptor(s.streamAuth),
+	)
+	queryv1.RegisterQueryServiceServer(gs, &grpcService{s: s})
+	if s.cfg.Server.FlightEnabled {
+		registerFlight(gs, s)
+	}
+	return gs
+}
+
+func (s *Server) unaryAuth(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) streamAuth(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authenticate(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authStream{ServerStream: ss, ctx: ctx})
+}
+
+// authStream overrides the stream context with the authenticated one.
+type authStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (a *authStream) Context() context.Context {
+	return a.ctx
+}
+
+// authenticate verifies the authorization, x-tenant and x-user metadata the
+// same way identify checks HTTP headers and stores the caller in ctx.
+func (s *Server) authenticate(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	first := func(key string) string {
+		if v := md.Get(key); len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+
+	tenant, user, err := s.auth.VerifyCredentials(ctx, first("authorization"), first("x-tenant"), first("x-user"))
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if tenant == "" {
+		return nil, status.Error(codes.InvalidArgument, errTenantRequired.Error())
+	}
+	ctx = context.WithValue(ctx, auth.ContextTenantKey, tenant)
+	ctx = context.WithValue(ctx, auth.ContextUserKey, user)
+	return ctx, nil
+}
+
+// caller returns the tenant and user stored by authenticate.
+func caller(ctx context.Context) (tenant, user string) {
+	tenant, _ = ctx.Value(auth.ContextTenantKey).(string)
+	user, _ = ctx.Value(auth.ContextUserKey).(string)
+	return tenant, user
+}
+
+func (g *grpcService) Query(ctx context.Context, in *queryv1.QueryRequest) (*queryv1.QueryResponse, error) {
+	resp, err := g.s.runGRPC(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return &queryv1.QueryResponse{
+		Lang:   resp.Lang,
+		Tenant: resp.Tenant,
+		Result: resp.Result,
+		Stats:  toProtoStats(resp.Stats),
+	}, nil
+}
+
+func (g *grpcService) QueryStream(in *queryv1.QueryRequest, stream grpc.ServerStreamingServer[queryv1.QueryChunk]) error {
+	resp, err := g.s.runGRPC(stream.Context(), in)
+	if err != nil {
+		return err
+	}
+
+	rows := resultRows(resp.Lang, resp.Result)
+	for len(rows) > 0 {
+		n := min(streamBatchSize, len(rows))
+		chunk := &queryv1.QueryChunk{Rows: make([][]byte, n)}
+		for i, row := range rows[:n] {
+			chunk.Rows[i] = row
+		}
+		if err := stream.Send(chunk); err != nil {
+			return err
+		}
+		rows = rows[n:]
+	}
+	return stream.Send(&queryv1.QueryChunk{Stats: toProtoStats(resp.Stats)})
+}
+
+// Explain goes through the same admission and audit log as Query, so it
+// counts against the tenant's rate limit.
+func (g *grpcService) Explain(ctx context.Context, in *queryv1.QueryRequest) (*queryv1.ExplainResponse, error) {
+	start := time.Now()
+	tenant, user := caller(ctx)
+	req := fromProtoRequest(in)
+	logEntry := func(backend, errMsg string) {
+		entry := auditEntry(tenant, user, req, start, errMsg)
+		entry.Backend, entry.Explain = backend, true
+		g.s.auditLog.Log(entry)
+	}
+	if err := checkRequest(&req); err != nil {
+		logEntry("", err.Error())
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if qerr := g.s.admit(ctx, tenant, user, &req, start, true); qerr != nil {
+		return nil, status.Error(grpcCode(qerr.status), qerr.msg)
+	}
+
+	out := &queryv1.ExplainResponse{Lang: req.Lang, Tenant: tenant}
+	if req.Lang == "promql" {
+		if res, ok := g.s.rules.Lookup(tenant, req); ok {
+			out.Backend, out.Statement, out.Precomputed = res.Backend, req.Query, true
+			logEntry(out.Backend, "")
+			return out, nil
+		}
+		if rewritten, ok := g.s.rules.Rewrite(tenant, req); ok {
+			req, out.Rewritten = rewritten, true
+		}
+	}
+
+	plan, err := g.s.backend.Explain(ctx, tenant, req)
+	if err != nil {
+		logEntry("", err.Error())
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	out.Backend, out.Statement, out.Org, out.Table = plan.Backend, plan.Statement, plan.Org, plan.Table
+	logEntry(out.Backend, "")
+	return out, nil
+}
+
+// runGRPC executes a request through the shared query pipeline and decodes
+// the response envelope.
+func (s *Server) runGRPC(ctx context.Context, in *queryv1.QueryRequest) (query.Response, error) {
+	atomic.AddInt64(&s.activeRequests, 1)
+	defer atomic.AddInt64(&s.activeRequests, -1)
+
+	start := time.Now()
+	tenant, user := caller(ctx)
+	req := fromProtoRequest(in)
+	if err := checkRequest(&req); err != nil {
+		s.auditLog.Log(auditEntry(tenant, user, req, start, err.Error()))
+		return query.Response{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	payload, qerr := s.run(ctx, tenant, user, req, start)
+	if qerr != nil {
+		return query.Response{}, status.Error(grpcCode(qerr.status), qerr.msg)
+	}
+	var resp query.Response
+	if err := json.Unmarshal(payload, &resp); err != nil {
+		return query.Response{}, status.Error(codes.Internal, "decode response failed")
+	}
+	return resp, nil
+}
+
+func fromProtoRequest(in *queryv1.QueryRequest) query.Request {
+	req := query.Request{
+		Lang:      in.GetLang(),
+		Query:     in.GetQuery(),
+		Step:      in.GetStep(),
+		Normalize: in.GetNormalize(),
+	}
+	if in.GetStart() != nil {
+		req.Start = in.GetStart().AsTime()
+	}
+	if in.GetEnd() != nil {
+		req.End = in.GetEnd().AsTime()
+	}
+	return req
+}
+
+func toProtoStats(st query.Stats) *queryv1.Stats {
+	return &queryv1.Stats{Backend: st.Backend, Cached: st.Cached, DurationMs: st.DurationMS, Cost: st.Cost}
+}
+
+// grpcCode maps the HTTP status of a queryError to a gRPC status code.
+func grpcCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusBadGateway:
+		return codes.Unavailable
+	default:
+		return codes.Internal
+	}
+}
+
+// resultRows splits an upstream payload into rows: hits for log and trace
+// searches and series for PromQL. Payloads of any other shape are returned as
+// a single row.
+func resultRows(lang string, result json.RawMessage) []json.RawMessage {
+	var rows []json.RawMessage
+	var err error
+	if lang == "promql" {
+		var body struct {
+			Data struct {
+				Result json.RawMessage `json:"result"`
+			} `json:"data"`
+		}
+		if err = json.Unmarshal(result, &body); err == nil {
+			err = json.Unmarshal(body.Data.Result, &rows)
+		}
+	} else {
+		var body struct {
+			Hits []json.RawMessage `json:"hits"`
+		}
+		err = json.Unmarshal(result, &body)
+		rows = body.Hits
+		if err == nil && body.Hits == nil {
+			err = errors.New("no hits")
+		}
+	}
+	if err != nil {
+		if len(result) == 0 {
+			return nil
+		}
+		return []json.RawMessage{result}
+	}
+	return rows
+}
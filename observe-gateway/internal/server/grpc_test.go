@@ -0,0 +1,43 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/xscopehub/observe-gateway/internal/audit"
+	"github.com/xscopehub/observe-gateway/internal/auth"
+	"github.com/xscopehub/observe-gateway/internal/limiter"
+	queryv1 "github.com/xscopehub/observe-gateway/proto/query/v1"
+)
+
+type recordSink struct{ entries []audit.Entry }
+
+func (s *recordSink) Record(e audit.Entry) { s.entries = append(s.entries, e) }
+
+func TestExplainIsRateLimitedAndAudited(t *testing.T) {
+	sink := &recordSink{}
+	s := &Server{
+		limiter:  limiter.New(limiter.Config{Enabled: true, RequestsPerSecond: 0.001, Burst: 1}),
+		auditLog: audit.New(false, nil),
+	}
+	s.auditLog.AddSink(sink)
+	ctx := context.WithValue(context.Background(), auth.ContextTenantKey, "acme")
+	// Use up the tenant's only token.
+	if err := s.limiter.Allow(ctx, "acme"); err != nil {
+		t.Fatalf("allow: %v", err)
+	}
+
+	_, err := (&grpcService{s: s}).Explain(ctx, &queryv1.QueryRequest{Lang: "promql", Query: "up"})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted, got %v", err)
+	}
+	if len(sink.entries) != 1 {
+		t.Fatalf("expected one audit entry, got %d", len(sink.entries))
+	}
+	if e := sink.entries[0]; !e.Explain || e.Tenant != "acme" || e.Error == "" {
+		t.Fatalf("unexpected audit entry %+v", e)
+	}
+}
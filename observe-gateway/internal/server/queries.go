@@ -0,0 +1,226 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/xscopehub/observe-gateway/internal/queries"
+	"github.com/xscopehub/observe-gateway/internal/query"
+)
+
+// savedQueries is the part of queries.Store behind the history and saved
+// query endpoints.
+type savedQueries interface {
+	History(ctx context.Context, tenant, user string, limit int) ([]queries.HistoryEntry, error)
+	CreateSaved(ctx context.Context, q queries.SavedQuery) (queries.SavedQuery, error)
+	ListSaved(ctx context.Context, tenant, user string) ([]queries.SavedQuery, error)
+	GetSaved(ctx context.Context, tenant, user string, id int64) (queries.SavedQuery, error)
+	DeleteSaved(ctx context.Context, tenant, user string, id int64) error
+}
+
+func (s *Server) mountQueries(r chi.Router) {
+	r.Get("/api/queries/history", s.handleQueryHistory)
+	r.Route("/api/queries/saved", func(r chi.Router) {
+		r.Get("/", s.handleListSaved)
+		r.Post("/", s.handleCreateSaved)
+		r.Get("/{id}", s.handleGetSaved)
+		r.Delete("/{id}", s.handleDeleteSaved)
+		r.Post("/{id}/run", s.handleRunSaved)
+	})
+}
+
+func (s *Server) handleQueryHistory(w http.ResponseWriter, r *http.Request) {
+	tenant, user, ok := s.identifyOrFail(w, r)
+	if !ok {
+		return
+	}
+
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			s.writeError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	entries, err := s.queries.History(r.Context(), tenant, user, limit)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"history": entries})
+}
+
+func (s *Server) handleListSaved(w http.ResponseWriter, r *http.Request) {
+	tenant, user, ok := s.identifyOrFail(w, r)
+	if !ok {
+		return
+	}
+
+	saved, err := s.queries.ListSaved(r.Context(), tenant, user)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"saved": saved})
+}
+
+func (s *Server) handleCreateSaved(w http.ResponseWriter, r *http.Request) {
+	tenant, user, ok := s.identifyOrFail(w, r)
+	if !ok {
+		return
+	}
+
+	var body queries.SavedQuery
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+	body.Tenant, body.Owner = tenant, user
+
+	saved, err := s.queries.CreateSaved(r.Context(), body)
+	if err != nil {
+		s.writeSavedError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusCreated, saved)
+}
+
+func (s *Server) handleGetSaved(w http.ResponseWriter, r *http.Request) {
+	tenant, user, ok := s.identifyOrFail(w, r)
+	if !ok {
+		return
+	}
+	id, ok := s.savedID(w, r)
+	if !ok {
+		return
+	}
+
+	saved, err := s.queries.GetSaved(r.Context(), tenant, user, id)
+	if err != nil {
+		s.writeSavedError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, saved)
+}
+
+func (s *Server) handleDeleteSaved(w http.ResponseWriter, r *http.Request) {
+	tenant, user, ok := s.identifyOrFail(w, r)
+	if !ok {
+		return
+	}
+	id, ok := s.savedID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.queries.DeleteSaved(r.Context(), tenant, user, id); err != nil {
+		s.writeSavedError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleRunSaved substitutes the supplied variables into a saved query and
+// executes it through the same pipeline as POST /api/query.
+func (s *Server) handleRunSaved(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt64(&s.activeRequests, 1)
+	defer atomic.AddInt64(&s.activeRequests, -1)
+
+	start := time.Now()
+
+	tenant, user, ok := s.identifyOrFail(w, r)
+	if !ok {
+		return
+	}
+	id, ok := s.savedID(w, r)
+	if !ok {
+		return
+	}
+
+	var run queries.RunRequest
+	if r.ContentLength != 0 {
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&run); err != nil {
+			s.writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+			return
+		}
+	}
+
+	saved, err := s.queries.GetSaved(r.Context(), tenant, user, id)
+	if err != nil {
+		s.writeSavedError(w, err)
+		return
+	}
+
+	text, err := queries.Render(saved.Query, saved.Parameters, run.Params)
+	if err != nil {
+		s.writeSavedError(w, err)
+		return
+	}
+
+	req := query.Request{
+		Lang:  saved.Lang,
+		Query: text,
+		Start: run.Start,
+		End:   run.End,
+		Step:  saved.Step,
+	}
+	if run.Step != "" {
+		req.Step = run.Step
+	}
+	if _, err := req.StepDuration(); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid step duration")
+		s.auditLog.Log(auditEntry(tenant, user, req, start, "invalid step"))
+		return
+	}
+
+	s.execute(w, r, tenant, user, req, start)
+}
+
+// identifyOrFail resolves the caller and writes the error response on failure.
+func (s *Server) identifyOrFail(w http.ResponseWriter, r *http.Request) (tenant, user string, ok bool) {
+	tenant, user, err := s.identify(r)
+	if err != nil {
+		status := http.StatusUnauthorized
+		if errors.Is(err, errTenantRequired) {
+			status = http.StatusBadRequest
+		}
+		s.writeError(w, status, err.Error())
+		return "", "", false
+	}
+	return tenant, user, true
+}
+
+func (s *Server) savedID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		s.writeError(w, http.StatusBadRequest, "invalid saved query id")
+		return 0, false
+	}
+	return id, true
+}
+
+func (s *Server) writeSavedError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, queries.ErrNotFound):
+		s.writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, queries.ErrInvalid):
+		s.writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
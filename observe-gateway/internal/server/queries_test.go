@@ -0,0 +1,180 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/xscopehub/observe-gateway/internal/audit"
+	"github.com/xscopehub/observe-gateway/internal/limiter"
+	"github.com/xscopehub/observe-gateway/internal/queries"
+)
+
+// fakeQueries keeps saved queries in memory. Visibility follows the real
+// store: owners see their own queries and everyone in the tenant sees shared
+// ones.
+type fakeQueries struct {
+	saved   []queries.SavedQuery
+	history []queries.HistoryEntry
+	limit   int
+}
+
+func (f *fakeQueries) History(ctx context.Context, tenant, user string, limit int) ([]queries.HistoryEntry, error) {
+	f.limit = limit
+	var out []queries.HistoryEntry
+	for _, e := range f.history {
+		if e.Tenant == tenant && e.User == user {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeQueries) CreateSaved(ctx context.Context, q queries.SavedQuery) (queries.SavedQuery, error) {
+	q.ID = int64(len(f.saved) + 1)
+	f.saved = append(f.saved, q)
+	return q, nil
+}
+
+func (f *fakeQueries) ListSaved(ctx context.Context, tenant, user string) ([]queries.SavedQuery, error) {
+	var out []queries.SavedQuery
+	for _, q := range f.saved {
+		if q.Tenant == tenant && (q.Owner == user || q.Shared) {
+			out = append(out, q)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeQueries) GetSaved(ctx context.Context, tenant, user string, id int64) (queries.SavedQuery, error) {
+	for _, q := range f.saved {
+		if q.ID == id && q.Tenant == tenant && (q.Owner == user || q.Shared) {
+			return q, nil
+		}
+	}
+	return queries.SavedQuery{}, queries.ErrNotFound
+}
+
+func (f *fakeQueries) DeleteSaved(ctx context.Context, tenant, user string, id int64) error {
+	for i, q := range f.saved {
+		if q.ID == id && q.Tenant == tenant && q.Owner == user {
+			f.saved = append(f.saved[:i], f.saved[i+1:]...)
+			return nil
+		}
+	}
+	return queries.ErrNotFound
+}
+
+// newQueriesServer serves the saved query endpoints. Its rate limit is
+// already used up, so a run stops right after rendering and the audited
+// entry shows the query that would have been executed.
+func newQueriesServer(t *testing.T) (http.Handler, *fakeQueries, *recordSink) {
+	t.Helper()
+	store := &fakeQueries{}
+	sink := &recordSink{}
+	s := &Server{
+		queries:  store,
+		limiter:  limiter.New(limiter.Config{Enabled: true, RequestsPerSecond: 0.001, Burst: 1}),
+		auditLog: audit.New(false, nil),
+	}
+	s.auditLog.AddSink(sink)
+	if err := s.limiter.Allow(context.Background(), "acme"); err != nil {
+		t.Fatalf("allow: %v", err)
+	}
+	r := chi.NewRouter()
+	s.mountQueries(r)
+	return r, store, sink
+}
+
+func call(h http.Handler, method, path, user, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("X-Tenant", "acme")
+	req.Header.Set("X-User", user)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestSavedQueryEndpoints(t *testing.T) {
+	h, store, sink := newQueriesServer(t)
+
+	w := call(h, http.MethodPost, "/api/queries/saved", "alice", `{"name":"errors","lang":"promql","query":"rate(errors{service=\"${service}\"}[${window}])","parameters":[{"name":"service","required":true},{"name":"window","type":"duration","default":"5m"}]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", w.Code, w.Body)
+	}
+	if q := store.saved[0]; q.Tenant != "acme" || q.Owner != "alice" {
+		t.Fatalf("expected the caller to own the query, got %+v", q)
+	}
+	if w := call(h, http.MethodPost, "/api/queries/saved", "alice", `{"name":"x","tenant_id":"other"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected unknown fields to be rejected, got %d", w.Code)
+	}
+
+	var list struct {
+		Saved []queries.SavedQuery `json:"saved"`
+	}
+	w = call(h, http.MethodGet, "/api/queries/saved", "alice", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || len(list.Saved) != 1 {
+		t.Fatalf("list: %d %s", w.Code, w.Body)
+	}
+	if w := call(h, http.MethodGet, "/api/queries/saved/1", "bob", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("expected another user's unshared query to be hidden, got %d", w.Code)
+	}
+	if w := call(h, http.MethodGet, "/api/queries/saved/abc", "alice", ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected an invalid id to be rejected, got %d", w.Code)
+	}
+
+	w = call(h, http.MethodPost, "/api/queries/saved/1/run", "alice", `{"params":{"service":"\"} or vector(1) #"}}`)
+	if w.Code != http.StatusTooManyRequests || len(sink.entries) != 1 {
+		t.Fatalf("run: %d %s", w.Code, w.Body)
+	}
+	if got, want := sink.entries[0].Query, `rate(errors{service="\"} or vector(1) #"}[5m])`; got != want {
+		t.Fatalf("ran %q, want %q", got, want)
+	}
+	if w := call(h, http.MethodPost, "/api/queries/saved/1/run", "alice", `{"params":{"service":"api","window":"5m]) or vector(1"}}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected an invalid duration to be rejected, got %d", w.Code)
+	}
+	if w := call(h, http.MethodPost, "/api/queries/saved/1/run", "alice", `{"params":{"region":"eu"}}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected an undeclared parameter to be rejected, got %d", w.Code)
+	}
+
+	if w := call(h, http.MethodDelete, "/api/queries/saved/1", "bob", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("expected only the owner to delete, got %d", w.Code)
+	}
+	if w := call(h, http.MethodDelete, "/api/queries/saved/1", "alice", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("delete: %d", w.Code)
+	}
+	if w := call(h, http.MethodPost, "/api/queries/saved/1/run", "alice", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("expected a deleted query to be gone, got %d", w.Code)
+	}
+}
+
+func TestQueryHistoryEndpoint(t *testing.T) {
+	h, store, _ := newQueriesServer(t)
+	store.history = []queries.HistoryEntry{
+		{Tenant: "acme", User: "alice", Lang: "promql", Query: "up"},
+		{Tenant: "acme", User: "bob", Lang: "promql", Query: "down"},
+	}
+
+	w := call(h, http.MethodGet, "/api/queries/history?limit=5", "alice", "")
+	var got struct {
+		History []queries.HistoryEntry `json:"history"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || len(got.History) != 1 || got.History[0].Query != "up" || store.limit != 5 {
+		t.Fatalf("history: %d %s", w.Code, w.Body)
+	}
+	if w := call(h, http.MethodGet, "/api/queries/history?limit=-1", "alice", ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected a negative limit to be rejected, got %d", w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/queries/history", nil)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected a request without a tenant to be rejected, got %d", w.Code)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"strings"
 	"sync/atomic"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"google.golang.org/grpc"
 
 	"github.com/xscopehub/observe-gateway/internal/audit"
 	"github.com/xscopehub/observe-gateway/internal/auth"
@@ -19,7 +21,9 @@ import (
 	"github.com/xscopehub/observe-gateway/internal/cache"
 	"github.com/xscopehub/observe-gateway/internal/config"
 	"github.com/xscopehub/observe-gateway/internal/limiter"
+	"github.com/xscopehub/observe-gateway/internal/queries"
 	"github.com/xscopehub/observe-gateway/internal/query"
+	"github.com/xscopehub/observe-gateway/internal/recording"
 )
 
 // Server represents the HTTP API server.
@@ -31,12 +35,14 @@ type Server struct {
 	cache    *cache.Cache
 	limiter  *limiter.Limiter
 	auditLog *audit.Logger
+	queries  savedQueries
+	rules    *recording.Engine
 
 	activeRequests int64
 }
 
 // New constructs a server with all dependencies wired.
-func New(cfg config.Config, auth *auth.Authenticator, backend *backend.Client, cache *cache.Cache, limiter *limiter.Limiter, auditLog *audit.Logger) *Server {
+func New(cfg config.Config, auth *auth.Authenticator, backend *backend.Client, cache *cache.Cache, limiter *limiter.Limiter, auditLog *audit.Logger, queryStore *queries.Store, rules *recording.Engine) *Server {
 	s := &Server{
 		cfg:      cfg,
 		auth:     auth,
@@ -44,6 +50,10 @@ func New(cfg config.Config, auth *auth.Authenticator, backend *backend.Client, c
 		cache:    cache,
 		limiter:  limiter,
 		auditLog: auditLog,
+		rules:    rules,
+	}
+	if queryStore != nil {
+		s.queries = queryStore
 	}
 
 	r := chi.NewRouter()
@@ -53,6 +63,11 @@ func New(cfg config.Config, auth *auth.Authenticator, backend *backend.Client, c
 	r.Use(middleware.Timeout(2 * time.Minute))
 
 	r.Post("/api/query", s.handleQuery)
+	r.Get("/api/correlate", s.handleCorrelate)
+
+	if s.queries != nil {
+		s.mountQueries(r)
+	}
 
 	s.router = r
 	return s
@@ -63,7 +78,8 @@ func (s *Server) Handler() http.Handler {
 	return s.router
 }
 
-// Run starts the HTTP server until context cancellation.
+// Run starts the HTTP server, and the gRPC server when configured, until
+// context cancellation.
 func (s *Server) Run(ctx context.Context) error {
 	srv := &http.Server{
 		Addr:         s.cfg.Server.Address,
@@ -73,22 +89,43 @@ func (s *Server) Run(ctx context.Context) error {
 		IdleTimeout:  s.cfg.Server.IdleTimeout,
 	}
 
-	errCh := make(chan error, 1)
+	errCh := make(chan error, 2)
 	go func() {
 		errCh <- srv.ListenAndServe()
 	}()
 
+	var grpcSrv *grpc.Server
+	if s.cfg.Server.GRPCAddress != "" {
+		lis, err := net.Listen("tcp", s.cfg.Server.GRPCAddress)
+		if err != nil {
+			_ = srv.Close()
+			return fmt.Errorf("listen grpc: %w", err)
+		}
+		grpcSrv = s.newGRPCServer()
+		go func() {
+			errCh <- grpcSrv.Serve(lis)
+		}()
+	}
+	stopGRPC := func() {
+		if grpcSrv != nil {
+			grpcSrv.GracefulStop()
+		}
+	}
+
 	select {
 	case <-ctx.Done():
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
 		_ = srv.Shutdown(shutdownCtx)
+		stopGRPC()
 		err := <-errCh
 		if errors.Is(err, http.ErrServerClosed) {
 			return nil
 		}
 		return err
 	case err := <-errCh:
+		_ = srv.Close()
+		stopGRPC()
 		if errors.Is(err, http.ErrServerClosed) {
 			return nil
 		}
@@ -107,85 +144,118 @@ func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
 	decoder.DisallowUnknownFields()
 	if err := decoder.Decode(&req); err != nil {
 		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
-		s.auditLog.Log(audit.Entry{Tenant: "", User: "", Lang: req.Lang, Query: req.Query, Duration: time.Since(start), Error: err.Error()})
+		s.auditLog.Log(auditEntry("", "", req, start, err.Error()))
 		return
 	}
 
-	req.Lang = strings.ToLower(req.Lang)
-	if req.Query == "" {
-		s.writeError(w, http.StatusBadRequest, "query is required")
-		s.auditLog.Log(audit.Entry{Lang: req.Lang, Query: req.Query, Duration: time.Since(start), Error: "query is required"})
+	if err := checkRequest(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		s.auditLog.Log(auditEntry("", "", req, start, err.Error()))
+		return
+	}
+
+	tenant, user, err := s.identify(r)
+	if err != nil {
+		status := http.StatusUnauthorized
+		if errors.Is(err, errTenantRequired) {
+			status = http.StatusBadRequest
+		}
+		s.writeError(w, status, err.Error())
+		s.auditLog.Log(auditEntry("", "", req, start, err.Error()))
 		return
 	}
 
+	s.execute(w, r, tenant, user, req, start)
+}
+
+// checkRequest normalises the language and rejects requests without a query
+// or with an unparsable step.
+func checkRequest(req *query.Request) error {
+	req.Lang = strings.ToLower(req.Lang)
+	if req.Query == "" {
+		return errors.New("query is required")
+	}
 	if req.Step != "" {
 		if _, err := req.StepDuration(); err != nil {
-			s.writeError(w, http.StatusBadRequest, "invalid step duration")
-			s.auditLog.Log(audit.Entry{Lang: req.Lang, Query: req.Query, Duration: time.Since(start), Error: "invalid step"})
-			return
+			return errors.New("invalid step duration")
 		}
 	}
+	return nil
+}
+
+// errTenantRequired indicates the caller could not be mapped to a tenant.
+var errTenantRequired = errors.New("tenant is required")
 
-	tenant := r.Header.Get("X-Tenant")
-	user := r.Header.Get("X-User")
+// identify resolves the caller's tenant and user from the auth token or headers.
+func (s *Server) identify(r *http.Request) (tenant, user string, err error) {
+	tenant = r.Header.Get("X-Tenant")
+	user = r.Header.Get("X-User")
 	if s.auth != nil {
-		if t, u, err := s.auth.Verify(r); err == nil {
-			tenant, user = t, u
-		} else {
-			s.writeError(w, http.StatusUnauthorized, err.Error())
-			s.auditLog.Log(audit.Entry{Lang: req.Lang, Query: req.Query, Duration: time.Since(start), Error: err.Error()})
-			return
+		tenant, user, err = s.auth.Verify(r)
+		if err != nil {
+			return "", "", err
 		}
 	}
 	if tenant == "" {
-		s.writeError(w, http.StatusBadRequest, "tenant is required")
-		s.auditLog.Log(audit.Entry{Lang: req.Lang, Query: req.Query, Duration: time.Since(start), Error: "tenant missing"})
-		return
+		return "", "", errTenantRequired
 	}
+	return tenant, user, nil
+}
 
-	if err := s.validate(&req); err != nil {
-		s.writeError(w, http.StatusBadRequest, err.Error())
-		s.auditLog.Log(audit.Entry{Tenant: tenant, User: user, Lang: req.Lang, Query: req.Query, Duration: time.Since(start), Error: err.Error()})
+// execute runs an authenticated query and writes the JSON response.
+func (s *Server) execute(w http.ResponseWriter, r *http.Request, tenant, user string, req query.Request, start time.Time) {
+	payload, qerr := s.run(r.Context(), tenant, user, req, start)
+	if qerr != nil {
+		s.writeError(w, qerr.status, qerr.msg)
 		return
 	}
 
-	if s.limiter != nil {
-		if err := s.limiter.Allow(r.Context(), tenant); err != nil {
-			status := http.StatusTooManyRequests
-			if !errors.Is(err, limiter.ErrRateLimited) {
-				status = http.StatusInternalServerError
-			}
-			s.writeError(w, status, err.Error())
-			s.auditLog.Log(audit.Entry{Tenant: tenant, User: user, Lang: req.Lang, Query: req.Query, Duration: time.Since(start), Error: err.Error()})
-			return
-		}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(payload)
+}
+
+// queryError is a failed query together with the HTTP status it maps to.
+type queryError struct {
+	status int
+	msg    string
+}
+
+func (e *queryError) Error() string {
+	return e.msg
+}
+
+// run executes an authenticated query through validation, rate limiting,
+// caching and backend dispatch and records the audit entry. It returns the
+// marshalled query.Response shared by the HTTP and gRPC APIs.
+func (s *Server) run(ctx context.Context, tenant, user string, req query.Request, start time.Time) ([]byte, *queryError) {
+	if qerr := s.admit(ctx, tenant, user, &req, start, false); qerr != nil {
+		return nil, qerr
 	}
 
 	cacheKey := buildCacheKey(req, tenant)
-	if data, ok := s.cache.Get(r.Context(), cacheKey); ok {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write(data)
-
+	if data, ok := s.cache.Get(ctx, cacheKey); ok {
+		entry := auditEntry(tenant, user, req, start, "")
+		entry.Cached = true
 		var cachedResp query.Response
 		if err := json.Unmarshal(data, &cachedResp); err == nil {
-			s.auditLog.Log(audit.Entry{Tenant: tenant, User: user, Lang: req.Lang, Query: req.Query, Duration: time.Since(start), Cached: true, Backend: cachedResp.Stats.Backend, Cost: cachedResp.Stats.Cost})
+			entry.Backend, entry.Cost = cachedResp.Stats.Backend, cachedResp.Stats.Cost
 		} else {
-			s.auditLog.Log(audit.Entry{Tenant: tenant, User: user, Lang: req.Lang, Query: req.Query, Duration: time.Since(start), Cached: true, Backend: "cache"})
+			entry.Backend = "cache"
 		}
-		return
+		s.auditLog.Log(entry)
+		return data, nil
 	}
 
-	result, err := s.dispatch(r.Context(), tenant, req)
+	result, err := s.dispatch(ctx, tenant, req)
 	if err != nil {
 		status := http.StatusBadGateway
 		var unsupported *backend.UnsupportedError
 		if errors.As(err, &unsupported) {
 			status = http.StatusBadRequest
 		}
-		s.writeError(w, status, err.Error())
-		s.auditLog.Log(audit.Entry{Tenant: tenant, User: user, Lang: req.Lang, Query: req.Query, Duration: time.Since(start), Error: err.Error()})
-		return
+		s.auditLog.Log(auditEntry(tenant, user, req, start, err.Error()))
+		return nil, &queryError{status: status, msg: err.Error()}
 	}
 
 	resp := query.Response{
@@ -202,23 +272,73 @@ func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
 
 	payload, err := json.Marshal(resp)
 	if err != nil {
-		s.writeError(w, http.StatusInternalServerError, "marshal response failed")
-		s.auditLog.Log(audit.Entry{Tenant: tenant, User: user, Lang: req.Lang, Query: req.Query, Duration: time.Since(start), Error: err.Error(), Backend: result.Backend})
-		return
+		entry := auditEntry(tenant, user, req, start, err.Error())
+		entry.Backend = result.Backend
+		s.auditLog.Log(entry)
+		return nil, &queryError{status: http.StatusInternalServerError, msg: "marshal response failed"}
 	}
 
-	s.cache.Set(r.Context(), cacheKey, payload, int64(len(payload)))
+	s.cache.Set(ctx, cacheKey, payload, int64(len(payload)))
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(payload)
+	entry := auditEntry(tenant, user, req, start, "")
+	entry.Cost, entry.Backend = result.Cost, result.Backend
+	s.auditLog.Log(entry)
+	return payload, nil
+}
 
-	s.auditLog.Log(audit.Entry{Tenant: tenant, User: user, Lang: req.Lang, Query: req.Query, Duration: time.Since(start), Cost: result.Cost, Backend: result.Backend})
+// admit validates req and charges it to the tenant's rate limit. A
+// rejection is audited and returned. Queries and explains share it so that
+// neither can bypass the other's limit.
+func (s *Server) admit(ctx context.Context, tenant, user string, req *query.Request, start time.Time, explain bool) *queryError {
+	reject := func(status int, err error) *queryError {
+		entry := auditEntry(tenant, user, *req, start, err.Error())
+		entry.Explain = explain
+		s.auditLog.Log(entry)
+		return &queryError{status: status, msg: err.Error()}
+	}
+	if err := s.validate(req); err != nil {
+		return reject(http.StatusBadRequest, err)
+	}
+	if s.limiter != nil {
+		if err := s.limiter.Allow(ctx, tenant); err != nil {
+			status := http.StatusTooManyRequests
+			if !errors.Is(err, limiter.ErrRateLimited) {
+				status = http.StatusInternalServerError
+			}
+			return reject(status, err)
+		}
+	}
+	return nil
+}
+
+// auditEntry fills the fields shared by every audit record for a query.
+func auditEntry(tenant, user string, req query.Request, start time.Time, errMsg string) audit.Entry {
+	return audit.Entry{
+		Tenant:   tenant,
+		User:     user,
+		Lang:     req.Lang,
+		Query:    req.Query,
+		Start:    req.Start,
+		End:      req.End,
+		Step:     req.Step,
+		Duration: time.Since(start),
+		Error:    errMsg,
+	}
 }
 
 func (s *Server) dispatch(ctx context.Context, tenant string, req query.Request) (backend.Result, error) {
 	switch req.Lang {
 	case "promql":
+		if res, ok := s.rules.Lookup(tenant, req); ok {
+			return res, nil
+		}
+		if rewritten, ok := s.rules.Rewrite(tenant, req); ok {
+			res, err := s.backend.QueryPromQL(ctx, tenant, rewritten)
+			if err == nil {
+				res.Backend += "+recording-rule"
+			}
+			return res, err
+		}
 		return s.backend.QueryPromQL(ctx, tenant, req)
 	case "logql":
 		return s.backend.QueryLogQL(ctx, tenant, req)
@@ -253,6 +373,17 @@ func (s *Server) writeError(w http.ResponseWriter, status int, msg string) {
 	w.Write(payload)
 }
 
+func (s *Server) writeJSON(w http.ResponseWriter, status int, v any) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, "marshal response failed")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(payload)
+}
+
 func buildCacheKey(req query.Request, tenant string) string {
 	var parts []string
 	parts = append(parts, strings.ToLower(req.Lang), req.Query, tenant)
@@ -0,0 +1,4 @@
+// Package queryv1 contains the generated gRPC bindings for the gateway query API.
+package queryv1
+
+//go:generate protoc -I . --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative query.proto
@@ -0,0 +1,509 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: query.proto
+
+package queryv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type QueryRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// lang is one of promql, logql or traceql.
+	Lang  string                 `protobuf:"bytes,1,opt,name=lang,proto3" json:"lang,omitempty"`
+	Query string                 `protobuf:"bytes,2,opt,name=query,proto3" json:"query,omitempty"`
+	Start *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=start,proto3" json:"start,omitempty"`
+	End   *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=end,proto3" json:"end,omitempty"`
+	// step is a Go duration string such as "30s".
+	Step          string `protobuf:"bytes,5,opt,name=step,proto3" json:"step,omitempty"`
+	Normalize     bool   `protobuf:"varint,6,opt,name=normalize,proto3" json:"normalize,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryRequest) Reset() {
+	*x = QueryRequest{}
+	mi := &file_query_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryRequest) ProtoMessage() {}
+
+func (x *QueryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_query_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryRequest.ProtoReflect.Descriptor instead.
+func (*QueryRequest) Descriptor() ([]byte, []int) {
+	return file_query_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *QueryRequest) GetLang() string {
+	if x != nil {
+		return x.Lang
+	}
+	return ""
+}
+
+func (x *QueryRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *QueryRequest) GetStart() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Start
+	}
+	return nil
+}
+
+func (x *QueryRequest) GetEnd() *timestamppb.Timestamp {
+	if x != nil {
+		return x.End
+	}
+	return nil
+}
+
+func (x *QueryRequest) GetStep() string {
+	if x != nil {
+		return x.Step
+	}
+	return ""
+}
+
+func (x *QueryRequest) GetNormalize() bool {
+	if x != nil {
+		return x.Normalize
+	}
+	return false
+}
+
+type Stats struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Backend       string                 `protobuf:"bytes,1,opt,name=backend,proto3" json:"backend,omitempty"`
+	Cached        bool                   `protobuf:"varint,2,opt,name=cached,proto3" json:"cached,omitempty"`
+	DurationMs    int64                  `protobuf:"varint,3,opt,name=duration_ms,json=durationMs,proto3" json:"duration_ms,omitempty"`
+	Cost          int64                  `protobuf:"varint,4,opt,name=cost,proto3" json:"cost,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Stats) Reset() {
+	*x = Stats{}
+	mi := &file_query_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Stats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Stats) ProtoMessage() {}
+
+func (x *Stats) ProtoReflect() protoreflect.Message {
+	mi := &file_query_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Stats.ProtoReflect.Descriptor instead.
+func (*Stats) Descriptor() ([]byte, []int) {
+	return file_query_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Stats) GetBackend() string {
+	if x != nil {
+		return x.Backend
+	}
+	return ""
+}
+
+func (x *Stats) GetCached() bool {
+	if x != nil {
+		return x.Cached
+	}
+	return false
+}
+
+func (x *Stats) GetDurationMs() int64 {
+	if x != nil {
+		return x.DurationMs
+	}
+	return 0
+}
+
+func (x *Stats) GetCost() int64 {
+	if x != nil {
+		return x.Cost
+	}
+	return 0
+}
+
+type QueryResponse struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Lang   string                 `protobuf:"bytes,1,opt,name=lang,proto3" json:"lang,omitempty"`
+	Tenant string                 `protobuf:"bytes,2,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	// result is the upstream JSON payload, identical to the HTTP API.
+	Result        []byte `protobuf:"bytes,3,opt,name=result,proto3" json:"result,omitempty"`
+	Stats         *Stats `protobuf:"bytes,4,opt,name=stats,proto3" json:"stats,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryResponse) Reset() {
+	*x = QueryResponse{}
+	mi := &file_query_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryResponse) ProtoMessage() {}
+
+func (x *QueryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_query_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryResponse.ProtoReflect.Descriptor instead.
+func (*QueryResponse) Descriptor() ([]byte, []int) {
+	return file_query_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *QueryResponse) GetLang() string {
+	if x != nil {
+		return x.Lang
+	}
+	return ""
+}
+
+func (x *QueryResponse) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+func (x *QueryResponse) GetResult() []byte {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+func (x *QueryResponse) GetStats() *Stats {
+	if x != nil {
+		return x.Stats
+	}
+	return nil
+}
+
+type QueryChunk struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// rows holds JSON encoded log or trace hits, or PromQL series.
+	Rows [][]byte `protobuf:"bytes,1,rep,name=rows,proto3" json:"rows,omitempty"`
+	// stats is only set on the final chunk.
+	Stats         *Stats `protobuf:"bytes,2,opt,name=stats,proto3" json:"stats,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryChunk) Reset() {
+	*x = QueryChunk{}
+	mi := &file_query_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryChunk) ProtoMessage() {}
+
+func (x *QueryChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_query_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryChunk.ProtoReflect.Descriptor instead.
+func (*QueryChunk) Descriptor() ([]byte, []int) {
+	return file_query_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *QueryChunk) GetRows() [][]byte {
+	if x != nil {
+		return x.Rows
+	}
+	return nil
+}
+
+func (x *QueryChunk) GetStats() *Stats {
+	if x != nil {
+		return x.Stats
+	}
+	return nil
+}
+
+type ExplainResponse struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Lang   string                 `protobuf:"bytes,1,opt,name=lang,proto3" json:"lang,omitempty"`
+	Tenant string                 `protobuf:"bytes,2,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	// backend names the upstream API the query is sent to.
+	Backend string `protobuf:"bytes,3,opt,name=backend,proto3" json:"backend,omitempty"`
+	// statement is the translated SQL for LogQL and TraceQL, or the PromQL
+	// expression after recording rule rewriting.
+	Statement string `protobuf:"bytes,4,opt,name=statement,proto3" json:"statement,omitempty"`
+	Org       string `protobuf:"bytes,5,opt,name=org,proto3" json:"org,omitempty"`
+	Table     string `protobuf:"bytes,6,opt,name=table,proto3" json:"table,omitempty"`
+	// rewritten is true when a recording rule replaced the PromQL expression.
+	Rewritten bool `protobuf:"varint,7,opt,name=rewritten,proto3" json:"rewritten,omitempty"`
+	// precomputed is true when a cached recording rule result would be served.
+	Precomputed   bool `protobuf:"varint,8,opt,name=precomputed,proto3" json:"precomputed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExplainResponse) Reset() {
+	*x = ExplainResponse{}
+	mi := &file_query_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExplainResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExplainResponse) ProtoMessage() {}
+
+func (x *ExplainResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_query_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExplainResponse.ProtoReflect.Descriptor instead.
+func (*ExplainResponse) Descriptor() ([]byte, []int) {
+	return file_query_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ExplainResponse) GetLang() string {
+	if x != nil {
+		return x.Lang
+	}
+	return ""
+}
+
+func (x *ExplainResponse) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+func (x *ExplainResponse) GetBackend() string {
+	if x != nil {
+		return x.Backend
+	}
+	return ""
+}
+
+func (x *ExplainResponse) GetStatement() string {
+	if x != nil {
+		return x.Statement
+	}
+	return ""
+}
+
+func (x *ExplainResponse) GetOrg() string {
+	if x != nil {
+		return x.Org
+	}
+	return ""
+}
+
+func (x *ExplainResponse) GetTable() string {
+	if x != nil {
+		return x.Table
+	}
+	return ""
+}
+
+func (x *ExplainResponse) GetRewritten() bool {
+	if x != nil {
+		return x.Rewritten
+	}
+	return false
+}
+
+func (x *ExplainResponse) GetPrecomputed() bool {
+	if x != nil {
+		return x.Precomputed
+	}
+	return false
+}
+
+var File_query_proto protoreflect.FileDescriptor
+
+const file_query_proto_rawDesc = "" +
+	"\n" +
+	"\vquery.proto\x12\x1axscopehub.gateway.query.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xca\x01\n" +
+	"\fQueryRequest\x12\x12\n" +
+	"\x04lang\x18\x01 \x01(\tR\x04lang\x12\x14\n" +
+	"\x05query\x18\x02 \x01(\tR\x05query\x120\n" +
+	"\x05start\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x05start\x12,\n" +
+	"\x03end\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\x03end\x12\x12\n" +
+	"\x04step\x18\x05 \x01(\tR\x04step\x12\x1c\n" +
+	"\tnormalize\x18\x06 \x01(\bR\tnormalize\"n\n" +
+	"\x05Stats\x12\x18\n" +
+	"\abackend\x18\x01 \x01(\tR\abackend\x12\x16\n" +
+	"\x06cached\x18\x02 \x01(\bR\x06cached\x12\x1f\n" +
+	"\vduration_ms\x18\x03 \x01(\x03R\n" +
+	"durationMs\x12\x12\n" +
+	"\x04cost\x18\x04 \x01(\x03R\x04cost\"\x8c\x01\n" +
+	"\rQueryResponse\x12\x12\n" +
+	"\x04lang\x18\x01 \x01(\tR\x04lang\x12\x16\n" +
+	"\x06tenant\x18\x02 \x01(\tR\x06tenant\x12\x16\n" +
+	"\x06result\x18\x03 \x01(\fR\x06result\x127\n" +
+	"\x05stats\x18\x04 \x01(\v2!.xscopehub.gateway.query.v1.StatsR\x05stats\"Y\n" +
+	"\n" +
+	"QueryChunk\x12\x12\n" +
+	"\x04rows\x18\x01 \x03(\fR\x04rows\x127\n" +
+	"\x05stats\x18\x02 \x01(\v2!.xscopehub.gateway.query.v1.StatsR\x05stats\"\xdd\x01\n" +
+	"\x0fExplainResponse\x12\x12\n" +
+	"\x04lang\x18\x01 \x01(\tR\x04lang\x12\x16\n" +
+	"\x06tenant\x18\x02 \x01(\tR\x06tenant\x12\x18\n" +
+	"\abackend\x18\x03 \x01(\tR\abackend\x12\x1c\n" +
+	"\tstatement\x18\x04 \x01(\tR\tstatement\x12\x10\n" +
+	"\x03org\x18\x05 \x01(\tR\x03org\x12\x14\n" +
+	"\x05table\x18\x06 \x01(\tR\x05table\x12\x1c\n" +
+	"\trewritten\x18\a \x01(\bR\trewritten\x12 \n" +
+	"\vprecomputed\x18\b \x01(\bR\vprecomputed2\xb1\x02\n" +
+	"\fQueryService\x12\\\n" +
+	"\x05Query\x12(.xscopehub.gateway.query.v1.QueryRequest\x1a).xscopehub.gateway.query.v1.QueryResponse\x12a\n" +
+	"\vQueryStream\x12(.xscopehub.gateway.query.v1.QueryRequest\x1a&.xscopehub.gateway.query.v1.QueryChunk0\x01\x12`\n" +
+	"\aExplain\x12(.xscopehub.gateway.query.v1.QueryRequest\x1a+.xscopehub.gateway.query.v1.ExplainResponseB=Z;github.com/xscopehub/observe-gateway/proto/query/v1;queryv1b\x06proto3"
+
+var (
+	file_query_proto_rawDescOnce sync.Once
+	file_query_proto_rawDescData []byte
+)
+
+func file_query_proto_rawDescGZIP() []byte {
+	file_query_proto_rawDescOnce.Do(func() {
+		file_query_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_query_proto_rawDesc), len(file_query_proto_rawDesc)))
+	})
+	return file_query_proto_rawDescData
+}
+
+var file_query_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_query_proto_goTypes = []any{
+	(*QueryRequest)(nil),          // 0: xscopehub.gateway.query.v1.QueryRequest
+	(*Stats)(nil),                 // 1: xscopehub.gateway.query.v1.Stats
+	(*QueryResponse)(nil),         // 2: xscopehub.gateway.query.v1.QueryResponse
+	(*QueryChunk)(nil),            // 3: xscopehub.gateway.query.v1.QueryChunk
+	(*ExplainResponse)(nil),       // 4: xscopehub.gateway.query.v1.ExplainResponse
+	(*timestamppb.Timestamp)(nil), // 5: google.protobuf.Timestamp
+}
+var file_query_proto_depIdxs = []int32{
+	5, // 0: xscopehub.gateway.query.v1.QueryRequest.start:type_name -> google.protobuf.Timestamp
+	5, // 1: xscopehub.gateway.query.v1.QueryRequest.end:type_name -> google.protobuf.Timestamp
+	1, // 2: xscopehub.gateway.query.v1.QueryResponse.stats:type_name -> xscopehub.gateway.query.v1.Stats
+	1, // 3: xscopehub.gateway.query.v1.QueryChunk.stats:type_name -> xscopehub.gateway.query.v1.Stats
+	0, // 4: xscopehub.gateway.query.v1.QueryService.Query:input_type -> xscopehub.gateway.query.v1.QueryRequest
+	0, // 5: xscopehub.gateway.query.v1.QueryService.QueryStream:input_type -> xscopehub.gateway.query.v1.QueryRequest
+	0, // 6: xscopehub.gateway.query.v1.QueryService.Explain:input_type -> xscopehub.gateway.query.v1.QueryRequest
+	2, // 7: xscopehub.gateway.query.v1.QueryService.Query:output_type -> xscopehub.gateway.query.v1.QueryResponse
+	3, // 8: xscopehub.gateway.query.v1.QueryService.QueryStream:output_type -> xscopehub.gateway.query.v1.QueryChunk
+	4, // 9: xscopehub.gateway.query.v1.QueryService.Explain:output_type -> xscopehub.gateway.query.v1.ExplainResponse
+	7, // [7:10] is the sub-list for method output_type
+	4, // [4:7] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_query_proto_init() }
+func file_query_proto_init() {
+	if File_query_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_query_proto_rawDesc), len(file_query_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_query_proto_goTypes,
+		DependencyIndexes: file_query_proto_depIdxs,
+		MessageInfos:      file_query_proto_msgTypes,
+	}.Build()
+	File_query_proto = out.File
+	file_query_proto_goTypes = nil
+	file_query_proto_depIdxs = nil
+}